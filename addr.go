@@ -0,0 +1,74 @@
+package bitfield
+
+import (
+	"net"
+	"net/netip"
+	"reflect"
+)
+
+var (
+	ipType   = reflect.TypeOf(net.IP(nil))
+	addrType = reflect.TypeOf(netip.Addr{})
+)
+
+// isAddrType reports whether t holds an IP address, read from 32 or 128 bits
+// in network byte order.
+func isAddrType(t reflect.Type) bool {
+	return t == ipType || t == addrType
+}
+
+// validateAddrSize validates the bit size of an IP address field.
+func validateAddrSize(field reflect.StructField, bitSize int) error {
+	if bitSize != 32 && bitSize != 128 {
+		return &FieldError{
+			Field:   field,
+			problem: "address field must have bit size 32 or 128",
+		}
+	}
+	return nil
+}
+
+// addrValue returns the value of an IP address field decoded from the bytes
+// of the address.
+func addrValue(t reflect.Type, b []byte) reflect.Value {
+	if t == ipType {
+		return reflect.ValueOf(net.IP(b))
+	}
+	if len(b) == net.IPv4len {
+		return reflect.ValueOf(netip.AddrFrom4([4]byte(b)))
+	}
+	return reflect.ValueOf(netip.AddrFrom16([16]byte(b)))
+}
+
+// addrBytes returns the bytes of the address of an IP address field of
+// bitSize bits. An IPv4 address is written as an IPv4-mapped IPv6 address in
+// 128 bits, and the zero value as zeros. ok is false if an IPv6 address does
+// not fit in 32 bits.
+func addrBytes(vf reflect.Value, bitSize int) (b []byte, ok bool) {
+	if ip, isIP := vf.Interface().(net.IP); isIP {
+		if len(ip) == 0 {
+			return nil, true
+		}
+		if bitSize == 32 {
+			b = ip.To4()
+		} else {
+			b = ip.To16()
+		}
+		return b, b != nil
+	}
+	addr := vf.Interface().(netip.Addr)
+	switch {
+	case !addr.IsValid():
+		return nil, true
+	case bitSize == 32:
+		addr = addr.Unmap()
+		if !addr.Is4() {
+			return nil, false
+		}
+		b4 := addr.As4()
+		return b4[:], true
+	default:
+		b16 := addr.As16()
+		return b16[:], true
+	}
+}
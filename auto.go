@@ -0,0 +1,79 @@
+package bitfield
+
+import "reflect"
+
+// Sequence holds the counters of the fields with an auto tag, given to
+// Marshal by [WithSequence]. Each encode writes the current count of every
+// such field, by its path, and advances the counts once the struct is
+// successfully encoded, so that a failed encode does not skip a number.
+// Counts wrap around to zero past the bit size of their fields.
+//
+// A Sequence must not be used by concurrent encodes. The zero value is a
+// Sequence counting from zero.
+type Sequence struct {
+	counts map[string]uint64
+}
+
+// Reset restarts the counts of every field from zero.
+func (s *Sequence) Reset() {
+	clear(s.counts)
+}
+
+// value returns the count of the field at path, wrapped to bitSize bits.
+func (s *Sequence) value(path string, bitSize int) uint64 {
+	n := s.counts[path]
+	if bitSize < 64 {
+		n &= 1<<bitSize - 1
+	}
+	return n
+}
+
+// advance increments the counts of the fields at paths.
+func (s *Sequence) advance(paths []string) {
+	if len(paths) > 0 && s.counts == nil {
+		s.counts = make(map[string]uint64)
+	}
+	for _, path := range paths {
+		s.counts[path]++
+	}
+}
+
+// isAuto reports whether a field has an auto tag.
+func isAuto(field reflect.StructField) bool {
+	_, found := field.Tag.Lookup("auto")
+	return found
+}
+
+// validateAutoTag validates the auto tag of a field, if any.
+func validateAutoTag(field reflect.StructField) error {
+	tag, found := field.Tag.Lookup("auto")
+	if !found {
+		return nil
+	}
+	switch field.Type.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "auto tag requires unsigned integer field",
+		}
+	}
+	if tag != "increment" {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "auto must be increment",
+		}
+	}
+	for _, key := range []string{"const", "check", "varint"} {
+		if _, found := field.Tag.Lookup(key); found {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "auto tag cannot be combined with " + key + " tag",
+			}
+		}
+	}
+	return nil
+}
@@ -1,8 +1,17 @@
 package bitfield
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
 	"reflect"
 	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
 )
 
 // Unmarshal parses a byte slice and stores the result in a struct with
@@ -35,7 +44,39 @@ import (
 // The bit size must be within the range of 1 to the size of the underlying
 // integer type. For example, uint8 A `bit:"9"` is not acceptable, which causes
 // [FieldError] to be returned. Fields must be listed in order, starting from
-// the least significant bit.
+// the least significant bit, or from the most significant bit with
+// [WithBitOrder]. To capture bits that the application does not
+// interpret, declare a field of type [Raw]. Field types decoding their own
+// bits implement [BitUnmarshaler].
+//
+// Bits that the application does not use can be skipped with a placeholder
+// field named "_", or with a struct tag "skip" giving the number of bits to
+// skip before a field:
+//
+//	var out struct {
+//		Type  uint8 `bit:"4"`
+//		Flags uint8 `bit:"4" skip:"20"` // after 20 reserved bits
+//	}
+//
+// The skipped bits are zero in the output of Marshal.
+//
+// A field of an array type is parsed as its elements one after another, each
+// as a field of the element type with the tags of the array field. Thus the
+// bit tag gives the bit size of each element, and elements of plain integer
+// fields start from the next byte:
+//
+//	var out struct {
+//		Addr  [6]byte
+//		Words [4]uint16 `bit:"12"`
+//	}
+//
+// A field of type bool with a bit tag is a flag. It is true if any of its bits
+// is set. Marshal encodes true as 1 and false as 0:
+//
+//	var out struct {
+//		Ack  bool  `bit:"1"`
+//		Kind uint8 `bit:"7"`
+//	}
 //
 // This library borrows the idea of bit-fields from the C language. The
 // function [Unmarshal] is aimed to make it easy to create an instance of a
@@ -63,6 +104,41 @@ import (
 //	fmt.Printf("A=%d, B=%d, C=%#x\n", out.A, out.B, out.C)
 //	// Output: "A=6, B=0, C=0x995c4"
 //
+// A struct tag "endian" overrides the byte order for a single bit-field or
+// plain integer field, for formats mixing byte orders:
+//
+//	var out struct {
+//		Length uint16
+//		Port   uint16 `endian:"big"`
+//	}
+//
+// The bit tag can carry modifiers of the field after its bit size, separated
+// by commas: be or le overrides the byte order as the endian tag does, msb or
+// lsb the bit order, and sm makes a signed field sign-magnitude instead of
+// two's complement:
+//
+//	var out struct {
+//		Flags uint8 `bit:"3,msb"`
+//		Delta int16 `bit:"12,be,sm"`
+//	}
+//
+// A field overriding the bit order occupies bytes of its own: it starts from
+// the next byte, and the field following it does too.
+//
+// A struct tag "byteorder" makes an integer or bool field a byte order mark,
+// whose value selects the byte order of the fields following it in the same
+// struct, including the fields of nested structs, as in TIFF headers:
+//
+//	var out struct {
+//		Mark   uint16 `byteorder:"little=0x4949,big=0x4D4D"`
+//		Magic  uint16
+//		Offset uint32
+//	}
+//
+// The mark itself is parsed in the byte order in effect before it. Unmarshal
+// returns [ByteOrderError] if the value of the mark is not listed. Since the
+// byte order depends on the data, such structs are not accepted by [LayoutOf].
+//
 // The provided struct can also have plain integer fields without a bit tag. If
 // an integer field does not have a bit tag, the bit size of the field will be
 // the size of the type. The difference between bit-fields and plain integer
@@ -90,11 +166,364 @@ import (
 //	fmt.Printf("A=%#x, B=%#x\n", out.A, out.B)
 //	// Output: "A=0x5, B=0xaa"
 //
+// Plain float32 and float64 fields hold IEEE 754 numbers, and are parsed like
+// plain integer fields of the same size, in the specified byte order. Bit and
+// bytes tags are not allowed on them.
+//
+// A plain integer field can be narrowed to fewer bytes than its type with a
+// struct tag "bytes", for example for 24-bit or 48-bit values. Such a field is
+// parsed like a plain integer field of the given number of bytes, from the LSB
+// of the next byte and in the specified byte order:
+//
+//	var out struct {
+//		A uint8  `bit:"4"`
+//		B uint32 `bytes:"3"`
+//	}
+//	data := []byte{0x0F, 0x12, 0x34, 0x56}
+//	_ = bitfield.Unmarshal(data, &out, bitfield.WithByteOrder(bitfield.BigEndian))
+//	fmt.Printf("A=%#x, B=%#x\n", out.A, out.B)
+//	// Output: "A=0xf, B=0x123456"
+//
+// A field of a struct type without a bit tag is parsed as a nested struct: its
+// fields are parsed in place, continuing from the bit following the last
+// parsed bit. Nested structs can be embedded, and can contain further nested
+// structs:
+//
+//	type Header struct {
+//		Version uint8 `bit:"4"`
+//		Flags   uint8 `bit:"4"`
+//	}
+//	var out struct {
+//		Header
+//		Length uint8 `bit:"6"`
+//	}
+//
+// The fields of an unexported nested struct are parsed but not stored, except
+// for the exported fields of an embedded struct, which are promoted as in Go.
+// Errors about a field of a nested struct report its path, such as
+// "Header.Version" for a field Version of a field Header. The fields of an
+// embedded struct are named without the name of the struct.
+//
+// A field of a slice of pointers to structs with a struct tag "count" holds a
+// variable number of nested structs. The tag names an integer field declared
+// before the slice in the same struct, whose value gives the number of
+// elements:
+//
+//	type Entry struct {
+//		Kind        uint8    `bit:"4"`
+//		NumChildren uint8    `bit:"4"`
+//		Children    []*Entry `count:"NumChildren"`
+//	}
+//	var out struct {
+//		NumEntries uint8
+//		Entries    []*Entry `count:"NumEntries"`
+//	}
+//
+// Each element is allocated, by [WithElementFactory] if given, and parsed in
+// place like a nested struct. Unmarshal returns [LengthError] if the count
+// exceeds the number of elements which the rest of the input can hold, and
+// [DepthError] if elements are nested deeper than [WithMaxDepth]. Such structs
+// have no fixed layout, and are not accepted by [LayoutOf].
+//
+// A field of type []bool with a struct tag "count" holds a variable number of
+// bits, one per element, in the bit order of the struct. Unlike plain integer
+// fields, the bits are not aligned, and can be packed with other bit-fields:
+//
+//	var out struct {
+//		NumFlags uint8  `bit:"4"`
+//		Flags    []bool `count:"NumFlags"`
+//	}
+//
+// Similarly, a field of type []byte or [Raw] with a struct tag "len" holds the
+// number of bytes given by a preceding integer field. The bytes start from the
+// next byte like a plain integer field, as in type-length-value records:
+//
+//	var out struct {
+//		Type       uint8
+//		PayloadLen uint16
+//		Payload    []byte `len:"PayloadLen"`
+//	}
+//
+// The length may count units of several bytes, given after the name of the
+// length field, such as len:"Words,unit=4" for a length in 32-bit words.
+// Unmarshal returns [LengthError] if the input ends before the last byte.
+//
+// A field of type [][TLV] with a struct tag "tlv" holds a sequence of
+// type-length-value entries, such as the options following a fixed header.
+// The tag gives the unsigned integer types of the type and the length of each
+// entry, which are in the specified byte order:
+//
+//	var out struct {
+//		Version uint8
+//		Options []bitfield.TLV `tlv:"type=uint8,len=uint16"`
+//	}
+//
+// The entries start from the next byte and extend to the end of the input, so
+// the field must be the last field of its struct. Unmarshal returns
+// [LengthError] if the last entry is truncated.
+//
+// Similarly, a field of type []byte or [Raw] with a struct tag rest:"true"
+// holds the bytes from the next byte to the end of the input, such as the
+// opaque payload following a header. It must be the last field of its struct,
+// and Marshal writes it back verbatim:
+//
+//	var out struct {
+//		Type    uint8
+//		Payload []byte `rest:"true"`
+//	}
+//
+// A field of type []bool with a struct tag rest:"true" holds the bits from the
+// next bit to the end of the input, one per element, as with a count tag. The
+// unused bits of the last byte are thus decoded as false elements.
+//
+// A nested struct field, or a field of a slice of pointers to structs, with a
+// struct tag "sizefrom" is decoded from a region whose size in bytes is given
+// by a preceding integer field, so that a decoder can skip extensions of the
+// payload it does not know. As with len tags, the size may count units of
+// several bytes:
+//
+//	var out struct {
+//		Length  uint8
+//		Body    Body `sizefrom:"Length"`
+//		Words   uint8
+//		Records []*Record `sizefrom:"Words,unit=4"`
+//	}
+//
+// The region starts from the next byte. A nested struct is decoded as if the
+// input ended with the region, and the elements of a slice are decoded until
+// the region ends. The fields following continue from the end of the region,
+// however much of it was decoded. Unmarshal returns [LengthError] if the input
+// ends before the region does, and Marshal if the encoding exceeds the region,
+// which is otherwise padded with zeros.
+//
+// Instead of following the order of declaration, the fields of a struct can be
+// placed by a struct tag "offset" giving the bit offset of each field from the
+// start of the struct. This lets generated structs keep related fields
+// together regardless of their position:
+//
+//	var out struct {
+//		Flags   uint8  `bit:"4" offset:"28"`
+//		Version uint8  `bit:"4" offset:"0"`
+//		Length  uint16 `offset:"8"`
+//	}
+//
+// If a field has an offset tag, every field of the struct occupying bits must
+// have one, and the fields must not overlap. Bits between the fields are
+// skipped. The offsets of plain integer fields must be multiples of 8, and
+// such a struct starts from the next byte when nested. Nested structs, arrays
+// and variable-length fields cannot be placed by offset.
+//
+// A struct tag "enum" restricts an integer field to a set of values, such as
+// the opcodes of a protocol, given as a list or as the name of a set
+// registered by [RegisterEnumSet]. Unmarshal returns [EnumError] if the
+// decoded value is not in the set:
+//
+//	var out struct {
+//		Version uint8 `bit:"4" enum:"4,6"`
+//		Opcode  uint8 `bit:"4" enum:"opcode"`
+//	}
+//
+// A struct tag "const" fixes the value of an integer field, such as magic
+// bytes, a sync word or a protocol version. Unmarshal returns [ConstError] if
+// the decoded value differs, even for a placeholder field:
+//
+//	var out struct {
+//		_       uint16 `const:"0x4D5A"`
+//		Version uint8  `const:"2"`
+//	}
+//
+// A struct tag "auto" with the value "increment" makes an unsigned integer
+// field a counter, such as a sequence number, which Marshal fills from a
+// [Sequence] and an [Encoder] from its own. The count wraps around past the
+// bit size of the field. Unmarshal decodes the field as any other:
+//
+//	type frame struct {
+//		Seq     uint8 `bit:"4" auto:"increment"`
+//		Command uint8 `bit:"4"`
+//	}
+//
+// A struct tag "default" gives the value of an integer or bool field which is
+// not wholly contained in short input, instead of zero, so that input written
+// before fields were added to a format decodes with sensible values:
+//
+//	var out struct {
+//		Mode       uint8 `bit:"4"`
+//		Brightness uint8 `default:"255"`
+//	}
+//
+// A struct tag "if" makes a field present only under a condition on a
+// preceding integer or bool field of the same struct, optionally masked,
+// compared with ==, !=, <, <=, > or >=. A condition naming only the field
+// holds if it is not zero. An absent field occupies no bits and is set to its
+// zero value:
+//
+//	var out struct {
+//		Flags     uint8
+//		Extension uint16 `if:"Flags&0x1!=0"`
+//		Checksum  uint32 `if:"Flags&0x2"`
+//	}
+//
+// A field of a pointer to an integer or bool type, such as *uint16, is parsed
+// as the type it points to, and is set to a new value. It is left nil if the
+// field is absent, either under an if tag or beyond the end of short input,
+// so that an absent field is told apart from a zero value. Such a field must
+// not have a default tag:
+//
+//	var out struct {
+//		Flags     uint8
+//		Extension *uint16 `if:"Flags&0x1!=0"`
+//		Trailer   *uint8
+//	}
+//
+// A struct tag "presentif" makes a field present only if a bit of a preceding
+// unsigned integer field of the same struct is set, as the optional fields of
+// GRE and GTP headers. It names the field and the bit counted from the least
+// significant bit, such as presentif:"Flags.3" for if:"Flags&0x8". Marshal
+// sets the bit if the field is not zero, or not nil:
+//
+//	var out struct {
+//		Flags    uint8   `bit:"4"`
+//		Version  uint8   `bit:"4"`
+//		Checksum *uint16 `presentif:"Flags.3"`
+//		Key      uint32  `presentif:"Flags.1"`
+//	}
+//
+// A struct tag "check" makes an integer field the checksum of a range of bytes
+// from the start of its struct, computed by an algorithm registered by
+// [RegisterChecksum]. The end of the range may be omitted for the bytes up to
+// the field. Unmarshal returns [ChecksumError] if the decoded value differs:
+//
+//	var out struct {
+//		Length  uint8
+//		Payload [8]byte
+//		CRC     uint16 `check:"crc16-ccitt,0:"`
+//	}
+//
+// A field of type [time.Time] with a bit tag and a struct tag "epoch" holds a
+// timestamp, the number of units elapsed since an epoch: "unix", "ntp" (1900)
+// or "gps" (1980-01-06, without leap seconds). The unit follows the epoch, one
+// of "s" (the default), "ms", "us" and "ns", or "frac32" for seconds with a
+// 32-bit binary fraction as in 64-bit NTP timestamps. Decoded times are in
+// UTC, and Marshal returns [OverflowError] for a time before the epoch or too
+// late for the field:
+//
+//	var out struct {
+//		Created  time.Time `bit:"32" epoch:"unix"`
+//		Received time.Time `bit:"64" epoch:"ntp,frac32" endian:"big"`
+//	}
+//
+// A field of type [net.IP] or [netip.Addr] holds an IPv4 address of 32 bits or
+// an IPv6 address of 128 bits, given by its bit tag or bytes tag, in network
+// byte order. Marshal writes an IPv4 address into 128 bits as an IPv4-mapped
+// IPv6 address, returns [OverflowError] for an IPv6 address in 32 bits, and
+// writes zeros for an empty or zero address:
+//
+//	var out struct {
+//		Src netip.Addr `bit:"32"`
+//		Dst netip.Addr `bit:"32"`
+//		Via net.IP     `bytes:"16"`
+//	}
+//
+// A [16]byte field, such as a UUID type defined on it, is read byte by byte
+// as any byte array. Its struct tag "uuid" may give the order of the bytes:
+// "rfc4122" for the order of RFC 4122, as stored, or "guid" for Microsoft
+// GUIDs as in SMB and NTFS, whose first three groups are little-endian. A
+// GUID field holds the bytes in the order of RFC 4122, so that its string form
+// is the usual one:
+//
+//	var out struct {
+//		ClientGUID [16]byte `uuid:"guid"`
+//	}
+//
+// An unsigned integer wider than 64 bits is held by a field of type
+// [*big.Int] with a bit tag of any size, or by a byte array field with a bit
+// tag over 64 bits, whose bytes hold the value in big-endian, right-aligned.
+// Such fields are read in the byte order as integer fields are, and Marshal
+// returns [OverflowError] for a negative value or one too large for the
+// field. Wide fields cannot be decoded with [WithDecodeHook]:
+//
+//	var out struct {
+//		Packets *big.Int `bit:"96" endian:"big"`
+//		Octets  [16]byte `bit:"128"`
+//	}
+//
+// An integer field with a struct tag "varint" is encoded in a variable number
+// of bytes from the next byte: "leb128" for unsigned LEB128 as the varints of
+// Protocol Buffers, "zigzag" for signed integers in LEB128 of their zigzag
+// encoding, or "quic" for the variable-length integers of QUIC, whose two most
+// significant bits give the length. A varint field can give the length of a
+// variable-length field:
+//
+//	var out struct {
+//		Key     uint64 `varint:"leb128"`
+//		Length  uint32 `varint:"leb128"`
+//		Payload []byte `len:"Length"`
+//	}
+//
+// The struct tag "enc" of an unsigned integer field gives the encoding of its
+// bits: "bcd" for packed binary-coded decimal, a decimal digit in each 4 bits
+// with the most significant digit in the most significant bits. Unmarshal
+// returns [DecodeError] for a digit over 9, and Marshal returns
+// [OverflowError] for a value with too many digits. "gray" is for the
+// reflected binary Gray code of rotary encoders, in which successive values
+// differ in a single bit. The values of the const and default tags are decoded
+// values rather than bits:
+//
+//	var out struct {
+//		Seconds  uint8  `bit:"8" enc:"bcd"`
+//		Year     uint16 `enc:"bcd" endian:"big"`
+//		Position uint16 `bit:"10" enc:"gray"`
+//	}
+//
+// A float field with bit:"16" and enc:"float16" holds an IEEE 754
+// half-precision number, as packed by sensors and GPUs among bit flags.
+// Marshal rounds the value to the nearest half-precision number, halves to
+// even, and values beyond its range to infinities.
+//
+// Signed integer fields are in two's complement unless their struct tag
+// "signed" gives another representation: "zigzag" for the zigzag encoding of
+// Protocol Buffers, in which 0, -1, 1, -2 and so on are 0, 1, 2, 3, so that
+// small negative values have few significant bits, "ones" for ones'
+// complement, or "magnitude" for sign-magnitude as the sm modifier of the bit
+// tag. Negative zero is decoded as zero, and Marshal returns [OverflowError]
+// for the most negative value of the bit size, which the latter two lack:
+//
+//	var out struct {
+//		Delta   int16 `bit:"12" signed:"zigzag"`
+//		Offset  int8  `bit:"8" signed:"ones"`
+//		Reading int16 `bit:"10" signed:"magnitude"`
+//	}
+//
+// A float field with a bit tag and a struct tag "scale" holds a fixed-point
+// value in engineering units: the raw unsigned integer of the bits times the
+// scale. "signed" after the scale makes the raw integer two's complement, and
+// "offset=" adds an offset to the value. Marshal writes the raw integer
+// nearest to the value, and returns [OverflowError] if it does not fit:
+//
+//	var out struct {
+//		Temp     float64 `bit:"12" scale:"0.0625,signed"`
+//		Humidity float32 `bit:"8" scale:"0.5"`
+//		Ambient  float64 `bit:"8" scale:"1,offset=-40"`
+//	}
+//
+// A float field with a struct tag "qformat" holds a signed fixed-point number
+// in the Q format Qm.n, common in DSP firmware: m+n bits of two's complement,
+// the m integer bits including the sign bit, with n fractional bits. The bit
+// tag is implied, and the field is packed as a bit field. Marshal rounds the
+// value to the nearest representable number, halves away from zero, or as
+// "round=even" (halves to even), "round=floor" (toward negative infinity) or
+// "round=zero" (toward zero) after the format gives. For example:
+//
+//	type Filter struct {
+//		Gain float64 `qformat:"8.8"`
+//		Coef float32 `qformat:"1.15,round=even"`
+//	}
+//
 // If out is not a non-nil pointer to a struct, Unmarshal returns [TypeError].
 //
 // opts is a variadic parameter to specify how to parse the byte slice.
-// Currently, only [WithByteOrder] option is available to specify the byte
-// order for multi-byte fields.
+// For example, [WithByteOrder] specifies the byte order for multi-byte fields.
+// See the functions returning [Option] for all available options.
 //
 // Paramters:
 //
@@ -107,190 +536,1265 @@ import (
 //
 //   - nil if the byte slice is successfully parsed and stored in the struct
 //   - [FieldError] if the struct pointed by out has an invalid bit-field
+//   - [LengthError] if the length of a slice exceeds the rest of the input
+//   - [DepthError] if structs are nested deeper than the limit
+//   - [EnumError] if a field holds a value not registered by [RegisterEnum]
+//     or not given by its enum tag
+//   - [ConstError] if a field with a const tag holds another value
+//   - [ReservedBitsError] if a placeholder has a bit set with
+//     [WithReservedMustBeZero]
+//   - [VariantError] if no variant is registered by [RegisterVariant] for
+//     the discriminator of a field with a switch tag
+//   - [ChecksumError] if a field with a check tag is not the checksum of its
+//     range of bytes
+//   - an error wrapping [io.ErrUnexpectedEOF] if data is too short for the
+//     struct with [WithStrictInput]
+//   - [TrailingDataError] if data is longer than the struct with
+//     [WithStrictSize]
+//   - an error wrapping the error returned by Validate of a struct
+//     implementing [Validator]
 //   - [TypeError] if out is not a non-nil pointer to a struct
+//
+// Errors decoding a field, such as [EnumError] or io.ErrUnexpectedEOF, are
+// wrapped in [DecodeError], which locates the field in data.
 func Unmarshal(data []byte, out any, opts ...Option) error {
-	if err := validateUnmarshalType(out); err != nil {
-		return err
-	}
 	options, err := collectOptions(opts)
 	if err != nil {
 		return err
 	}
-	unmarshal(data, out, options)
-	return nil
+	if err := validateUnmarshalType(out, options); err != nil {
+		return err
+	}
+	_, err = unmarshal(data, len(data)*8, out, options)
+	return err
 }
 
-func unmarshal(data []byte, out any, options options) {
-	iData := 0
-	iBitInData := 0
-	rt := reflect.TypeOf(out).Elem()
-	byteOrder := options.byteOrder
-	for iField := 0; iField < rt.NumField(); iField++ {
-		vf := reflect.ValueOf(out).Elem().Field(iField)
-		var bitSize int
-		if tag, ok := rt.Field(iField).Tag.Lookup("bit"); ok {
-			// Already checked error
-			bitSize, _ = strconv.Atoi(tag)
-		} else if isFixedInteger(rt.Field(iField).Type.Kind()) {
-			bitSize = rt.Field(iField).Type.Bits()
-			// If the previous field is not fully read, the next plain integer
-			// field should be read from the next byte
-			if iBitInData > 0 {
-				iData++
-				iBitInData = 0
-			}
-		} else {
-			// Ignore non-integer fields
-			continue
-		}
-		var val uint64
-		val, iData, iBitInData = parseValue(data, bitSize, iData, iBitInData, byteOrder)
-
-		if rt.Field(iField).IsExported() {
-			if vf.CanUint() {
-				vf.SetUint(val)
-			} else if vf.CanInt() {
-				vf.SetInt(signed(val, bitSize))
-			}
-		}
+// UnmarshalN is like [Unmarshal] but also returns the number of bytes of data
+// occupied by the struct, so that the caller can continue parsing the data
+// following it, such as options, a payload or the next record:
+//
+//	n, err := bitfield.UnmarshalN(data, &header)
+//	if err != nil {
+//		return err
+//	}
+//	payload := data[n:]
+//
+// A struct ending in the middle of a byte occupies the whole byte. If data is
+// too short for the struct and the missing fields are zero-filled, n is
+// len(data). On error, n is the number of bytes occupied by the fields parsed
+// before the error.
+func UnmarshalN(data []byte, out any, opts ...Option) (n int, err error) {
+	options, err := collectOptions(opts)
+	if err != nil {
+		return 0, err
+	}
+	if err := validateUnmarshalType(out, options); err != nil {
+		return 0, err
 	}
+	end, err := unmarshal(data, len(data)*8, out, options)
+	return min((end+7)/8, len(data)), err
 }
 
-func parseValue(
-	data []byte,
-	bitSize, iData, iBitInData int,
-	byteOrder ByteOrder,
-) (val uint64, nextIData, nextIBitInData int) {
-	if byteOrder == LittleEndian {
-		return parseValueLittleEndian(data, bitSize, iData, iBitInData)
-	} else {
-		return parseValueBigEndian(data, bitSize, iData, iBitInData)
+// UnmarshalBits is like [Unmarshal] but only the first nbits bits of data are
+// parsed. Use it for input whose meaningful length is not a whole number of
+// bytes. Bits beyond nbits are treated in the same way as bits beyond the end
+// of data.
+//
+// The bits are counted from the least significant bit of the first byte. For
+// example, if nbits is 12, all bits of data[0] and the low 4 bits of data[1]
+// are parsed.
+//
+// UnmarshalBits returns an error if nbits is negative or greater than the
+// number of bits in data.
+func UnmarshalBits(data []byte, nbits int, out any, opts ...Option) error {
+	if nbits < 0 || nbits > len(data)*8 {
+		return fmt.Errorf("bitfield: nbits %d out of range [0, %d]", nbits, len(data)*8)
+	}
+	options, err := collectOptions(opts)
+	if err != nil {
+		return err
+	}
+	if err := validateUnmarshalType(out, options); err != nil {
+		return err
 	}
+	_, err = unmarshal(data, nbits, out, options)
+	return err
 }
 
-func parseValueLittleEndian(
-	data []byte,
-	bitSize, iData, iBitInData int,
-) (val uint64, nextIData, nextIBitInData int) {
-	i := 0
-	for i < bitSize && iData < len(data) {
-		d := uint64(data[iData])
-		for ; iBitInData < 8 && i < bitSize; iBitInData, i = iBitInData+1, i+1 {
-			val |= (((d >> iBitInData) & 1) << i)
-		}
-		if iBitInData >= 8 {
-			iData++
-			iBitInData = 0
-		}
+// UnmarshalAt is like [Unmarshal] but parses the struct from the bit offset
+// bitOffset of data, so that a struct can be decoded from any position of a
+// larger bit stream, such as after a variable-length preamble. It returns the
+// number of bits occupied by the struct, from which the caller can continue:
+//
+//	n, err := bitfield.UnmarshalAt(data, offset, &header)
+//	if err != nil {
+//		return err
+//	}
+//	offset += n
+//
+// The bit offset is counted in the bit order of the input: with [LSBFirst],
+// offset 12 starts at the bit 4 of data[1], and with [MSBFirst] at the bit 3.
+// Bits before the offset are not parsed, but [WithWordSize] still counts words
+// from the start of data. As with [UnmarshalN], the bits of zero-filled fields
+// missing from data are not counted, and on error, bitsRead is the number of
+// bits occupied by the fields parsed before the error.
+//
+// UnmarshalAt returns an error if bitOffset is negative or greater than the
+// number of bits in data.
+func UnmarshalAt(data []byte, bitOffset int, out any, opts ...Option) (bitsRead int, err error) {
+	if bitOffset < 0 || bitOffset > len(data)*8 {
+		return 0, fmt.Errorf("bitfield: bitOffset %d out of range [0, %d]", bitOffset, len(data)*8)
+	}
+	options, err := collectOptions(opts)
+	if err != nil {
+		return 0, err
+	}
+	if err := validateUnmarshalType(out, options); err != nil {
+		return 0, err
 	}
-	nextIData = iData
-	nextIBitInData = iBitInData
-	return val, nextIData, nextIBitInData
+	end, err := unmarshalAt(data, bitOffset, len(data)*8, out, options)
+	return end - bitOffset, err
 }
 
-func parseValueBigEndian(
-	data []byte,
-	bitSize, iData, iBitInData int,
-) (val uint64, nextIData, nextIBitInData int) {
-	for consumedBits := 0; consumedBits < bitSize && iData < len(data); {
-		remainedBitInThisByte := 8 - iBitInData
-		var wantBitInThisByte int
-		if (bitSize - consumedBits) < remainedBitInThisByte {
-			wantBitInThisByte = bitSize - consumedBits
-		} else {
-			wantBitInThisByte = remainedBitInThisByte
-		}
-
-		var mask byte = 0xff >> (8 - wantBitInThisByte)
-		var b byte = data[iData] >> iBitInData
-		consumedBits += wantBitInThisByte
-		val = (val << wantBitInThisByte) | uint64(b&mask)
-		iBitInData += wantBitInThisByte
-		if iBitInData >= 8 {
-			iData++
-			iBitInData = 0
-		}
+// UnmarshalAs is like [Unmarshal] but returns the parsed struct of type T
+// instead of storing it into a pointer:
+//
+//	header, err := bitfield.UnmarshalAs[Header](data)
+//
+// On error, it returns the zero value of T. T must be a struct type, otherwise
+// UnmarshalAs returns [TypeError].
+func UnmarshalAs[T any](data []byte, opts ...Option) (T, error) {
+	var out T
+	if err := Unmarshal(data, &out, opts...); err != nil {
+		var zero T
+		return zero, err
 	}
-	nextIData = iData
-	nextIBitInData = iBitInData
-	return val, nextIData, nextIBitInData
+	return out, nil
 }
 
-/**
- * Convert an unsigned integer with a specific bit length to a signed integer
- * For example, signed(val = 0b00101101, bitSize = 6) returns 0b11101101
- */
-func signed(val uint64, bitSize int) int64 {
-	msb := val >> (bitSize - 1)
-	pattern := (0 - msb) << bitSize
-	return int64(val | pattern)
+// Partial describes which fields of a struct were populated by
+// [UnmarshalPartial]. Placeholders and unexported fields are not counted, as
+// with [WithPresence].
+type Partial struct {
+	// Populated is the number of fields whose bits were all contained in
+	// the input.
+	Populated int
+	// Missing lists the names of the fields which were fully or partially
+	// zero-filled because the input was too short, in order of parsing.
+	Missing []string
 }
 
-func isFixedInteger(kind reflect.Kind) bool {
-	switch kind {
-	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return true
-	default:
-		return false
+// Complete reports whether all fields were populated from the input.
+func (p Partial) Complete() bool {
+	return len(p.Missing) == 0
+}
+
+// UnmarshalPartial is like [Unmarshal] with [WithAllowPartial], but also
+// returns which fields were populated, so that the caller can distinguish
+// fields absent from the input from fields whose value is zero:
+//
+//	partial, err := bitfield.UnmarshalPartial(data, &out)
+//	if err != nil {
+//		return err
+//	}
+//	if !partial.Complete() {
+//		log.Printf("fields %v not in input", partial.Missing)
+//	}
+//
+// On error, the result describes the fields parsed before the error.
+func UnmarshalPartial(data []byte, out any, opts ...Option) (Partial, error) {
+	var partial Partial
+	options, err := collectOptions(append(opts[:len(opts):len(opts)], WithAllowPartial()))
+	if err != nil {
+		return partial, err
+	}
+	if err := validateUnmarshalType(out, options); err != nil {
+		return partial, err
 	}
+	options.partial = &partial
+	_, err = unmarshal(data, len(data)*8, out, options)
+	return partial, err
 }
 
-func ensureNonNilPointerToStruct(v any) error {
-	errMsg := "de/encoded object must be non-nil pointer to struct"
-	rv := reflect.ValueOf(v)
-	if rv.Kind() == reflect.Invalid {
-		return &TypeError{
-			Type:    reflect.TypeOf(v),
-			problem: errMsg + " (nil passed)",
-		}
+// UnmarshalSlice parses data holding records of the same struct one after
+// another, such as a log file or a capture dump, and stores them in the slice
+// pointed by out:
+//
+//	var records []Record
+//	err := bitfield.UnmarshalSlice(data, &records)
+//
+// Each record occupies the bytes given by [Size], so a record ending in the
+// middle of a byte is followed by the next one from the next byte. By default,
+// as many complete records as data contains are parsed, and bytes following
+// them are ignored unless [WithStrictSize] is given. With [WithCount], the
+// given number of records are parsed.
+//
+// The records are stored in a new slice. The paths of the fields in errors and
+// in the names recorded by [WithPresence] start with the index of the record,
+// such as "[2].Version".
+//
+// Returns:
+//
+//   - nil if the records are successfully parsed and stored in the slice
+//   - [FieldError] if the struct has an invalid bit-field or a variable-length
+//     field
+//   - [TypeError] if out is not a non-nil pointer to a slice of structs
+//   - any other error returned by [Unmarshal] for a record
+func UnmarshalSlice(data []byte, out any, opts ...Option) error {
+	options, err := collectOptions(opts)
+	if err != nil {
+		return err
 	}
-	if rv.Kind() != reflect.Pointer {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Slice || rv.Type().Elem().Elem().Kind() != reflect.Struct {
 		return &TypeError{
-			Type:    reflect.TypeOf(v),
-			problem: errMsg + " (" + rv.Type().String() + " passed)",
+			Type:    reflect.TypeOf(out),
+			problem: "decoded object must be non-nil pointer to slice of structs",
 		}
 	}
-	if rv.IsNil() {
-		return &TypeError{
-			Type:    reflect.TypeOf(v),
-			problem: errMsg + " (nil " + rv.Type().String() + " passed)",
+	rt := rv.Type().Elem().Elem()
+	if err := validateStruct(rt, options); err != nil {
+		return err
+	}
+	if field, path, ok := variableField(rt, ""); ok {
+		return &FieldError{
+			Field:   field,
+			Path:    path,
+			problem: "variable-length field in record of UnmarshalSlice",
 		}
 	}
-	if rv.Elem().Kind() != reflect.Struct {
-		return &TypeError{
-			Type:    reflect.TypeOf(v),
-			problem: errMsg + " (" + rv.Type().String() + " passed)",
+	size := (options.wordEnd(planOf(rt).bits) + 7) / 8
+	n := options.count
+	if !options.counted {
+		n = 0
+		if size > 0 {
+			n = len(data) / size
 		}
 	}
-	return nil
-}
-
-func validateStruct(v any) error {
-	rt := reflect.TypeOf(v).Elem()
-	for i := 0; i < rt.NumField(); i++ {
-		field := rt.Field(i)
-		if err := validateField(field); err != nil {
+	records := reflect.MakeSlice(rv.Type().Elem(), n, n)
+	r := &bitReader{data: data, bitOrder: options.bitOrder, wordSize: options.wordSize}
+	for i := 0; i < n; i++ {
+		// Bits of the following records are not parsed as part of the record
+		r.iData, r.iBitInData = min(i*size, len(data)), 0
+		r.nbits = min((i+1)*size, len(data)) * 8
+		if err := r.unmarshalStruct(records.Index(i), "["+strconv.Itoa(i)+"].", true, options); err != nil {
 			return err
 		}
 	}
+	if options.strictSize && n*size < len(data) {
+		return &TrailingDataError{Type: rv.Type().Elem(), Size: n * size, Len: len(data)}
+	}
+	rv.Elem().Set(records)
 	return nil
 }
 
-func validateField(field reflect.StructField) error {
-	tag, ok := field.Tag.Lookup("bit")
-	if !ok {
-		return nil
+// unmarshal parses data into the struct pointed by out, and returns the bit
+// offset following the fields parsed.
+func unmarshal(data []byte, nbits int, out any, options options) (end int, err error) {
+	return unmarshalAt(data, 0, nbits, out, options)
+}
+
+// unmarshalAt is like unmarshal, but parses the struct from the bit offset
+// start of data.
+func unmarshalAt(data []byte, start, nbits int, out any, options options) (end int, err error) {
+	r := &bitReader{data: data, nbits: nbits, bitOrder: options.bitOrder, wordSize: options.wordSize}
+	r.iData, r.iBitInData = start/8, start%8
+	rv := reflect.ValueOf(out).Elem()
+	if options.zero {
+		rv.SetZero()
+	}
+	err = r.unmarshalStruct(rv, "", true, options)
+	end = r.iData*8 + r.iBitInData
+	if size := (options.wordEnd(end) + 7) / 8; err == nil && options.strictSize && size*8 < nbits {
+		err = &TrailingDataError{Type: rv.Type(), Size: size, Len: (nbits + 7) / 8}
 	}
+	return end, err
+}
 
-	bitSize, err := strconv.Atoi(tag)
-	if err != nil {
+// unmarshalStruct reads the fields of a struct. prefix is the path of the
+// struct followed by a dot, or empty for the top-level struct, and settable
+// reports whether the fields of the struct can be set.
+func (r *bitReader) unmarshalStruct(rv reflect.Value, prefix string, settable bool, options options) error {
+	plan := planOf(rv.Type())
+	if err := r.unmarshalFields(rv, plan, prefix, settable, options); err != nil {
+		return err
+	}
+	if plan.validator && settable {
+		return validate(rv, prefix)
+	}
+	return nil
+}
+
+// unmarshalFields reads the fields of a struct whose plan is plan.
+func (r *bitReader) unmarshalFields(rv reflect.Value, plan *structPlan, prefix string, settable bool, options options) error {
+	if plan.offsets {
+		return r.unmarshalOffsets(rv, plan, prefix, settable, options)
+	}
+	var counts []uint64 // values of the fields, kept for variable-length fields
+	if plan.variable {
+		counts = make([]uint64, len(plan.fields))
+	}
+	start := r.iData
+	var checked []checkedField // fields with check tags, verified at the end
+	for iField := range plan.fields {
+		f := &plan.fields[iField]
+		vf, exported := settableField(f.StructField, rv.Field(iField), settable, options)
+		if f.cond != nil && !f.cond.holds(counts[f.cond.field]) {
+			// An absent field is left zero
+			if exported {
+				vf.SetZero()
+			}
+			continue
+		}
+		if f.elem != nil {
+			elem := *f.elem
+			for i := 0; i < vf.Len(); i++ {
+				elem.Name = f.names[i]
+				if err := r.unmarshalField(&elem, vf.Index(i), prefix, exported, settable, options); err != nil {
+					return err
+				}
+			}
+			if f.guid && exported {
+				swapGUID(vf.Slice(0, vf.Len()).Bytes())
+			}
+			continue
+		}
+		offset := r.iData*8 + r.iBitInData
+		if f.variant {
+			if err := r.unmarshalVariant(f.StructField, vf, prefix, counts[f.count], exported, options); err != nil {
+				return decodeError(f.StructField, prefix+f.Name, offset, err)
+			}
+			continue
+		}
+		if f.sized {
+			if err := r.unmarshalSized(f.StructField, vf, prefix, counts[f.count], exported, options); err != nil {
+				return decodeError(f.StructField, prefix+f.Name, offset, err)
+			}
+			continue
+		}
+		if f.variable {
+			if err := r.unmarshalSlice(f.StructField, vf, prefix, counts[f.count], exported, options); err != nil {
+				return decodeError(f.StructField, prefix+f.Name, offset, err)
+			}
+			continue
+		}
+		if f.tlv {
+			if err := r.unmarshalTLVs(f.StructField, vf, prefix, exported, options); err != nil {
+				return decodeError(f.StructField, prefix+f.Name, offset, err)
+			}
+			continue
+		}
+		if f.rest {
+			if err := r.unmarshalRest(f.StructField, vf, prefix, exported, options); err != nil {
+				return decodeError(f.StructField, prefix+f.Name, offset, err)
+			}
+			continue
+		}
+		if f.varint {
+			if err := r.unmarshalVarint(f, vf, prefix, exported, options); err != nil {
+				return decodeError(f.StructField, prefix+f.Name, offset, err)
+			}
+			if counts != nil {
+				counts[iField] = r.last
+			}
+			continue
+		}
+		if err := r.unmarshalField(f, vf, prefix, exported, settable, options); err != nil {
+			return err
+		}
+		if f.mark {
+			if err := switchByteOrder(f.StructField, prefix, r.last, &options); err != nil {
+				return decodeError(f.StructField, prefix+f.Name, offset, err)
+			}
+		}
+		if f.check != nil {
+			end := r.iData*8 + r.iBitInData
+			checked = append(checked, checkedField{f: f, value: r.last, start: start, offset: f.fieldStart(end)})
+		}
+		if counts != nil {
+			counts[iField] = r.last
+		}
+	}
+	for _, c := range checked {
+		if err := c.verify(r, prefix); err != nil {
+			return decodeError(c.f.StructField, prefix+c.f.Name, c.offset, err)
+		}
+	}
+	return nil
+}
+
+// settableField returns a field vf of a struct and whether it can be
+// accessed, given whether the fields of the struct can be. Named unexported
+// fields are accessed through their address with SetUnexported.
+func settableField(field reflect.StructField, vf reflect.Value, settable bool, options options) (reflect.Value, bool) {
+	if !settable || field.IsExported() {
+		return vf, settable
+	}
+	if field.Name == "_" || options.unexported != SetUnexported {
+		return vf, false
+	}
+	return reflect.NewAt(vf.Type(), unsafe.Pointer(vf.UnsafeAddr())).Elem(), true
+}
+
+// unmarshalNested reads a nested struct, or an element of a slice, whose path
+// is path. It stops at the depth limit to keep from overflowing the stack.
+func (r *bitReader) unmarshalNested(rv reflect.Value, prefix, path string, settable bool, options options) error {
+	if r.depth >= options.depthLimit() {
+		return &DepthError{Path: path, MaxDepth: options.depthLimit()}
+	}
+	r.depth++
+	err := r.unmarshalStruct(rv, prefix, settable, options)
+	r.depth--
+	return err
+}
+
+// unmarshalField reads a field of a struct, or an element of an array field.
+// exported reports whether the field can be set.
+func (r *bitReader) unmarshalField(f *fieldPlan, vf reflect.Value, prefix string, exported, settable bool, options options) (err error) {
+	if f.skip > 0 {
+		r.seek(r.iData*8 + r.iBitInData + f.skip)
+	}
+	if f.nested {
+		if f.Anonymous {
+			// Exported fields of an unexported embedded struct are promoted,
+			// and can be set
+			exported = exported || settable
+		}
+		return r.unmarshalNested(vf, nestedPrefix(prefix, f.StructField), prefix+f.Name, exported, options)
+	}
+	if !f.occupies {
+		// Ignore non-integer fields
+		return nil
+	}
+	bitSize := f.bitSize
+	if f.byteAligned {
+		// If the previous field is not fully read, the next plain integer
+		// field should be read from the next byte
+		r.alignToByte()
+	}
+	offset := r.iData*8 + r.iBitInData
+	defer func() {
+		if err != nil {
+			err = decodeError(f.StructField, prefix+f.Name, offset, err)
+		}
+	}()
+	if f.modifiers.ownBitOrder {
+		bitOrder := r.bitOrder
+		r.bitOrder = f.modifiers.bitOrder
+		defer func() {
+			r.bitOrder = bitOrder
+			r.alignToByte()
+		}()
+	}
+	if options.strict && offset+bitSize > r.nbits {
+		return fmt.Errorf("bitfield: input of %d bits ends before field %s at bits %d to %d: %w",
+			r.nbits, prefix+f.Name, offset, offset+bitSize, io.ErrUnexpectedEOF)
+	}
+	if options.presence != nil && exported {
+		options.presence[prefix+f.Name] = offset+bitSize <= r.nbits
+	}
+	if options.partial != nil && exported {
+		if offset+bitSize <= r.nbits {
+			options.partial.Populated++
+		} else {
+			options.partial.Missing = append(options.partial.Missing, prefix+f.Name)
+		}
+	}
+	if f.optional {
+		if offset+bitSize > r.nbits {
+			// An optional field not wholly contained in the input is nil
+			r.seek(offset + bitSize)
+			if exported {
+				vf.SetZero()
+			}
+			return nil
+		}
+		ptr := reflect.New(f.Type)
+		if exported {
+			vf.Set(ptr)
+		}
+		vf = ptr.Elem()
+	}
+	if f.unmarshaler {
+		r.seek(offset + bitSize)
+		if options.logger != nil {
+			logField(options.logger, prefix+f.Name, offset, bitSize, r.nbits)
+		}
+		if !exported {
+			return nil
+		}
+		if err := vf.Addr().Interface().(BitUnmarshaler).UnmarshalBits(r.data, offset, bitSize); err != nil {
+			return fmt.Errorf("bitfield: UnmarshalBits failed for %s: %w", prefix+f.Name, err)
+		}
+		if options.dump != nil {
+			dumpField(options.dump, prefix+f.Name, offset, bitSize, r.nbits, "-", vf.Interface())
+		}
+		return nil
+	}
+	if f.raw {
+		raw := r.readRaw(bitSize, options.scratch)
+		if options.logger != nil {
+			logField(options.logger, prefix+f.Name, offset, bitSize, r.nbits, slog.Any("value", raw))
+		}
+		if options.dump != nil {
+			dumpField(options.dump, prefix+f.Name, offset, bitSize, r.nbits, fmt.Sprintf("%x", []byte(raw)), "-")
+		}
+		if options.reserved && f.Name == "_" && !isZero(raw) {
+			return &ReservedBitsError{Path: prefix + f.Name, Offset: offset, Bits: bitSize}
+		}
+		if exported {
+			vf.SetBytes(raw)
+		}
+		return nil
+	}
+	if f.addr {
+		addr := addrValue(f.Type, r.readRaw(bitSize, nil))
+		if options.logger != nil {
+			logField(options.logger, prefix+f.Name, offset, bitSize, r.nbits, slog.Any("value", addr.Interface()))
+		}
+		if options.dump != nil {
+			dumpField(options.dump, prefix+f.Name, offset, bitSize, r.nbits, "-", addr.Interface())
+		}
+		if exported {
+			vf.Set(addr)
+		}
+		return nil
+	}
+	byteOrder := f.byteOrder(options.byteOrder)
+	if f.wide {
+		val := r.readBig(bitSize, byteOrder)
+		if options.logger != nil {
+			logField(options.logger, prefix+f.Name, offset, bitSize, r.nbits, slog.String("value", val.String()))
+		}
+		if options.dump != nil {
+			dumpField(options.dump, prefix+f.Name, offset, bitSize, r.nbits, fmt.Sprintf("%0*b", bitSize, val), val)
+		}
+		if options.reserved && f.Name == "_" && val.Sign() != 0 {
+			return &ReservedBitsError{Path: prefix + f.Name, Offset: offset, Bits: bitSize}
+		}
+		if exported {
+			setWide(vf, val)
+		}
+		return nil
+	}
+	bits := r.readValue(bitSize, byteOrder)
+	val := bits
+	if f.modifiers.signMagnitude {
+		val = fromSignMagnitude(bits, bitSize)
+	}
+	if f.signed != "" {
+		val = f.fromSigned(bits, bitSize)
+	}
+	if f.enc != "" {
+		if val, err = f.decodeBits(bits); err != nil {
+			return err
+		}
+	}
+	if f.fallback != nil && offset+bitSize > r.nbits {
+		val = *f.fallback
+	}
+	r.last = val
+	if options.logger != nil {
+		logField(options.logger, prefix+f.Name, offset, bitSize, r.nbits, slog.Uint64("value", val), stringAttr(f.Type, val, bitSize))
+	}
+	if options.dump != nil {
+		var value any
+		if f.epoch != nil {
+			value = f.epoch.time(val)
+		} else if f.scale != nil {
+			value = f.scale.value(val, bitSize)
+		} else {
+			value = decodedValue(f.Type, val, bitSize)
+		}
+		dumpField(options.dump, prefix+f.Name, offset, bitSize, r.nbits, fmt.Sprintf("%0*b", bitSize, bits), value)
+	}
+	if options.reserved && f.Name == "_" && val != 0 {
+		return &ReservedBitsError{Path: prefix + f.Name, Offset: offset, Bits: bitSize}
+	}
+	if f.constant != nil && val != *f.constant {
+		return &ConstError{
+			Field: f.StructField,
+			Path:  prefix + f.Name,
+			Value: decodedValue(f.Type, val, bitSize),
+			Const: decodedValue(f.Type, *f.constant, bitSize),
+		}
+	}
+	if !exported {
+		return nil
+	}
+
+	if f.epoch != nil {
+		vf.Set(reflect.ValueOf(f.epoch.time(val)))
+	} else if f.scale != nil {
+		vf.SetFloat(f.scale.value(val, bitSize))
+	} else if vf.CanUint() {
+		vf.SetUint(val)
+	} else if vf.CanInt() {
+		vf.SetInt(signed(val, bitSize))
+	} else if vf.Kind() == reflect.Bool {
+		vf.SetBool(val != 0)
+	} else if vf.Kind() == reflect.Float32 {
+		vf.SetFloat(float64(math.Float32frombits(uint32(val))))
+	} else if vf.CanFloat() {
+		vf.SetFloat(math.Float64frombits(val))
+	}
+	if options.decodeHook != nil {
+		info := FieldInfo{
+			FieldLayout: FieldLayout{
+				Name:      prefix + f.Name,
+				Type:      f.Type,
+				Offset:    offset,
+				Bits:      bitSize,
+				Signed:    isSignedInteger(f.Type.Kind()),
+				ByteOrder: byteOrder,
+			},
+			Tag: f.Tag,
+		}
+		if err := options.decodeHook(info, vf, val); err != nil {
+			return fmt.Errorf("bitfield: decode hook failed for %s: %w", info.Name, err)
+		}
+	}
+	if isFixedInteger(vf.Kind()) {
+		if f.enum != nil && !f.enum[enumKey(vf)] {
+			return &EnumError{Field: f.StructField, Path: prefix + f.Name, Value: vf.Interface()}
+		}
+		return checkEnum(f.StructField, vf, prefix+f.Name)
+	}
+	return nil
+}
+
+// logField logs a decoded field with the attributes of its value if logger is
+// enabled for the debug level.
+func logField(logger *slog.Logger, name string, offset, bitSize, nbits int, value ...slog.Attr) {
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("field", name),
+		slog.Int("offset", offset),
+		slog.Int("bits", bitSize),
+	}
+	attrs = append(attrs, value...)
+	attrs = append(attrs, slog.Bool("present", offset+bitSize <= nbits))
+	logger.LogAttrs(context.Background(), slog.LevelDebug, "bitfield: decoded field", attrs...)
+}
+
+// bitReader reads bits from a byte slice, starting from the least significant
+// bit of each byte, or from the most significant bit with MSBFirst. Bits
+// beyond the valid bits of the slice are not read.
+type bitReader struct {
+	data       []byte
+	nbits      int // number of valid bits in data
+	iData      int
+	iBitInData int // number of bits already read in data[iData]
+	bitOrder   BitOrder
+	wordSize   int    // bits of the words grouping the bits, if given by WithWordSize
+	last       uint64 // value of the last integer field read
+	depth      int    // depth of the struct being read
+}
+
+func (r *bitReader) hasBits() bool {
+	return r.iData*8+r.iBitInData < r.nbits
+}
+
+func (r *bitReader) alignToByte() {
+	if r.iBitInData > 0 {
+		r.iData++
+		r.iBitInData = 0
+	}
+}
+
+func (r *bitReader) readValue(bitSize int, byteOrder ByteOrder) uint64 {
+	if r.wordSize > 8 {
+		return r.readWordValue(bitSize, byteOrder)
+	}
+	if byteOrder == LittleEndian {
+		return r.readValueLittleEndian(bitSize)
+	} else {
+		return r.readValueBigEndian(bitSize)
+	}
+}
+
+func (r *bitReader) readValueLittleEndian(bitSize int) (val uint64) {
+	for consumedBits := 0; consumedBits < bitSize && r.hasBits(); {
+		chunk, n := r.readChunk(bitSize - consumedBits)
+		val |= chunk << consumedBits
+		consumedBits += n
+	}
+	return val
+}
+
+func (r *bitReader) readValueBigEndian(bitSize int) (val uint64) {
+	for consumedBits := 0; consumedBits < bitSize && r.hasBits(); {
+		chunk, n := r.readChunk(bitSize - consumedBits)
+		val = (val << n) | chunk
+		consumedBits += n
+	}
+	return val
+}
+
+// readWordValue is readValue for data grouped in words. The bits of each word
+// are numbered within the value of the word stored in byteOrder, and the
+// earlier words hold the more significant bits in big-endian, as the earlier
+// bytes do without words.
+func (r *bitReader) readWordValue(bitSize int, byteOrder ByteOrder) (val uint64) {
+	for consumedBits := 0; consumedBits < bitSize && r.hasBits(); {
+		offset := r.iData*8 + r.iBitInData
+		inWord := offset % r.wordSize
+		n := min(r.wordSize-inWord, bitSize-consumedBits, r.nbits-offset)
+		shift := inWord
+		if r.bitOrder == MSBFirst {
+			shift = r.wordSize - inWord - n
+		}
+		chunk := r.readWord(offset-inWord, byteOrder) >> shift & (1<<n - 1)
+		if byteOrder == LittleEndian {
+			val |= chunk << consumedBits
+		} else {
+			val = (val << n) | chunk
+		}
+		consumedBits += n
+		r.seek(offset + n)
+	}
+	return val
+}
+
+// readWord returns the value of the word starting at the bit offset. Bytes
+// beyond the end of the data are zero.
+func (r *bitReader) readWord(offset int, byteOrder ByteOrder) (word uint64) {
+	nbytes := r.wordSize / 8
+	for i := 0; i < nbytes && offset/8+i < len(r.data); i++ {
+		b := uint64(r.data[offset/8+i])
+		if byteOrder == LittleEndian {
+			word |= b << (i * 8)
+		} else {
+			word |= b << ((nbytes - 1 - i) * 8)
+		}
+	}
+	return word
+}
+
+// readChunk reads up to want bits from the current byte, limited by the bits
+// remaining in the byte and the valid bits, and returns them as an unsigned
+// integer along with the number of bits read. With MSBFirst, the bit read
+// first is the most significant bit of the chunk.
+func (r *bitReader) readChunk(want int) (chunk uint64, n int) {
+	n = 8 - r.iBitInData
+	if validBits := r.nbits - r.iData*8 - r.iBitInData; validBits < n {
+		n = validBits
+	}
+	if want < n {
+		n = want
+	}
+	var mask byte = 0xff >> (8 - n)
+	var b byte
+	if r.bitOrder == MSBFirst {
+		b = r.data[r.iData] >> (8 - r.iBitInData - n)
+	} else {
+		b = r.data[r.iData] >> r.iBitInData
+	}
+	r.iBitInData += n
+	if r.iBitInData >= 8 {
+		r.iData++
+		r.iBitInData = 0
+	}
+	return uint64(b & mask), n
+}
+
+/**
+ * Convert an unsigned integer with a specific bit length to a signed integer
+ * For example, signed(val = 0b00101101, bitSize = 6) returns 0b11101101
+ */
+func signed(val uint64, bitSize int) int64 {
+	if bitSize >= 64 {
+		// All bits are already in place
+		return int64(val)
+	}
+	msb := (val >> (bitSize - 1)) & 1
+	pattern := (0 - msb) << bitSize
+	return int64(val | pattern)
+}
+
+// fromSignMagnitude returns the two's complement bits of a sign-magnitude
+// value of bitSize bits.
+func fromSignMagnitude(val uint64, bitSize int) uint64 {
+	magnitude := val &^ (1 << (bitSize - 1))
+	if magnitude == val {
+		return val
+	}
+	return -magnitude & (math.MaxUint64 >> (64 - bitSize))
+}
+
+// toSignMagnitude returns the sign-magnitude bits of a two's complement value
+// of bitSize bits. ok is false for the most negative value, which has no
+// sign-magnitude representation.
+func toSignMagnitude(val uint64, bitSize int) (uint64, bool) {
+	v := signed(val, bitSize)
+	switch {
+	case v >= 0:
+		return val, true
+	case v == -1<<(bitSize-1):
+		return 0, false
+	default:
+		return 1<<(bitSize-1) | uint64(-v), true
+	}
+}
+
+func isFixedInteger(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// isFloat reports whether a plain field of the kind is decoded as an IEEE 754
+// floating-point number.
+func isFloat(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
+
+func ensureNonNilPointerToStruct(v any) error {
+	errMsg := "de/encoded object must be non-nil pointer to struct"
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Invalid {
+		return &TypeError{
+			Type:    reflect.TypeOf(v),
+			problem: errMsg + " (nil passed)",
+		}
+	}
+	if rv.Kind() != reflect.Pointer {
+		return &TypeError{
+			Type:    reflect.TypeOf(v),
+			problem: errMsg + " (" + rv.Type().String() + " passed)",
+		}
+	}
+	if rv.IsNil() {
+		return &TypeError{
+			Type:    reflect.TypeOf(v),
+			problem: errMsg + " (nil " + rv.Type().String() + " passed)",
+		}
+	}
+	if rv.Elem().Kind() != reflect.Struct {
+		return &TypeError{
+			Type:    reflect.TypeOf(v),
+			problem: errMsg + " (" + rv.Type().String() + " passed)",
+		}
+	}
+	return nil
+}
+
+// validationKey identifies a struct type and the options affecting its
+// validation.
+type validationKey struct {
+	rt         reflect.Type
+	decodeHook bool
+	unexported UnexportedPolicy
+	encoding   bool
+	wordSize   int
+}
+
+// validated holds the validationKey of the struct types found valid, so that
+// each type is validated once for the same options.
+var validated sync.Map
+
+func validateStruct(rt reflect.Type, options options) error {
+	key := validationKey{rt, options.decodeHook != nil, options.unexported, options.encoding, options.wordSize}
+	if _, ok := validated.Load(key); ok {
+		return nil
+	}
+	if err := validateFields(rt, options, nil); err != nil {
+		return err
+	}
+	validated.Store(key, true)
+	return nil
+}
+
+// validateFields validates the fields of a struct. visiting lists the element
+// types of variable-length fields being validated.
+func validateFields(rt reflect.Type, options options, visiting []reflect.Type) error {
+	for i := 0; i < rt.NumField(); i++ {
+		field := optionalElement(rt.Field(i))
+		if isArray(field) {
+			// All elements are the same
+			field.Type = field.Type.Elem()
+			field.Anonymous = false
+		}
+		if err := validateCondition(rt, i); err != nil {
+			return err
+		}
+		if err := validateEpochTag(field); err != nil {
+			return err
+		}
+		if err := validateUUIDTag(rt.Field(i)); err != nil {
+			return err
+		}
+		if isSwitch(rt.Field(i)) {
+			if err := validateSwitch(rt, i); err != nil {
+				return err
+			}
+		} else if isSized(rt.Field(i)) {
+			if err := validateSized(rt, i, options, visiting); err != nil {
+				return err
+			}
+		} else if isTLV(rt.Field(i)) {
+			if err := validateTLV(rt, i, options); err != nil {
+				return err
+			}
+		} else if isRest(rt.Field(i)) {
+			if err := validateRest(rt, i, options); err != nil {
+				return err
+			}
+		} else if isVarint(rt.Field(i)) {
+			if err := validateVarint(rt, i); err != nil {
+				return err
+			}
+		} else if isVariable(field) {
+			if err := validateVariable(rt, i, options, visiting); err != nil {
+				return err
+			}
+		} else if isNestedStruct(field) {
+			if err := validateFields(field.Type, options, visiting); err != nil {
+				var fieldErr *FieldError
+				if errors.As(err, &fieldErr) {
+					fieldErr.Path = nestedPrefix("", field) + fieldErr.Path
+				}
+				return err
+			}
+		} else if err := validateField(field, options); err != nil {
+			var fieldErr *FieldError
+			if errors.As(err, &fieldErr) {
+				fieldErr.Path = field.Name
+			}
+			return err
+		} else if _, ok := fieldAccess(field); !ok {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "access must be rw, ro, wo or w1c",
+			}
+		} else if err := validateEndian(field); err != nil {
+			return err
+		} else if err := validateEnumTag(field); err != nil {
+			return err
+		} else if err := validateConstTag(field); err != nil {
+			return err
+		} else if err := validateDefaultTag(field); err != nil {
+			return err
+		} else if err := validateAutoTag(field); err != nil {
+			return err
+		} else if err := validateCheckTag(rt.Field(i)); err != nil {
+			return err
+		} else if err := validateEncTag(field); err != nil {
+			return err
+		} else if err := validateSignedTag(field); err != nil {
+			return err
+		} else if err := validateScaleTag(field); err != nil {
+			return err
+		} else if err := validateQFormatTag(field); err != nil {
+			return err
+		} else if err := validateOptional(rt.Field(i)); err != nil {
+			return err
+		} else if err := validateByteOrderMark(rt.Field(i)); err != nil {
+			return err
+		}
+		if err := validateSkip(rt.Field(i)); err != nil {
+			return err
+		}
+		if err := validateExported(field, options); err != nil {
+			return err
+		}
+	}
+	if hasOffsets(rt) {
+		return validateOffsets(rt)
+	}
+	return nil
+}
+
+// validateExported rejects a named unexported field occupying bits if the
+// options say so.
+func validateExported(field reflect.StructField, options options) error {
+	if options.unexported != RejectUnexported || field.IsExported() || field.Name == "_" || field.Anonymous {
+		return nil
+	}
+	if _, _, ok := fieldBitSize(field); !ok && !isNestedStruct(field) && !isVariable(field) && !isTLV(field) && !isRest(field) && !isSwitch(field) && !isSized(field) {
+		return nil
+	}
+	return &FieldError{
+		Field:   field,
+		Path:    field.Name,
+		problem: "unexported field cannot be stored",
+	}
+}
+
+// arrayElement returns a field standing for the i-th element of an array
+// field. The elements are parsed one after another as fields of the element
+// type with the tags of the array field.
+func arrayElement(field reflect.StructField, i int) reflect.StructField {
+	field.Name += "[" + strconv.Itoa(i) + "]"
+	field.Type = field.Type.Elem()
+	field.Anonymous = false
+	return field
+}
+
+// nestedPrefix returns the prefix of the paths of the fields of a nested
+// struct. An embedded struct adds nothing to the paths, as its fields are
+// promoted.
+func nestedPrefix(prefix string, field reflect.StructField) string {
+	if field.Anonymous {
+		return prefix
+	}
+	return prefix + field.Name + "."
+}
+
+// isNestedStruct reports whether the field is a struct whose fields are parsed
+// in place of the field.
+func isNestedStruct(field reflect.StructField) bool {
+	if field.Type.Kind() != reflect.Struct || field.Type == addrType {
+		return false
+	}
+	_, hasBit := field.Tag.Lookup("bit")
+	_, hasBytes := field.Tag.Lookup("bytes")
+	return !hasBit && !hasBytes
+}
+
+// fieldBitSize returns the bit size of a validated field, and whether the
+// field is read from the next byte like a plain integer field. ok is false if
+// the field is ignored.
+func fieldBitSize(field reflect.StructField) (bitSize int, byteAligned, ok bool) {
+	field = optionalElement(field)
+	if isVarint(field) {
+		// The shortest encoding
+		return 8, true, true
+	}
+	if _, bitSize, _ := qformatTag(field); bitSize > 0 {
+		return bitSize, false, true
+	}
+	if tag, ok := field.Tag.Lookup("bit"); ok {
+		bitSize, modifiers, _ := bitTag(tag)
+		return bitSize, modifiers.ownBitOrder, true
+	}
+	if tag, ok := field.Tag.Lookup("bytes"); ok {
+		byteSize, _ := strconv.Atoi(tag)
+		return byteSize * 8, true, true
+	}
+	if isFixedInteger(field.Type.Kind()) || isFloat(field.Type.Kind()) {
+		return field.Type.Bits(), true, true
+	}
+	return 0, false, false
+}
+
+// encodedBitSize returns the number of bits occupied by the fields of a
+// validated struct type.
+func encodedBitSize(rt reflect.Type) int {
+	return structEnd(rt, 0)
+}
+
+// structEnd returns the offset following the fields of a struct starting at
+// offset.
+func structEnd(rt reflect.Type, offset int) int {
+	if hasOffsets(rt) {
+		return offsetsEnd(rt, offset)
+	}
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if isArray(field) {
+			for j := 0; j < field.Type.Len(); j++ {
+				offset = fieldEnd(arrayElement(field, j), offset)
+			}
+			continue
+		}
+		offset = fieldEnd(field, offset)
+	}
+	return offset
+}
+
+// fieldEnd returns the offset following a field starting at offset.
+func fieldEnd(field reflect.StructField, offset int) int {
+	offset += fieldSkip(field)
+	if isNestedStruct(field) {
+		return structEnd(field.Type, offset)
+	}
+	bitSize, byteAligned, ok := fieldBitSize(field)
+	if !ok {
+		return offset
+	}
+	if byteAligned {
+		offset = (offset + 7) / 8 * 8
+	}
+	if fieldModifiers(field).ownBitOrder {
+		// The following field starts from the next byte
+		return (offset + bitSize + 7) / 8 * 8
+	}
+	return offset + bitSize
+}
+
+// bitModifiers are the modifiers of a field following its size in the bit
+// tag, such as bit:"4,be,msb".
+type bitModifiers struct {
+	endian        string // "little" or "big" given by le or be, or empty
+	bitOrder      BitOrder
+	ownBitOrder   bool // whether bitOrder is given by msb or lsb
+	signMagnitude bool // given by sm
+}
+
+// bitTag parses a bit tag into the bit size and the modifiers of the field.
+// problem describes an invalid modifier.
+func bitTag(tag string) (bitSize int, modifiers bitModifiers, problem string) {
+	size, rest, found := strings.Cut(tag, ",")
+	bitSize, err := strconv.Atoi(size)
+	if err != nil {
+		return 0, modifiers, "bit size must be integer"
+	}
+	if !found {
+		return bitSize, modifiers, ""
+	}
+	var endian, bitOrder bool
+	for _, m := range strings.Split(rest, ",") {
+		switch m {
+		case "le", "be":
+			if endian {
+				return bitSize, modifiers, "bit tag must have at most one byte order modifier"
+			}
+			endian = true
+			modifiers.endian = map[string]string{"le": "little", "be": "big"}[m]
+		case "lsb", "msb":
+			if bitOrder {
+				return bitSize, modifiers, "bit tag must have at most one bit order modifier"
+			}
+			bitOrder = true
+			modifiers.ownBitOrder = true
+			if m == "msb" {
+				modifiers.bitOrder = MSBFirst
+			}
+		case "sm":
+			modifiers.signMagnitude = true
+		default:
+			return bitSize, modifiers, "bit tag modifier must be le, be, lsb, msb or sm"
+		}
+	}
+	return bitSize, modifiers, ""
+}
+
+// fieldModifiers returns the modifiers of the bit tag of a validated field.
+func fieldModifiers(field reflect.StructField) bitModifiers {
+	_, modifiers, _ := bitTag(field.Tag.Get("bit"))
+	return modifiers
+}
+
+// fieldEndian returns the byte order given by the endian tag of a validated
+// field or by a modifier of its bit tag, "little" or "big", or empty if the
+// field has neither.
+func fieldEndian(field reflect.StructField) string {
+	if endian, ok := field.Tag.Lookup("endian"); ok {
+		return endian
+	}
+	return fieldModifiers(field).endian
+}
+
+func validateField(field reflect.StructField, options options) error {
+	if tag, ok := field.Tag.Lookup("bytes"); ok {
+		return validateBytesField(field, tag)
+	}
+	tag, ok := field.Tag.Lookup("bit")
+	if !ok {
+		if field.Type == rawType {
+			return &FieldError{
+				Field:   field,
+				problem: "raw field must have bit size",
+			}
+		}
+		if isAddrType(field.Type) {
+			return validateAddrSize(field, 0)
+		}
+		if field.Type == bigIntType {
+			return &FieldError{
+				Field:   field,
+				problem: "big.Int field must have bit size",
+			}
+		}
+		return nil
+	}
+
+	bitSize, modifiers, problem := bitTag(tag)
+	if problem != "" {
 		return &FieldError{
 			Field:   field,
-			problem: "bit size must be integer",
+			problem: problem,
 		}
 	}
+	if err := validateModifiers(field, modifiers, options); err != nil {
+		return err
+	}
+	if field.Type == rawType {
+		if bitSize < 1 {
+			return &FieldError{
+				Field:   field,
+				problem: "bit size must be positive",
+			}
+		}
+		return nil
+	}
+	if isAddrType(field.Type) {
+		return validateAddrSize(field, bitSize)
+	}
+	if isWide(field) {
+		return validateWideSize(field, bitSize, options)
+	}
+	if field.Type.Kind() == reflect.Bool || field.Type == timeType {
+		if !(1 <= bitSize && bitSize <= 64) {
+			return &FieldError{
+				Field:   field,
+				problem: "bit size must be within range 1 to 64",
+			}
+		}
+		return nil
+	}
+	if _, scaled := field.Tag.Lookup("scale"); (scaled || field.Tag.Get("enc") == "float16") && isFloat(field.Type.Kind()) {
+		if !(1 <= bitSize && bitSize <= 64) {
+			return &FieldError{
+				Field:   field,
+				problem: "bit size must be within range 1 to 64",
+			}
+		}
+		return nil
+	}
 	if !isFixedInteger(field.Type.Kind()) {
+		if options.encoding && isBitMarshaler(field.Type) {
+			if !(1 <= bitSize && bitSize <= 64) {
+				return &FieldError{
+					Field:   field,
+					problem: "bit size must be within range 1 to 64",
+				}
+			}
+			return nil
+		}
+		if isBitUnmarshaler(field.Type) {
+			if options.encoding {
+				return &FieldError{
+					Field:   field,
+					problem: "field decoded by BitUnmarshaler cannot be encoded without BitMarshaler",
+				}
+			}
+			if bitSize < 1 {
+				return &FieldError{
+					Field:   field,
+					problem: "bit size must be positive",
+				}
+			}
+			return nil
+		}
+		if options.decodeHook != nil {
+			// The decode hook sets the field
+			if !(1 <= bitSize && bitSize <= 64) {
+				return &FieldError{
+					Field:   field,
+					problem: "bit size must be within range 1 to 64",
+				}
+			}
+			return nil
+		}
 		return &FieldError{
 			Field:   field,
 			problem: "bit field must be fixed-size integer type",
@@ -305,9 +1809,218 @@ func validateField(field reflect.StructField) error {
 	return nil
 }
 
-func validateUnmarshalType(v any) error {
+// validateSkip validates the skip tag of a field, which gives the number of
+// bits skipped before the field.
+func validateSkip(field reflect.StructField) error {
+	tag, ok := field.Tag.Lookup("skip")
+	if !ok {
+		return nil
+	}
+	if field.Type.Kind() == reflect.Array || isVariable(field) {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "skip tag is not supported for array or variable-length field",
+		}
+	}
+	if skip, err := strconv.Atoi(tag); err != nil || skip < 1 {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "skip must be positive integer",
+		}
+	}
+	return nil
+}
+
+// fieldSkip returns the number of bits skipped before a validated field.
+func fieldSkip(field reflect.StructField) int {
+	tag, ok := field.Tag.Lookup("skip")
+	if !ok {
+		return 0
+	}
+	skip, _ := strconv.Atoi(tag)
+	return skip
+}
+
+// validateModifiers validates the modifiers of the bit tag of a field.
+func validateModifiers(field reflect.StructField, modifiers bitModifiers, options options) error {
+	_, hasEndian := field.Tag.Lookup("endian")
+	switch {
+	case modifiers.endian != "" && hasEndian:
+		return &FieldError{
+			Field:   field,
+			problem: "endian tag and byte order modifier must not be used together",
+		}
+	case modifiers.endian != "" && field.Type == rawType:
+		return &FieldError{
+			Field:   field,
+			problem: "byte order does not apply to raw field",
+		}
+	case modifiers.endian != "" && isAddrType(field.Type):
+		return &FieldError{
+			Field:   field,
+			problem: "byte order does not apply to address field",
+		}
+	case modifiers.ownBitOrder && options.wordSize > 8:
+		return &FieldError{
+			Field:   field,
+			problem: "bit order modifier does not apply with words larger than a byte",
+		}
+	case modifiers.signMagnitude && !isSignedInteger(field.Type.Kind()):
+		return &FieldError{
+			Field:   field,
+			problem: "sm modifier requires signed integer field",
+		}
+	}
+	return nil
+}
+
+// validateEndian validates the byte order tag of a field.
+func validateEndian(field reflect.StructField) error {
+	tag, ok := field.Tag.Lookup("endian")
+	if !ok {
+		return nil
+	}
+	if tag != "little" && tag != "big" {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "endian must be little or big",
+		}
+	}
+	if field.Type == rawType {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "byte order does not apply to raw field",
+		}
+	}
+	if isAddrType(field.Type) {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "byte order does not apply to address field",
+		}
+	}
+	return nil
+}
+
+// validateByteOrderMark validates the byteorder tag of a field, which lists
+// the values selecting each byte order, such as "little=0,big=1".
+func validateByteOrderMark(field reflect.StructField) error {
+	tag, ok := field.Tag.Lookup("byteorder")
+	if !ok {
+		return nil
+	}
+	if field.Type.Kind() == reflect.Array || !isCountType(field) && field.Type.Kind() != reflect.Bool {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "byte order mark must be integer or bool field",
+		}
+	}
+	for _, entry := range strings.Split(tag, ",") {
+		order, value, _ := strings.Cut(entry, "=")
+		if _, err := strconv.ParseUint(value, 0, 64); err != nil || order != "little" && order != "big" {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "byteorder must list little=N or big=N",
+			}
+		}
+	}
+	return nil
+}
+
+// switchByteOrder sets the byte order of options for the fields following a
+// validated field if the field is a byte order mark, whose value is val.
+func switchByteOrder(field reflect.StructField, prefix string, val uint64, options *options) error {
+	tag, ok := field.Tag.Lookup("byteorder")
+	if !ok {
+		return nil
+	}
+	for _, entry := range strings.Split(tag, ",") {
+		order, value, _ := strings.Cut(entry, "=")
+		if v, _ := strconv.ParseUint(value, 0, 64); v == val {
+			options.byteOrder = LittleEndian
+			if order == "big" {
+				options.byteOrder = BigEndian
+			}
+			return nil
+		}
+	}
+	return &ByteOrderError{Field: field, Path: prefix + field.Name, Value: val}
+}
+
+// byteOrderMark returns the first byte order mark of a validated struct type,
+// including the fields of nested structs, and its path.
+func byteOrderMark(rt reflect.Type, prefix string) (reflect.StructField, string, bool) {
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if isArray(field) {
+			field.Type = field.Type.Elem()
+		}
+		if _, ok := field.Tag.Lookup("byteorder"); ok {
+			return field, prefix + field.Name, true
+		}
+		if isNestedStruct(field) {
+			if f, path, ok := byteOrderMark(field.Type, nestedPrefix(prefix, field)); ok {
+				return f, path, true
+			}
+		}
+	}
+	return reflect.StructField{}, "", false
+}
+
+// fieldByteOrder returns the byte order of a validated field, which is given
+// by its endian tag or bit tag if any, or byteOrder otherwise.
+func fieldByteOrder(field reflect.StructField, byteOrder ByteOrder) ByteOrder {
+	switch fieldEndian(field) {
+	case "little":
+		return LittleEndian
+	case "big":
+		return BigEndian
+	default:
+		return byteOrder
+	}
+}
+
+func validateBytesField(field reflect.StructField, tag string) error {
+	if _, ok := field.Tag.Lookup("bit"); ok {
+		return &FieldError{
+			Field:   field,
+			problem: "bit and bytes tags must not be used together",
+		}
+	}
+	byteSize, err := strconv.Atoi(tag)
+	if err != nil {
+		return &FieldError{
+			Field:   field,
+			problem: "byte size must be integer",
+		}
+	}
+	if isAddrType(field.Type) {
+		return validateAddrSize(field, byteSize*8)
+	}
+	if !isFixedInteger(field.Type.Kind()) {
+		return &FieldError{
+			Field:   field,
+			problem: "bytes field must be fixed-size integer type",
+		}
+	}
+	if !(1 <= byteSize && byteSize <= field.Type.Bits()/8) {
+		return &FieldError{
+			Field:   field,
+			problem: "byte size must be within range 1 to its type size in bytes",
+		}
+	}
+	return nil
+}
+
+func validateUnmarshalType(v any, options options) error {
 	if err := ensureNonNilPointerToStruct(v); err != nil {
 		return err
 	}
-	return validateStruct(v)
+	return validateStruct(reflect.TypeOf(v).Elem(), options)
 }
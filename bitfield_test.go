@@ -1,7 +1,20 @@
 package bitfield
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/big"
+	"net"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -366,3 +379,4346 @@ func TestUnmarshal_BigEndian2byte_split(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, want, got)
 }
+
+func TestUnmarshal_Raw(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8 `bit:"4"`
+		B Raw   `bit:"12"`
+		C uint8
+		D Raw `bit:"3"`
+		_ Raw `bit:"5"`
+	}
+	inputData := []byte{0x5A, 0xA5, 0x12, 0xFF}
+	want := a{A: 0xA, B: Raw{0x55, 0x0A}, C: 0x12, D: Raw{0x07}}
+
+	testCases := map[string]ByteOrder{
+		"LittleEndian": LittleEndian,
+		"BigEndian":    BigEndian,
+	}
+	for name, order := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got a
+			err := Unmarshal(inputData, &got, WithByteOrder(order))
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestUnmarshal_RawError(t *testing.T) {
+	// Setup
+	var noTag struct {
+		A Raw
+	}
+	var sizeZero struct {
+		A Raw `bit:"0"`
+	}
+	testCases := map[string]any{
+		"No tag":    &noTag,
+		"Size zero": &sizeZero,
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x00}, out)
+
+			// Verify
+			var fieldError *FieldError
+			assert.ErrorAs(t, err, &fieldError)
+		})
+	}
+}
+
+func TestUnmarshalAs(t *testing.T) {
+	// Setup
+	type s struct {
+		A uint8 `bit:"4"`
+		B uint8 `bit:"4"`
+		C uint16
+	}
+	data := []byte{0x21, 0x34, 0x12}
+
+	// Exercise
+	got, err := UnmarshalAs[s](data)
+	gotBig, errBig := UnmarshalAs[s](data, WithByteOrder(BigEndian))
+	gotInvalid, errInvalid := UnmarshalAs[struct {
+		A uint8 `bit:"9"`
+	}](data)
+	_, errType := UnmarshalAs[int](data)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, s{A: 1, B: 2, C: 0x1234}, got)
+	assert.Nil(t, errBig)
+	assert.Equal(t, s{A: 1, B: 2, C: 0x3412}, gotBig)
+	assertFieldError("A")(t, errInvalid)
+	assert.Zero(t, gotInvalid)
+	var typeError *TypeError
+	assert.ErrorAs(t, errType, &typeError)
+}
+
+func TestUnmarshalWithPlan(t *testing.T) {
+	// Setup
+	type s struct {
+		A uint8 `bit:"4"`
+		B uint8 `bit:"4"`
+		C uint16
+	}
+	data := []byte{0x21, 0x34, 0x12}
+	plan, errPlan := PlanOf((*s)(nil), WithByteOrder(BigEndian))
+	_, errInvalid := PlanOf(struct {
+		A uint8 `bit:"9"`
+	}{})
+	_, errType := PlanOf(1)
+
+	// Exercise
+	var got s
+	err := UnmarshalWithPlan(plan, data, &got)
+	var other struct{ A uint8 }
+	errOther := UnmarshalWithPlan(plan, data, &other)
+	errNil := UnmarshalWithPlan(plan, data, (*s)(nil))
+
+	// Verify
+	assert.Nil(t, errPlan)
+	assert.Nil(t, err)
+	assert.Equal(t, s{A: 1, B: 2, C: 0x3412}, got)
+	assertFieldError("A")(t, errInvalid)
+	var typeError *TypeError
+	assert.ErrorAs(t, errType, &typeError)
+	assert.ErrorAs(t, errOther, &typeError)
+	assert.ErrorAs(t, errNil, &typeError)
+	assert.Panics(t, func() {
+		MustPlanOf(struct {
+			A uint8 `bit:"9"`
+		}{})
+	})
+}
+
+func TestCompile(t *testing.T) {
+	// Setup
+	type s struct {
+		A uint8 `bit:"4"`
+		B uint8 `bit:"4"`
+		C uint16
+	}
+	type v struct {
+		N uint8
+		D []byte `len:"N"`
+	}
+	data := []byte{0x21, 0x34, 0x12}
+	codec, errCodec := Compile(reflect.TypeOf(s{}), WithByteOrder(BigEndian))
+	variable, errVariable := Compile(reflect.TypeOf((*v)(nil)))
+	_, errInvalid := Compile(reflect.TypeOf(struct {
+		A uint8 `bit:"9"`
+	}{}))
+	_, errType := Compile(reflect.TypeOf(1))
+
+	// Exercise
+	var got s
+	err := codec.Unmarshal(data, &got)
+	encoded, errMarshal := codec.Marshal(&got)
+	appended, errAppend := codec.MarshalAppend([]byte{0xFF}, got)
+	size, errSize := codec.Size(nil)
+	variableSize, errVariableSize := variable.Size(v{N: 2, D: []byte{1, 2}})
+	_, errOther := codec.Marshal(v{})
+	errNil := codec.Unmarshal(data, (*s)(nil))
+
+	// Verify
+	assert.Nil(t, errCodec)
+	assert.Nil(t, errVariable)
+	assert.Equal(t, reflect.TypeOf(s{}), codec.Type())
+	assert.Nil(t, err)
+	assert.Equal(t, s{A: 1, B: 2, C: 0x3412}, got)
+	assert.Nil(t, errMarshal)
+	assert.Equal(t, data, encoded)
+	assert.Nil(t, errAppend)
+	assert.Equal(t, append([]byte{0xFF}, data...), appended)
+	assert.Nil(t, errSize)
+	assert.Equal(t, 3, size)
+	assert.Nil(t, errVariableSize)
+	assert.Equal(t, 3, variableSize)
+	assertFieldError("A")(t, errInvalid)
+	var typeError *TypeError
+	assert.ErrorAs(t, errType, &typeError)
+	assert.ErrorAs(t, errOther, &typeError)
+	assert.ErrorAs(t, errNil, &typeError)
+	assert.Panics(t, func() {
+		MustCompile(reflect.TypeOf(1))
+	})
+}
+
+func TestUnmarshalSlice(t *testing.T) {
+	// Setup
+	type record struct {
+		A uint8 `bit:"4"`
+		B uint8 `bit:"8"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOpts []Option
+		want    []record
+	}{
+		"Complete": {
+			argData: []byte{0x21, 0x03, 0x54, 0x06},
+			want:    []record{{A: 1, B: 0x32}, {A: 4, B: 0x65}},
+		},
+		"TrailingData": {
+			argData: []byte{0x21, 0x03, 0x54},
+			want:    []record{{A: 1, B: 0x32}},
+		},
+		"Empty": {
+			argData: []byte{},
+			want:    []record{},
+		},
+		"Count": {
+			argData: []byte{0x21, 0x03, 0x54, 0x06},
+			argOpts: []Option{WithCount(1)},
+			want:    []record{{A: 1, B: 0x32}},
+		},
+		"CountBeyondData": {
+			argData: []byte{0x21, 0x03, 0x54},
+			argOpts: []Option{WithCount(3)},
+			want:    []record{{A: 1, B: 0x32}, {A: 4, B: 0x05}, {}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got []record
+			err := UnmarshalSlice(tc.argData, &got, tc.argOpts...)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshalSliceError(t *testing.T) {
+	// Setup
+	type record struct {
+		A uint8 `bit:"4"`
+		B uint8 `bit:"8"`
+	}
+	data := []byte{0x21, 0x03, 0x54}
+	var invalid []struct {
+		A uint8 `bit:"9"`
+	}
+	var variable []struct {
+		N uint8
+		B []byte `len:"N"`
+	}
+	var notSlice record
+	var records []record
+
+	// Exercise
+	errInvalid := UnmarshalSlice(data, &invalid)
+	errVariable := UnmarshalSlice(data, &variable)
+	errNotSlice := UnmarshalSlice(data, &notSlice)
+	errNil := UnmarshalSlice(data, (*[]record)(nil))
+	errStrict := UnmarshalSlice(data, &records, WithCount(2), WithStrictInput(true))
+	errCount := UnmarshalSlice(data, &records, WithCount(-1))
+	errSize := UnmarshalSlice(data, &records, WithStrictSize(true))
+
+	// Verify
+	assertFieldError("A")(t, errInvalid)
+	assertFieldError("B")(t, errVariable)
+	var typeError *TypeError
+	assert.ErrorAs(t, errNotSlice, &typeError)
+	assert.ErrorAs(t, errNil, &typeError)
+	assert.ErrorIs(t, errStrict, io.ErrUnexpectedEOF)
+	assert.ErrorContains(t, errStrict, "[1].B")
+	assert.Error(t, errCount)
+	var trailingDataError *TrailingDataError
+	assert.ErrorAs(t, errSize, &trailingDataError)
+	assert.Nil(t, records)
+}
+
+func TestUnmarshalN(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8 `bit:"4"`
+		B uint16
+		C uint8 `bit:"3"`
+	}
+	type tlv struct {
+		Type  uint8
+		Len   uint8
+		Value []byte `len:"Len"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOut  any
+		want    int
+	}{
+		"TrailingData": {
+			argData: []byte{0x01, 0x02, 0x03, 0x04, 0xFF, 0xFF},
+			argOut:  &a{},
+			want:    4,
+		},
+		"Exact": {
+			argData: []byte{0x01, 0x02, 0x03, 0x04},
+			argOut:  &a{},
+			want:    4,
+		},
+		"Short": {
+			argData: []byte{0x01, 0x02},
+			argOut:  &a{},
+			want:    2,
+		},
+		"Variable": {
+			argData: []byte{0x01, 0x02, 0xAA, 0xBB, 0x01},
+			argOut:  &tlv{},
+			want:    4,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := UnmarshalN(tc.argData, tc.argOut)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshalNError(t *testing.T) {
+	// Setup
+	var out struct {
+		A uint8 `bit:"9"`
+	}
+
+	// Exercise
+	n, err := UnmarshalN([]byte{0x00, 0x00}, &out)
+
+	// Verify
+	assertFieldError("A")(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestUnmarshalBits(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8  `bit:"4"`
+		B uint16 `bit:"12"`
+		C Raw    `bit:"8"`
+	}
+	inputData := []byte{0xFF, 0xFF, 0xFF}
+	testCases := map[string]struct {
+		nbits int
+		order ByteOrder
+		want  a
+	}{
+		"All bits":                {24, LittleEndian, a{A: 0xF, B: 0xFFF, C: Raw{0xFF}}},
+		"Limit in raw":            {19, LittleEndian, a{A: 0xF, B: 0xFFF, C: Raw{0x07}}},
+		"Limit in field":          {10, LittleEndian, a{A: 0xF, B: 0x3F, C: Raw{0x00}}},
+		"Limit in field (BE)":     {10, BigEndian, a{A: 0xF, B: 0x3F, C: Raw{0x00}}},
+		"Limit at byte (BE)":      {8, BigEndian, a{A: 0xF, B: 0xF, C: Raw{0x00}}},
+		"No bits":                 {0, LittleEndian, a{C: Raw{0x00}}},
+		"Limit in last byte (BE)": {20, BigEndian, a{A: 0xF, B: 0xFFF, C: Raw{0x0F}}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got a
+			err := UnmarshalBits(inputData, tc.nbits, &got, WithByteOrder(tc.order))
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshalBitsError(t *testing.T) {
+	// Setup
+	var out struct {
+		A uint8
+	}
+
+	// Exercise
+	errNegative := UnmarshalBits([]byte{0x00}, -1, &out)
+	errTooLarge := UnmarshalBits([]byte{0x00}, 9, &out)
+
+	// Verify
+	assert.NotNil(t, errNegative)
+	assert.NotNil(t, errTooLarge)
+}
+
+func TestUnmarshalAt(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8 `bit:"4"`
+		B uint8 `bit:"4"`
+	}
+	type b struct {
+		A uint8 `bit:"4"`
+		C uint8
+	}
+	inputData := []byte{0x21, 0x43, 0x65}
+	testCases := map[string]struct {
+		bitOffset int
+		out       any
+		opts      []Option
+		want      any
+		wantBits  int
+	}{
+		"Byte":     {8, &a{}, nil, &a{A: 0x3, B: 0x4}, 8},
+		"Nibble":   {4, &a{}, nil, &a{A: 0x2, B: 0x3}, 8},
+		"MSBFirst": {4, &a{}, []Option{WithBitOrder(MSBFirst)}, &a{A: 0x1, B: 0x4}, 8},
+		"Aligned":  {4, &b{}, nil, &b{A: 0x2, C: 0x43}, 12},
+		"End":      {24, &a{}, nil, &a{}, 0},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			n, err := UnmarshalAt(inputData, tc.bitOffset, tc.out, tc.opts...)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, tc.out)
+			assert.Equal(t, tc.wantBits, n)
+		})
+	}
+}
+
+func TestUnmarshalAtError(t *testing.T) {
+	// Setup
+	var out struct {
+		A uint8
+	}
+
+	// Exercise
+	_, errNegative := UnmarshalAt([]byte{0x00}, -1, &out)
+	_, errTooLarge := UnmarshalAt([]byte{0x00}, 9, &out)
+	_, errType := UnmarshalAt([]byte{0x00}, 0, out)
+
+	// Verify
+	assert.NotNil(t, errNegative)
+	assert.NotNil(t, errTooLarge)
+	var typeErr *TypeError
+	assert.ErrorAs(t, errType, &typeErr)
+}
+
+func TestUnmarshal_WithPresence(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8 `bit:"4"`
+		_ uint8 `bit:"4"`
+		B uint8
+		C Raw `bit:"4"`
+		d uint8
+		E uint16
+	}
+	inputData := []byte{0x12, 0x34, 0x56}
+	presence := map[string]bool{"Other": true}
+	want := map[string]bool{"Other": true, "A": true, "B": true, "C": true, "E": false}
+
+	// Exercise
+	var got a
+	err := Unmarshal(inputData, &got, WithPresence(presence))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, presence)
+}
+
+func TestUnmarshal_WithPresenceNil(t *testing.T) {
+	// Setup
+	var out struct{ A uint8 }
+
+	// Exercise
+	err := Unmarshal([]byte{0x00}, &out, WithPresence(nil))
+
+	// Verify
+	assert.NotNil(t, err)
+}
+
+func TestUnmarshal_WithZeroBeforeDecode(t *testing.T) {
+	// Setup
+	type a struct {
+		A    uint8 `bit:"4"`
+		Name string
+		b    uint8
+	}
+	testCases := map[string]struct {
+		argOpts []Option
+		want    a
+	}{
+		"Default": {
+			argOpts: nil,
+			want:    a{A: 0x5, Name: "kept", b: 0x7},
+		},
+		"Disabled": {
+			argOpts: []Option{WithZeroBeforeDecode(false)},
+			want:    a{A: 0x5, Name: "kept", b: 0x7},
+		},
+		"Enabled": {
+			argOpts: []Option{WithZeroBeforeDecode(true)},
+			want:    a{A: 0x5},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Setup
+			out := a{A: 0xF, Name: "kept", b: 0x7}
+
+			// Exercise
+			err := Unmarshal([]byte{0x05}, &out, tc.argOpts...)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, out)
+		})
+	}
+}
+
+func TestUnmarshal_WithStrictInput(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8 `bit:"4"`
+		_ uint8 `bit:"4"`
+		B uint16
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOpts []Option
+		wantErr string
+	}{
+		"Complete": {
+			argData: []byte{0x01, 0x02, 0x03},
+			argOpts: []Option{WithStrictInput(true)},
+		},
+		"ShortWithoutStrict": {
+			argData: []byte{0x01, 0x02},
+			argOpts: []Option{WithStrictInput(false)},
+		},
+		"Short": {
+			argData: []byte{0x01, 0x02},
+			argOpts: []Option{WithStrictInput(true)},
+			wantErr: "field B at bits 8 to 24",
+		},
+		"Empty": {
+			argData: []byte{},
+			argOpts: []Option{WithStrictInput(true)},
+			wantErr: "field A at bits 0 to 4",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var out a
+			err := Unmarshal(tc.argData, &out, tc.argOpts...)
+
+			// Verify
+			if tc.wantErr == "" {
+				assert.Nil(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestUnmarshal_WithStrictSize(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8 `bit:"4"`
+		B uint8 `bit:"6"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOpts []Option
+		wantErr string
+	}{
+		"Exact": {
+			argData: []byte{0x01, 0x02},
+			argOpts: []Option{WithStrictSize(true)},
+		},
+		"TrailingWithoutStrict": {
+			argData: []byte{0x01, 0x02, 0x03},
+			argOpts: []Option{WithStrictSize(false)},
+		},
+		"Trailing": {
+			argData: []byte{0x01, 0x02, 0x03, 0x04},
+			argOpts: []Option{WithStrictSize(true)},
+			wantErr: "bitfield: 2 bytes left over after bitfield.a of 2 bytes in input of 4 bytes",
+		},
+		"TrailingWords": {
+			argData: []byte{0x01, 0x02, 0x03, 0x04},
+			argOpts: []Option{WithStrictSize(true), WithWordSize(32)},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var out a
+			err := Unmarshal(tc.argData, &out, tc.argOpts...)
+
+			// Verify
+			if tc.wantErr == "" {
+				assert.Nil(t, err)
+				return
+			}
+			var trailingDataError *TrailingDataError
+			assert.ErrorAs(t, err, &trailingDataError)
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestUnmarshal_WithReservedMustBeZero(t *testing.T) {
+	// Setup
+	type inner struct {
+		_ Raw   `bit:"12"`
+		A uint8 `bit:"4"`
+	}
+	type a struct {
+		A     uint8 `bit:"3"`
+		_     uint8 `bit:"5"`
+		Inner inner
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOpts []Option
+		wantErr string
+	}{
+		"Zero": {
+			argData: []byte{0x07, 0x00, 0xF0},
+			argOpts: []Option{WithReservedMustBeZero()},
+		},
+		"NonZeroWithoutOption": {
+			argData: []byte{0xFF, 0xFF, 0xFF},
+		},
+		"NonZero": {
+			argData: []byte{0x0F, 0x00, 0x00},
+			argOpts: []Option{WithReservedMustBeZero()},
+			wantErr: "bitfield: at byte 0 bit 3: reserved bits 3 to 8 (_) are not zero",
+		},
+		"NonZeroRaw": {
+			argData: []byte{0x07, 0x00, 0x08},
+			argOpts: []Option{WithReservedMustBeZero()},
+			wantErr: "bitfield: at byte 1 bit 0: reserved bits 8 to 20 (Inner._) are not zero",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var out a
+			err := Unmarshal(tc.argData, &out, tc.argOpts...)
+
+			// Verify
+			if tc.wantErr == "" {
+				assert.Nil(t, err)
+				return
+			}
+			var reservedBitsError *ReservedBitsError
+			assert.ErrorAs(t, err, &reservedBitsError)
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestUnmarshal_DefaultTag(t *testing.T) {
+	// Setup
+	type config struct {
+		Mode       uint8 `bit:"4"`
+		Enabled    bool  `bit:"1" default:"true"`
+		_          uint8 `bit:"3"`
+		Level      int8  `bit:"4" default:"-1"`
+		Brightness uint8 `default:"0xFF"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		want    config
+	}{
+		"Complete": {
+			argData: []byte{0x02, 0x03, 0x40},
+			want:    config{Mode: 2, Enabled: false, Level: 3, Brightness: 0x40},
+		},
+		"Partial": {
+			argData: []byte{0x02, 0x03},
+			want:    config{Mode: 2, Enabled: false, Level: 3, Brightness: 0xFF},
+		},
+		"PartialField": {
+			argData: []byte{0x02},
+			want:    config{Mode: 2, Enabled: false, Level: -1, Brightness: 0xFF},
+		},
+		"Empty": {
+			argData: []byte{},
+			want:    config{Enabled: true, Level: -1, Brightness: 0xFF},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got config
+			err := Unmarshal(tc.argData, &got)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_DefaultTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"NotInteger": &struct {
+			A Raw `bit:"8" default:"1"`
+		}{},
+		"InvalidBool": &struct {
+			A bool `default:"yes"`
+		}{},
+		"Overflow": &struct {
+			A int8 `bit:"4" default:"8"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x01}, out)
+
+			// Verify
+			assertFieldError("A")(t, err)
+		})
+	}
+}
+
+func TestUnmarshal_DecodeError(t *testing.T) {
+	// Setup
+	type header struct {
+		Version uint8 `bit:"4" const:"4"`
+		Opcode  uint8 `bit:"4" enum:"1,2"`
+	}
+	type a struct {
+		Length uint8
+		Header header
+		Values [2]uint16 `bit:"12"`
+	}
+	testCases := map[string]struct {
+		argData  []byte
+		argOpts  []Option
+		wantPath string
+		wantByte int
+		wantBit  int
+		wantErr  string
+	}{
+		"Nested": {
+			argData:  []byte{0x00, 0x34},
+			wantPath: "Header.Opcode",
+			wantByte: 1,
+			wantBit:  4,
+			wantErr:  "bitfield: at byte 1 bit 4: value 3 is not in enum \"1,2\" (Header.Opcode uint8 `bit:\"4\" enum:\"1,2\"`)",
+		},
+		"ArrayElement": {
+			argData:  []byte{0x00, 0x14, 0x00, 0x00},
+			argOpts:  []Option{WithStrictInput(true)},
+			wantPath: "Values[1]",
+			wantByte: 3,
+			wantBit:  4,
+			wantErr:  "bitfield: at byte 3 bit 4: input of 32 bits ends before field Values[1] at bits 28 to 40: unexpected EOF",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var out a
+			err := Unmarshal(tc.argData, &out, tc.argOpts...)
+
+			// Verify
+			var decodeError *DecodeError
+			assert.ErrorAs(t, err, &decodeError)
+			assert.Equal(t, tc.wantPath, decodeError.Path)
+			assert.Equal(t, tc.wantByte, decodeError.Byte)
+			assert.Equal(t, tc.wantBit, decodeError.Bit)
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestDump(t *testing.T) {
+	// Setup
+	type inner struct {
+		A int8 `bit:"4"`
+		R Raw  `bit:"12"`
+	}
+	type a struct {
+		Version uint8 `bit:"4"`
+		IHL     uint8 `bit:"4" enum:"5"`
+		_       uint8
+		Inner   inner
+		Length  uint16 `endian:"big"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		want    string
+		wantErr string
+	}{
+		"Short": {
+			argData: []byte{0x54, 0x00, 0xFE, 0xCA, 0x00},
+			want: "FIELD     BITS    RAW                          VALUE\n" +
+				"Version   0-3     0100                         4\n" +
+				"IHL       4-7     0101                         5\n" +
+				"_         8-15    00000000                     0\n" +
+				"Inner.A   16-19   1110                         -2\n" +
+				"Inner.R   20-31   af0c                         -\n" +
+				"Length    32-47   0000000000000000 (missing)   0\n",
+		},
+		"Error": {
+			argData: []byte{0x44},
+			want: "FIELD     BITS   RAW    VALUE\n" +
+				"Version   0-3    0100   4\n" +
+				"IHL       4-7    0100   4\n" +
+				"error: bitfield: at byte 0 bit 4: value 4 is not in enum \"5\" (IHL uint8 `bit:\"4\" enum:\"5\"`)\n",
+			wantErr: "bitfield: at byte 0 bit 4: value 4 is not in enum \"5\" (IHL uint8 `bit:\"4\" enum:\"5\"`)",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var out a
+			var b strings.Builder
+			err := Dump(tc.argData, &out, &b)
+
+			// Verify
+			if tc.wantErr == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+			assert.Equal(t, tc.want, b.String())
+		})
+	}
+}
+
+func TestUnmarshalPartial(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8 `bit:"4"`
+		_ uint8 `bit:"4"`
+		B uint16
+		C [2]uint8
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOpts []Option
+		want    a
+		wantRes Partial
+	}{
+		"Complete": {
+			argData: []byte{0x01, 0x02, 0x03, 0x04, 0x05},
+			want:    a{A: 1, B: 0x0302, C: [2]uint8{4, 5}},
+			wantRes: Partial{Populated: 4},
+		},
+		"Short": {
+			argData: []byte{0x01, 0x02},
+			want:    a{A: 1, B: 0x02},
+			wantRes: Partial{Populated: 1, Missing: []string{"B", "C[0]", "C[1]"}},
+		},
+		"OverridesStrict": {
+			argData: []byte{0x01, 0x02, 0x03, 0x04},
+			argOpts: []Option{WithStrictInput(true)},
+			want:    a{A: 1, B: 0x0302, C: [2]uint8{4, 0}},
+			wantRes: Partial{Populated: 3, Missing: []string{"C[1]"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var out a
+			got, err := UnmarshalPartial(tc.argData, &out, tc.argOpts...)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, out)
+			assert.Equal(t, tc.wantRes, got)
+			assert.Equal(t, len(tc.wantRes.Missing) == 0, got.Complete())
+		})
+	}
+}
+
+func TestUnmarshal_WithAllowPartial(t *testing.T) {
+	// Setup
+	var out struct {
+		A uint8
+		B uint8
+	}
+
+	// Exercise
+	err := Unmarshal([]byte{0x01}, &out, WithStrictInput(true), WithAllowPartial())
+	errStrict := Unmarshal([]byte{0x01}, &out, WithAllowPartial(), WithStrictInput(true))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, uint8(1), out.A)
+	assert.ErrorIs(t, errStrict, io.ErrUnexpectedEOF)
+}
+
+func TestUnmarshal_WithScratch(t *testing.T) {
+	// Setup
+	type a struct {
+		A Raw `bit:"12"`
+		B Raw `bit:"4"`
+	}
+	var scratch Scratch
+
+	// Exercise
+	var got1, got2 a
+	err1 := Unmarshal([]byte{0x21, 0x43}, &got1, WithScratch(&scratch))
+	err2 := Unmarshal([]byte{0x65, 0x87}, &got2, WithScratch(&scratch))
+	errNil := Unmarshal([]byte{0x00}, &got1, WithScratch(nil))
+
+	// Verify
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.NotNil(t, errNil)
+	assert.Equal(t, a{A: Raw{0x21, 0x03}, B: Raw{0x04}}, got1)
+	assert.Equal(t, a{A: Raw{0x65, 0x07}, B: Raw{0x08}}, got2)
+}
+
+func TestUnmarshal_NestedStruct(t *testing.T) {
+	// Setup
+	type Inner struct {
+		B uint8 `bit:"4"`
+		C uint16
+	}
+	type middle struct {
+		Inner
+		D int8 `bit:"3"`
+	}
+	type outer struct {
+		A     uint8 `bit:"2"`
+		M     middle
+		hid   Inner
+		E     uint8 `bit:"1"`
+		Label string
+	}
+	data := []byte{0b0111_1101, 0x34, 0x12, 0b1011_0110, 0xFF, 0xFF, 0x01}
+	want := outer{A: 0b01, M: middle{Inner: Inner{B: 0xF, C: 0x1234}, D: -2}, E: 1}
+
+	// Exercise
+	var got outer
+	presence := map[string]bool{}
+	err := Unmarshal(data, &got, WithPresence(presence))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, map[string]bool{"A": true, "M.B": true, "M.C": true, "M.D": true, "E": true}, presence)
+}
+
+func TestUnmarshal_EmbeddedStruct(t *testing.T) {
+	// Setup
+	type Header struct {
+		Version uint8 `bit:"4"`
+		Kind    uint8 `bit:"4"`
+	}
+	type extra struct {
+		Flag   uint8 `bit:"1"`
+		hidden uint8 `bit:"7"`
+	}
+	type message struct {
+		Header
+		extra
+		Length uint8
+	}
+	want := message{Header: Header{Version: 0x1, Kind: 0x2}, extra: extra{Flag: 1}, Length: 0x10}
+
+	// Exercise
+	var got message
+	presence := map[string]bool{}
+	err := Unmarshal([]byte{0x21, 0xFF, 0x10}, &got, WithPresence(presence))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, map[string]bool{"Version": true, "Kind": true, "Flag": true, "Length": true}, presence)
+}
+
+func TestUnmarshal_NestedStructError(t *testing.T) {
+	// Setup
+	type Inner struct {
+		B uint8 `bit:"9"`
+	}
+	var out struct {
+		A     uint8
+		Outer struct{ Inner Inner }
+	}
+
+	// Exercise
+	err := Unmarshal([]byte{0x00}, &out)
+
+	// Verify
+	var fieldError *FieldError
+	assert.ErrorAs(t, err, &fieldError)
+	assert.Equal(t, "Outer.Inner.B", fieldError.Path)
+	assert.ErrorContains(t, err, "(Outer.Inner.B uint8 `bit:\"9\"`)")
+}
+
+func TestUnmarshal_WithUnexported(t *testing.T) {
+	// Setup
+	type inner struct {
+		C uint8 `bit:"4"`
+		d uint8 `bit:"4"`
+	}
+	type s struct {
+		A  uint8 `bit:"4"`
+		_  uint8 `bit:"4"`
+		b  uint8
+		in inner
+	}
+	inputData := []byte{0xA5, 0x12, 0x34}
+	testCases := map[string]struct {
+		argOpts []Option
+		want    s
+	}{
+		"Default": {
+			argOpts: nil,
+			want:    s{A: 0x5},
+		},
+		"Ignore": {
+			argOpts: []Option{WithUnexported(IgnoreUnexported)},
+			want:    s{A: 0x5},
+		},
+		"Set": {
+			argOpts: []Option{WithUnexported(SetUnexported)},
+			want:    s{A: 0x5, b: 0x12, in: inner{C: 0x4, d: 0x3}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got s
+			err := Unmarshal(inputData, &got, tc.argOpts...)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_WithUnexportedReject(t *testing.T) {
+	// Setup
+	type inner struct{ C uint8 }
+	testCases := map[string]struct {
+		argOut  any
+		wantErr bool
+	}{
+		"Placeholder": {
+			argOut: &struct {
+				_ uint8 `bit:"4"`
+			}{},
+		},
+		"Non-integer": {
+			argOut: &struct{ name string }{},
+		},
+		"Bit-field": {
+			argOut: &struct {
+				a uint8 `bit:"4"`
+			}{},
+			wantErr: true,
+		},
+		"Plain integer": {
+			argOut:  &struct{ a uint16 }{},
+			wantErr: true,
+		},
+		"Nested struct": {
+			argOut:  &struct{ in inner }{},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x00, 0x00}, tc.argOut, WithUnexported(RejectUnexported))
+
+			// Verify
+			if tc.wantErr {
+				var fieldError *FieldError
+				assert.ErrorAs(t, err, &fieldError)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_Bool(t *testing.T) {
+	// Setup
+	type flags struct {
+		A bool  `bit:"1"`
+		B bool  `bit:"1"`
+		C bool  `bit:"3"`
+		D bool  `bit:"3"`
+		E bool  `bit:"2"`
+		F uint8 `bit:"6"`
+	}
+	data := []byte{0b000_100_0_1, 0b111111_00}
+	want := flags{A: true, B: false, C: true, D: false, E: false, F: 0x3F}
+
+	// Exercise
+	var got flags
+	err := Unmarshal(data, &got, WithByteOrder(BigEndian))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestUnmarshal_BoolError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"Zero bits": &struct {
+			A bool `bit:"0"`
+		}{},
+		"Too wide": &struct {
+			A bool `bit:"65"`
+		}{},
+		"Bytes tag": &struct {
+			A bool `bytes:"1"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x00}, out)
+
+			// Verify
+			var fieldError *FieldError
+			assert.ErrorAs(t, err, &fieldError)
+		})
+	}
+}
+
+func TestUnmarshal_Array(t *testing.T) {
+	// Setup
+	type pair struct {
+		X uint8 `bit:"2"`
+		Y int8  `bit:"2"`
+	}
+	type s struct {
+		A     uint8 `bit:"4"`
+		Addr  [2]byte
+		Words [2]uint16 `bit:"12"`
+		Flags [4]bool   `bit:"1"`
+		Pairs [2]pair
+		Empty [0]uint8
+	}
+	data := []byte{0x0F, 0x12, 0x34, 0x56, 0x78, 0x9A, 0b0110_0101, 0b0000_1100}
+	want := s{
+		A:     0xF,
+		Addr:  [2]byte{0x12, 0x34},
+		Words: [2]uint16{0x856, 0x9A7},
+		Flags: [4]bool{true, false, true, false},
+		Pairs: [2]pair{{X: 0b10, Y: 0b01}, {X: 0b00, Y: -1}},
+	}
+
+	// Exercise
+	var got s
+	presence := map[string]bool{}
+	err := Unmarshal(data, &got, WithPresence(presence))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+	assert.True(t, presence["Words[1]"])
+	assert.True(t, presence["Pairs[1].Y"])
+}
+
+func TestUnmarshal_ArrayError(t *testing.T) {
+	// Setup
+	var out struct {
+		Words [2]uint8 `bit:"9"`
+	}
+
+	// Exercise
+	err := Unmarshal([]byte{0x00}, &out)
+
+	// Verify
+	var fieldError *FieldError
+	assert.ErrorAs(t, err, &fieldError)
+	assert.Equal(t, "Words", fieldError.Path)
+}
+
+func TestUnmarshal_Slice(t *testing.T) {
+	// Setup
+	type entry struct {
+		Kind        uint8    `bit:"4"`
+		NumChildren uint8    `bit:"4"`
+		Children    []*entry `count:"NumChildren"`
+		Label       string
+	}
+	type s struct {
+		NumEntries uint8
+		Entries    []*entry `count:"NumEntries"`
+		Trailer    uint8
+	}
+	data := []byte{0x02, 0x21, 0x03, 0x04, 0x05, 0xFF}
+	testCases := map[string]struct {
+		argData []byte
+		argOpts []Option
+		want    s
+	}{
+		"Nested": {
+			argData: data,
+			want: s{
+				NumEntries: 2,
+				Entries: []*entry{
+					{Kind: 1, NumChildren: 2, Children: []*entry{{Kind: 3}, {Kind: 4}}},
+					{Kind: 5},
+				},
+				Trailer: 0xFF,
+			},
+		},
+		"Empty": {
+			argData: []byte{0x00, 0xFF},
+			want:    s{Trailer: 0xFF},
+		},
+		"WithElementFactory": {
+			argData: []byte{0x01, 0x01},
+			argOpts: []Option{WithElementFactory(func(rt reflect.Type) (any, error) {
+				return &entry{Label: "made"}, nil
+			})},
+			want: s{NumEntries: 1, Entries: []*entry{{Kind: 1, Label: "made"}}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got s
+			err := Unmarshal(tc.argData, &got, tc.argOpts...)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_SliceError(t *testing.T) {
+	// Setup
+	type entry struct {
+		Kind uint8
+	}
+	type valid struct {
+		N       uint8
+		Entries []*entry `count:"N"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOut  any
+		argOpts []Option
+		check   func(t *testing.T, err error)
+	}{
+		"NotPointers": {
+			argOut: &struct {
+				N       uint8
+				Entries []entry `count:"N"`
+			}{},
+			check: assertFieldError("Entries"),
+		},
+		"CountAfter": {
+			argOut: &struct {
+				Entries []*entry `count:"N"`
+				N       uint8
+			}{},
+			check: assertFieldError("Entries"),
+		},
+		"CountNotInteger": {
+			argOut: &struct {
+				N       bool     `bit:"1"`
+				Entries []*entry `count:"N"`
+			}{},
+			check: assertFieldError("Entries"),
+		},
+		"InvalidElement": {
+			argOut: &struct {
+				N       uint8
+				Entries []*struct {
+					A uint8 `bit:"9"`
+				} `count:"N"`
+			}{},
+			check: assertFieldError("Entries.A"),
+		},
+		"CountTooLarge": {
+			argData: []byte{0x03, 0x01, 0x02},
+			argOut:  &valid{},
+			check: func(t *testing.T, err error) {
+				var lengthError *LengthError
+				assert.ErrorAs(t, err, &lengthError)
+				assert.Equal(t, "Entries", lengthError.Path)
+			},
+		},
+		"FactoryError": {
+			argData: []byte{0x01, 0x01},
+			argOut:  &valid{},
+			argOpts: []Option{WithElementFactory(func(rt reflect.Type) (any, error) {
+				return nil, errors.New("no entry")
+			})},
+			check: func(t *testing.T, err error) {
+				assert.EqualError(t, err, "bitfield: at byte 1 bit 0: cannot allocate Entries[0]: no entry")
+			},
+		},
+		"FactoryWrongType": {
+			argData: []byte{0x01, 0x01},
+			argOut:  &valid{},
+			argOpts: []Option{WithElementFactory(func(rt reflect.Type) (any, error) {
+				return entry{}, nil
+			})},
+			check: func(t *testing.T, err error) {
+				assert.NotNil(t, err)
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(tc.argData, tc.argOut, tc.argOpts...)
+
+			// Verify
+			tc.check(t, err)
+		})
+	}
+}
+
+func TestUnmarshal_WithMaxDepth(t *testing.T) {
+	// Setup
+	type node struct {
+		N        uint8
+		Children []*node `count:"N"`
+	}
+	type wrapper struct {
+		Root node
+	}
+	data := []byte{0x01, 0x01, 0x01, 0x00}
+	testCases := map[string]struct {
+		argOut   any
+		argOpts  []Option
+		wantPath string
+	}{
+		"Default": {
+			argOut: &node{},
+		},
+		"WithinLimit": {
+			argOut:  &node{},
+			argOpts: []Option{WithMaxDepth(3)},
+		},
+		"ExceedsLimit": {
+			argOut:   &node{},
+			argOpts:  []Option{WithMaxDepth(2)},
+			wantPath: "Children[0].Children[0].Children[0]",
+		},
+		"NestedStruct": {
+			argOut:   &wrapper{},
+			argOpts:  []Option{WithMaxDepth(3)},
+			wantPath: "Root.Children[0].Children[0].Children[0]",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(data, tc.argOut, tc.argOpts...)
+
+			// Verify
+			if tc.wantPath == "" {
+				assert.Nil(t, err)
+				return
+			}
+			var depthError *DepthError
+			assert.ErrorAs(t, err, &depthError)
+			assert.Equal(t, tc.wantPath, depthError.Path)
+		})
+	}
+}
+
+func TestUnmarshal_WithMaxDepthError(t *testing.T) {
+	// Setup
+	var out struct {
+		A uint8
+	}
+
+	// Exercise
+	err := Unmarshal([]byte{0x01}, &out, WithMaxDepth(0))
+
+	// Verify
+	assert.ErrorContains(t, err, "max depth must be positive")
+}
+
+func TestUnmarshal_LenTag(t *testing.T) {
+	// Setup
+	type record struct {
+		Type       uint8  `bit:"4"`
+		PayloadLen uint16 `bit:"12"`
+		Payload    []byte `len:"PayloadLen"`
+		Extra      Raw    `len:"Type"`
+		Trailer    uint8
+	}
+	testCases := map[string]struct {
+		argData []byte
+		want    record
+	}{
+		"Payload": {
+			argData: []byte{0x21, 0x00, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE},
+			want:    record{Type: 1, PayloadLen: 2, Payload: []byte{0xAA, 0xBB}, Extra: Raw{0xCC}, Trailer: 0xDD},
+		},
+		"Empty": {
+			argData: []byte{0x00, 0x00, 0xEE},
+			want:    record{Trailer: 0xEE},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got record
+			err := Unmarshal(tc.argData, &got)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_Float(t *testing.T) {
+	// Setup
+	type s struct {
+		Valid bool `bit:"1"`
+		F32   float32
+		F64   float64 `endian:"big"`
+	}
+	data := []byte{
+		0x01,
+		0x00, 0x00, 0x20, 0x40,
+		0xC0, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+	// Exercise
+	var got s
+	err := Unmarshal(data, &got)
+	errBitTag := Unmarshal(data, &struct {
+		F float32 `bit:"16"`
+	}{})
+	errCount := Unmarshal(data, &struct {
+		N float32
+		B []byte `len:"N"`
+	}{})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, s{Valid: true, F32: 2.5, F64: -2.5}, got)
+	assertFieldError("F")(t, errBitTag)
+	assertFieldError("B")(t, errCount)
+}
+
+func TestUnmarshal_CountTagBools(t *testing.T) {
+	// Setup
+	type s struct {
+		N     uint8  `bit:"4"`
+		Flags []bool `count:"N"`
+		Tail  uint8  `bit:"4"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOpts []Option
+		want    s
+		wantErr bool
+	}{
+		"LSBFirst": {
+			argData: []byte{0xD4, 0x0F},
+			want:    s{N: 4, Flags: []bool{true, false, true, true}, Tail: 0xF},
+		},
+		"MSBFirst": {
+			argData: []byte{0x4B, 0xF0},
+			argOpts: []Option{WithBitOrder(MSBFirst)},
+			want:    s{N: 4, Flags: []bool{true, false, true, true}, Tail: 0xF},
+		},
+		"Empty": {
+			argData: []byte{0xF0},
+			want:    s{Tail: 0xF},
+		},
+		"TooLong": {
+			argData: []byte{0xFF},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got s
+			err := Unmarshal(tc.argData, &got, tc.argOpts...)
+
+			// Verify
+			if tc.wantErr {
+				var lengthError *LengthError
+				assert.ErrorAs(t, err, &lengthError)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_TLVTag(t *testing.T) {
+	// Setup
+	type s struct {
+		Version uint8
+		Options []TLV `tlv:"type=uint8,len=uint16"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOpts []Option
+		want    s
+		wantErr bool
+	}{
+		"Entries": {
+			argData: []byte{0x01, 0x03, 0x02, 0x00, 0xAA, 0xBB, 0x07, 0x00, 0x00},
+			want: s{Version: 1, Options: []TLV{
+				{Type: 3, Value: []byte{0xAA, 0xBB}},
+				{Type: 7},
+			}},
+		},
+		"BigEndian": {
+			argData: []byte{0x01, 0x03, 0x00, 0x01, 0xAA},
+			argOpts: []Option{WithByteOrder(BigEndian)},
+			want:    s{Version: 1, Options: []TLV{{Type: 3, Value: []byte{0xAA}}}},
+		},
+		"NoEntries": {
+			argData: []byte{0x01},
+			want:    s{Version: 1},
+		},
+		"TruncatedHeader": {
+			argData: []byte{0x01, 0x03, 0x02},
+			wantErr: true,
+		},
+		"TruncatedValue": {
+			argData: []byte{0x01, 0x03, 0x02, 0x00, 0xAA},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got s
+			err := Unmarshal(tc.argData, &got, tc.argOpts...)
+
+			// Verify
+			if tc.wantErr {
+				var lengthError *LengthError
+				assert.ErrorAs(t, err, &lengthError)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_TLVTagError(t *testing.T) {
+	// Setup
+	var notTLV struct {
+		Options []byte `tlv:"type=uint8,len=uint8"`
+	}
+	var invalidTag struct {
+		Options []TLV `tlv:"type=uint24,len=uint8"`
+	}
+	var notLast struct {
+		Options []TLV `tlv:"type=uint8,len=uint8"`
+		Trailer uint8
+	}
+
+	// Exercise
+	errNotTLV := Unmarshal([]byte{}, &notTLV)
+	errInvalidTag := Unmarshal([]byte{}, &invalidTag)
+	errNotLast := Unmarshal([]byte{}, &notLast)
+	_, errLayout := LayoutOf(struct {
+		Options []TLV `tlv:"type=uint8,len=uint8"`
+	}{})
+
+	// Verify
+	assertFieldError("Options")(t, errNotTLV)
+	assertFieldError("Options")(t, errInvalidTag)
+	assertFieldError("Options")(t, errNotLast)
+	assertFieldError("Options")(t, errLayout)
+}
+
+func TestUnmarshal_LenTagUnit(t *testing.T) {
+	// Setup
+	type header struct {
+		Words   uint8  `bit:"4"`
+		Kind    uint8  `bit:"4"`
+		Options []byte `len:"Words,unit=4"`
+		Trailer uint8
+	}
+	data := []byte{0x11, 0x01, 0x02, 0x03, 0x04, 0xFF}
+
+	// Exercise
+	var got header
+	err := Unmarshal(data, &got)
+	errLength := Unmarshal(data[:4], &got)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, header{Words: 1, Kind: 1, Options: []byte{0x01, 0x02, 0x03, 0x04}, Trailer: 0xFF}, got)
+	var lengthError *LengthError
+	assert.ErrorAs(t, errLength, &lengthError)
+}
+
+func TestUnmarshal_LenTagError(t *testing.T) {
+	// Setup
+	type record struct {
+		Len     uint8
+		Payload []byte `len:"Len"`
+	}
+	var notBytes struct {
+		Len     uint8
+		Payload []uint16 `len:"Len"`
+	}
+	var bothTags struct {
+		Len     uint8
+		Payload []byte `len:"Len" count:"Len"`
+	}
+	var invalidUnit struct {
+		Len     uint8
+		Payload []byte `len:"Len,unit=0"`
+	}
+	var unknownOption struct {
+		Len     uint8
+		Payload []byte `len:"Len,words"`
+	}
+
+	// Exercise
+	var out record
+	errLength := Unmarshal([]byte{0x03, 0x01, 0x02}, &out)
+	errNotBytes := Unmarshal([]byte{0x00}, &notBytes)
+	errBothTags := Unmarshal([]byte{0x00}, &bothTags)
+	errInvalidUnit := Unmarshal([]byte{0x00}, &invalidUnit)
+	errUnknownOption := Unmarshal([]byte{0x00}, &unknownOption)
+
+	// Verify
+	var lengthError *LengthError
+	assert.ErrorAs(t, errLength, &lengthError)
+	assert.Equal(t, "Payload", lengthError.Path)
+	assertFieldError("Payload")(t, errNotBytes)
+	assertFieldError("Payload")(t, errBothTags)
+	assertFieldError("Payload")(t, errInvalidUnit)
+	assertFieldError("Payload")(t, errUnknownOption)
+}
+
+func assertFieldError(path string) func(t *testing.T, err error) {
+	return func(t *testing.T, err error) {
+		var fieldError *FieldError
+		assert.ErrorAs(t, err, &fieldError)
+		assert.Equal(t, path, fieldError.Path)
+	}
+}
+
+func TestUnmarshal_WithLogger(t *testing.T) {
+	// Setup
+	type s struct {
+		A uint8 `bit:"4"`
+		_ uint8 `bit:"4"`
+		B Raw   `bit:"12"`
+	}
+	var buf strings.Builder
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	want := `level=DEBUG msg="bitfield: decoded field" field=A offset=0 bits=4 value=5 present=true
+level=DEBUG msg="bitfield: decoded field" field=_ offset=4 bits=4 value=10 present=true
+level=DEBUG msg="bitfield: decoded field" field=B offset=8 bits=12 value="4\x00" present=false
+`
+
+	// Exercise
+	var out s
+	err := Unmarshal([]byte{0xA5, 0x34}, &out, WithLogger(slog.New(handler)))
+	errNil := Unmarshal([]byte{0xA5, 0x34}, &out, WithLogger(nil))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.NotNil(t, errNil)
+	assert.Equal(t, want, buf.String())
+}
+
+type testOpcode uint8
+
+func (o testOpcode) String() string {
+	return "op" + strconv.Itoa(int(o))
+}
+
+func TestUnmarshal_RegisterEnum(t *testing.T) {
+	// Setup
+	RegisterEnum(testOpcode(1), testOpcode(2))
+	type s struct {
+		Op testOpcode `bit:"4"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOpts []Option
+		want    s
+		wantErr bool
+	}{
+		"Registered": {
+			argData: []byte{0x02},
+			want:    s{Op: 2},
+		},
+		"Unregistered": {
+			argData: []byte{0x03},
+			wantErr: true,
+		},
+		"MappedByHook": {
+			argData: []byte{0x03},
+			argOpts: []Option{WithDecodeHook(func(f FieldInfo, v reflect.Value, raw uint64) error {
+				if _, ok := v.Interface().(fmt.Stringer); ok && raw == 3 {
+					v.SetUint(1)
+				}
+				return nil
+			})},
+			want: s{Op: 1},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got s
+			err := Unmarshal(tc.argData, &got, tc.argOpts...)
+
+			// Verify
+			if tc.wantErr {
+				var enumError *EnumError
+				if assert.ErrorAs(t, err, &enumError) {
+					assert.Equal(t, "Op", enumError.Path)
+					assert.Equal(t, testOpcode(3), enumError.Value)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_EnumTag(t *testing.T) {
+	// Setup
+	RegisterEnumSet("testOpcode", 0x1, 0x2, 0x7)
+	type s struct {
+		Version uint8 `bit:"4" enum:"4, 6"`
+		Opcode  uint8 `bit:"4" enum:"testOpcode"`
+		Delta   int8  `enum:"-1,0,1"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		want    s
+		wantErr string
+	}{
+		"Valid": {
+			argData: []byte{0x74, 0xFF},
+			want:    s{Version: 4, Opcode: 7, Delta: -1},
+		},
+		"List": {
+			argData: []byte{0x75, 0x00},
+			wantErr: "bitfield: at byte 0 bit 0: value 5 is not in enum \"4, 6\" (Version uint8 `bit:\"4\" enum:\"4, 6\"`)",
+		},
+		"Set": {
+			argData: []byte{0x36, 0x00},
+			wantErr: "bitfield: at byte 0 bit 4: value 3 is not in enum \"testOpcode\" (Opcode uint8 `bit:\"4\" enum:\"testOpcode\"`)",
+		},
+		"Signed": {
+			argData: []byte{0x16, 0xFE},
+			wantErr: "bitfield: at byte 1 bit 0: value -2 is not in enum \"-1,0,1\" (Delta int8 `enum:\"-1,0,1\"`)",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got s
+			err := Unmarshal(tc.argData, &got)
+
+			// Verify
+			if tc.wantErr != "" {
+				var enumError *EnumError
+				assert.ErrorAs(t, err, &enumError)
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_EnumTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"NotInteger": &struct {
+			A Raw `bit:"8" enum:"1"`
+		}{},
+		"InvalidValue": &struct {
+			A uint8 `enum:"1,x2"`
+		}{},
+		"UnregisteredSet": &struct {
+			A uint8 `enum:"testUnregistered"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x01}, out)
+
+			// Verify
+			assertFieldError("A")(t, err)
+		})
+	}
+}
+
+func TestUnmarshal_ConstTag(t *testing.T) {
+	// Setup
+	type s struct {
+		_       uint16 `const:"0x4D5A"`
+		Version int8   `bit:"4" const:"-2"`
+		Flags   uint8  `bit:"4"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		want    s
+		wantErr string
+	}{
+		"Valid": {
+			argData: []byte{0x5A, 0x4D, 0x3E},
+			want:    s{Version: -2, Flags: 3},
+		},
+		"Magic": {
+			argData: []byte{0x50, 0x4B, 0x3E},
+			wantErr: "bitfield: at byte 0 bit 0: value 19280 is not constant 19802 (_ uint16 `const:\"0x4D5A\"`)",
+		},
+		"Version": {
+			argData: []byte{0x5A, 0x4D, 0x31},
+			wantErr: "bitfield: at byte 2 bit 0: value 1 is not constant -2 (Version int8 `bit:\"4\" const:\"-2\"`)",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got s
+			err := Unmarshal(tc.argData, &got)
+
+			// Verify
+			if tc.wantErr != "" {
+				var constError *ConstError
+				assert.ErrorAs(t, err, &constError)
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_ConstTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"NotInteger": &struct {
+			A bool `bit:"1" const:"1"`
+		}{},
+		"InvalidValue": &struct {
+			A uint8 `const:"magic"`
+		}{},
+		"Overflow": &struct {
+			A uint8 `bit:"4" const:"16"`
+		}{},
+		"Negative": &struct {
+			A uint8 `const:"-1"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x01}, out)
+
+			// Verify
+			assertFieldError("A")(t, err)
+		})
+	}
+}
+
+func TestRegisterEnumSetPanic(t *testing.T) {
+	assert.Panics(t, func() { RegisterEnumSet("") })
+	assert.Panics(t, func() { RegisterEnumSet("1st") })
+}
+
+func TestHintTag(t *testing.T) {
+	// Setup
+	RegisterEnum(testOpcode(1), testOpcode(2))
+	type s struct {
+		Op testOpcode `bit:"4" hint:"check DIP switch 3 sets protocol v2"`
+	}
+
+	// Exercise
+	errEnum := Unmarshal([]byte{0x03}, &s{})
+	_, errOverflow := Marshal(s{Op: 0x10})
+	errField := Unmarshal([]byte{0x03}, &struct {
+		Op testOpcode `bit:"9" hint:"see section 4.2"`
+	}{})
+	errNoHint := Unmarshal([]byte{0x03}, &struct {
+		Op testOpcode `bit:"9"`
+	}{})
+
+	// Verify
+	assert.EqualError(t, errEnum, "bitfield: at byte 0 bit 0: value op3 is not registered for bitfield.testOpcode (Op bitfield.testOpcode `bit:\"4\" hint:\"check DIP switch 3 sets protocol v2\"`); check DIP switch 3 sets protocol v2")
+	assert.ErrorContains(t, errOverflow, "`); check DIP switch 3 sets protocol v2")
+	assert.ErrorContains(t, errField, "`); see section 4.2")
+	assert.ErrorContains(t, errNoHint, "`)")
+	assert.NotContains(t, errNoHint.Error(), ";")
+}
+
+func TestRegisterEnumPanic(t *testing.T) {
+	assert.Panics(t, func() { RegisterEnum() })
+	assert.Panics(t, func() { RegisterEnum(uint8(1)) })
+	assert.Panics(t, func() { RegisterEnum(testOpcode(1), uint8(2)) })
+}
+
+func TestUnmarshal_WithLoggerStringer(t *testing.T) {
+	// Setup
+	var out struct {
+		Op testOpcode `bit:"4"`
+	}
+	var buf strings.Builder
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	want := `{"level":"DEBUG","msg":"bitfield: decoded field","field":"Op","offset":0,"bits":4,"value":2,"string":"op2","present":true}
+`
+
+	// Exercise
+	err := Unmarshal([]byte{0x02}, &out, WithLogger(slog.New(handler)))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, buf.String())
+}
+
+// testFixed is a Q4.4 fixed-point number encoding and decoding its own bits.
+type testFixed float64
+
+func (f *testFixed) UnmarshalBits(data []byte, bitOff, bitLen int) error {
+	var raw int
+	for i := 0; i < bitLen; i++ {
+		if bit := bitOff + i; bit/8 < len(data) && data[bit/8]>>(bit%8)&1 != 0 {
+			raw |= 1 << i
+		}
+	}
+	if raw == 0xFF {
+		return errors.New("invalid value")
+	}
+	*f = testFixed(raw) / 16
+	return nil
+}
+
+func (f *testFixed) MarshalBits(bitLen int) (uint64, error) {
+	raw := int(*f * 16)
+	if raw < 0 || raw >= 0xFF {
+		return 0, errors.New("out of range")
+	}
+	return uint64(raw), nil
+}
+
+func TestUnmarshal_BitUnmarshaler(t *testing.T) {
+	// Setup
+	type s struct {
+		A    uint8     `bit:"4"`
+		Temp testFixed `bit:"8"`
+		B    uint8     `bit:"4"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		want    s
+		wantErr bool
+	}{
+		"Decoded": {
+			argData: []byte{0x81, 0x52},
+			want:    s{A: 1, Temp: 2.5, B: 5},
+		},
+		"Short": {
+			argData: []byte{0x81},
+			want:    s{A: 1, Temp: 0.5},
+		},
+		"Error": {
+			argData: []byte{0xF1, 0x0F},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got s
+			err := Unmarshal(tc.argData, &got)
+
+			// Verify
+			if tc.wantErr {
+				assert.ErrorContains(t, err, "UnmarshalBits failed for Temp: invalid value")
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_BitUnmarshalerError(t *testing.T) {
+	// Exercise
+	errUnmarshal := Unmarshal([]byte{0x00}, &struct {
+		Temp testFixed `bit:"0"`
+	}{})
+	_, errMarshal := Marshal(struct {
+		Temp testFixed `bit:"65"`
+	}{})
+
+	// Verify
+	assertFieldError("Temp")(t, errUnmarshal)
+	assertFieldError("Temp")(t, errMarshal)
+}
+
+type testVersioned struct {
+	Version uint8 `bit:"4"`
+	Flags   uint8 `bit:"4"`
+}
+
+var errTestVersion = errors.New("unsupported version")
+
+func (v *testVersioned) Validate() error {
+	if v.Version != 4 {
+		return errTestVersion
+	}
+	return nil
+}
+
+func TestUnmarshal_Validator(t *testing.T) {
+	// Setup
+	type outer struct {
+		Header testVersioned
+		hidden testVersioned
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOut  any
+		wantErr string
+	}{
+		"Valid": {
+			argData: []byte{0x14},
+			argOut:  &testVersioned{},
+		},
+		"Invalid": {
+			argData: []byte{0x16},
+			argOut:  &testVersioned{},
+			wantErr: "bitfield: Validate failed for bitfield.testVersioned: unsupported version",
+		},
+		"Nested": {
+			argData: []byte{0x16, 0x00},
+			argOut:  &outer{},
+			wantErr: "bitfield: Validate failed for Header: unsupported version",
+		},
+		"NotStored": {
+			argData: []byte{0x14, 0x16},
+			argOut:  &outer{},
+		},
+		"Slice": {
+			argData: []byte{0x14, 0x16},
+			argOut:  &[]testVersioned{},
+			wantErr: "bitfield: Validate failed for [1]: unsupported version",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var err error
+			if reflect.TypeOf(tc.argOut).Elem().Kind() == reflect.Slice {
+				err = UnmarshalSlice(tc.argData, tc.argOut)
+			} else {
+				err = Unmarshal(tc.argData, tc.argOut)
+			}
+
+			// Verify
+			if tc.wantErr == "" {
+				assert.Nil(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, errTestVersion)
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestUnmarshal_WithBitOrder(t *testing.T) {
+	// Setup
+	type ipv4 struct {
+		Version     uint8 `bit:"4"`
+		IHL         uint8 `bit:"4"`
+		TOS         uint8
+		TotalLength uint16
+	}
+	type spanning struct {
+		A uint8  `bit:"4"`
+		B uint16 `bit:"12"`
+		C Raw    `bit:"12"`
+		D bool   `bit:"1"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOpts []Option
+		want    any
+	}{
+		"RFC diagram": {
+			argData: []byte{0x45, 0x00, 0x01, 0x23},
+			argOpts: []Option{WithBitOrder(MSBFirst), WithByteOrder(BigEndian)},
+			want:    &ipv4{Version: 4, IHL: 5, TotalLength: 0x0123},
+		},
+		"BigEndian": {
+			argData: []byte{0xAB, 0xCD, 0xEF, 0x18},
+			argOpts: []Option{WithBitOrder(MSBFirst), WithByteOrder(BigEndian)},
+			want:    &spanning{A: 0xA, B: 0xBCD, C: Raw{0xEF, 0x10}, D: true},
+		},
+		"LittleEndian": {
+			argData: []byte{0xAB, 0xCD, 0xEF, 0x18},
+			argOpts: []Option{WithBitOrder(MSBFirst)},
+			want:    &spanning{A: 0xA, B: 0xCDB, C: Raw{0xEF, 0x10}, D: true},
+		},
+		"LSBFirst": {
+			argData: []byte{0xAB, 0xCD, 0xEF, 0x18},
+			argOpts: []Option{WithBitOrder(LSBFirst)},
+			want:    &spanning{A: 0xB, B: 0xCDA, C: Raw{0xEF, 0x08}, D: true},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got := reflect.New(reflect.TypeOf(tc.want).Elem()).Interface()
+			err := Unmarshal(tc.argData, got, tc.argOpts...)
+			data, errMarshal := Marshal(got, tc.argOpts...)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Nil(t, errMarshal)
+			assert.Equal(t, tc.argData, data)
+		})
+	}
+}
+
+func TestUnmarshal_WithWordSize(t *testing.T) {
+	// Setup
+	type register struct {
+		Length uint16 `bit:"12"`
+		Type   uint8  `bit:"4"`
+	}
+	type spanning struct {
+		A uint8  `bit:"8"`
+		B uint16 `bit:"16"`
+		C uint8  `bit:"8"`
+	}
+	type word32 struct {
+		A uint8  `bit:"8"`
+		B uint32 `bit:"24"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOpts []Option
+		want    any
+	}{
+		"BigEndian": {
+			argData: []byte{0x51, 0x23},
+			argOpts: []Option{WithByteOrder(BigEndian), WithWordSize(16)},
+			want:    &register{Length: 0x123, Type: 5},
+		},
+		"SpanningWords": {
+			argData: []byte{0x11, 0x22, 0x33, 0x44},
+			argOpts: []Option{WithByteOrder(BigEndian), WithWordSize(16)},
+			want:    &spanning{A: 0x22, B: 0x1144, C: 0x33},
+		},
+		"Word32": {
+			argData: []byte{0x11, 0x22, 0x33, 0x44},
+			argOpts: []Option{WithByteOrder(BigEndian), WithWordSize(32)},
+			want:    &word32{A: 0x44, B: 0x112233},
+		},
+		"Word8": {
+			argData: []byte{0x11, 0x22, 0x33, 0x44},
+			argOpts: []Option{WithByteOrder(BigEndian), WithWordSize(8)},
+			want:    &spanning{A: 0x11, B: 0x2233, C: 0x44},
+		},
+		"BigEndianMSBFirst": {
+			argData: []byte{0x11, 0x22, 0x33, 0x44},
+			argOpts: []Option{WithByteOrder(BigEndian), WithBitOrder(MSBFirst), WithWordSize(16)},
+			want:    &spanning{A: 0x11, B: 0x2233, C: 0x44},
+		},
+		"LittleEndian": {
+			argData: []byte{0x11, 0x22, 0x33, 0x44},
+			argOpts: []Option{WithWordSize(16)},
+			want:    &spanning{A: 0x11, B: 0x3322, C: 0x44},
+		},
+		"LittleEndianMSBFirst": {
+			argData: []byte{0x11, 0x22},
+			argOpts: []Option{WithBitOrder(MSBFirst), WithWordSize(16)},
+			want:    &register{Length: 0x221, Type: 1},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got := reflect.New(reflect.TypeOf(tc.want).Elem()).Interface()
+			err := Unmarshal(tc.argData, got, tc.argOpts...)
+			data, errMarshal := Marshal(got, tc.argOpts...)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Nil(t, errMarshal)
+			assert.Equal(t, tc.argData, data)
+		})
+	}
+}
+
+func TestUnmarshal_WithWordSizeError(t *testing.T) {
+	// Setup
+	var out struct {
+		A uint8 `bit:"4"`
+	}
+
+	// Exercise
+	err := Unmarshal([]byte{0x00, 0x00}, &out, WithWordSize(12))
+
+	// Verify
+	assert.Error(t, err)
+}
+
+func TestUnmarshal_WithWordSizeValidated(t *testing.T) {
+	// Setup
+	type msb struct {
+		A uint8 `bit:"4,msb"`
+		B uint8 `bit:"4"`
+	}
+	type wide struct {
+		W [9]byte `bit:"72"`
+	}
+	data := make([]byte, 10)
+
+	// Exercise
+	errMSB := Unmarshal(data, &msb{})
+	errMSBWord := Unmarshal(data, &msb{}, WithWordSize(16))
+	errWide := Unmarshal(data, &wide{})
+	errWideWord := Unmarshal(data, &wide{}, WithWordSize(16))
+
+	// Verify
+	assert.Nil(t, errMSB)
+	assertFieldError("A")(t, errMSBWord)
+	assert.Nil(t, errWide)
+	assertFieldError("W")(t, errWideWord)
+}
+
+func TestUnmarshalBits_WithBitOrder(t *testing.T) {
+	// Setup
+	var out struct {
+		A uint8 `bit:"3"`
+		B uint8 `bit:"5"`
+	}
+
+	// Exercise
+	err := UnmarshalBits([]byte{0xBF}, 4, &out, WithBitOrder(MSBFirst))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, uint8(0b101), out.A)
+	assert.Equal(t, uint8(0b1), out.B)
+}
+
+func TestUnmarshal_EndianTag(t *testing.T) {
+	// Setup
+	type s struct {
+		A uint16
+		B uint16 `endian:"big"`
+		C uint16 `bit:"12" endian:"little"`
+	}
+	data := []byte{0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC}
+	testCases := map[string]struct {
+		argOpts []Option
+		want    s
+	}{
+		"LittleEndian": {
+			want: s{A: 0x3412, B: 0x5678, C: 0xC9A},
+		},
+		"BigEndian": {
+			argOpts: []Option{WithByteOrder(BigEndian)},
+			want:    s{A: 0x1234, B: 0x5678, C: 0xC9A},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got s
+			err := Unmarshal(data, &got, tc.argOpts...)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_EndianTagError(t *testing.T) {
+	// Setup
+	var invalid struct {
+		A uint16 `endian:"middle"`
+	}
+	var raw struct {
+		A Raw `bit:"16" endian:"big"`
+	}
+
+	// Exercise
+	errInvalid := Unmarshal([]byte{0x00, 0x00}, &invalid)
+	errRaw := Unmarshal([]byte{0x00, 0x00}, &raw)
+
+	// Verify
+	assertFieldError("A")(t, errInvalid)
+	assertFieldError("A")(t, errRaw)
+}
+
+func TestUnmarshal_BitTagModifiers(t *testing.T) {
+	// Setup
+	type s struct {
+		A uint8  `bit:"3"`
+		B uint8  `bit:"3,msb"`
+		C uint16 `bit:"12,be"`
+		D int8   `bit:"4,sm"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		want    s
+	}{
+		"Negative": {
+			argData: []byte{0x05, 0xA0, 0x12, 0xB3},
+			want:    s{A: 5, B: 5, C: 0x123, D: -3},
+		},
+		"NegativeZero": {
+			argData: []byte{0x05, 0xA0, 0x12, 0x83},
+			want:    s{A: 5, B: 5, C: 0x123, D: 0},
+		},
+		"Positive": {
+			argData: []byte{0x05, 0xA0, 0x12, 0x73},
+			want:    s{A: 5, B: 5, C: 0x123, D: 7},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got s
+			err := Unmarshal(tc.argData, &got)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_BitTagModifiersError(t *testing.T) {
+	// Setup
+	testCases := map[string]struct {
+		argOut  any
+		argOpts []Option
+	}{
+		"UnknownModifier": {
+			argOut: &struct {
+				A uint8 `bit:"4,big"`
+			}{},
+		},
+		"ConflictingByteOrders": {
+			argOut: &struct {
+				A uint16 `bit:"12,le,be"`
+			}{},
+		},
+		"ConflictingBitOrders": {
+			argOut: &struct {
+				A uint8 `bit:"4,msb,lsb"`
+			}{},
+		},
+		"EndianTag": {
+			argOut: &struct {
+				A uint16 `bit:"12,be" endian:"big"`
+			}{},
+		},
+		"RawByteOrder": {
+			argOut: &struct {
+				A Raw `bit:"12,be"`
+			}{},
+		},
+		"UnsignedSignMagnitude": {
+			argOut: &struct {
+				A uint8 `bit:"4,sm"`
+			}{},
+		},
+		"BitOrderWithWords": {
+			argOut: &struct {
+				A uint8 `bit:"4,msb"`
+			}{},
+			argOpts: []Option{WithWordSize(16)},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x00, 0x00}, tc.argOut, tc.argOpts...)
+
+			// Verify
+			assertFieldError("A")(t, err)
+		})
+	}
+}
+
+type testBody interface{ isTestBody() }
+
+type testPing struct {
+	Seq uint16
+}
+
+type testData struct {
+	Length  uint8
+	Payload []byte `len:"Length"`
+}
+
+func (testPing) isTestBody()  {}
+func (*testData) isTestBody() {}
+
+func init() {
+	RegisterVariant[testBody](1, testPing{})
+	RegisterVariant[testBody](2, &testData{})
+}
+
+type testPacket struct {
+	Type    uint8    `bit:"4"`
+	Version uint8    `bit:"4"`
+	Body    testBody `switch:"Type"`
+}
+
+func TestUnmarshal_SwitchTag(t *testing.T) {
+	// Setup
+	testCases := map[string]struct {
+		argData []byte
+		want    testPacket
+		wantErr string
+	}{
+		"Struct": {
+			argData: []byte{0x21, 0x34, 0x12},
+			want:    testPacket{Type: 1, Version: 2, Body: testPing{Seq: 0x1234}},
+		},
+		"Pointer": {
+			argData: []byte{0x22, 0x02, 0xAB, 0xCD},
+			want:    testPacket{Type: 2, Version: 2, Body: &testData{Length: 2, Payload: []byte{0xAB, 0xCD}}},
+		},
+		"Unregistered": {
+			argData: []byte{0x23, 0x00},
+			wantErr: "bitfield: at byte 1 bit 0: no variant of bitfield.testBody registered for value 3 (Body bitfield.testBody `switch:\"Type\"`)",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got testPacket
+			err := Unmarshal(tc.argData, &got)
+
+			// Verify
+			if tc.wantErr != "" {
+				var variantError *VariantError
+				assert.ErrorAs(t, err, &variantError)
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_SwitchTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"NotInterface": &struct {
+			Type uint8
+			Body testPing `switch:"Type"`
+		}{},
+		"Missing": &struct {
+			Body testBody `switch:"Type"`
+		}{},
+		"Following": &struct {
+			Body testBody `switch:"Type"`
+			Type uint8
+		}{},
+		"NotInteger": &struct {
+			Type Raw      `bit:"8"`
+			Body testBody `switch:"Type"`
+		}{},
+		"BitTag": &struct {
+			Type uint8
+			Body testBody `switch:"Type" bit:"8"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x01, 0x00, 0x00}, out)
+
+			// Verify
+			assertFieldError("Body")(t, err)
+		})
+	}
+}
+
+type testPointers struct {
+	Flags     uint8   `bit:"4"`
+	Enabled   *bool   `bit:"4"`
+	Extension *uint16 `if:"Flags&0x1!=0"`
+	Level     *int8
+}
+
+type testPresentIf struct {
+	Flags    uint8   `bit:"4"`
+	Version  uint8   `bit:"4"`
+	Checksum *uint16 `presentif:"Flags.3"`
+	Key      uint32  `presentif:"Flags.1"`
+}
+
+func TestRegisterVariantPanic(t *testing.T) {
+	assert.Panics(t, func() { RegisterVariant[testPing](1, testPing{}) })
+	assert.Panics(t, func() { RegisterVariant[any](1, 1) })
+}
+
+type testOptional struct {
+	Flags     uint8  `bit:"4"`
+	Level     int8   `bit:"4"`
+	Extension uint16 `if:"Flags&0x1!=0"`
+	Checksum  uint8  `if:"Flags&0x2"`
+	Note      uint8  `if:"Level<0"`
+}
+
+func TestUnmarshal_IfTag(t *testing.T) {
+	// Setup
+	testCases := map[string]struct {
+		argData []byte
+		want    testOptional
+	}{
+		"Absent": {
+			argData: []byte{0x10},
+			want:    testOptional{Level: 1},
+		},
+		"Extension": {
+			argData: []byte{0x11, 0x34, 0x12},
+			want:    testOptional{Flags: 1, Level: 1, Extension: 0x1234},
+		},
+		"Checksum": {
+			argData: []byte{0x12, 0xAB},
+			want:    testOptional{Flags: 2, Level: 1, Checksum: 0xAB},
+		},
+		"Signed": {
+			argData: []byte{0xF3, 0x34, 0x12, 0xAB, 0xCD},
+			want:    testOptional{Flags: 3, Level: -1, Extension: 0x1234, Checksum: 0xAB, Note: 0xCD},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got := testOptional{Extension: 0xFFFF, Checksum: 0xFF, Note: 0xFF}
+			err := Unmarshal(tc.argData, &got)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_IfTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"Missing": &struct {
+			Body uint8 `if:"Flags!=0"`
+		}{},
+		"Following": &struct {
+			Body  uint8 `if:"Flags!=0"`
+			Flags uint8
+		}{},
+		"NotInteger": &struct {
+			Flags Raw   `bit:"8"`
+			Body  uint8 `if:"Flags!=0"`
+		}{},
+		"Value": &struct {
+			Flags uint8
+			Body  uint8 `if:"Flags==one"`
+		}{},
+		"Mask": &struct {
+			Flags uint8
+			Body  uint8 `if:"Flags&-1"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x01, 0x00}, out)
+
+			// Verify
+			assertFieldError("Body")(t, err)
+		})
+	}
+}
+
+func TestUnmarshal_Pointer(t *testing.T) {
+	// Setup
+	enabled, extension, level := true, uint16(0x1234), int8(-2)
+	disabled := false
+	testCases := map[string]struct {
+		argData []byte
+		want    testPointers
+	}{
+		"Present": {
+			argData: []byte{0x11, 0x34, 0x12, 0xFE},
+			want:    testPointers{Flags: 1, Enabled: &enabled, Extension: &extension, Level: &level},
+		},
+		"Absent": {
+			argData: []byte{0x00},
+			want:    testPointers{Enabled: &disabled},
+		},
+		"Short": {
+			argData: []byte{0x11, 0x34},
+			want:    testPointers{Flags: 1, Enabled: &enabled},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var stale uint16
+			got := testPointers{Extension: &stale, Level: new(int8)}
+			err := Unmarshal(tc.argData, &got)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.NotSame(t, &stale, got.Extension)
+		})
+	}
+}
+
+func TestUnmarshal_PointerPartial(t *testing.T) {
+	// Exercise
+	var got testPointers
+	partial, err := UnmarshalPartial([]byte{0x10}, &got)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Level"}, partial.Missing)
+	assert.Nil(t, got.Level)
+}
+
+func TestUnmarshal_PointerError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"Default": &struct {
+			V *uint8 `default:"1"`
+		}{},
+		"BitSize": &struct {
+			V *uint8 `bit:"9"`
+		}{},
+		"Condition": &struct {
+			F *uint8
+			V uint8 `if:"F"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x01, 0x00}, out)
+
+			// Verify
+			assertFieldError("V")(t, err)
+		})
+	}
+}
+
+func TestUnmarshal_PresentIfTag(t *testing.T) {
+	// Setup
+	checksum := uint16(0xBEEF)
+	testCases := map[string]struct {
+		argData []byte
+		want    testPresentIf
+	}{
+		"Both": {
+			argData: []byte{0x0A, 0xEF, 0xBE, 0x04, 0x03, 0x02, 0x01},
+			want:    testPresentIf{Flags: 0xA, Checksum: &checksum, Key: 0x01020304},
+		},
+		"KeyOnly": {
+			argData: []byte{0x12, 0x04, 0x03, 0x02, 0x01},
+			want:    testPresentIf{Flags: 0x2, Version: 1, Key: 0x01020304},
+		},
+		"None": {
+			argData: []byte{0x00},
+			want:    testPresentIf{},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got testPresentIf
+			err := Unmarshal(tc.argData, &got)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_PresentIfTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"Format": &struct {
+			Flags uint8
+			V     uint8 `presentif:"Flags3"`
+		}{},
+		"Missing": &struct {
+			V uint8 `presentif:"Flags.3"`
+		}{},
+		"Signed": &struct {
+			Flags int8
+			V     uint8 `presentif:"Flags.3"`
+		}{},
+		"Bool": &struct {
+			Flags bool  `bit:"1"`
+			V     uint8 `presentif:"Flags.0"`
+		}{},
+		"BitIndex": &struct {
+			Flags uint8 `bit:"4"`
+			V     uint8 `presentif:"Flags.4"`
+		}{},
+		"WithIf": &struct {
+			Flags uint8
+			V     uint8 `if:"Flags" presentif:"Flags.3"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x01, 0x00}, out)
+
+			// Verify
+			assertFieldError("V")(t, err)
+		})
+	}
+}
+
+func TestUnmarshal_RestTag(t *testing.T) {
+	// Setup
+	type message struct {
+		Type    uint8  `bit:"4"`
+		Payload []byte `rest:"true"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		want    message
+	}{
+		"Payload": {
+			argData: []byte{0x01, 0xAB, 0xCD},
+			want:    message{Type: 1, Payload: []byte{0xAB, 0xCD}},
+		},
+		"Empty": {
+			argData: []byte{0x01},
+			want:    message{Type: 1},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got := message{Payload: []byte{0xFF}}
+			err := Unmarshal(tc.argData, &got, WithStrictSize(true))
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_RestTagBools(t *testing.T) {
+	// Setup
+	type status struct {
+		Type  uint8  `bit:"4"`
+		Flags []bool `rest:"true"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		want    status
+	}{
+		"Partial byte": {
+			argData: []byte{0x51},
+			want:    status{Type: 1, Flags: []bool{true, false, true, false}},
+		},
+		"Bytes": {
+			argData: []byte{0x01, 0x81},
+			want:    status{Type: 1, Flags: []bool{false, false, false, false, true, false, false, false, false, false, false, true}},
+		},
+		"Empty": {
+			argData: []byte{},
+			want:    status{},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got := status{Flags: []bool{true}}
+			err := Unmarshal(tc.argData, &got)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_RestTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"NotBytes": &struct {
+			Payload []uint16 `rest:"true"`
+		}{},
+		"NotTrue": &struct {
+			Payload []byte `rest:"yes"`
+		}{},
+		"NotLast": &struct {
+			Payload []byte `rest:"true"`
+			Tail    uint8
+		}{},
+		"LenTag": &struct {
+			Length  uint8
+			Payload []byte `rest:"true" len:"Length"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x01, 0x00}, out)
+
+			// Verify
+			assertFieldError("Payload")(t, err)
+		})
+	}
+}
+
+type testSizedBody struct {
+	A uint8
+	B uint8
+}
+
+type testSizedRecord struct {
+	X uint16
+}
+
+type testSized struct {
+	Length  uint8
+	Body    testSizedBody `sizefrom:"Length"`
+	Words   uint8
+	Records []*testSizedRecord `sizefrom:"Words,unit=4"`
+	Tail    uint8
+}
+
+func TestUnmarshal_SizeFromTag(t *testing.T) {
+	// Setup
+	testCases := map[string]struct {
+		argData []byte
+		want    testSized
+	}{
+		"Exact": {
+			argData: []byte{0x02, 0x11, 0x22, 0x01, 0x34, 0x12, 0x78, 0x56, 0xFF},
+			want: testSized{
+				Length:  2,
+				Body:    testSizedBody{A: 0x11, B: 0x22},
+				Words:   1,
+				Records: []*testSizedRecord{{X: 0x1234}, {X: 0x5678}},
+				Tail:    0xFF,
+			},
+		},
+		"Longer": {
+			argData: []byte{0x03, 0x11, 0x22, 0x99, 0x00, 0xFF},
+			want:    testSized{Length: 3, Body: testSizedBody{A: 0x11, B: 0x22}, Tail: 0xFF},
+		},
+		"Shorter": {
+			argData: []byte{0x01, 0x11, 0x00, 0xFF},
+			want:    testSized{Length: 1, Body: testSizedBody{A: 0x11}, Tail: 0xFF},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got testSized
+			err := Unmarshal(tc.argData, &got)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_SizeFromTagError(t *testing.T) {
+	t.Run("Exceeds", func(t *testing.T) {
+		// Exercise
+		var got testSized
+		err := Unmarshal([]byte{0x05, 0x11}, &got)
+
+		// Verify
+		var lengthError *LengthError
+		assert.ErrorAs(t, err, &lengthError)
+		assert.EqualError(t, err, "bitfield: at byte 1 bit 0: size 5 exceeds the rest of the input (Body bitfield.testSizedBody `sizefrom:\"Length\"`)")
+	})
+
+	// Setup
+	testCases := map[string]any{
+		"NotStruct": &struct {
+			Length uint8
+			Body   uint8 `sizefrom:"Length"`
+		}{},
+		"Missing": &struct {
+			Body testSizedBody `sizefrom:"Length"`
+		}{},
+		"Following": &struct {
+			Body   testSizedBody `sizefrom:"Length"`
+			Length uint8
+		}{},
+		"Unit": &struct {
+			Length uint8
+			Body   testSizedBody `sizefrom:"Length,unit=0"`
+		}{},
+		"CountTag": &struct {
+			Length uint8
+			Body   []*testSizedBody `sizefrom:"Length" count:"Length"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x01, 0x00, 0x00}, out)
+
+			// Verify
+			assertFieldError("Body")(t, err)
+		})
+	}
+}
+
+func TestRegisterChecksum(t *testing.T) {
+	// Setup
+	testCases := map[string]uint64{
+		"crc16-ccitt": 0x29B1,
+		"crc32":       0xCBF43926,
+		"crc32c":      0xE3069283,
+		"internet":    0xF62A,
+		"sum8":        0xDD,
+		"xor8":        0x31,
+	}
+
+	for name, want := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			fn, ok := checksums.Load(name)
+
+			// Verify
+			assert.True(t, ok)
+			assert.Equal(t, want, fn.(func([]byte) uint64)([]byte("123456789")))
+		})
+	}
+}
+
+func TestRegisterChecksumPanic(t *testing.T) {
+	assert.Panics(t, func() { RegisterChecksum("", func([]byte) uint64 { return 0 }) })
+	assert.Panics(t, func() { RegisterChecksum("none", nil) })
+}
+
+func TestUnmarshal_CheckTag(t *testing.T) {
+	// Setup
+	type trailer struct {
+		Length  uint8
+		Payload [2]byte
+		Sum     uint8 `check:"sum8,0:"`
+	}
+	type leading struct {
+		CRC  uint16 `check:"crc16-ccitt,2:11" endian:"big"`
+		Data [9]byte
+	}
+	type wrapper struct {
+		Kind    uint8
+		Trailer trailer
+	}
+	testCases := map[string]struct {
+		argData []byte
+		out     any
+		want    any
+		wantErr string
+	}{
+		"Trailer": {
+			argData: []byte{0x02, 0x10, 0x20, 0x32},
+			out:     &trailer{},
+			want:    &trailer{Length: 2, Payload: [2]byte{0x10, 0x20}, Sum: 0x32},
+		},
+		"Leading": {
+			argData: append([]byte{0x29, 0xB1}, "123456789"...),
+			out:     &leading{},
+			want:    &leading{CRC: 0x29B1, Data: [9]byte([]byte("123456789"))},
+		},
+		"Nested": {
+			argData: []byte{0xFF, 0x02, 0x10, 0x20, 0x32},
+			out:     &wrapper{},
+			want:    &wrapper{Kind: 0xFF, Trailer: trailer{Length: 2, Payload: [2]byte{0x10, 0x20}, Sum: 0x32}},
+		},
+		"Mismatch": {
+			argData: []byte{0x02, 0x10, 0x20, 0x33},
+			out:     &trailer{},
+			wantErr: "bitfield: at byte 3 bit 0: value 0x33 is not checksum 0x32 (Sum uint8 `check:\"sum8,0:\"`)",
+		},
+		"Short": {
+			argData: []byte{0x29, 0xB1, 0x31},
+			out:     &leading{},
+			wantErr: "bitfield: at byte 0 bit 0: checksum range ends at byte 11 beyond the input (CRC uint16 `check:\"crc16-ccitt,2:11\" endian:\"big\"`)",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(tc.argData, tc.out)
+
+			// Verify
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, tc.out)
+		})
+	}
+}
+
+func TestUnmarshal_CheckTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"NotInteger": &struct {
+			Sum [2]uint8 `check:"sum8,0:"`
+		}{},
+		"Unregistered": &struct {
+			Sum uint8 `check:"sum7,0:"`
+		}{},
+		"NoRange": &struct {
+			Sum uint8 `check:"sum8"`
+		}{},
+		"Reversed": &struct {
+			Sum uint8 `check:"sum8,2:1"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x01, 0x00}, out)
+
+			// Verify
+			assertFieldError("Sum")(t, err)
+		})
+	}
+}
+
+type testTimestamps struct {
+	Unix   time.Time `bit:"32" epoch:"unix"`
+	Millis time.Time `bit:"64" epoch:"unix,ms"`
+	NTP    time.Time `bit:"64" epoch:"ntp,frac32" endian:"big"`
+	GPS    time.Time `bit:"32" epoch:"gps" endian:"big"`
+}
+
+var testTimestampsData = []byte{
+	0x80, 0x00, 0x92, 0x65,
+	0x7B, 0xF4, 0x51, 0xC2, 0x8C, 0x01, 0x00, 0x00,
+	0xE9, 0x3C, 0x7F, 0x00, 0x80, 0x00, 0x00, 0x00,
+	0x52, 0xBC, 0xC3, 0x00,
+}
+
+func TestUnmarshal_EpochTag(t *testing.T) {
+	// Setup
+	newYear := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	want := testTimestamps{
+		Unix:   newYear,
+		Millis: newYear.Add(123 * time.Millisecond),
+		NTP:    newYear.Add(500 * time.Millisecond),
+		GPS:    newYear,
+	}
+
+	// Exercise
+	var got testTimestamps
+	err := Unmarshal(testTimestampsData, &got)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestUnmarshal_EpochTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"NoEpoch": &struct {
+			Time time.Time `bit:"32"`
+		}{},
+		"NoBit": &struct {
+			Time time.Time `epoch:"unix"`
+		}{},
+		"NotTime": &struct {
+			Time uint32 `bit:"32" epoch:"unix"`
+		}{},
+		"Epoch": &struct {
+			Time time.Time `bit:"32" epoch:"mars"`
+		}{},
+		"Precision": &struct {
+			Time time.Time `bit:"32" epoch:"unix,min"`
+		}{},
+		"Frac": &struct {
+			Time time.Time `bit:"32" epoch:"ntp,frac32"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(make([]byte, 8), out)
+
+			// Verify
+			assertFieldError("Time")(t, err)
+		})
+	}
+}
+
+type testAddrs struct {
+	Version uint8      `bit:"4"`
+	Src     netip.Addr `bit:"32"`
+	Dst     net.IP     `bytes:"4"`
+	Via     netip.Addr `bytes:"16"`
+}
+
+var testAddrsData = []byte{
+	0x4C, 0x0A, 0x80, 0x00, 0xA0, 0x0A, 0x00, 0x00, 0x01, 0x20, 0x01, 0x0D, 0xB8,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+}
+
+func TestUnmarshal_Addr(t *testing.T) {
+	// Setup
+	want := testAddrs{
+		Version: 4,
+		Src:     netip.MustParseAddr("192.168.0.10"),
+		Dst:     net.IP{10, 0, 0, 1},
+		Via:     netip.MustParseAddr("2001:db8::1"),
+	}
+
+	// Exercise
+	var got testAddrs
+	err := Unmarshal(testAddrsData, &got, WithBitOrder(MSBFirst))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestUnmarshal_AddrError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"NoBit": &struct {
+			Addr netip.Addr
+		}{},
+		"NoBitIP": &struct {
+			Addr net.IP
+		}{},
+		"BitSize": &struct {
+			Addr netip.Addr `bit:"64"`
+		}{},
+		"ByteSize": &struct {
+			Addr net.IP `bytes:"6"`
+		}{},
+		"Endian": &struct {
+			Addr netip.Addr `bit:"32" endian:"big"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(make([]byte, 16), out)
+
+			// Verify
+			assertFieldError("Addr")(t, err)
+		})
+	}
+}
+
+func TestUnmarshal_UUIDTag(t *testing.T) {
+	// Setup
+	type uuid [16]byte
+	type ids struct {
+		RFC  uuid     `uuid:"rfc4122"`
+		GUID [16]byte `uuid:"guid"`
+	}
+	data := []byte{
+		0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF,
+		0x33, 0x22, 0x11, 0x00, 0x55, 0x44, 0x77, 0x66, 0x88, 0x99, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF,
+	}
+	id := [16]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
+
+	// Exercise
+	var got ids
+	err := Unmarshal(data, &got)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, ids{RFC: id, GUID: id}, got)
+}
+
+func TestUnmarshal_UUIDTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"Type": &struct {
+			ID [8]byte `uuid:"guid"`
+		}{},
+		"Bit": &struct {
+			ID [16]byte `uuid:"guid" bit:"4"`
+		}{},
+		"Order": &struct {
+			ID [16]byte `uuid:"ms"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(make([]byte, 16), out)
+
+			// Verify
+			assertFieldError("ID")(t, err)
+		})
+	}
+}
+
+func TestUnmarshal_Wide(t *testing.T) {
+	// Setup
+	type counters struct {
+		Big    *big.Int `bit:"96" endian:"big"`
+		Little *big.Int `bit:"96" endian:"little"`
+		Array  [16]byte `bit:"96" endian:"big"`
+	}
+	type unaligned struct {
+		A uint8    `bit:"4"`
+		B *big.Int `bit:"68"`
+	}
+	data := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C}
+	big96, _ := new(big.Int).SetString("0102030405060708090A0B0C", 16)
+	little96, _ := new(big.Int).SetString("0C0B0A090807060504030201", 16)
+	testCases := map[string]struct {
+		argData []byte
+		argOut  any
+		want    any
+	}{
+		"Aligned": {
+			argData: bytes.Repeat(data, 3),
+			argOut:  &counters{},
+			want: &counters{
+				Big:    big96,
+				Little: little96,
+				Array:  [16]byte{0, 0, 0, 0, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C},
+			},
+		},
+		"Unaligned": {
+			argData: []byte{0xF1, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+			argOut:  &unaligned{},
+			want:    &unaligned{A: 1, B: new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 68), big.NewInt(1))},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(tc.argData, tc.argOut)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, tc.argOut)
+		})
+	}
+}
+
+func TestUnmarshal_WideError(t *testing.T) {
+	// Setup
+	testCases := map[string]struct {
+		argOut  any
+		argOpts []Option
+	}{
+		"NoBit": {
+			argOut: &struct {
+				Wide *big.Int
+			}{},
+		},
+		"ArraySize": {
+			argOut: &struct {
+				Wide [8]byte `bit:"72"`
+			}{},
+		},
+		"WordSize": {
+			argOut: &struct {
+				Wide *big.Int `bit:"96"`
+			}{},
+			argOpts: []Option{WithWordSize(16)},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(make([]byte, 16), tc.argOut, tc.argOpts...)
+
+			// Verify
+			assertFieldError("Wide")(t, err)
+		})
+	}
+}
+
+type testVarints struct {
+	Unsigned uint64 `varint:"leb128"`
+	Signed   int32  `varint:"zigzag"`
+	Negative int32  `varint:"leb128"`
+	QUIC     uint64 `varint:"quic"`
+	Length   uint16 `varint:"quic"`
+	Payload  []byte `len:"Length"`
+}
+
+var testVarintsData = []byte{
+	0xAC, 0x02,
+	0x03,
+	0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01,
+	0xC2, 0x19, 0x7C, 0x5E, 0xFF, 0x14, 0xE8, 0x8C,
+	0x02, 0xAB, 0xCD,
+}
+
+func TestUnmarshal_Varint(t *testing.T) {
+	// Setup
+	want := testVarints{
+		Unsigned: 300,
+		Signed:   -2,
+		Negative: -1,
+		QUIC:     151288809941952652,
+		Length:   2,
+		Payload:  []byte{0xAB, 0xCD},
+	}
+
+	// Exercise
+	var got testVarints
+	err := Unmarshal(testVarintsData, &got)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestUnmarshal_VarintCondition(t *testing.T) {
+	// Setup
+	type compared struct {
+		N int16 `varint:"zigzag"`
+		X uint8 `if:"N>100"`
+	}
+	type flagged struct {
+		Flags uint16 `varint:"leb128"`
+		X     uint8  `presentif:"Flags.10"`
+	}
+
+	// Exercise
+	var gotCompared compared
+	errCompared := Unmarshal([]byte{0x90, 0x03, 0x07}, &gotCompared)
+	var gotFlagged flagged
+	errFlagged := Unmarshal([]byte{0x80, 0x08, 0x07}, &gotFlagged)
+
+	// Verify
+	assert.Nil(t, errCompared)
+	assert.Equal(t, compared{N: 200, X: 7}, gotCompared)
+	assert.Nil(t, errFlagged)
+	assert.Equal(t, flagged{Flags: 0x400, X: 7}, gotFlagged)
+}
+
+func TestUnmarshal_VarintDecodeError(t *testing.T) {
+	// Setup
+	type short struct {
+		A uint8  `bit:"4"`
+		V uint32 `varint:"leb128"`
+	}
+	type quic struct {
+		V uint64 `varint:"quic"`
+	}
+	type narrow struct {
+		V uint8 `varint:"leb128"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOut  any
+		wantEOF bool
+	}{
+		"Truncated":     {argData: []byte{0x0F, 0x80, 0x80}, argOut: &short{}, wantEOF: true},
+		"TruncatedQUIC": {argData: []byte{0x80, 0x00, 0x01}, argOut: &quic{}, wantEOF: true},
+		"TooLong":       {argData: bytes.Repeat([]byte{0xFF}, 11), argOut: &narrow{}},
+		"Narrow":        {argData: []byte{0xAC, 0x02}, argOut: &narrow{}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(tc.argData, tc.argOut)
+
+			// Verify
+			var decodeErr *DecodeError
+			assert.ErrorAs(t, err, &decodeErr)
+			assert.Equal(t, "V", decodeErr.Path)
+			assert.Equal(t, tc.wantEOF, errors.Is(err, io.ErrUnexpectedEOF))
+		})
+	}
+}
+
+func TestUnmarshal_VarintError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"Type": &struct {
+			V float64 `varint:"leb128"`
+		}{},
+		"Encoding": &struct {
+			V uint64 `varint:"vlq"`
+		}{},
+		"ZigzagUnsigned": &struct {
+			V uint64 `varint:"zigzag"`
+		}{},
+		"QUICSigned": &struct {
+			V int64 `varint:"quic"`
+		}{},
+		"Bit": &struct {
+			V uint64 `varint:"leb128" bit:"8"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(make([]byte, 16), out)
+
+			// Verify
+			assertFieldError("V")(t, err)
+		})
+	}
+}
+
+type testBCD struct {
+	Seconds uint8    `bit:"8" enc:"bcd"`
+	Digit   uint8    `bit:"4" enc:"bcd"`
+	Year    uint16   `enc:"bcd" endian:"big"`
+	IMSI    [2]uint8 `enc:"bcd"`
+}
+
+var testBCDData = []byte{0x59, 0x07, 0x20, 0x24, 0x12, 0x34}
+
+func TestUnmarshal_EncTag(t *testing.T) {
+	// Setup
+	want := testBCD{Seconds: 59, Digit: 7, Year: 2024, IMSI: [2]uint8{12, 34}}
+
+	// Exercise
+	var got testBCD
+	err := Unmarshal(testBCDData, &got)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestUnmarshal_EncTagGray(t *testing.T) {
+	// Setup
+	type encoder struct {
+		Position uint16 `bit:"10" enc:"gray"`
+		Step     uint8  `bit:"3" enc:"gray"`
+		_        uint8  `bit:"3"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		want    encoder
+	}{
+		"Zero":    {argData: []byte{0x00, 0x00}, want: encoder{}},
+		"One":     {argData: []byte{0x01, 0x00}, want: encoder{Position: 1}},
+		"Two":     {argData: []byte{0x03, 0x00}, want: encoder{Position: 2}},
+		"Largest": {argData: []byte{0x00, 0x12}, want: encoder{Position: 1023, Step: 7}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got encoder
+			err := Unmarshal(tc.argData, &got)
+			data, errMarshal := Marshal(got)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Nil(t, errMarshal)
+			assert.Equal(t, tc.argData, data)
+		})
+	}
+}
+
+func TestUnmarshal_EncTagDecodeError(t *testing.T) {
+	// Setup
+	var out testBCD
+
+	// Exercise
+	err := Unmarshal([]byte{0x59, 0x07, 0x20, 0x2A, 0x12, 0x34}, &out)
+
+	// Verify
+	var decodeErr *DecodeError
+	assert.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, "Year", decodeErr.Path)
+}
+
+func TestUnmarshal_EncTagFloat16(t *testing.T) {
+	// Setup
+	type half struct {
+		V float32 `bit:"16" enc:"float16"`
+		W float64 `bit:"16" enc:"float16"`
+	}
+	testCases := map[string]struct {
+		data []byte
+		want float64
+	}{
+		"One":       {data: []byte{0x00, 0x3C}, want: 1},
+		"Negative":  {data: []byte{0x00, 0xC0}, want: -2},
+		"Fraction":  {data: []byte{0x55, 0x35}, want: 0.333251953125},
+		"Max":       {data: []byte{0xFF, 0x7B}, want: 65504},
+		"Subnormal": {data: []byte{0x01, 0x00}, want: 0x1p-24},
+		"Infinity":  {data: []byte{0x00, 0x7C}, want: math.Inf(1)},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got half
+			err := Unmarshal(append(tc.data, tc.data...), &got)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, half{V: float32(tc.want), W: tc.want}, got)
+		})
+	}
+}
+
+func TestUnmarshal_EncTagFloat16NaN(t *testing.T) {
+	// Setup
+	var got struct {
+		V float32 `bit:"16" enc:"float16"`
+	}
+
+	// Exercise
+	err := Unmarshal([]byte{0x00, 0x7E}, &got)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.True(t, math.IsNaN(float64(got.V)))
+}
+
+func TestUnmarshal_EncTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"Signed": &struct {
+			V int8 `enc:"bcd"`
+		}{},
+		"BitSize": &struct {
+			V uint8 `bit:"6" enc:"bcd"`
+		}{},
+		"Encoding": &struct {
+			V uint8 `enc:"ebcdic"`
+		}{},
+		"GraySigned": &struct {
+			V int16 `bit:"10" enc:"gray"`
+		}{},
+		"Float16Integer": &struct {
+			V uint16 `bit:"16" enc:"float16"`
+		}{},
+		"Float16BitSize": &struct {
+			V float32 `bit:"12" enc:"float16"`
+		}{},
+		"Float16NoBit": &struct {
+			V float32 `enc:"float16"`
+		}{},
+		"Float16Scale": &struct {
+			V float32 `bit:"16" enc:"float16" scale:"0.5"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(make([]byte, 2), out)
+
+			// Verify
+			assertFieldError("V")(t, err)
+		})
+	}
+}
+
+func TestUnmarshal_SignedTag(t *testing.T) {
+	// Setup
+	type deltas struct {
+		A int8  `bit:"4" signed:"zigzag"`
+		B int8  `bit:"4" signed:"zigzag"`
+		C int16 `bit:"12" signed:"zigzag"`
+		D int64 `signed:"zigzag"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		want    deltas
+	}{
+		"Zero":     {argData: make([]byte, 10), want: deltas{}},
+		"Small":    {argData: []byte{0x21, 0x03, 0x00, 0x03, 0, 0, 0, 0, 0, 0}, want: deltas{A: -1, B: 1, C: -2, D: -2}},
+		"Extremes": {argData: []byte{0xEF, 0xFF, 0x0F, 0xFE, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, want: deltas{A: -8, B: 7, C: -2048, D: math.MaxInt64}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got deltas
+			err := Unmarshal(tc.argData, &got)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_SignedTagOnesAndMagnitude(t *testing.T) {
+	// Setup
+	type legacy struct {
+		Ones      int8 `bit:"4" signed:"ones"`
+		Magnitude int8 `bit:"4" signed:"magnitude"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		want    legacy
+	}{
+		"Positive":     {argData: []byte{0x57}, want: legacy{Ones: 7, Magnitude: 5}},
+		"Negative":     {argData: []byte{0x9E}, want: legacy{Ones: -1, Magnitude: -1}},
+		"Extremes":     {argData: []byte{0xF8}, want: legacy{Ones: -7, Magnitude: -7}},
+		"NegativeZero": {argData: []byte{0x8F}, want: legacy{}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got legacy
+			err := Unmarshal(tc.argData, &got)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_SignedTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"Unsigned": &struct {
+			V uint8 `signed:"zigzag"`
+		}{},
+		"SignMagnitude": &struct {
+			V int8 `bit:"4,sm" signed:"zigzag"`
+		}{},
+		"Representation": &struct {
+			V int8 `signed:"offset"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(make([]byte, 2), out)
+
+			// Verify
+			assertFieldError("V")(t, err)
+		})
+	}
+}
+
+type testScaled struct {
+	Temp     float64 `bit:"12" scale:"0.0625,signed"`
+	Humidity float32 `bit:"8" scale:"0.5"`
+	_        uint8   `bit:"4"`
+	Ambient  float64 `bit:"8" scale:"1,offset=-40"`
+}
+
+var testScaledData = []byte{0x91, 0x0F, 0x01, 0x3C}
+
+func TestUnmarshal_ScaleTag(t *testing.T) {
+	// Setup
+	want := testScaled{Temp: -6.9375, Humidity: 8, Ambient: 20}
+
+	// Exercise
+	var got testScaled
+	err := Unmarshal(testScaledData, &got)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestUnmarshal_ScaleTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"NoBit": &struct {
+			V float64 `scale:"0.5"`
+		}{},
+		"NotFloat": &struct {
+			V uint8 `bit:"8" scale:"0.5"`
+		}{},
+		"Zero": &struct {
+			V float64 `bit:"8" scale:"0"`
+		}{},
+		"Option": &struct {
+			V float64 `bit:"8" scale:"0.5,bias=1"`
+		}{},
+		"BitSize": &struct {
+			V float64 `bit:"65" scale:"0.5"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(make([]byte, 16), out)
+
+			// Verify
+			assertFieldError("V")(t, err)
+		})
+	}
+}
+
+type testQFormat struct {
+	Gain float64 `qformat:"8.8"`
+	Coef float32 `qformat:"1.15"`
+	Step float64 `qformat:"4.4,round=floor"`
+}
+
+var testQFormatData = []byte{0x80, 0xFE, 0x00, 0x40, 0xFC}
+
+func TestUnmarshal_QFormatTag(t *testing.T) {
+	// Setup
+	want := testQFormat{Gain: -1.5, Coef: 0.5, Step: -0.25}
+
+	// Exercise
+	var got testQFormat
+	err := Unmarshal(testQFormatData, &got)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestUnmarshal_QFormatTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"NotFloat": &struct {
+			V int16 `qformat:"8.8"`
+		}{},
+		"WithBit": &struct {
+			V float64 `bit:"16" qformat:"8.8"`
+		}{},
+		"WithScale": &struct {
+			V float64 `qformat:"8.8" scale:"0.5"`
+		}{},
+		"NoFraction": &struct {
+			V float64 `qformat:"8"`
+		}{},
+		"NoSignBit": &struct {
+			V float64 `qformat:"0.16"`
+		}{},
+		"TooWide": &struct {
+			V float64 `qformat:"32.33"`
+		}{},
+		"Rounding": &struct {
+			V float64 `qformat:"8.8,round=up"`
+		}{},
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(make([]byte, 16), out)
+
+			// Verify
+			assertFieldError("V")(t, err)
+		})
+	}
+}
+
+func TestUnmarshal_OffsetTag(t *testing.T) {
+	// Setup
+	type header struct {
+		Flags   uint8  `bit:"4" offset:"28"`
+		Version uint8  `bit:"4" offset:"0"`
+		Length  uint16 `offset:"8"`
+	}
+	type wrapper struct {
+		Kind   uint8 `bit:"3"`
+		Header header
+		Tail   uint8 `bit:"4"`
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOut  any
+		want    any
+	}{
+		"Reordered": {
+			argData: []byte{0xF5, 0x34, 0x12, 0xAF},
+			argOut:  &header{},
+			want:    &header{Flags: 0xA, Version: 5, Length: 0x1234},
+		},
+		"Nested": {
+			argData: []byte{0x02, 0x05, 0x34, 0x12, 0xA0, 0x0C},
+			argOut:  &wrapper{},
+			want:    &wrapper{Kind: 2, Header: header{Flags: 0xA, Version: 5, Length: 0x1234}, Tail: 0xC},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(tc.argData, tc.argOut)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, tc.argOut)
+		})
+	}
+}
+
+func TestUnmarshal_OffsetTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]struct {
+		argOut   any
+		wantPath string
+	}{
+		"Missing": {
+			argOut: &struct {
+				A uint8 `bit:"4" offset:"4"`
+				B uint8 `bit:"4"`
+			}{},
+			wantPath: "B",
+		},
+		"Negative": {
+			argOut: &struct {
+				A uint8 `bit:"4" offset:"-4"`
+			}{},
+			wantPath: "A",
+		},
+		"Overlap": {
+			argOut: &struct {
+				A uint8 `bit:"4" offset:"4"`
+				B uint8 `bit:"4" offset:"6"`
+			}{},
+			wantPath: "B",
+		},
+		"Unaligned": {
+			argOut: &struct {
+				A uint16 `offset:"4"`
+			}{},
+			wantPath: "A",
+		},
+		"NoBits": {
+			argOut: &struct {
+				A uint8  `bit:"4" offset:"0"`
+				B string `offset:"4"`
+			}{},
+			wantPath: "B",
+		},
+		"Nested": {
+			argOut: &struct {
+				A uint8 `bit:"4" offset:"0"`
+				B struct {
+					C uint8
+				}
+			}{},
+			wantPath: "B",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x00, 0x00}, tc.argOut)
+
+			// Verify
+			assertFieldError(tc.wantPath)(t, err)
+		})
+	}
+}
+
+func TestUnmarshal_ByteOrderMark(t *testing.T) {
+	// Setup
+	type ifd struct {
+		Count uint16
+	}
+	type tiff struct {
+		Mark   uint16 `byteorder:"little=0x4949,big=0x4D4D"`
+		Magic  uint16
+		Offset uint32
+		IFD    ifd
+	}
+	type flagged struct {
+		Big   bool  `bit:"1" byteorder:"little=0,big=1"`
+		_     uint8 `bit:"7"`
+		Value uint16
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOut  any
+		want    any
+	}{
+		"Little": {
+			argData: []byte{0x49, 0x49, 0x2A, 0x00, 0x08, 0x00, 0x00, 0x00, 0x01, 0x00},
+			argOut:  &tiff{},
+			want:    &tiff{Mark: 0x4949, Magic: 42, Offset: 8, IFD: ifd{Count: 1}},
+		},
+		"Big": {
+			argData: []byte{0x4D, 0x4D, 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08, 0x00, 0x01},
+			argOut:  &tiff{},
+			want:    &tiff{Mark: 0x4D4D, Magic: 42, Offset: 8, IFD: ifd{Count: 1}},
+		},
+		"Flag": {
+			argData: []byte{0x01, 0x12, 0x34},
+			argOut:  &flagged{},
+			want:    &flagged{Big: true, Value: 0x1234},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(tc.argData, tc.argOut)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, tc.argOut)
+		})
+	}
+}
+
+func TestUnmarshal_ByteOrderMarkError(t *testing.T) {
+	// Setup
+	var valid struct {
+		Mark  uint16 `byteorder:"little=0x4949,big=0x4D4D"`
+		Magic uint16
+	}
+	var invalidTag struct {
+		Mark uint16 `byteorder:"middle=1"`
+	}
+	var notInteger struct {
+		Mark Raw `bit:"16" byteorder:"little=0"`
+	}
+
+	// Exercise
+	errValue := Unmarshal([]byte{0x12, 0x34, 0x00, 0x00}, &valid)
+	errTag := Unmarshal([]byte{0x00, 0x00}, &invalidTag)
+	errType := Unmarshal([]byte{0x00, 0x00}, &notInteger)
+	_, errLayout := LayoutOf(valid)
+
+	// Verify
+	var byteOrderError *ByteOrderError
+	if assert.ErrorAs(t, errValue, &byteOrderError) {
+		assert.Equal(t, "Mark", byteOrderError.Path)
+		assert.Equal(t, uint64(0x3412), byteOrderError.Value)
+	}
+	assertFieldError("Mark")(t, errTag)
+	assertFieldError("Mark")(t, errType)
+	assertFieldError("Mark")(t, errLayout)
+}
+
+func TestUnmarshal_SkipTag(t *testing.T) {
+	// Setup
+	type inner struct {
+		C uint8 `bit:"4"`
+	}
+	type s struct {
+		A uint8 `bit:"4"`
+		B uint8 `bit:"4" skip:"20"`
+		D uint16
+		E inner `skip:"4"`
+	}
+	data := []byte{0xF1, 0xFF, 0xFF, 0xF2, 0x34, 0x12, 0x5F}
+	want := s{A: 1, B: 2, D: 0x1234, E: inner{C: 5}}
+
+	// Exercise
+	var got s
+	err := Unmarshal(data, &got)
+	layout, errLayout := LayoutOf(s{})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+	assert.Nil(t, errLayout)
+	assert.Equal(t, 24, layout.Fields[1].Offset)
+	assert.Equal(t, 52, layout.Fields[3].Offset)
+	assert.Equal(t, 56, layout.BitSize)
+}
+
+func TestUnmarshal_SkipTagError(t *testing.T) {
+	// Setup
+	var zero struct {
+		A uint8 `bit:"4" skip:"0"`
+	}
+	var array struct {
+		A [2]uint8 `skip:"4"`
+	}
+	var offset struct {
+		A uint8 `bit:"4" offset:"0"`
+		B uint8 `bit:"4" offset:"8" skip:"4"`
+	}
+
+	// Exercise
+	errZero := Unmarshal([]byte{0x00}, &zero)
+	errArray := Unmarshal([]byte{0x00}, &array)
+	errOffset := Unmarshal([]byte{0x00}, &offset)
+
+	// Verify
+	assertFieldError("A")(t, errZero)
+	assertFieldError("A")(t, errArray)
+	assertFieldError("B")(t, errOffset)
+}
+
+func TestUnmarshal_BytesTag(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8  `bit:"4"`
+		B uint32 `bytes:"3"`
+		C int64  `bytes:"6"`
+		D uint16 `bytes:"2"`
+	}
+	inputData := []byte{
+		0xFF,
+		0x12, 0x34, 0x56,
+		0xFE, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+		0xAB, 0xCD,
+	}
+	testCases := map[string]struct {
+		order ByteOrder
+		want  a
+	}{
+		"LittleEndian": {LittleEndian, a{A: 0xF, B: 0x563412, C: -2, D: 0xCDAB}},
+		"BigEndian":    {BigEndian, a{A: 0xF, B: 0x123456, C: -0x10000000001, D: 0xABCD}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got a
+			err := Unmarshal(inputData, &got, WithByteOrder(tc.order))
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnmarshal_BytesTagError(t *testing.T) {
+	// Setup
+	var withBitTag struct {
+		A uint32 `bit:"24" bytes:"3"`
+	}
+	var sizeNonNumber struct {
+		A uint32 `bytes:"x"`
+	}
+	var sizeZero struct {
+		A uint32 `bytes:"0"`
+	}
+	var overTypeSize struct {
+		A uint32 `bytes:"5"`
+	}
+	var rawField struct {
+		A Raw `bytes:"2"`
+	}
+	testCases := map[string]any{
+		"With bit tag":    &withBitTag,
+		"Size non-number": &sizeNonNumber,
+		"Size zero":       &sizeZero,
+		"Over type size":  &overTypeSize,
+		"Raw field":       &rawField,
+	}
+
+	for name, out := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal([]byte{0x00}, out)
+
+			// Verify
+			var fieldError *FieldError
+			assert.ErrorAs(t, err, &fieldError)
+		})
+	}
+}
+
+func TestUnmarshal_SignedBoundaries(t *testing.T) {
+	// Setup
+	type width1 struct {
+		A int8 `bit:"1"`
+		B int8 `bit:"1"`
+	}
+	type width63 struct {
+		A int64 `bit:"63"`
+		_ uint8 `bit:"1"`
+	}
+	type width64 struct {
+		A int64 `bit:"64"`
+	}
+	testCases := map[string]struct {
+		order ByteOrder
+		data  []byte
+		out   any
+		want  any
+	}{
+		"1 bit":                  {LittleEndian, []byte{0x01}, &width1{}, &width1{A: -1, B: 0}},
+		"1 bit (BE)":             {BigEndian, []byte{0x01}, &width1{}, &width1{A: -1, B: 0}},
+		"63 bits min":            {LittleEndian, []byte{0, 0, 0, 0, 0, 0, 0, 0x40}, &width63{}, &width63{A: -1 << 62}},
+		"63 bits max":            {LittleEndian, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xBF}, &width63{}, &width63{A: 1<<62 - 1}},
+		"63 bits minus one":      {LittleEndian, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F}, &width63{}, &width63{A: -1}},
+		"63 bits min (BE)":       {BigEndian, []byte{0x80, 0, 0, 0, 0, 0, 0, 0}, &width63{}, &width63{A: -1 << 62}},
+		"63 bits max (BE)":       {BigEndian, []byte{0x7F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, &width63{}, &width63{A: 1<<62 - 1}},
+		"63 bits minus one (BE)": {BigEndian, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F}, &width63{}, &width63{A: -1}},
+		"64 bits min":            {LittleEndian, []byte{0, 0, 0, 0, 0, 0, 0, 0x80}, &width64{}, &width64{A: -1 << 63}},
+		"64 bits max":            {LittleEndian, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F}, &width64{}, &width64{A: 1<<63 - 1}},
+		"64 bits minus one":      {LittleEndian, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, &width64{}, &width64{A: -1}},
+		"64 bits min (BE)":       {BigEndian, []byte{0x80, 0, 0, 0, 0, 0, 0, 0}, &width64{}, &width64{A: -1 << 63}},
+		"64 bits max (BE)":       {BigEndian, []byte{0x7F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, &width64{}, &width64{A: 1<<63 - 1}},
+		"64 bits minus one (BE)": {BigEndian, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, &width64{}, &width64{A: -1}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := Unmarshal(tc.data, tc.out, WithByteOrder(tc.order))
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, tc.out)
+		})
+	}
+}
+
+func TestUnmarshal_WithDecodeHook(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8   `bit:"4"`
+		B float64 `bit:"12" step:"0.5"`
+		c uint8
+		D int8 `bit:"8"`
+		E Raw  `bit:"8"`
+	}
+	inputData := []byte{0x31, 0x02, 0xFF, 0xFE, 0xAA}
+	var infos []FieldInfo
+	var raws []uint64
+	hook := func(f FieldInfo, v reflect.Value, raw uint64) error {
+		infos = append(infos, f)
+		raws = append(raws, raw)
+		switch v.Kind() {
+		case reflect.Float64:
+			step, _ := strconv.ParseFloat(f.Tag.Get("step"), 64)
+			v.SetFloat(float64(raw) * step)
+		case reflect.Uint8:
+			v.SetUint(v.Uint() * 10)
+		}
+		return nil
+	}
+	want := a{A: 10, B: 17.5, D: -2, E: Raw{0xAA}}
+
+	// Exercise
+	var got a
+	err := Unmarshal(inputData, &got, WithDecodeHook(hook))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, []uint64{0x1, 0x023, 0xFE}, raws)
+	assert.Equal(t, []FieldInfo{
+		{FieldLayout{Name: "A", Type: reflect.TypeOf(uint8(0)), Offset: 0, Bits: 4}, `bit:"4"`},
+		{FieldLayout{Name: "B", Type: reflect.TypeOf(float64(0)), Offset: 4, Bits: 12}, `bit:"12" step:"0.5"`},
+		{FieldLayout{Name: "D", Type: reflect.TypeOf(int8(0)), Offset: 24, Bits: 8, Signed: true}, `bit:"8"`},
+	}, infos)
+}
+
+func TestUnmarshal_WithDecodeHookError(t *testing.T) {
+	// Setup
+	var out struct {
+		A uint8 `bit:"4"`
+	}
+	hookErr := errors.New("rejected")
+	hook := func(f FieldInfo, v reflect.Value, raw uint64) error {
+		return hookErr
+	}
+	var tooWide struct {
+		A [16]byte `bit:"65"`
+	}
+
+	// Exercise
+	err := Unmarshal([]byte{0x00}, &out, WithDecodeHook(hook))
+	errTooWide := Unmarshal([]byte{0x00}, &tooWide, WithDecodeHook(hook))
+
+	// Verify
+	assert.ErrorIs(t, err, hookErr)
+	var fieldError *FieldError
+	assert.ErrorAs(t, errTooWide, &fieldError)
+}
+
+func TestOption(t *testing.T) {
+	// Setup
+	testCases := map[string]struct {
+		arg        Option
+		wantName   string
+		wantValue  any
+		wantString string
+	}{
+		"ByteOrder": {
+			arg:        WithByteOrder(BigEndian),
+			wantName:   "WithByteOrder",
+			wantValue:  BigEndian,
+			wantString: "WithByteOrder(BigEndian)",
+		},
+		"BitOrder": {
+			arg:        WithBitOrder(MSBFirst),
+			wantName:   "WithBitOrder",
+			wantValue:  MSBFirst,
+			wantString: "WithBitOrder(MSBFirst)",
+		},
+		"Unexported": {
+			arg:        WithUnexported(RejectUnexported),
+			wantName:   "WithUnexported",
+			wantValue:  RejectUnexported,
+			wantString: "WithUnexported(RejectUnexported)",
+		},
+		"NoArguments": {
+			arg:        WithAllowPartial(),
+			wantName:   "WithAllowPartial",
+			wantValue:  []any{},
+			wantString: "WithAllowPartial()",
+		},
+		"SeveralArguments": {
+			arg:        WithFrameLength("Length", -4),
+			wantName:   "WithFrameLength",
+			wantValue:  []any{"Length", -4},
+			wantString: `WithFrameLength("Length", -4)`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			gotName := tc.arg.Name()
+			gotValue := tc.arg.Value()
+			gotString := tc.arg.String()
+
+			// Verify
+			assert.Equal(t, tc.wantName, gotName)
+			assert.Equal(t, tc.wantValue, gotValue)
+			assert.Equal(t, tc.wantString, gotString)
+		})
+	}
+}
+
+func TestOption_Merge(t *testing.T) {
+	// Setup
+	defaults := []Option{WithByteOrder(BigEndian), WithMaxDepth(8)}
+	overrides := []Option{WithByteOrder(LittleEndian)}
+	merged := map[string]Option{}
+	for _, opt := range append(defaults, overrides...) {
+		merged[opt.Name()] = opt
+	}
+	var out struct{ A uint16 }
+
+	// Exercise
+	err := Unmarshal([]byte{0x34, 0x12}, &out, merged["WithByteOrder"], merged["WithMaxDepth"])
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(0x1234), out.A)
+	assert.Len(t, merged, 2)
+}
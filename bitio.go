@@ -0,0 +1,171 @@
+package bitfield
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// A BitReader reads bits from a byte slice with the same cursor as
+// [Unmarshal], for formats which need manual bit surgery alongside decoding
+// structs. The bits are read in the bit order of the options, and values of
+// several bytes in their byte order, as integer fields are:
+//
+//	r := bitfield.NewBitReader(data, bitfield.WithBitOrder(bitfield.MSBFirst))
+//	n, _ := r.ReadBits(5)
+//	preamble := make([]uint64, n)
+//	for i := range preamble {
+//		preamble[i], _ = r.ReadBits(3)
+//	}
+//	err := r.Unmarshal(&header)
+type BitReader struct {
+	r       bitReader
+	options options
+	err     error // error from the options, returned by every read
+}
+
+// NewBitReader returns a new reader of the bits of data. The opts apply to
+// every read, as in [Unmarshal].
+func NewBitReader(data []byte, opts ...Option) *BitReader {
+	options, err := collectOptions(opts)
+	return &BitReader{
+		r:       bitReader{data: data, nbits: len(data) * 8, bitOrder: options.bitOrder, wordSize: options.wordSize},
+		options: options,
+		err:     err,
+	}
+}
+
+// ReadBits reads n bits, from 0 to 64, as an unsigned integer. If fewer than
+// n bits remain, it reads nothing and returns an error wrapping
+// [io.ErrUnexpectedEOF].
+func (r *BitReader) ReadBits(n int) (uint64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if n < 0 || n > 64 {
+		return 0, fmt.Errorf("bitfield: bit count %d out of range [0, 64]", n)
+	}
+	if remaining := r.Remaining(); n > remaining {
+		return 0, fmt.Errorf("bitfield: %d bits requested with %d bits remaining: %w", n, remaining, io.ErrUnexpectedEOF)
+	}
+	return r.r.readValue(n, r.options.byteOrder), nil
+}
+
+// Unmarshal reads the struct pointed by out from the current bit, as
+// [UnmarshalAt] does, and moves past it. [WithStrictSize] does not apply, as
+// the data may continue after the struct.
+func (r *BitReader) Unmarshal(out any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if err := validateUnmarshalType(out, r.options); err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(out).Elem()
+	if r.options.zero {
+		rv.SetZero()
+	}
+	return r.r.unmarshalStruct(rv, "", true, r.options)
+}
+
+// Align moves to the start of the next byte, unless at the start of a byte.
+func (r *BitReader) Align() {
+	r.r.alignToByte()
+}
+
+// Offset returns the number of bits from the start of the data to the
+// current bit.
+func (r *BitReader) Offset() int {
+	return r.r.iData*8 + r.r.iBitInData
+}
+
+// Remaining returns the number of bits from the current bit to the end of the
+// data.
+func (r *BitReader) Remaining() int {
+	return max(r.r.nbits-r.Offset(), 0)
+}
+
+// A BitWriter writes bits into a growing byte slice with the same cursor as
+// [Marshal], the counterpart of [BitReader]. Bits not written, such as those
+// skipped by [BitWriter.Align], are zero.
+type BitWriter struct {
+	w       bitWriter
+	options options
+	err     error // error from the options, returned by every write
+}
+
+// NewBitWriter returns a new writer of bits. The opts apply to every write,
+// as in [Marshal].
+func NewBitWriter(opts ...Option) *BitWriter {
+	options, err := collectOptions(opts)
+	return &BitWriter{
+		w:       bitWriter{bitOrder: options.bitOrder, wordSize: options.wordSize},
+		options: options,
+		err:     err,
+	}
+}
+
+// WriteBits writes the low n bits of v, from 0 to 64. It returns an error if
+// v does not fit in n bits.
+func (w *BitWriter) WriteBits(v uint64, n int) error {
+	if w.err != nil {
+		return w.err
+	}
+	if n < 0 || n > 64 {
+		return fmt.Errorf("bitfield: bit count %d out of range [0, 64]", n)
+	}
+	if n < 64 && v>>n != 0 {
+		return fmt.Errorf("bitfield: value %#x overflows %d bits", v, n)
+	}
+	w.w.writeValue(v, n, w.options.byteOrder)
+	return nil
+}
+
+// Marshal writes the struct v, or the struct pointed by v, from the current
+// bit as [Marshal] does, and moves past it. On error, nothing is written.
+func (w *BitWriter) Marshal(v any) error {
+	if w.err != nil {
+		return w.err
+	}
+	rv, err := marshaledValue(v)
+	if err != nil {
+		return err
+	}
+	if err := validateMarshalType(rv.Type(), w.options); err != nil {
+		return err
+	}
+	w.w.autos = w.w.autos[:0]
+	saved, n := w.w, len(w.w.data)
+	var partial byte // byte being written, if any
+	if saved.iData < n {
+		partial = saved.data[saved.iData]
+	}
+	if err := marshal(&w.w, addressable(rv, w.options), "", true, w.options); err != nil {
+		saved.data = w.w.data[:n]
+		if saved.iData < n {
+			saved.data[saved.iData] = partial
+		}
+		w.w = saved
+		return err
+	}
+	if w.options.sequence != nil {
+		w.options.sequence.advance(w.w.autos)
+	}
+	return nil
+}
+
+// Align moves to the start of the next byte, unless at the start of a byte.
+func (w *BitWriter) Align() {
+	w.w.alignToByte()
+}
+
+// Offset returns the number of bits written.
+func (w *BitWriter) Offset() int {
+	return w.w.iData*8 + w.w.iBitInData
+}
+
+// Bytes returns the bytes written, the last of which is padded with zeros if
+// partially written. The slice is valid until the next write.
+func (w *BitWriter) Bytes() []byte {
+	return w.w.data[:(w.Offset()+7)/8]
+}
@@ -0,0 +1,108 @@
+package bitfield
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitReader(t *testing.T) {
+	// Setup
+	type header struct {
+		A uint8 `bit:"4"`
+		B uint8
+	}
+	r := NewBitReader([]byte{0xA5, 0x3C, 0x42, 0xFF}, WithBitOrder(MSBFirst))
+
+	// Exercise
+	first, errFirst := r.ReadBits(3)
+	second, errSecond := r.ReadBits(5)
+	offset := r.Offset()
+	var got header
+	errUnmarshal := r.Unmarshal(&got)
+	r.Align()
+	last, errLast := r.ReadBits(8)
+	_, errEOF := r.ReadBits(1)
+
+	// Verify
+	assert.Nil(t, errFirst)
+	assert.Equal(t, uint64(0b101), first)
+	assert.Nil(t, errSecond)
+	assert.Equal(t, uint64(0b00101), second)
+	assert.Equal(t, 8, offset)
+	assert.Nil(t, errUnmarshal)
+	assert.Equal(t, header{A: 0x3, B: 0x42}, got)
+	assert.Nil(t, errLast)
+	assert.Equal(t, uint64(0xFF), last)
+	assert.ErrorIs(t, errEOF, io.ErrUnexpectedEOF)
+	assert.Equal(t, 32, r.Offset())
+	assert.Equal(t, 0, r.Remaining())
+}
+
+func TestBitReaderError(t *testing.T) {
+	// Setup
+	r := NewBitReader([]byte{0xFF})
+
+	// Exercise
+	_, errRange := r.ReadBits(65)
+	_, errShort := r.ReadBits(9)
+	errType := r.Unmarshal(struct{}{})
+	_, errOption := NewBitReader(nil, WithWordSize(12)).ReadBits(1)
+
+	// Verify
+	assert.NotNil(t, errRange)
+	assert.ErrorIs(t, errShort, io.ErrUnexpectedEOF)
+	assert.Equal(t, 0, r.Offset())
+	var typeErr *TypeError
+	assert.ErrorAs(t, errType, &typeErr)
+	assert.NotNil(t, errOption)
+}
+
+func TestBitWriter(t *testing.T) {
+	// Setup
+	type header struct {
+		A uint8 `bit:"4"`
+		B uint8
+	}
+	w := NewBitWriter(WithBitOrder(MSBFirst))
+
+	// Exercise
+	errFirst := w.WriteBits(0b101, 3)
+	errSecond := w.WriteBits(0b00101, 5)
+	errMarshal := w.Marshal(header{A: 0x3, B: 0x42})
+	w.WriteBits(0b1, 1)
+	w.Align()
+
+	// Verify
+	assert.Nil(t, errFirst)
+	assert.Nil(t, errSecond)
+	assert.Nil(t, errMarshal)
+	assert.Equal(t, 32, w.Offset())
+	assert.Equal(t, []byte{0xA5, 0x30, 0x42, 0x80}, w.Bytes())
+}
+
+func TestBitWriterError(t *testing.T) {
+	// Setup
+	type header struct {
+		A uint8 `bit:"4"`
+		B uint8 `const:"1"`
+	}
+	w := NewBitWriter()
+	w.WriteBits(0x5, 4)
+
+	// Exercise
+	errRange := w.WriteBits(0, 65)
+	errOverflow := w.WriteBits(0x10, 4)
+	errMarshal := w.Marshal(header{A: 0xF, B: 2})
+	errOption := NewBitWriter(WithWordSize(12)).WriteBits(0, 1)
+
+	// Verify
+	assert.NotNil(t, errRange)
+	assert.NotNil(t, errOverflow)
+	var constErr *ConstError
+	assert.ErrorAs(t, errMarshal, &constErr)
+	assert.Equal(t, 4, w.Offset())
+	assert.Equal(t, []byte{0x05}, w.Bytes())
+	assert.NotNil(t, errOption)
+}
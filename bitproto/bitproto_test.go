@@ -0,0 +1,180 @@
+package bitproto
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const droneProto = `// Drone status
+proto drone
+
+option c.struct_packing_alignment = 1
+
+const MAX_SPEED = 0x7F
+
+type Timestamp = int64
+
+enum DroneStatus : uint3 {
+    DRONE_STATUS_UNKNOWN = 0
+    DRONE_STATUS_RUNNING = 1;
+    DRONE_STATUS_LANDING = 2
+}
+
+message Pose {
+    int32 latitude = 2
+    uint7 speed = 1
+    bool stable = 3
+    message Flags {
+        bool armed = 1
+        uint3 mode = 2
+    }
+    DroneStatus status = 4;
+    Timestamp time = 5
+}
+`
+
+func TestParse(t *testing.T) {
+	// Setup
+	want := &File{
+		Proto:   "drone",
+		Consts:  []Const{{Name: "MAX_SPEED", Value: 0x7F}},
+		Aliases: []Alias{{Name: "Timestamp", Type: Type{Bits: 64, Signed: true}}},
+		Enums: []Enum{{Name: "DroneStatus", Bits: 3, Values: []Const{
+			{Name: "DRONE_STATUS_UNKNOWN", Value: 0},
+			{Name: "DRONE_STATUS_RUNNING", Value: 1},
+			{Name: "DRONE_STATUS_LANDING", Value: 2},
+		}}},
+		Messages: []Message{
+			{Name: "Pose", Fields: []Field{
+				{Name: "latitude", Type: Type{Bits: 32, Signed: true}, Number: 2},
+				{Name: "speed", Type: Type{Bits: 7}, Number: 1},
+				{Name: "stable", Type: Type{Bits: 1, Bool: true}, Number: 3},
+				{Name: "status", Type: Type{Name: "DroneStatus"}, Number: 4},
+				{Name: "time", Type: Type{Name: "Timestamp"}, Number: 5},
+			}},
+			{Name: "Pose.Flags", Fields: []Field{
+				{Name: "armed", Type: Type{Bits: 1, Bool: true}, Number: 1},
+				{Name: "mode", Type: Type{Bits: 3}, Number: 2},
+			}},
+		},
+	}
+
+	// Exercise
+	got, err := Parse(strings.NewReader(droneProto))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestGenerate(t *testing.T) {
+	// Setup
+	file, _ := Parse(strings.NewReader(droneProto))
+	want := "// Code generated by bitproto2go. DO NOT EDIT.\n" +
+		"// Source: proto drone\n" +
+		"\n" +
+		"package drone\n" +
+		"\n" +
+		"const (\n" +
+		"\tMaxSpeed = 127\n" +
+		")\n" +
+		"\n" +
+		"type Timestamp int64\n" +
+		"\n" +
+		"type DroneStatus uint8\n" +
+		"\n" +
+		"const (\n" +
+		"\tDroneStatusUnknown DroneStatus = 0\n" +
+		"\tDroneStatusRunning DroneStatus = 1\n" +
+		"\tDroneStatusLanding DroneStatus = 2\n" +
+		")\n" +
+		"\n" +
+		"type Pose struct {\n" +
+		"\tSpeed    uint8       `bit:\"7\"`\n" +
+		"\tLatitude int32       `bit:\"32\"`\n" +
+		"\tStable   uint8       `bit:\"1\"`\n" +
+		"\tStatus   DroneStatus `bit:\"3\"`\n" +
+		"\tTime     Timestamp   `bit:\"64\"`\n" +
+		"}\n" +
+		"\n" +
+		"type PoseFlags struct {\n" +
+		"\tArmed uint8 `bit:\"1\"`\n" +
+		"\tMode  uint8 `bit:\"3\"`\n" +
+		"}\n"
+
+	// Exercise
+	var got strings.Builder
+	err := Generate(&got, file, "drone")
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.String())
+}
+
+func TestParseError(t *testing.T) {
+	testCases := map[string]string{
+		"Import":            `import "base.bitproto"`,
+		"Missing number":    "message A {\n uint8 a =\n}",
+		"Invalid type":      "message A {\n uint65 a = 1\n}",
+		"Signed enum":       "enum E : int8 {\n}",
+		"Unclosed message":  "message A {\n uint8 a = 1\n",
+		"Unknown statement": "foo",
+	}
+
+	for name, src := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			_, err := Parse(strings.NewReader(src))
+
+			// Verify
+			var syntaxError *SyntaxError
+			assert.ErrorAs(t, err, &syntaxError)
+		})
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	testCases := map[string]string{
+		"Array":          "message A {\n uint8[4] a = 1\n}",
+		"Message field":  "message B {\n uint8 b = 1\n}\nmessage A {\n B b = 1\n}",
+		"Undefined type": "message A {\n Foo a = 1\n}",
+	}
+
+	for name, src := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Setup
+			file, err := Parse(strings.NewReader(src))
+			assert.Nil(t, err)
+
+			// Exercise
+			err = Generate(&strings.Builder{}, file, "p")
+
+			// Verify
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestStructOf(t *testing.T) {
+	// Setup
+	file, _ := Parse(strings.NewReader(droneProto))
+	type pose struct {
+		Speed    uint8 `bit:"7"`
+		Latitude int32 `bit:"32"`
+		Stable   uint8 `bit:"1"`
+		Status   uint8 `bit:"3"`
+		Time     int64 `bit:"64"`
+	}
+
+	// Exercise
+	got, err := StructOf(file, "Pose")
+	_, errNotFound := StructOf(file, "Unknown")
+
+	// Verify
+	assert.Nil(t, err)
+	assert.True(t, got.ConvertibleTo(reflect.TypeOf(pose{})))
+	assert.NotNil(t, errNotFound)
+}
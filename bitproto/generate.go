@@ -0,0 +1,153 @@
+package bitproto
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Generate writes Go source declaring the constants, enums, aliases and
+// messages of f in package pkg.
+//
+// Messages become structs whose fields are all bit-fields, sorted by field
+// number, which is the order in which bitproto encodes them. Enums and aliases
+// become named integer types. bool fields become uint8 bit-fields of size 1.
+// Names are converted to Go's exported CamelCase; the name of a nested message
+// is prefixed with the name of its parent.
+func Generate(w io.Writer, f *File, pkg string) error {
+	g := &generator{file: f}
+	g.printf("// Code generated by bitproto2go. DO NOT EDIT.\n")
+	if f.Proto != "" {
+		g.printf("// Source: proto %s\n", f.Proto)
+	}
+	g.printf("\npackage %s\n", pkg)
+
+	if len(f.Consts) > 0 {
+		g.printf("\nconst (\n")
+		for _, c := range f.Consts {
+			g.printf("%s = %d\n", goName(c.Name), c.Value)
+		}
+		g.printf(")\n")
+	}
+	for _, a := range f.Aliases {
+		if a.Type.Name != "" || a.Type.Len > 0 {
+			return fmt.Errorf("bitproto: alias %s: only base types are supported", a.Name)
+		}
+		g.printf("\ntype %s %s\n", goName(a.Name), goType(a.Type.Bits, a.Type.Signed))
+	}
+	for _, e := range f.Enums {
+		name := goName(e.Name)
+		g.printf("\ntype %s %s\n", name, goType(e.Bits, false))
+		if len(e.Values) > 0 {
+			g.printf("\nconst (\n")
+			for _, v := range e.Values {
+				g.printf("%s %s = %d\n", goName(v.Name), name, v.Value)
+			}
+			g.printf(")\n")
+		}
+	}
+	for _, m := range f.Messages {
+		if err := g.message(m); err != nil {
+			return err
+		}
+	}
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("bitproto: format generated code: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+type generator struct {
+	file *File
+	buf  bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) message(m Message) error {
+	g.printf("\ntype %s struct {\n", goName(m.Name))
+	for _, field := range sortedFields(m) {
+		typ, bits, err := g.fieldType(field.Type)
+		if err != nil {
+			return fmt.Errorf("bitproto: field %s.%s: %w", m.Name, field.Name, err)
+		}
+		g.printf("%s %s `bit:\"%d\"`\n", goName(field.Name), typ, bits)
+	}
+	g.printf("}\n")
+	return nil
+}
+
+// sortedFields returns the fields of a message in order of field number.
+func sortedFields(m Message) []Field {
+	fields := append([]Field(nil), m.Fields...)
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Number < fields[j].Number
+	})
+	return fields
+}
+
+// fieldType returns the Go type and the bit size of a field type.
+func (g *generator) fieldType(t Type) (string, int, error) {
+	if t.Len > 0 {
+		return "", 0, fmt.Errorf("arrays are not supported")
+	}
+	if t.Name == "" {
+		return goType(t.Bits, t.Signed), t.Bits, nil
+	}
+	for _, e := range g.file.Enums {
+		if e.Name == t.Name {
+			return goName(e.Name), e.Bits, nil
+		}
+	}
+	for _, a := range g.file.Aliases {
+		if a.Name == t.Name {
+			return goName(a.Name), a.Type.Bits, nil
+		}
+	}
+	for _, m := range g.file.Messages {
+		if m.Name == t.Name || strings.HasSuffix(m.Name, "."+t.Name) {
+			return "", 0, fmt.Errorf("message-typed fields are not supported")
+		}
+	}
+	return "", 0, fmt.Errorf("undefined type %s", t.Name)
+}
+
+func goType(bits int, signed bool) string {
+	size := 8
+	for size < bits {
+		size *= 2
+	}
+	if signed {
+		return fmt.Sprintf("int%d", size)
+	}
+	return fmt.Sprintf("uint%d", size)
+}
+
+// goName converts a bitproto name such as "drone_status", "DRONE_STATUS" or
+// "Outer.Inner" into an exported Go name.
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '.' }) {
+		r := []rune(part)
+		allUpper := strings.ToUpper(part) == part
+		for i, c := range r {
+			if i == 0 {
+				b.WriteRune(unicode.ToUpper(c))
+			} else if allUpper {
+				b.WriteRune(unicode.ToLower(c))
+			} else {
+				b.WriteRune(c)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,481 @@
+// Package bitproto imports bit layouts written in the [bitproto] language and
+// generates Go structs annotated with bit tags for them.
+//
+// bitproto packs fields in little-endian byte order, starting from the least
+// significant bit, which is the default of [bitfield.Unmarshal]. The generated
+// structs can therefore be decoded without options.
+//
+// The following subset of the language is supported: proto, option, const
+// and type alias declarations, enums, messages (including nested message
+// declarations), and fields of the types bool, byte, uintN, intN, enums and
+// aliases. Imports, arrays and message-typed fields are not supported.
+//
+// [bitproto]: https://bitproto.readthedocs.io/
+package bitproto
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// File is a parsed bitproto file.
+type File struct {
+	// Proto is the name given by the proto statement.
+	Proto string
+	// Consts lists the integer constants in order of declaration.
+	Consts []Const
+	// Aliases lists the type aliases in order of declaration.
+	Aliases []Alias
+	// Enums lists the enums in order of declaration.
+	Enums []Enum
+	// Messages lists the messages in order of declaration. Nested messages
+	// follow their parent and are named with the parent's name as a prefix
+	// separated by ".".
+	Messages []Message
+}
+
+// Const is an integer constant.
+type Const struct {
+	Name  string
+	Value int64
+}
+
+// Alias is a type alias such as "type Timestamp = int64".
+type Alias struct {
+	Name string
+	Type Type
+}
+
+// Enum is an enum with an unsigned integer underlying type.
+type Enum struct {
+	Name   string
+	Bits   int
+	Values []Const
+}
+
+// Message is a message with fields.
+type Message struct {
+	Name   string
+	Fields []Field
+}
+
+// Field is a field of a message.
+type Field struct {
+	Name   string
+	Type   Type
+	Number int
+}
+
+// Type is the type of a field or an alias.
+type Type struct {
+	// Name is the name of an enum, an alias or a message. It is empty for a
+	// base type.
+	Name string
+	// Bits is the bit size of a base type.
+	Bits int
+	// Signed reports whether a base type is a signed integer.
+	Signed bool
+	// Bool reports whether a base type is bool.
+	Bool bool
+	// Len is the length of an array type, or 0 if the type is not an array.
+	Len int
+}
+
+// SyntaxError describes a syntax error in a bitproto file.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return "bitproto: line " + strconv.Itoa(e.Line) + ": " + e.Msg
+}
+
+// Parse parses a bitproto file.
+func Parse(r io.Reader) (*File, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokenize(string(src)), file: &File{}}
+	if err := p.parseFile(); err != nil {
+		return nil, err
+	}
+	return p.file, nil
+}
+
+type token struct {
+	text string
+	line int
+}
+
+func tokenize(src string) []token {
+	var tokens []token
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c && src[j] != '\n' {
+				j++
+			}
+			tokens = append(tokens, token{src[i:min(j+1, len(src))], line})
+			i = j + 1
+		case isIdentByte(c):
+			j := i
+			for j < len(src) && (isIdentByte(src[j]) || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{src[i:j], line})
+			i = j
+		default:
+			tokens = append(tokens, token{string(c), line})
+			i++
+		}
+	}
+	return tokens
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '-' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	file   *File
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	line := 0
+	if p.pos < len(p.tokens) {
+		line = p.tokens[p.pos].line
+	} else if len(p.tokens) > 0 {
+		line = p.tokens[len(p.tokens)-1].line
+	}
+	return &SyntaxError{Line: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].text
+	}
+	return ""
+}
+
+func (p *parser) next() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", p.errorf("unexpected end of file")
+	}
+	p.pos++
+	return p.tokens[p.pos-1].text, nil
+}
+
+func (p *parser) expect(want string) error {
+	if got := p.peek(); got != want {
+		return p.errorf("expected %q, found %q", want, got)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) ident() (string, error) {
+	name, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	if name == "" || !(name[0] == '_' || unicode.IsLetter(rune(name[0]))) {
+		p.pos--
+		return "", p.errorf("expected identifier, found %q", name)
+	}
+	return name, nil
+}
+
+func (p *parser) integer() (int64, error) {
+	text, err := p.next()
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(text, 0, 64)
+	if err != nil {
+		p.pos--
+		return 0, p.errorf("expected integer, found %q", text)
+	}
+	return v, nil
+}
+
+// skipSemicolon skips an optional statement terminator.
+func (p *parser) skipSemicolon() {
+	if p.peek() == ";" {
+		p.pos++
+	}
+}
+
+func (p *parser) parseFile() error {
+	for p.pos < len(p.tokens) {
+		switch keyword, _ := p.next(); keyword {
+		case "proto":
+			name, err := p.ident()
+			if err != nil {
+				return err
+			}
+			p.file.Proto = name
+		case "import":
+			p.pos--
+			return p.errorf("import is not supported")
+		case "option":
+			if err := p.parseOption(); err != nil {
+				return err
+			}
+		case "const":
+			if err := p.parseConst(); err != nil {
+				return err
+			}
+		case "type", "typedef":
+			if err := p.parseAlias(); err != nil {
+				return err
+			}
+		case "enum":
+			if err := p.parseEnum(); err != nil {
+				return err
+			}
+		case "message":
+			if err := p.parseMessage(""); err != nil {
+				return err
+			}
+		default:
+			p.pos--
+			return p.errorf("unexpected %q", keyword)
+		}
+		p.skipSemicolon()
+	}
+	return nil
+}
+
+// parseOption skips an option, which does not affect the layout.
+func (p *parser) parseOption() error {
+	if _, err := p.ident(); err != nil {
+		return err
+	}
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	_, err := p.next()
+	return err
+}
+
+func (p *parser) parseConst() error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	if _, err := strconv.ParseInt(p.peek(), 0, 64); err != nil {
+		// Non-integer constants are not used by layouts
+		_, err = p.next()
+		return err
+	}
+	v, err := p.integer()
+	if err != nil {
+		return err
+	}
+	p.file.Consts = append(p.file.Consts, Const{Name: name, Value: v})
+	return nil
+}
+
+func (p *parser) parseAlias() error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	typ, err := p.parseType()
+	if err != nil {
+		return err
+	}
+	p.file.Aliases = append(p.file.Aliases, Alias{Name: name, Type: typ})
+	return nil
+}
+
+func (p *parser) parseEnum() error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if err := p.expect(":"); err != nil {
+		return err
+	}
+	typ, err := p.parseType()
+	if err != nil {
+		return err
+	}
+	if typ.Name != "" || typ.Signed || typ.Bool || typ.Len > 0 {
+		return p.errorf("enum %s must be unsigned integer", name)
+	}
+	enum := Enum{Name: name, Bits: typ.Bits}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for p.peek() != "}" {
+		if p.peek() == "option" {
+			p.pos++
+			if err := p.parseOption(); err != nil {
+				return err
+			}
+			p.skipSemicolon()
+			continue
+		}
+		valueName, err := p.ident()
+		if err != nil {
+			return err
+		}
+		if err := p.expect("="); err != nil {
+			return err
+		}
+		v, err := p.integer()
+		if err != nil {
+			return err
+		}
+		enum.Values = append(enum.Values, Const{Name: valueName, Value: v})
+		p.skipSemicolon()
+	}
+	p.pos++
+	p.file.Enums = append(p.file.Enums, enum)
+	return nil
+}
+
+func (p *parser) parseMessage(parent string) error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if parent != "" {
+		name = parent + "." + name
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	msg := Message{Name: name}
+	// Nested declarations are appended after the parent
+	var nested []Message
+	for p.peek() != "}" {
+		switch p.peek() {
+		case "":
+			return p.errorf("unexpected end of file")
+		case "option":
+			p.pos++
+			if err := p.parseOption(); err != nil {
+				return err
+			}
+		case "message":
+			p.pos++
+			saved := p.file.Messages
+			p.file.Messages = nil
+			if err := p.parseMessage(name); err != nil {
+				return err
+			}
+			nested = append(nested, p.file.Messages...)
+			p.file.Messages = saved
+		case "enum":
+			p.pos++
+			if err := p.parseEnum(); err != nil {
+				return err
+			}
+		default:
+			field, err := p.parseField()
+			if err != nil {
+				return err
+			}
+			msg.Fields = append(msg.Fields, field)
+		}
+		p.skipSemicolon()
+	}
+	p.pos++
+	p.file.Messages = append(p.file.Messages, msg)
+	p.file.Messages = append(p.file.Messages, nested...)
+	return nil
+}
+
+func (p *parser) parseField() (Field, error) {
+	typ, err := p.parseType()
+	if err != nil {
+		return Field{}, err
+	}
+	name, err := p.ident()
+	if err != nil {
+		return Field{}, err
+	}
+	if err := p.expect("="); err != nil {
+		return Field{}, err
+	}
+	number, err := p.integer()
+	if err != nil {
+		return Field{}, err
+	}
+	return Field{Name: name, Type: typ, Number: int(number)}, nil
+}
+
+func (p *parser) parseType() (Type, error) {
+	name, err := p.ident()
+	if err != nil {
+		return Type{}, err
+	}
+	typ, err := baseType(name)
+	if err != nil {
+		p.pos--
+		return Type{}, p.errorf("%v", err)
+	}
+	if p.peek() == "[" {
+		p.pos++
+		n, err := p.integer()
+		if err != nil {
+			return Type{}, err
+		}
+		if err := p.expect("]"); err != nil {
+			return Type{}, err
+		}
+		typ.Len = int(n)
+	}
+	return typ, nil
+}
+
+func baseType(name string) (Type, error) {
+	switch {
+	case name == "bool":
+		return Type{Bits: 1, Bool: true}, nil
+	case name == "byte":
+		return Type{Bits: 8}, nil
+	case strings.HasPrefix(name, "uint"):
+		if bits, err := strconv.Atoi(name[4:]); err == nil {
+			if bits < 1 || bits > 64 {
+				return Type{}, fmt.Errorf("invalid type %s", name)
+			}
+			return Type{Bits: bits}, nil
+		}
+	case strings.HasPrefix(name, "int"):
+		if bits, err := strconv.Atoi(name[3:]); err == nil {
+			if bits < 1 || bits > 64 {
+				return Type{}, fmt.Errorf("invalid type %s", name)
+			}
+			return Type{Bits: bits, Signed: true}, nil
+		}
+	}
+	return Type{Name: name}, nil
+}
@@ -0,0 +1,63 @@
+package bitproto
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// StructOf returns a struct type with the same fields as the struct generated
+// by [Generate] for the named message, for decoding layouts which are loaded
+// at run time. Enums and aliases are replaced with their underlying types.
+func StructOf(f *File, message string) (reflect.Type, error) {
+	for _, m := range f.Messages {
+		if m.Name != message {
+			continue
+		}
+		g := &generator{file: f}
+		var fields []reflect.StructField
+		for _, field := range sortedFields(m) {
+			_, bits, err := g.fieldType(field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("bitproto: field %s.%s: %w", m.Name, field.Name, err)
+			}
+			fields = append(fields, reflect.StructField{
+				Name: goName(field.Name),
+				Type: reflectType(g.underlying(field.Type), bits),
+				Tag:  reflect.StructTag(`bit:"` + strconv.Itoa(bits) + `"`),
+			})
+		}
+		return reflect.StructOf(fields), nil
+	}
+	return nil, fmt.Errorf("bitproto: message %s not found", message)
+}
+
+// underlying resolves an enum or an alias into its base type.
+func (g *generator) underlying(t Type) Type {
+	for _, e := range g.file.Enums {
+		if e.Name == t.Name {
+			return Type{Bits: e.Bits}
+		}
+	}
+	for _, a := range g.file.Aliases {
+		if a.Name == t.Name {
+			return a.Type
+		}
+	}
+	return t
+}
+
+var integerTypes = map[string]reflect.Type{
+	"uint8":  reflect.TypeOf(uint8(0)),
+	"uint16": reflect.TypeOf(uint16(0)),
+	"uint32": reflect.TypeOf(uint32(0)),
+	"uint64": reflect.TypeOf(uint64(0)),
+	"int8":   reflect.TypeOf(int8(0)),
+	"int16":  reflect.TypeOf(int16(0)),
+	"int32":  reflect.TypeOf(int32(0)),
+	"int64":  reflect.TypeOf(int64(0)),
+}
+
+func reflectType(t Type, bits int) reflect.Type {
+	return integerTypes[goType(bits, t.Signed)]
+}
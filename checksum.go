@@ -0,0 +1,266 @@
+package bitfield
+
+import (
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// checksums maps the name of a checksum algorithm to its function.
+var checksums sync.Map
+
+func init() {
+	RegisterChecksum("crc16-ccitt", crc16CCITT)
+	RegisterChecksum("crc32", func(b []byte) uint64 { return uint64(crc32.ChecksumIEEE(b)) })
+	RegisterChecksum("crc32c", func(b []byte) uint64 { return uint64(crc32.Checksum(b, castagnoli)) })
+	RegisterChecksum("internet", internetChecksum)
+	RegisterChecksum("sum8", func(b []byte) uint64 {
+		var sum uint8
+		for _, c := range b {
+			sum += c
+		}
+		return uint64(sum)
+	})
+	RegisterChecksum("xor8", func(b []byte) uint64 {
+		var sum uint8
+		for _, c := range b {
+			sum ^= c
+		}
+		return uint64(sum)
+	})
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// RegisterChecksum registers a checksum algorithm, which integer fields can
+// refer to with a struct tag "check" naming the algorithm and the range of
+// bytes it covers, from the start of the struct:
+//
+//	func init() {
+//		bitfield.RegisterChecksum("sum16", func(b []byte) uint64 {
+//			var sum uint16
+//			for _, c := range b {
+//				sum += uint16(c)
+//			}
+//			return uint64(sum)
+//		})
+//	}
+//
+//	type frame struct {
+//		Length  uint8
+//		Payload [8]byte
+//		Sum     uint16 `check:"sum16,0:9"`
+//	}
+//
+// Unmarshal returns [ChecksumError] if the value of the field differs from
+// the checksum of the bytes, truncated to the bit size of the field, and
+// [Marshal] writes the checksum into the field. If the range covers the field
+// itself, as the header checksum of IPv4 does, the bits of the field are taken
+// as zero.
+// The following algorithms are registered: "crc16-ccitt" (CRC-16/CCITT-FALSE),
+// "crc32" (IEEE), "crc32c" (Castagnoli), "internet" (the ones' complement sum
+// of RFC 1071), "sum8" and "xor8".
+//
+// The algorithm must be registered before a struct referring to it is first
+// used. Registering an algorithm again replaces it. RegisterChecksum panics if
+// the name is empty or fn is nil.
+func RegisterChecksum(name string, fn func([]byte) uint64) {
+	if name == "" || fn == nil {
+		panic(fmt.Sprintf("bitfield: invalid checksum %q", name))
+	}
+	checksums.Store(name, fn)
+}
+
+// crc16CCITT returns the CRC-16/CCITT-FALSE of b, with polynomial 0x1021 and
+// initial value 0xFFFF.
+func crc16CCITT(b []byte) uint64 {
+	crc := uint16(0xFFFF)
+	for _, c := range b {
+		crc ^= uint16(c) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return uint64(crc)
+}
+
+// internetChecksum returns the checksum of RFC 1071, the ones' complement of
+// the ones' complement sum of the big-endian 16-bit words of b.
+func internetChecksum(b []byte) uint64 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xFFFF + sum>>16
+	}
+	return uint64(^uint16(sum))
+}
+
+// checkSpec is the checksum of a field given by its struct tag "check", such
+// as check:"crc16-ccitt,0:10". The range of bytes is counted from the start of
+// the struct, and to is -1 if omitted, as in check:"crc32,0:", for the bytes
+// up to the field.
+type checkSpec struct {
+	name     string
+	from, to int
+}
+
+// checkTag returns the checksum given by the check tag of a field. spec is nil
+// if the field has no check tag, and ok is false if the tag is invalid or the
+// algorithm is not registered.
+func checkTag(field reflect.StructField) (spec *checkSpec, ok bool) {
+	tag, found := field.Tag.Lookup("check")
+	if !found {
+		return nil, true
+	}
+	name, span, found := strings.Cut(tag, ",")
+	if !found {
+		return nil, false
+	}
+	if _, registered := checksums.Load(name); !registered {
+		return nil, false
+	}
+	from, to, found := strings.Cut(span, ":")
+	if !found {
+		return nil, false
+	}
+	spec = &checkSpec{name: name, to: -1}
+	var err error
+	if spec.from, err = strconv.Atoi(from); err != nil || spec.from < 0 {
+		return nil, false
+	}
+	if to != "" {
+		if spec.to, err = strconv.Atoi(to); err != nil || spec.to < spec.from {
+			return nil, false
+		}
+	}
+	return spec, true
+}
+
+// validateCheckTag validates the check tag of a field, if any.
+func validateCheckTag(field reflect.StructField) error {
+	if _, found := field.Tag.Lookup("check"); !found {
+		return nil
+	}
+	if !isFixedInteger(field.Type.Kind()) {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "check tag requires integer field",
+		}
+	}
+	if _, ok := checkTag(field); !ok {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "check must be name of checksum registered by RegisterChecksum and range of bytes such as 0:10",
+		}
+	}
+	return nil
+}
+
+// checkedField is a field with a check tag, whose checksum is verified or
+// written once the struct holding it is decoded or encoded. start is the
+// offset of the struct in bytes, and offset that of the field in bits.
+type checkedField struct {
+	f      *fieldPlan
+	value  uint64
+	start  int
+	offset int
+}
+
+// fieldStart returns the offset of a plain integer field ending at end.
+func (f *fieldPlan) fieldStart(end int) int {
+	if f.modifiers.ownBitOrder {
+		return end - (f.bitSize+7)/8*8
+	}
+	return end - f.bitSize
+}
+
+// span returns the range of bytes of data covered by the checksum. ok is
+// false if the range ends beyond the first nbits bits.
+func (c checkedField) span(nbits int) (from, to int, ok bool) {
+	from, to = c.start+c.f.check.from, c.start+c.f.check.to
+	if c.f.check.to < 0 {
+		to = c.offset / 8
+	}
+	return min(from, to), to, to*8 <= nbits
+}
+
+// sum returns the checksum of data[from:to], in which the bits of the field
+// itself are zero, truncated to the bit size of the field.
+func (c checkedField) sum(data []byte, from, to int, bitOrder BitOrder) uint64 {
+	b := data[from:to]
+	if c.offset < to*8 && from*8 < c.offset+c.f.bitSize {
+		// The range covers the field, as the header checksum of IPv4 does
+		b = append([]byte(nil), b...)
+		if c.f.modifiers.ownBitOrder {
+			bitOrder = c.f.modifiers.bitOrder
+		}
+		for k := max(c.offset, from*8); k < min(c.offset+c.f.bitSize, to*8); k++ {
+			if bitOrder == MSBFirst {
+				b[k/8-from] &^= 0x80 >> (k % 8)
+			} else {
+				b[k/8-from] &^= 1 << (k % 8)
+			}
+		}
+	}
+	fn, _ := checksums.Load(c.f.check.name)
+	sum := fn.(func([]byte) uint64)(b)
+	if c.f.bitSize < 64 {
+		sum &= 1<<c.f.bitSize - 1
+	}
+	return sum
+}
+
+// verify returns [ChecksumError] if the decoded value of the field is not the
+// checksum of its range of bytes, and [LengthError] if the range exceeds the
+// input.
+func (c checkedField) verify(r *bitReader, prefix string) error {
+	from, to, ok := c.span(r.nbits)
+	if !ok {
+		return &LengthError{
+			Field:   c.f.StructField,
+			Path:    prefix + c.f.Name,
+			problem: "checksum range ends at byte " + strconv.Itoa(to) + " beyond the input",
+		}
+	}
+	if sum := c.sum(r.data, from, to, r.bitOrder); sum != c.value {
+		return &ChecksumError{Field: c.f.StructField, Path: prefix + c.f.Name, Value: c.value, Sum: sum}
+	}
+	return nil
+}
+
+// fill writes the checksum of the range of bytes of the field over the zero
+// bits written for the field, and returns [LengthError] if the range exceeds
+// the bytes encoded so far.
+func (c checkedField) fill(w *bitWriter, prefix string, options options) error {
+	from, to, ok := c.span(w.iData*8 + w.iBitInData)
+	if !ok {
+		return &LengthError{
+			Field:   c.f.StructField,
+			Path:    prefix + c.f.Name,
+			problem: "checksum range ends at byte " + strconv.Itoa(to-w.origin) + " beyond the encoding",
+		}
+	}
+	sum := c.sum(w.data, from, to, w.bitOrder)
+	iData, iBitInData, bitOrder := w.iData, w.iBitInData, w.bitOrder
+	if c.f.modifiers.ownBitOrder {
+		w.bitOrder = c.f.modifiers.bitOrder
+	}
+	w.seek(c.offset)
+	w.writeValue(sum, c.f.bitSize, c.f.byteOrder(options.byteOrder))
+	w.iData, w.iBitInData, w.bitOrder = iData, iBitInData, bitOrder
+	return nil
+}
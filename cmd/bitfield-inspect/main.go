@@ -0,0 +1,225 @@
+// Command bitfield-inspect is an interactive inspector of binary files. It
+// splits a file into records of a layout and shows the decoded fields of each
+// record.
+//
+// Usage:
+//
+//	bitfield-inspect [-message name] [-big] layout data
+//
+// The layout is read from a bitproto file if its name ends with ".bitproto",
+// and from an RFC-style ASCII packet diagram otherwise. For a bitproto file,
+// -message selects the message (default: the first message). The byte order
+// is little-endian for bitproto files and big-endian for diagrams, unless -big
+// or -little is given. The bits are in LSB-first order until toggled.
+//
+// Commands are read line by line:
+//
+//	n, <Enter>  show the next record
+//	p           show the previous record
+//	g N         go to record N
+//	e           toggle the byte order
+//	b           toggle the bit order
+//	q           quit
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jmatsuzawa/go-bitfield"
+	"github.com/jmatsuzawa/go-bitfield/bitproto"
+	"github.com/jmatsuzawa/go-bitfield/rfcdiagram"
+)
+
+func main() {
+	message := flag.String("message", "", "message to use from a bitproto file")
+	big := flag.Bool("big", false, "decode in big-endian byte order")
+	little := flag.Bool("little", false, "decode in little-endian byte order")
+	flag.Parse()
+	if flag.NArg() != 2 || *big && *little {
+		fmt.Fprintln(os.Stderr, "usage: bitfield-inspect [-message name] [-big|-little] layout data")
+		os.Exit(2)
+	}
+	typ, order, err := loadLayout(flag.Arg(0), *message)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bitfield-inspect:", err)
+		os.Exit(1)
+	}
+	if *big {
+		order = bitfield.BigEndian
+	} else if *little {
+		order = bitfield.LittleEndian
+	}
+	data, err := os.ReadFile(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bitfield-inspect:", err)
+		os.Exit(1)
+	}
+	ins, err := newInspector(typ, order, data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bitfield-inspect:", err)
+		os.Exit(1)
+	}
+	ins.run(os.Stdin, os.Stdout)
+}
+
+// loadLayout builds a struct type from a layout file, and returns it with the
+// natural byte order of the file format.
+func loadLayout(path, message string) (reflect.Type, bitfield.ByteOrder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	if strings.HasSuffix(path, ".bitproto") {
+		file, err := bitproto.Parse(f)
+		if err != nil {
+			return nil, 0, err
+		}
+		if message == "" {
+			if len(file.Messages) == 0 {
+				return nil, 0, errors.New("no message in " + path)
+			}
+			message = file.Messages[0].Name
+		}
+		typ, err := bitproto.StructOf(file, message)
+		return typ, bitfield.LittleEndian, err
+	}
+
+	d, err := rfcdiagram.Parse(f)
+	if err != nil {
+		return nil, 0, err
+	}
+	typ, err := rfcdiagram.StructOf(d)
+	return typ, bitfield.BigEndian, err
+}
+
+type inspector struct {
+	typ        reflect.Type
+	order      bitfield.ByteOrder
+	bitOrder   bitfield.BitOrder
+	data       []byte
+	recordSize int
+	index      int
+}
+
+func newInspector(typ reflect.Type, order bitfield.ByteOrder, data []byte) (*inspector, error) {
+	layout, err := bitfield.LayoutOf(reflect.New(typ).Interface())
+	if err != nil {
+		return nil, err
+	}
+	recordSize := (layout.BitSize + 7) / 8
+	if recordSize == 0 {
+		return nil, errors.New("layout has no fields")
+	}
+	return &inspector{typ: typ, order: order, data: data, recordSize: recordSize}, nil
+}
+
+func (ins *inspector) records() int {
+	return (len(ins.data) + ins.recordSize - 1) / ins.recordSize
+}
+
+func (ins *inspector) run(in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	ins.show(out)
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return
+		}
+		args := strings.Fields(scanner.Text())
+		cmd := "n"
+		if len(args) > 0 {
+			cmd = args[0]
+		}
+		switch cmd {
+		case "n":
+			if ins.index+1 < ins.records() {
+				ins.index++
+			}
+		case "p":
+			if ins.index > 0 {
+				ins.index--
+			}
+		case "g":
+			n, err := strconv.Atoi(strings.Join(args[1:], ""))
+			if err != nil || n < 0 || n >= ins.records() {
+				fmt.Fprintf(out, "record number must be within 0 to %d\n", ins.records()-1)
+				continue
+			}
+			ins.index = n
+		case "e":
+			if ins.order == bitfield.LittleEndian {
+				ins.order = bitfield.BigEndian
+			} else {
+				ins.order = bitfield.LittleEndian
+			}
+		case "b":
+			if ins.bitOrder == bitfield.LSBFirst {
+				ins.bitOrder = bitfield.MSBFirst
+			} else {
+				ins.bitOrder = bitfield.LSBFirst
+			}
+		case "q":
+			return
+		default:
+			fmt.Fprintln(out, "commands: n (next), p (previous), g N (go to), e (toggle byte order), b (toggle bit order), q (quit)")
+			continue
+		}
+		ins.show(out)
+	}
+}
+
+func (ins *inspector) show(out io.Writer) {
+	start := ins.index * ins.recordSize
+	end := min(start+ins.recordSize, len(ins.data))
+	record := ins.data[start:end]
+	order := "little-endian"
+	if ins.order == bitfield.BigEndian {
+		order = "big-endian"
+	}
+	if ins.bitOrder == bitfield.MSBFirst {
+		order += ", MSB-first"
+	}
+	fmt.Fprintf(out, "record %d/%d at byte %d, %s\n", ins.index, ins.records()-1, start, order)
+	fmt.Fprintf(out, "  % x\n", record)
+
+	v := reflect.New(ins.typ)
+	opts := []bitfield.Option{bitfield.WithByteOrder(ins.order), bitfield.WithBitOrder(ins.bitOrder)}
+	if err := bitfield.Unmarshal(record, v.Interface(), opts...); err != nil {
+		fmt.Fprintln(out, "  error:", err)
+		return
+	}
+	layout, _ := bitfield.LayoutOf(v.Interface(), opts...)
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  FIELD\tBITS\tVALUE")
+	for _, f := range layout.Fields {
+		if f.Name == "_" {
+			continue
+		}
+		fmt.Fprintf(tw, "  %s\t[%d:%d)\t%s\n", f.Name, f.Offset, f.Offset+f.Bits, formatValue(v.Elem().FieldByName(f.Name)))
+	}
+	tw.Flush()
+}
+
+func formatValue(v reflect.Value) string {
+	switch {
+	case v.Type() == reflect.TypeOf(bitfield.Raw(nil)):
+		return hex.EncodeToString(v.Bytes())
+	case v.CanInt():
+		return fmt.Sprintf("%d (%#x)", v.Int(), v.Int())
+	default:
+		return fmt.Sprintf("%d (%#x)", v.Uint(), v.Uint())
+	}
+}
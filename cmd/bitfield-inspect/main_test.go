@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jmatsuzawa/go-bitfield"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspector(t *testing.T) {
+	// Setup
+	type record struct {
+		A uint8  `bit:"4"`
+		B uint8  `bit:"4"`
+		C uint16 `bit:"16"`
+	}
+	data := []byte{0x21, 0x12, 0x34, 0x43, 0x56, 0x78}
+	ins, err := newInspector(reflect.TypeOf(record{}), bitfield.LittleEndian, data)
+	assert.Nil(t, err)
+
+	// Exercise
+	var out strings.Builder
+	ins.run(strings.NewReader("n\ne\np\ng 5\nb\nb\nq\n"), &out)
+
+	// Verify
+	got := out.String()
+	assert.Contains(t, got, "record 0/1 at byte 0, little-endian\n  21 12 34\n")
+	assert.Contains(t, got, "  C      [8:24)  13330 (0x3412)\n")
+	assert.Contains(t, got, "record 1/1 at byte 3, little-endian\n")
+	assert.Contains(t, got, "record 1/1 at byte 3, big-endian\n")
+	assert.Contains(t, got, "record 0/1 at byte 0, big-endian\n")
+	assert.Contains(t, got, "  C      [8:24)  4660 (0x1234)\n")
+	assert.Contains(t, got, "record number must be within 0 to 1\n")
+	assert.Contains(t, got, "record 0/1 at byte 0, big-endian, MSB-first\n")
+	assert.Contains(t, got, "  A      [0:4)   1 (0x1)\n")
+	assert.Contains(t, got, "  A      [0:4)   2 (0x2)\n")
+}
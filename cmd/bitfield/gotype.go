@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jmatsuzawa/go-bitfield"
+)
+
+// basicTypes are the predeclared types of fields.
+var basicTypes = map[string]reflect.Type{
+	"bool":    reflect.TypeOf(false),
+	"byte":    reflect.TypeOf(uint8(0)),
+	"uint8":   reflect.TypeOf(uint8(0)),
+	"uint16":  reflect.TypeOf(uint16(0)),
+	"uint32":  reflect.TypeOf(uint32(0)),
+	"uint64":  reflect.TypeOf(uint64(0)),
+	"int8":    reflect.TypeOf(int8(0)),
+	"int16":   reflect.TypeOf(int16(0)),
+	"int32":   reflect.TypeOf(int32(0)),
+	"int64":   reflect.TypeOf(int64(0)),
+	"float32": reflect.TypeOf(float32(0)),
+	"float64": reflect.TypeOf(float64(0)),
+}
+
+// goFile resolves the types declared in a Go file to reflect types.
+type goFile struct {
+	fset  *token.FileSet
+	decls map[string]ast.Expr // types of the type declarations by name
+	// resolving holds the names of the declarations being resolved, to stop
+	// at recursive types
+	resolving map[string]bool
+}
+
+// goType returns the reflect type of the struct type named name in a Go file,
+// or of the first struct type with tagged fields if name is empty.
+func goType(path, name string) (reflect.Type, error) {
+	f := &goFile{fset: token.NewFileSet(), decls: map[string]ast.Expr{}, resolving: map[string]bool{}}
+	file, err := parser.ParseFile(f.fset, path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			spec := spec.(*ast.TypeSpec)
+			f.decls[spec.Name.Name] = spec.Type
+			if st, ok := spec.Type.(*ast.StructType); name == "" && ok && hasTags(st) {
+				name = spec.Name.Name
+			}
+		}
+	}
+	if name == "" {
+		return nil, errors.New("no struct type with tagged fields in " + path)
+	}
+	expr, ok := f.decls[name]
+	if !ok {
+		return nil, fmt.Errorf("type %s not found in %s", name, path)
+	}
+	if _, ok := expr.(*ast.StructType); !ok {
+		return nil, fmt.Errorf("type %s is not struct", name)
+	}
+	f.resolving[name] = true
+	return f.reflectType(expr)
+}
+
+// hasTags reports whether a field of the struct has a tag.
+func hasTags(st *ast.StructType) bool {
+	for _, f := range st.Fields.List {
+		if f.Tag != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// reflectType returns the reflect type of a type expression of the file.
+// Named types are resolved to their underlying types.
+func (f *goFile) reflectType(expr ast.Expr) (reflect.Type, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if rt, ok := basicTypes[t.Name]; ok {
+			return rt, nil
+		}
+		decl, ok := f.decls[t.Name]
+		if !ok {
+			break
+		}
+		if f.resolving[t.Name] {
+			return nil, fmt.Errorf("recursive type %s is not supported", t.Name)
+		}
+		f.resolving[t.Name] = true
+		defer delete(f.resolving, t.Name)
+		return f.reflectType(decl)
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "bitfield" {
+			switch t.Sel.Name {
+			case "Raw":
+				return reflect.TypeOf(bitfield.Raw(nil)), nil
+			case "TLV":
+				return reflect.TypeOf(bitfield.TLV{}), nil
+			}
+		}
+	case *ast.StarExpr:
+		elem, err := f.reflectType(t.X)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PointerTo(elem), nil
+	case *ast.ArrayType:
+		elem, err := f.reflectType(t.Elt)
+		if err != nil {
+			return nil, err
+		}
+		if t.Len == nil {
+			return reflect.SliceOf(elem), nil
+		}
+		if lit, ok := t.Len.(*ast.BasicLit); ok && lit.Kind == token.INT {
+			if n, err := strconv.Atoi(lit.Value); err == nil {
+				return reflect.ArrayOf(n, elem), nil
+			}
+		}
+	case *ast.StructType:
+		return f.structType(t)
+	}
+	var b bytes.Buffer
+	format.Node(&b, f.fset, expr)
+	return nil, fmt.Errorf("field type %s is not supported", b.String())
+}
+
+func (f *goFile) structType(st *ast.StructType) (rt reflect.Type, err error) {
+	var fields []reflect.StructField
+	for _, field := range st.Fields.List {
+		ft, err := f.reflectType(field.Type)
+		if err != nil {
+			return nil, err
+		}
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			s, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(s)
+		}
+		if enum := tag.Get("enum"); enum != "" && !strings.ContainsAny(enum[:1], "0123456789+-") {
+			// Sets named by enum tags are registered when the application
+			// runs, so the values are not checked
+			tag = reflect.StructTag(strings.TrimSpace(strings.Replace(string(tag), `enum:"`+enum+`"`, "", 1)))
+		}
+		if len(field.Names) == 0 {
+			ident, ok := field.Type.(*ast.Ident)
+			if !ok || !token.IsExported(ident.Name) {
+				return nil, errors.New("embedded field of unexported or non-local type is not supported")
+			}
+			fields = append(fields, reflect.StructField{Name: ident.Name, Type: ft, Tag: tag, Anonymous: true})
+			continue
+		}
+		for _, n := range field.Names {
+			sf := reflect.StructField{Name: n.Name, Type: ft, Tag: tag}
+			if !token.IsExported(n.Name) {
+				sf.PkgPath = "main"
+			}
+			fields = append(fields, sf)
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("struct type is not supported: %v", r)
+		}
+	}()
+	return reflect.StructOf(fields), nil
+}
@@ -0,0 +1,235 @@
+// Command bitfield decodes binary data against a layout and lists its fields,
+// for debugging on machines where the application using the layout cannot
+// run.
+//
+// Usage:
+//
+//	bitfield [-type T] [-big|-little] [-msb] layout hex
+//	bitfield [-type T] [-big|-little] [-msb] -pcap file [-skip n] [-packet n] layout
+//
+// The layout is read from a schema document of the package schema if its name
+// ends with ".json", ".yaml" or ".yml", and from a Go file otherwise. For a Go
+// file, -type selects the struct type (default: the first struct type with
+// tagged fields). Its fields may be of the types accepted by bitfield.Unmarshal
+// declared in the same file, and enum tags naming sets registered by the
+// application are ignored.
+//
+// The data is a hex string, in which spaces, colons and a leading "0x" are
+// ignored, or "-" to read one from the standard input. With -pcap, the data is
+// instead the payload of each packet of a pcap file, or of the packet numbered
+// from 0 by -packet. -skip gives the number of bytes before the payload. By
+// default, the Ethernet, IP and UDP or TCP headers of Ethernet and raw IP
+// captures are skipped, and nothing is skipped for other link types.
+//
+// The byte order is that of the schema document, or little-endian for a Go
+// file, unless -big or -little is given. -msb selects MSB-first bit order.
+// Each field is listed with its range of bits, its raw bits and its value, as
+// bitfield.Dump does. The exit status is 1 if any data cannot be decoded.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jmatsuzawa/go-bitfield"
+	"github.com/jmatsuzawa/go-bitfield/schema"
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	typeName := flag.String("type", "", "struct type to use from a Go file")
+	big := flag.Bool("big", false, "decode in big-endian byte order")
+	little := flag.Bool("little", false, "decode in little-endian byte order")
+	msb := flag.Bool("msb", false, "decode in MSB-first bit order")
+	pcap := flag.String("pcap", "", "pcap file whose packet payloads to decode")
+	skip := flag.Int("skip", -1, "bytes before the payload of each packet (default: headers of Ethernet and IP captures)")
+	packet := flag.Int("packet", -1, "number of the only packet to decode, from 0")
+	flag.Parse()
+	if *pcap == "" && flag.NArg() != 2 || *pcap != "" && flag.NArg() != 1 || *big && *little {
+		fmt.Fprintln(os.Stderr, "usage: bitfield [-type T] [-big|-little] [-msb] layout hex")
+		fmt.Fprintln(os.Stderr, "       bitfield [-type T] [-big|-little] [-msb] -pcap file [-skip n] [-packet n] layout")
+		os.Exit(2)
+	}
+	decode, err := loadLayout(flag.Arg(0), *typeName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bitfield:", err)
+		os.Exit(1)
+	}
+	var opts []bitfield.Option
+	if *big {
+		opts = append(opts, bitfield.WithByteOrder(bitfield.BigEndian))
+	} else if *little {
+		opts = append(opts, bitfield.WithByteOrder(bitfield.LittleEndian))
+	}
+	if *msb {
+		opts = append(opts, bitfield.WithBitOrder(bitfield.MSBFirst))
+	}
+
+	if *pcap == "" {
+		data, err := readHex(flag.Arg(1), os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "bitfield:", err)
+			os.Exit(1)
+		}
+		if decode(os.Stdout, data, opts) != nil {
+			os.Exit(1)
+		}
+		return
+	}
+	data, err := os.ReadFile(*pcap)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bitfield:", err)
+		os.Exit(1)
+	}
+	if decodePcap(os.Stdout, data, *skip, *packet, decode, opts) != nil {
+		os.Exit(1)
+	}
+}
+
+// decoder writes the listing of the fields of data to w. The error of the
+// decoding is also written to w.
+type decoder func(w io.Writer, data []byte, opts []bitfield.Option) error
+
+// loadLayout returns the decoder of a schema document or of a struct type
+// named typeName in a Go file.
+func loadLayout(path, typeName string) (decoder, error) {
+	var parse func([]byte) (*schema.Schema, error)
+	switch filepath.Ext(path) {
+	case ".json":
+		parse = schema.ParseJSON
+	case ".yaml", ".yml":
+		parse = func(data []byte) (*schema.Schema, error) {
+			return schema.Parse(data, yaml.Unmarshal)
+		}
+	default:
+		rt, err := goType(path, typeName)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer, data []byte, opts []bitfield.Option) error {
+			return bitfield.Dump(data, reflect.New(rt).Interface(), w, opts...)
+		}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return func(w io.Writer, data []byte, opts []bitfield.Option) error {
+		return dumpSchema(w, s, data, opts)
+	}, nil
+}
+
+// dumpSchema writes the listing of the fields of data decoded with a schema in
+// the format of bitfield.Dump, with the names of enum values. Bits missing from
+// short data are marked as Dump does.
+func dumpSchema(w io.Writer, s *schema.Schema, data []byte, opts []bitfield.Option) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	fmt.Fprintf(tw, "FIELD\tBITS\tRAW\tVALUE\n")
+	values, err := s.Decode(data, opts...)
+	if err != nil {
+		fmt.Fprintf(tw, "error: %v\n", err)
+		tw.Flush()
+		return err
+	}
+	offset := 0
+	for _, f := range s.Fields() {
+		if f.Name == "" {
+			offset += f.Bits
+			continue
+		}
+		v := values[0]
+		values = values[1:]
+		var bits uint64
+		switch val := v.Value.(type) {
+		case bool:
+			if val {
+				bits = 1
+			}
+		case int64:
+			bits = uint64(val)
+		case uint64:
+			bits = val
+		}
+		if f.Bits < 64 {
+			bits &= 1<<f.Bits - 1
+		}
+		raw := fmt.Sprintf("%0*b", f.Bits, bits)
+		if offset+f.Bits > len(data)*8 {
+			raw += " (missing)"
+		}
+		value := fmt.Sprint(v.Value)
+		if v.Enum != "" {
+			value += " (" + v.Enum + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%d-%d\t%s\t%s\n", v.Name, offset, offset+f.Bits-1, raw, value)
+		offset += f.Bits
+	}
+	return tw.Flush()
+}
+
+// readHex returns the bytes of a hex string, or of the hex string read from
+// stdin if arg is "-".
+func readHex(arg string, stdin io.Reader) ([]byte, error) {
+	if arg == "-" {
+		b, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		arg = string(b)
+	}
+	s := strings.TrimPrefix(strings.TrimSpace(arg), "0x")
+	s = strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return r == ':' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	}), "")
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex data: %w", err)
+	}
+	return data, nil
+}
+
+// decodePcap writes the listing of the payload of each packet of a pcap
+// file, or of the packet numbered only if it is not negative. skip is the
+// number of bytes before the payload, or negative to skip the headers of the
+// link type. It returns the first error of the packets.
+func decodePcap(w io.Writer, data []byte, skip, only int, decode decoder, opts []bitfield.Option) error {
+	packets, linkType, err := readPcap(data)
+	if err != nil {
+		fmt.Fprintln(w, "error:", err)
+		return err
+	}
+	if only >= len(packets) {
+		err := fmt.Errorf("packet %d not found in %d packets", only, len(packets))
+		fmt.Fprintln(w, "error:", err)
+		return err
+	}
+	var firstErr error
+	for i, p := range packets {
+		if only >= 0 && i != only {
+			continue
+		}
+		payload, err := packetPayload(p, linkType, skip)
+		if err == nil {
+			fmt.Fprintf(w, "packet %d: %d bytes of payload\n", i, len(payload))
+			err = decode(w, payload, opts)
+		} else {
+			fmt.Fprintf(w, "packet %d: error: %v\n", i, err)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		fmt.Fprintln(w)
+	}
+	return firstErr
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmatsuzawa/go-bitfield"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSchema = `{
+  "byteOrder": "big",
+  "fields": [
+    {"name": "ver", "bits": 4},
+    {"name": "state", "bits": 4, "enum": [
+      {"name": "idle", "value": 0},
+      {"name": "busy", "value": 1}
+    ]},
+    {"bits": 8},
+    {"name": "temp", "bits": 16, "signed": true}
+  ]
+}`
+
+const testGoFile = `package sample
+
+type Opcode uint8
+
+type Header struct {
+	Version uint8  ` + "`bit:\"4\"`" + `
+	Op      Opcode ` + "`bit:\"4\" enum:\"opcode\"`" + `
+	_       uint8  ` + "`bit:\"8\"`" + `
+	Length  uint16
+}
+`
+
+// writeFile writes a file of the name and content in a temporary directory,
+// and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadLayout(t *testing.T) {
+	testCases := map[string]struct {
+		argName, argContent string
+		argData             []byte
+		argOpts             []bitfield.Option
+		want                []string
+	}{
+		"JSON schema": {
+			argName:    "status.json",
+			argContent: testSchema,
+			argData:    []byte{0x12, 0x00, 0xFF, 0xFE},
+			want: []string{
+				"FIELD   BITS    RAW                VALUE\n",
+				"ver     0-3     0010               2\n",
+				"state   4-7     0001               1 (busy)\n",
+				"temp    16-31   1111111111111110   -2\n",
+			},
+		},
+		"YAML schema": {
+			argName:    "status.yaml",
+			argContent: "fields:\n  - {name: ver, bits: 4}\n  - {name: ok, bits: 1, bool: true}\n",
+			argData:    []byte{0x12},
+			want:       []string{"ok      4-4    1      true\n"},
+		},
+		"schema with option": {
+			argName:    "status.json",
+			argContent: testSchema,
+			argData:    []byte{0x12, 0x00, 0xFE, 0xFF},
+			argOpts:    []bitfield.Option{bitfield.WithByteOrder(bitfield.LittleEndian)},
+			want:       []string{"temp    16-31   1111111111111110   -2\n"},
+		},
+		"short schema data": {
+			argName:    "status.json",
+			argContent: testSchema,
+			argData:    []byte{0x12, 0x00, 0xFF},
+			want:       []string{"temp    16-31   0000000011111111 (missing)   255\n"},
+		},
+		"Go file": {
+			argName:    "header.go",
+			argContent: testGoFile,
+			argData:    []byte{0x21, 0x00, 0x34, 0x12},
+			want: []string{
+				"Version   0-3     0001               1\n",
+				"Op        4-7     0010               2\n",
+				"Length    16-31   0001001000110100   4660\n",
+			},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Setup
+			decode, err := loadLayout(writeFile(t, tc.argName, tc.argContent), "")
+			assert.Nil(t, err)
+
+			// Exercise
+			var out strings.Builder
+			err = decode(&out, tc.argData, tc.argOpts)
+
+			// Verify
+			assert.Nil(t, err)
+			for _, want := range tc.want {
+				assert.Contains(t, out.String(), want)
+			}
+		})
+	}
+}
+
+func TestLoadLayoutError(t *testing.T) {
+	testCases := map[string]struct {
+		argName, argContent, argType string
+	}{
+		"invalid schema":     {argName: "s.json", argContent: `{"fields": [{"name": "a", "bits": 0}]}`},
+		"no struct":          {argName: "a.go", argContent: "package a\n\ntype A uint8\n"},
+		"type not found":     {argName: "a.go", argContent: testGoFile, argType: "Trailer"},
+		"type not struct":    {argName: "a.go", argContent: testGoFile, argType: "Opcode"},
+		"unsupported type":   {argName: "a.go", argContent: "package a\n\ntype A struct {\n\tB string `bit:\"8\"`\n}\n"},
+		"invalid Go file":    {argName: "a.go", argContent: "package"},
+		"nonexistent schema": {argName: "", argContent: ""},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Setup
+			path := filepath.Join(t.TempDir(), "missing.json")
+			if tc.argName != "" {
+				path = writeFile(t, tc.argName, tc.argContent)
+			}
+
+			// Exercise
+			_, err := loadLayout(path, tc.argType)
+
+			// Verify
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestLoadLayout_DecodeError(t *testing.T) {
+	// Setup
+	decode, err := loadLayout(writeFile(t, "status.json", testSchema), "")
+	assert.Nil(t, err)
+
+	// Exercise
+	var out strings.Builder
+	err = decode(&out, []byte{0x25, 0x00, 0xFF, 0xFE}, nil)
+
+	// Verify
+	var enumError *bitfield.EnumError
+	assert.ErrorAs(t, err, &enumError)
+	assert.Contains(t, out.String(), "error: ")
+}
+
+func TestReadHex(t *testing.T) {
+	testCases := map[string]struct {
+		argArg, argStdin string
+		want             []byte
+		wantErr          bool
+	}{
+		"plain":       {argArg: "deadBEEF", want: []byte{0xDE, 0xAD, 0xBE, 0xEF}},
+		"prefixed":    {argArg: "0x0102", want: []byte{1, 2}},
+		"separated":   {argArg: "01:02 03\t04", want: []byte{1, 2, 3, 4}},
+		"stdin":       {argArg: "-", argStdin: "0102\n0304\n", want: []byte{1, 2, 3, 4}},
+		"odd length":  {argArg: "012", wantErr: true},
+		"not hex":     {argArg: "0g", wantErr: true},
+		"empty stdin": {argArg: "-", want: []byte{}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := readHex(tc.argArg, strings.NewReader(tc.argStdin))
+
+			// Verify
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// pcapFile returns a pcap file in little-endian byte order of packets of the
+// link type.
+func pcapFile(linkType uint32, packets ...[]byte) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, 0xA1B2C3D4)
+	b = binary.LittleEndian.AppendUint16(b, 2)
+	b = binary.LittleEndian.AppendUint16(b, 4)
+	b = append(b, make([]byte, 12)...)
+	b = binary.LittleEndian.AppendUint32(b, linkType)
+	for _, p := range packets {
+		b = append(b, make([]byte, 8)...)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(p)))
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(p)))
+		b = append(b, p...)
+	}
+	return b
+}
+
+// udpPacket returns an Ethernet frame of an IPv4 UDP packet with the payload.
+func udpPacket(payload ...byte) []byte {
+	b := make([]byte, 12)
+	b = append(b, 0x08, 0x00)
+	ip := make([]byte, 20)
+	ip[0], ip[9] = 0x45, 17
+	b = append(b, ip...)
+	b = append(b, make([]byte, 8)...)
+	return append(b, payload...)
+}
+
+func TestDecodePcap(t *testing.T) {
+	// Setup
+	decode, err := loadLayout(writeFile(t, "status.json", testSchema), "")
+	assert.Nil(t, err)
+	arp := append(make([]byte, 12), 0x08, 0x06, 0x00)
+	data := pcapFile(linkTypeEthernet, udpPacket(0x12, 0x00, 0x00, 0x01), arp, udpPacket(0x01, 0x00, 0x00, 0x02))
+
+	// Exercise
+	var out strings.Builder
+	err = decodePcap(&out, data, -1, -1, decode, nil)
+	var outOnly strings.Builder
+	errOnly := decodePcap(&outOnly, data, -1, 2, decode, nil)
+	var outSkip strings.Builder
+	errSkip := decodePcap(&outSkip, data, 42, 0, decode, nil)
+	var outMissing strings.Builder
+	errMissing := decodePcap(&outMissing, data, -1, 3, decode, nil)
+	errNotPcap := decodePcap(&outMissing, []byte("not a pcap file of 24 bytes"), -1, -1, decode, nil)
+
+	// Verify
+	got := out.String()
+	assert.NotNil(t, err)
+	assert.Contains(t, got, "packet 0: 4 bytes of payload\n")
+	assert.Contains(t, got, "temp    16-31   0000000000000001   1\n")
+	assert.Contains(t, got, "packet 1: error: EtherType 0x0806 is not IP\n")
+	assert.Contains(t, got, "packet 2: 4 bytes of payload\n")
+	assert.Nil(t, errOnly)
+	assert.NotContains(t, outOnly.String(), "packet 0")
+	assert.Contains(t, outOnly.String(), "state   4-7     0000               0 (idle)\n")
+	assert.Nil(t, errSkip)
+	assert.Contains(t, outSkip.String(), "packet 0: 4 bytes of payload\n")
+	assert.NotNil(t, errMissing)
+	assert.NotNil(t, errNotPcap)
+}
+
+func TestPacketPayload(t *testing.T) {
+	// Setup
+	tcp := make([]byte, 24)
+	tcp[12] = 6 << 4
+	ipv6 := make([]byte, 40)
+	ipv6[0], ipv6[6] = 0x60, 6
+	vlan := append(make([]byte, 12), 0x81, 0x00, 0x00, 0x01, 0x08, 0x00)
+
+	testCases := map[string]struct {
+		argPacket   []byte
+		argLinkType uint32
+		argSkip     int
+		want        []byte
+		wantErr     bool
+	}{
+		"IPv6 TCP":         {argPacket: append(append(ipv6, tcp...), 0xAB), argLinkType: linkTypeRaw, argSkip: -1, want: []byte{0xAB}},
+		"VLAN":             {argPacket: append(vlan, udpPacket(0xCD)[14:]...), argLinkType: linkTypeEthernet, argSkip: -1, want: []byte{0xCD}},
+		"other link type":  {argPacket: []byte{1, 2}, argLinkType: 105, argSkip: -1, want: []byte{1, 2}},
+		"skip":             {argPacket: []byte{1, 2, 3}, argLinkType: linkTypeEthernet, argSkip: 2, want: []byte{3}},
+		"skip too long":    {argPacket: []byte{1, 2, 3}, argLinkType: linkTypeEthernet, argSkip: 4, wantErr: true},
+		"truncated IPv4":   {argPacket: []byte{0x45, 0}, argLinkType: linkTypeIPv4, argSkip: -1, wantErr: true},
+		"not UDP nor TCP":  {argPacket: append(ipv6[:6:6], append([]byte{58}, ipv6[7:]...)...), argLinkType: linkTypeIPv6, argSkip: -1, wantErr: true},
+		"truncated header": {argPacket: make([]byte, 10), argLinkType: linkTypeEthernet, argSkip: -1, wantErr: true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := packetPayload(tc.argPacket, tc.argLinkType, tc.argSkip)
+
+			// Verify
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
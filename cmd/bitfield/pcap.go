@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Link types of pcap files whose headers are skipped by default.
+const (
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	linkTypeIPv4     = 228
+	linkTypeIPv6     = 229
+)
+
+// readPcap returns the captured bytes of the packets of a pcap file, in the
+// classic format of libpcap with either byte order and timestamp resolution,
+// and the link type of the packets.
+func readPcap(data []byte) (packets [][]byte, linkType uint32, err error) {
+	if len(data) < 24 {
+		return nil, 0, errors.New("pcap file is shorter than its header")
+	}
+	var order binary.ByteOrder
+	switch binary.LittleEndian.Uint32(data) {
+	case 0xA1B2C3D4, 0xA1B23C4D:
+		order = binary.LittleEndian
+	case 0xD4C3B2A1, 0x4D3CB2A1:
+		order = binary.BigEndian
+	default:
+		return nil, 0, errors.New("not a pcap file")
+	}
+	linkType = order.Uint32(data[20:])
+	for i := 24; i < len(data); {
+		if len(data)-i < 16 {
+			return nil, 0, fmt.Errorf("packet %d is truncated", len(packets))
+		}
+		n := int(order.Uint32(data[i+8:]))
+		i += 16
+		if n < 0 || len(data)-i < n {
+			return nil, 0, fmt.Errorf("packet %d is truncated", len(packets))
+		}
+		packets = append(packets, data[i:i+n])
+		i += n
+	}
+	return packets, linkType, nil
+}
+
+// packetPayload returns the payload of a packet after skip bytes, or if skip
+// is negative, after the Ethernet, IP and UDP or TCP headers of the link
+// types carrying them.
+func packetPayload(packet []byte, linkType uint32, skip int) ([]byte, error) {
+	if skip >= 0 {
+		if skip > len(packet) {
+			return nil, fmt.Errorf("packet of %d bytes is shorter than %d bytes to skip", len(packet), skip)
+		}
+		return packet[skip:], nil
+	}
+	switch linkType {
+	case linkTypeEthernet:
+		if len(packet) < 14 {
+			return nil, errors.New("truncated Ethernet header")
+		}
+		etherType, i := binary.BigEndian.Uint16(packet[12:]), 14
+		for etherType == 0x8100 || etherType == 0x88A8 {
+			// VLAN tags
+			if len(packet) < i+4 {
+				return nil, errors.New("truncated VLAN tag")
+			}
+			etherType, i = binary.BigEndian.Uint16(packet[i+2:]), i+4
+		}
+		if etherType != 0x0800 && etherType != 0x86DD {
+			return nil, fmt.Errorf("EtherType %#04x is not IP", etherType)
+		}
+		return ipPayload(packet[i:])
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+		return ipPayload(packet)
+	}
+	return packet, nil
+}
+
+// ipPayload returns the payload of the UDP or TCP segment of an IPv4 or IPv6
+// packet. IPv6 extension headers are not supported.
+func ipPayload(packet []byte) ([]byte, error) {
+	if len(packet) == 0 {
+		return nil, errors.New("truncated IP header")
+	}
+	var protocol byte
+	switch packet[0] >> 4 {
+	case 4:
+		n := int(packet[0]&0xF) * 4
+		if n < 20 || len(packet) < n {
+			return nil, errors.New("truncated IPv4 header")
+		}
+		protocol, packet = packet[9], packet[n:]
+	case 6:
+		if len(packet) < 40 {
+			return nil, errors.New("truncated IPv6 header")
+		}
+		protocol, packet = packet[6], packet[40:]
+	default:
+		return nil, fmt.Errorf("IP version %d is not supported", packet[0]>>4)
+	}
+	switch protocol {
+	case 6:
+		if len(packet) < 20 || len(packet) < int(packet[12]>>4)*4 {
+			return nil, errors.New("truncated TCP header")
+		}
+		return packet[int(packet[12]>>4)*4:], nil
+	case 17:
+		if len(packet) < 8 {
+			return nil, errors.New("truncated UDP header")
+		}
+		return packet[8:], nil
+	}
+	return nil, fmt.Errorf("IP protocol %d is neither UDP nor TCP", protocol)
+}
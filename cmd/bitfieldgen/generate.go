@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jmatsuzawa/go-bitfield"
+)
+
+// basicTypes are the field types supported by the generated code.
+var basicTypes = map[string]reflect.Type{
+	"bool":   reflect.TypeOf(false),
+	"byte":   reflect.TypeOf(uint8(0)),
+	"uint8":  reflect.TypeOf(uint8(0)),
+	"uint16": reflect.TypeOf(uint16(0)),
+	"uint32": reflect.TypeOf(uint32(0)),
+	"uint64": reflect.TypeOf(uint64(0)),
+	"int8":   reflect.TypeOf(int8(0)),
+	"int16":  reflect.TypeOf(int16(0)),
+	"int32":  reflect.TypeOf(int32(0)),
+	"int64":  reflect.TypeOf(int64(0)),
+}
+
+// codec is a struct type to generate methods for.
+type codec struct {
+	name   string
+	layout *bitfield.Layout
+	// fields maps the name of a field in the layout to the expression of the
+	// struct field, or to an empty string for fields which are not stored
+	fields map[string]string
+	// types maps the name of a field in the layout to its Go type
+	types map[string]string
+}
+
+// newCodec returns the codec of a struct type declared as st. The layout is
+// computed by bitfield.LayoutOf from a struct type with the same fields, so
+// that the generated code agrees with bitfield.Unmarshal and bitfield.Marshal.
+func newCodec(name string, st *ast.StructType, opts []bitfield.Option) (*codec, error) {
+	c := &codec{name: name, fields: map[string]string{}, types: map[string]string{}}
+	var fields []reflect.StructField
+	for _, f := range st.Fields.List {
+		rt, typeName, err := fieldType(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			s, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(s)
+		}
+		if len(f.Names) == 0 {
+			return nil, fmt.Errorf("%s: embedded field %s is not supported", name, typeName)
+		}
+		for _, key := range []string{"const", "enum", "check", "uuid", "enc", "signed"} {
+			if _, ok := tag.Lookup(key); ok {
+				// The generated code does not check nor write constants, enum
+				// values and checksums, nor reorder the bytes of GUIDs nor
+				// encode values
+				return nil, fmt.Errorf("%s: %s tag is not supported", name, key)
+			}
+		}
+		for _, n := range f.Names {
+			key := n.Name
+			if token.IsExported(key) {
+				c.fields[key] = "v." + key
+			} else {
+				// Placeholders and unexported fields are not stored, and are
+				// renamed, as StructOf does not accept unexported fields
+				key = "Unexported" + strconv.Itoa(len(fields))
+			}
+			fields = append(fields, reflect.StructField{Name: key, Type: rt, Tag: tag})
+			c.types[key] = typeName
+		}
+	}
+	layout, err := bitfield.LayoutOf(reflect.New(reflect.StructOf(fields)).Interface(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	for _, f := range layout.Fields {
+		if f.Encoding != "" {
+			return nil, fmt.Errorf("%s: field %s encoded as %s is not supported", name, f.Name, f.Encoding)
+		}
+		if f.Bits > 64 {
+			return nil, fmt.Errorf("%s: field %s wider than 64 bits is not supported", name, f.Name)
+		}
+	}
+	c.layout = layout
+	return c, nil
+}
+
+// fieldType returns the reflect type and the Go type of a field of a basic
+// type or an array of a basic type.
+func fieldType(expr ast.Expr) (reflect.Type, string, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if rt, ok := basicTypes[t.Name]; ok {
+			return rt, t.Name, nil
+		}
+	case *ast.ArrayType:
+		lit, ok := t.Len.(*ast.BasicLit)
+		if !ok || lit.Kind != token.INT {
+			break
+		}
+		n, err := strconv.Atoi(lit.Value)
+		if err != nil {
+			break
+		}
+		if elem, ok := t.Elt.(*ast.Ident); ok {
+			if rt, ok := basicTypes[elem.Name]; ok {
+				return reflect.ArrayOf(n, rt), elem.Name, nil
+			}
+		}
+	}
+	var b bytes.Buffer
+	format.Node(&b, token.NewFileSet(), expr)
+	return nil, "", fmt.Errorf("field type %s is not supported", b.String())
+}
+
+// expr returns the expression of a field in the layout, such as "v.Addr[1]",
+// and its Go type. expr is empty if the field is not stored.
+func (c *codec) expr(f bitfield.FieldLayout) (expr, typ string) {
+	key, index, _ := strings.Cut(f.Name, "[")
+	expr = c.fields[key]
+	if expr != "" && index != "" {
+		expr += "[" + index
+	}
+	return expr, c.types[key]
+}
+
+// chunk is a run of bits of a field within a single byte.
+type chunk struct {
+	index int // index of the byte
+	shift int // shift of the bits in the byte
+	mask  int // mask of the bits after the shift
+	value int // shift of the bits in the value of the field
+}
+
+// chunks splits a field into runs of bits per byte, in the order in which
+// they are read, as bitfield reads them.
+func (c *codec) chunks(f bitfield.FieldLayout) []chunk {
+	var cs []chunk
+	for consumed := 0; consumed < f.Bits; {
+		offset := f.Offset + consumed
+		n := min(8-offset%8, f.Bits-consumed)
+		ch := chunk{index: offset / 8, shift: offset % 8, mask: 1<<n - 1, value: consumed}
+		if f.BitOrder == bitfield.MSBFirst {
+			ch.shift = 8 - offset%8 - n
+		}
+		if f.ByteOrder == bitfield.BigEndian {
+			// The earlier bytes hold the more significant bits
+			ch.value = f.Bits - consumed - n
+		}
+		cs = append(cs, ch)
+		consumed += n
+	}
+	return cs
+}
+
+func (c *codec) size() int {
+	return (c.layout.BitSize + 7) / 8
+}
+
+// generate writes the methods of the codec.
+func (c *codec) generate(b *bytes.Buffer, options []string) {
+	fmt.Fprintf(b, "\n// UnmarshalBitfield decodes data into v as bitfield.Unmarshal does, without\n")
+	fmt.Fprintf(b, "// reflection. It returns an error wrapping io.ErrUnexpectedEOF if data is\n")
+	fmt.Fprintf(b, "// shorter than %d bytes.\n", c.size())
+	writeOptions(b, append(options[:len(options):len(options)], "bitfield.WithStrictInput(true)"))
+	fmt.Fprintf(b, "func (v *%s) UnmarshalBitfield(data []byte) error {\n", c.name)
+	fmt.Fprintf(b, "if len(data) < %d {\n", c.size())
+	fmt.Fprintf(b, "return fmt.Errorf(\"bitfield: input of %%d bytes is shorter than %s of %d bytes: %%w\", len(data), io.ErrUnexpectedEOF)\n}\n", c.name, c.size())
+	for _, f := range c.layout.Fields {
+		expr, typ := c.expr(f)
+		if expr == "" {
+			continue
+		}
+		var terms []string
+		for _, ch := range c.chunks(f) {
+			term := fmt.Sprintf("data[%d]", ch.index)
+			if ch.shift > 0 {
+				term += fmt.Sprintf(">>%d", ch.shift)
+			}
+			if ch.mask != 0xff {
+				term += fmt.Sprintf("&%#x", ch.mask)
+			}
+			term = "uint64(" + term + ")"
+			if ch.value > 0 {
+				term += fmt.Sprintf("<<%d", ch.value)
+			}
+			terms = append(terms, term)
+		}
+		val := strings.Join(terms, " | ")
+		switch {
+		case typ == "bool":
+			fmt.Fprintf(b, "%s = %s != 0\n", expr, val)
+		case f.Signed && f.Bits < basicTypes[typ].Bits():
+			if len(terms) > 1 {
+				val = "(" + val + ")"
+			}
+			fmt.Fprintf(b, "%s = %s(int64(%s<<%d) >> %d)\n", expr, typ, val, 64-f.Bits, 64-f.Bits)
+		default:
+			fmt.Fprintf(b, "%s = %s(%s)\n", expr, typ, val)
+		}
+	}
+	fmt.Fprintf(b, "return nil\n}\n")
+
+	fmt.Fprintf(b, "\n// MarshalBitfield encodes v as bitfield.Marshal does, without reflection.\n")
+	writeOptions(b, options)
+	fmt.Fprintf(b, "func (v *%s) MarshalBitfield() ([]byte, error) {\n", c.name)
+	fmt.Fprintf(b, "data := make([]byte, %d)\n", c.size())
+	for _, f := range c.layout.Fields {
+		expr, typ := c.expr(f)
+		if expr == "" {
+			continue
+		}
+		fmt.Fprintf(b, "{\n")
+		switch {
+		case typ == "bool":
+			fmt.Fprintf(b, "var x uint64\nif %s {\nx = 1\n}\n", expr)
+		case f.Signed && f.Bits < basicTypes[typ].Bits():
+			fmt.Fprintf(b, "if %s < %d || %s > %d {\n", expr, int64(-1)<<(f.Bits-1), expr, int64(1)<<(f.Bits-1)-1)
+			c.overflow(b, f, expr)
+			fmt.Fprintf(b, "}\nx := uint64(%s) & %#x\n", expr, uint64(1)<<f.Bits-1)
+		case !f.Signed && f.Bits < basicTypes[typ].Bits():
+			fmt.Fprintf(b, "x := uint64(%s)\nif x>>%d != 0 {\n", expr, f.Bits)
+			c.overflow(b, f, expr)
+			fmt.Fprintf(b, "}\n")
+		default:
+			fmt.Fprintf(b, "x := uint64(%s)\n", expr)
+		}
+		for _, ch := range c.chunks(f) {
+			term := "byte(x"
+			if ch.value > 0 {
+				term += fmt.Sprintf(">>%d", ch.value)
+			}
+			term += ")"
+			if ch.mask != 0xff {
+				term += fmt.Sprintf("&%#x", ch.mask)
+			}
+			if ch.shift > 0 {
+				term += fmt.Sprintf("<<%d", ch.shift)
+			}
+			fmt.Fprintf(b, "data[%d] |= %s\n", ch.index, term)
+		}
+		fmt.Fprintf(b, "}\n")
+	}
+	fmt.Fprintf(b, "return data, nil\n}\n")
+}
+
+// writeOptions writes a doc comment listing the options passed to
+// bitfield.Unmarshal and bitfield.Marshal for the same result.
+func writeOptions(b *bytes.Buffer, options []string) {
+	if len(options) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "//\n// It corresponds to the options:\n//\n")
+	for _, opt := range options {
+		fmt.Fprintf(b, "//\t%s\n", opt)
+	}
+}
+
+func (c *codec) overflow(b *bytes.Buffer, f bitfield.FieldLayout, expr string) {
+	name := strings.TrimPrefix(expr, "v.")
+	fmt.Fprintf(b, "return nil, fmt.Errorf(\"bitfield: value %%v overflows bit size of field %s\", %s)\n", name, expr)
+}
+
+// generate writes Go source declaring the methods of the codecs in package
+// pkg. options is the Go source of the options passed to bitfield.Unmarshal
+// and bitfield.Marshal for the same result, such as
+// "bitfield.WithByteOrder(bitfield.BigEndian)".
+func generate(pkg string, codecs []*codec, options []string) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by bitfieldgen. DO NOT EDIT.\n\npackage %s\n", pkg)
+	fmt.Fprintf(&b, "\nimport (\n\"fmt\"\n\"io\"\n)\n")
+	for _, c := range codecs {
+		c.generate(&b, options)
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return src, nil
+}
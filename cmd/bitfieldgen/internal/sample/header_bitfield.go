@@ -0,0 +1,101 @@
+// Code generated by bitfieldgen. DO NOT EDIT.
+
+package sample
+
+import (
+	"fmt"
+	"io"
+)
+
+// UnmarshalBitfield decodes data into v as bitfield.Unmarshal does, without
+// reflection. It returns an error wrapping io.ErrUnexpectedEOF if data is
+// shorter than 14 bytes.
+//
+// It corresponds to the options:
+//
+//	bitfield.WithStrictInput(true)
+func (v *Header) UnmarshalBitfield(data []byte) error {
+	if len(data) < 14 {
+		return fmt.Errorf("bitfield: input of %d bytes is shorter than Header of 14 bytes: %w", len(data), io.ErrUnexpectedEOF)
+	}
+	v.Version = uint8(uint64(data[0] & 0xf))
+	v.Priority = int8(int64(uint64(data[0]>>4&0x7)<<61) >> 61)
+	v.Urgent = uint64(data[0]>>7&0x1) != 0
+	v.Length = uint16(uint64(data[1]) | uint64(data[2])<<8)
+	v.Offset = int32(int64((uint64(data[3]>>2&0x3f)|uint64(data[4]&0x7f)<<6)<<51) >> 51)
+	v.Flags = uint8(uint64(data[5] & 0x7))
+	v.Addr[0] = uint8(uint64(data[6]))
+	v.Addr[1] = uint8(uint64(data[7]))
+	v.Sequence = uint32(uint64(data[8])<<16 | uint64(data[9])<<8 | uint64(data[10]))
+	v.Count = int16(uint64(data[12]) | uint64(data[13])<<8)
+	return nil
+}
+
+// MarshalBitfield encodes v as bitfield.Marshal does, without reflection.
+func (v *Header) MarshalBitfield() ([]byte, error) {
+	data := make([]byte, 14)
+	{
+		x := uint64(v.Version)
+		if x>>4 != 0 {
+			return nil, fmt.Errorf("bitfield: value %v overflows bit size of field Version", v.Version)
+		}
+		data[0] |= byte(x) & 0xf
+	}
+	{
+		if v.Priority < -4 || v.Priority > 3 {
+			return nil, fmt.Errorf("bitfield: value %v overflows bit size of field Priority", v.Priority)
+		}
+		x := uint64(v.Priority) & 0x7
+		data[0] |= byte(x) & 0x7 << 4
+	}
+	{
+		var x uint64
+		if v.Urgent {
+			x = 1
+		}
+		data[0] |= byte(x) & 0x1 << 7
+	}
+	{
+		x := uint64(v.Length)
+		data[1] |= byte(x)
+		data[2] |= byte(x >> 8)
+	}
+	{
+		if v.Offset < -4096 || v.Offset > 4095 {
+			return nil, fmt.Errorf("bitfield: value %v overflows bit size of field Offset", v.Offset)
+		}
+		x := uint64(v.Offset) & 0x1fff
+		data[3] |= byte(x) & 0x3f << 2
+		data[4] |= byte(x>>6) & 0x7f
+	}
+	{
+		x := uint64(v.Flags)
+		if x>>3 != 0 {
+			return nil, fmt.Errorf("bitfield: value %v overflows bit size of field Flags", v.Flags)
+		}
+		data[5] |= byte(x) & 0x7
+	}
+	{
+		x := uint64(v.Addr[0])
+		data[6] |= byte(x)
+	}
+	{
+		x := uint64(v.Addr[1])
+		data[7] |= byte(x)
+	}
+	{
+		x := uint64(v.Sequence)
+		if x>>24 != 0 {
+			return nil, fmt.Errorf("bitfield: value %v overflows bit size of field Sequence", v.Sequence)
+		}
+		data[8] |= byte(x >> 16)
+		data[9] |= byte(x >> 8)
+		data[10] |= byte(x)
+	}
+	{
+		x := uint64(v.Count)
+		data[12] |= byte(x)
+		data[13] |= byte(x >> 8)
+	}
+	return data, nil
+}
@@ -0,0 +1,93 @@
+// Code generated by bitfieldgen. DO NOT EDIT.
+
+package sample
+
+import (
+	"fmt"
+	"io"
+)
+
+// UnmarshalBitfield decodes data into v as bitfield.Unmarshal does, without
+// reflection. It returns an error wrapping io.ErrUnexpectedEOF if data is
+// shorter than 9 bytes.
+//
+// It corresponds to the options:
+//
+//	bitfield.WithByteOrder(bitfield.BigEndian)
+//	bitfield.WithBitOrder(bitfield.MSBFirst)
+//	bitfield.WithStrictInput(true)
+func (v *Packet) UnmarshalBitfield(data []byte) error {
+	if len(data) < 9 {
+		return fmt.Errorf("bitfield: input of %d bytes is shorter than Packet of 9 bytes: %w", len(data), io.ErrUnexpectedEOF)
+	}
+	v.Version = uint8(uint64(data[0] >> 4 & 0xf))
+	v.IHL = uint8(uint64(data[0] & 0xf))
+	v.TOS = uint8(uint64(data[1] >> 2 & 0x3f))
+	v.Label = uint32(uint64(data[1]&0x3)<<18 | uint64(data[2])<<10 | uint64(data[3])<<2 | uint64(data[4]>>6&0x3))
+	v.Length = uint16(uint64(data[5])<<8 | uint64(data[6]))
+	v.Delta = int16(int64((uint64(data[7])<<2|uint64(data[8]>>6&0x3))<<54) >> 54)
+	v.Last = uint64(data[8]>>5&0x1) != 0
+	return nil
+}
+
+// MarshalBitfield encodes v as bitfield.Marshal does, without reflection.
+//
+// It corresponds to the options:
+//
+//	bitfield.WithByteOrder(bitfield.BigEndian)
+//	bitfield.WithBitOrder(bitfield.MSBFirst)
+func (v *Packet) MarshalBitfield() ([]byte, error) {
+	data := make([]byte, 9)
+	{
+		x := uint64(v.Version)
+		if x>>4 != 0 {
+			return nil, fmt.Errorf("bitfield: value %v overflows bit size of field Version", v.Version)
+		}
+		data[0] |= byte(x) & 0xf << 4
+	}
+	{
+		x := uint64(v.IHL)
+		if x>>4 != 0 {
+			return nil, fmt.Errorf("bitfield: value %v overflows bit size of field IHL", v.IHL)
+		}
+		data[0] |= byte(x) & 0xf
+	}
+	{
+		x := uint64(v.TOS)
+		if x>>6 != 0 {
+			return nil, fmt.Errorf("bitfield: value %v overflows bit size of field TOS", v.TOS)
+		}
+		data[1] |= byte(x) & 0x3f << 2
+	}
+	{
+		x := uint64(v.Label)
+		if x>>20 != 0 {
+			return nil, fmt.Errorf("bitfield: value %v overflows bit size of field Label", v.Label)
+		}
+		data[1] |= byte(x>>18) & 0x3
+		data[2] |= byte(x >> 10)
+		data[3] |= byte(x >> 2)
+		data[4] |= byte(x) & 0x3 << 6
+	}
+	{
+		x := uint64(v.Length)
+		data[5] |= byte(x >> 8)
+		data[6] |= byte(x)
+	}
+	{
+		if v.Delta < -512 || v.Delta > 511 {
+			return nil, fmt.Errorf("bitfield: value %v overflows bit size of field Delta", v.Delta)
+		}
+		x := uint64(v.Delta) & 0x3ff
+		data[7] |= byte(x >> 2)
+		data[8] |= byte(x) & 0x3 << 6
+	}
+	{
+		var x uint64
+		if v.Last {
+			x = 1
+		}
+		data[8] |= byte(x) & 0x1 << 5
+	}
+	return data, nil
+}
@@ -0,0 +1,32 @@
+// Package sample holds structs with codecs generated by bitfieldgen, which
+// are tested against bitfield.Unmarshal and bitfield.Marshal.
+package sample
+
+//go:generate go run github.com/jmatsuzawa/go-bitfield/cmd/bitfieldgen -type Header
+//go:generate go run github.com/jmatsuzawa/go-bitfield/cmd/bitfieldgen -type Packet -big -msb
+
+// Header exercises fields spanning bytes in little-endian byte order.
+type Header struct {
+	Version  uint8 `bit:"4"`
+	Priority int8  `bit:"3"`
+	Urgent   bool  `bit:"1"`
+	Length   uint16
+	_        uint8 `bit:"2"`
+	Offset   int32 `bit:"13"`
+	Flags    uint8 `bit:"3" skip:"1"`
+	Addr     [2]uint8
+	Sequence uint32 `bytes:"3" endian:"big"`
+	reserved uint8
+	Count    int16
+}
+
+// Packet exercises big-endian byte order and MSB-first bit order.
+type Packet struct {
+	Version uint8  `bit:"4"`
+	IHL     uint8  `bit:"4"`
+	TOS     uint8  `bit:"6"`
+	Label   uint32 `bit:"20"`
+	Length  uint16
+	Delta   int16 `bit:"10"`
+	Last    bool  `bit:"1"`
+}
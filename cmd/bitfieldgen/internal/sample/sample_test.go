@@ -0,0 +1,71 @@
+package sample
+
+import (
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/jmatsuzawa/go-bitfield"
+	"github.com/stretchr/testify/assert"
+)
+
+// codec is implemented by the structs with generated codecs.
+type codec interface {
+	UnmarshalBitfield(data []byte) error
+	MarshalBitfield() ([]byte, error)
+}
+
+func TestGenerated(t *testing.T) {
+	// Setup
+	testCases := map[string]struct {
+		newValue func() codec
+		opts     []bitfield.Option
+	}{
+		"Header": {
+			newValue: func() codec { return &Header{} },
+		},
+		"Packet": {
+			newValue: func() codec { return &Packet{} },
+			opts:     []bitfield.Option{bitfield.WithByteOrder(bitfield.BigEndian), bitfield.WithBitOrder(bitfield.MSBFirst)},
+		},
+	}
+	rnd := rand.New(rand.NewSource(1))
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				data := make([]byte, rnd.Intn(20))
+				rnd.Read(data)
+
+				// Exercise
+				got, want := tc.newValue(), tc.newValue()
+				err := got.UnmarshalBitfield(data)
+				errWant := bitfield.Unmarshal(data, want, append(tc.opts, bitfield.WithStrictInput(true))...)
+				encoded, errEncode := got.MarshalBitfield()
+				wantEncoded, errWantEncode := bitfield.Marshal(want, tc.opts...)
+
+				// Verify
+				if errWant != nil {
+					assert.ErrorIs(t, errWant, io.ErrUnexpectedEOF)
+					assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+					continue
+				}
+				assert.Nil(t, err)
+				assert.Equal(t, want, got, "data %x", data)
+				assert.Nil(t, errEncode)
+				assert.Nil(t, errWantEncode)
+				assert.Equal(t, wantEncoded, encoded, "data %x", data)
+			}
+		})
+	}
+}
+
+func TestGenerated_Overflow(t *testing.T) {
+	// Exercise
+	_, errUnsigned := (&Header{Version: 0x10}).MarshalBitfield()
+	_, errSigned := (&Packet{Delta: -513}).MarshalBitfield()
+
+	// Verify
+	assert.EqualError(t, errUnsigned, "bitfield: value 16 overflows bit size of field Version")
+	assert.EqualError(t, errSigned, "bitfield: value -513 overflows bit size of field Delta")
+}
@@ -0,0 +1,151 @@
+// Command bitfieldgen generates reflection-free codecs for structs with
+// bit-fields. For each named struct type, it writes methods
+//
+//	func (v *T) UnmarshalBitfield(data []byte) error
+//	func (v *T) MarshalBitfield() ([]byte, error)
+//
+// which decode and encode the struct as bitfield.Unmarshal and
+// bitfield.Marshal do, with straight-line bit arithmetic instead of reflection.
+// The positions of the fields are computed by bitfield.LayoutOf when the code
+// is generated.
+//
+// Usage:
+//
+//	bitfieldgen -type T[,T...] [-big] [-msb] [-o output.go] [dir]
+//
+// The types are looked up in the Go files of dir (default: the current
+// directory), excluding tests. -big and -msb select big-endian byte order and
+// MSB-first bit order, as bitfield.WithByteOrder and bitfield.WithBitOrder do.
+// The output defaults to the lower-cased name of the first type followed by
+// "_bitfield.go" in dir. It can be used with go:generate:
+//
+//	//go:generate go run github.com/jmatsuzawa/go-bitfield/cmd/bitfieldgen -type Header -big
+//
+// Fields must be of type bool, a fixed-size integer type, or an array of such
+// a type, with the tags accepted by bitfield.LayoutOf except the const, enum,
+// check, uuid, enc and signed tags, which check or convert values. Unlike
+// bitfield.Unmarshal, the generated code takes no options, rejects data shorter
+// than the struct as bitfield.WithStrictInput does, and does not check values
+// registered by bitfield.RegisterEnum.
+//
+// With "vet", bitfieldgen checks the layouts of a repository instead:
+//
+//	bitfieldgen vet [packages]
+//
+// It finds the struct types with tags of the package bitfield in the packages
+// listed by go list (default: ./...), validates them for bitfield.Unmarshal and
+// bitfield.Marshal, including overlapping offset tags, and prints a line for
+// each problem. A directive in the doc comment of a type asserts its size in
+// bytes, as returned by bitfield.Size:
+//
+//	//bitfield:size 20
+//	type IPv4Header struct { ... }
+//
+// Types with fields of types other than integers, floats, bools, bitfield.Raw,
+// bitfield.TLV, and arrays, slices, pointers and structs of them declared in
+// the same package are reported as skipped. The exit status is 1 if any
+// problem is found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmatsuzawa/go-bitfield"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "vet" {
+		os.Exit(vetMain(os.Args[2:]))
+	}
+	types := flag.String("type", "", "comma-separated names of the struct types")
+	big := flag.Bool("big", false, "use big-endian byte order")
+	msb := flag.Bool("msb", false, "use MSB-first bit order")
+	out := flag.String("o", "", "output file (default <type>_bitfield.go in dir)")
+	flag.Parse()
+	if *types == "" || flag.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "usage: bitfieldgen -type T[,T...] [-big] [-msb] [-o output.go] [dir]")
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	names := strings.Split(*types, ",")
+	output := *out
+	if output == "" {
+		output = filepath.Join(dir, strings.ToLower(names[0])+"_bitfield.go")
+	}
+	if err := run(dir, names, *big, *msb, output); err != nil {
+		fmt.Fprintln(os.Stderr, "bitfieldgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, names []string, big, msb bool, output string) error {
+	src, err := generateDir(dir, names, big, msb, output)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(output, src, 0o644)
+}
+
+// generateDir returns the generated source for the named struct types declared
+// in the Go files of dir. The output file is not read, as it may be stale.
+func generateDir(dir string, names []string, big, msb bool, output string) ([]byte, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	structs := map[string]*ast.StructType{}
+	pkg := ""
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") || filepath.Clean(path) == filepath.Clean(output) {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		pkg = f.Name.Name
+		ast.Inspect(f, func(n ast.Node) bool {
+			if spec, ok := n.(*ast.TypeSpec); ok {
+				if st, ok := spec.Type.(*ast.StructType); ok {
+					structs[spec.Name.Name] = st
+				}
+			}
+			return true
+		})
+	}
+
+	var opts []bitfield.Option
+	var options []string
+	if big {
+		opts = append(opts, bitfield.WithByteOrder(bitfield.BigEndian))
+		options = append(options, "bitfield.WithByteOrder(bitfield.BigEndian)")
+	}
+	if msb {
+		opts = append(opts, bitfield.WithBitOrder(bitfield.MSBFirst))
+		options = append(options, "bitfield.WithBitOrder(bitfield.MSBFirst)")
+	}
+	var codecs []*codec
+	for _, name := range names {
+		st, ok := structs[name]
+		if !ok {
+			return nil, fmt.Errorf("struct type %s not found in %s", name, dir)
+		}
+		c, err := newCodec(name, st, opts)
+		if err != nil {
+			return nil, err
+		}
+		codecs = append(codecs, c)
+	}
+	return generate(pkg, codecs, options)
+}
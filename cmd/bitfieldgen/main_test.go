@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateDir(t *testing.T) {
+	// Setup
+	dir := filepath.Join("internal", "sample")
+	testCases := map[string]struct {
+		argNames []string
+		argBig   bool
+		argMSB   bool
+		output   string
+	}{
+		"LittleEndian": {
+			argNames: []string{"Header"},
+			output:   "header_bitfield.go",
+		},
+		"BigEndianMSBFirst": {
+			argNames: []string{"Packet"},
+			argBig:   true,
+			argMSB:   true,
+			output:   "packet_bitfield.go",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			output := filepath.Join(dir, tc.output)
+			want, _ := os.ReadFile(output)
+
+			// Exercise
+			got, err := generateDir(dir, tc.argNames, tc.argBig, tc.argMSB, output)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, string(want), string(got), "run go generate in %s", dir)
+		})
+	}
+}
+
+func TestGenerateDirError(t *testing.T) {
+	// Setup
+	dir := t.TempDir()
+	src := "package p\n\ntype Nested struct {\n\tA struct{ B uint8 }\n}\n\ntype Wide struct {\n\tA uint8 `bit:\"9\"`\n}\n\ntype Const struct {\n\tA uint8 `const:\"1\"`\n}\n\ntype Check struct {\n\tA uint8 `check:\"sum8,0:\"`\n}\n\ntype GUID struct {\n\tA [16]byte `uuid:\"guid\"`\n}\n\ntype Counter struct {\n\tA [12]byte `bit:\"96\"`\n}\n\ntype BCD struct {\n\tA uint8 `enc:\"bcd\"`\n}\n\ntype Zigzag struct {\n\tA int8 `signed:\"zigzag\"`\n}\n\ntype Enum struct {\n\tA uint8 `bit:\"4\" enum:\"1,2\"`\n}\n"
+	os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0o644)
+	output := filepath.Join(dir, "out.go")
+
+	// Exercise
+	_, errNotFound := generateDir(dir, []string{"Missing"}, false, false, output)
+	_, errNested := generateDir(dir, []string{"Nested"}, false, false, output)
+	_, errWide := generateDir(dir, []string{"Wide"}, false, false, output)
+	_, errConst := generateDir(dir, []string{"Const"}, false, false, output)
+	_, errCheck := generateDir(dir, []string{"Check"}, false, false, output)
+	_, errGUID := generateDir(dir, []string{"GUID"}, false, false, output)
+	_, errCounter := generateDir(dir, []string{"Counter"}, false, false, output)
+	_, errBCD := generateDir(dir, []string{"BCD"}, false, false, output)
+	_, errZigzag := generateDir(dir, []string{"Zigzag"}, false, false, output)
+	_, errEnum := generateDir(dir, []string{"Enum"}, false, false, output)
+
+	// Verify
+	assert.ErrorContains(t, errNotFound, "struct type Missing not found")
+	assert.ErrorContains(t, errNested, "Nested: field type struct{ B uint8 } is not supported")
+	assert.ErrorContains(t, errWide, "Wide: bitfield: bit size must be within range 1 to its type size (A uint8")
+	assert.ErrorContains(t, errConst, "Const: const tag is not supported")
+	assert.ErrorContains(t, errCheck, "Check: check tag is not supported")
+	assert.ErrorContains(t, errGUID, "GUID: uuid tag is not supported")
+	assert.ErrorContains(t, errCounter, "Counter: field A wider than 64 bits is not supported")
+	assert.ErrorContains(t, errBCD, "BCD: enc tag is not supported")
+	assert.ErrorContains(t, errZigzag, "Zigzag: signed tag is not supported")
+	assert.ErrorContains(t, errEnum, "Enum: enum tag is not supported")
+}
+
+func TestVetDir(t *testing.T) {
+	// Setup
+	dir := t.TempDir()
+	src := "package p\n\nimport \"github.com/jmatsuzawa/go-bitfield\"\n\n" +
+		"//bitfield:size 3\ntype Good struct {\n\tA uint8 `bit:\"4\"`\n\t_ uint8 `bit:\"4\"`\n\tB Kind\n\tC bitfield.Raw `bit:\"8\"`\n}\n\n" +
+		"type Kind uint8\n\n" +
+		"type Wide struct {\n\tA uint8 `bit:\"9\"`\n}\n\n" +
+		"type Overlap struct {\n\tA uint8 `bit:\"4\" offset:\"0\"`\n\tb uint8 `bit:\"4\" offset:\"2\"`\n}\n\n" +
+		"//bitfield:size 2\ntype Sized struct {\n\tA uint8 `bit:\"4\"`\n}\n\n" +
+		"type Entry struct {\n\tN uint8\n\tChildren []*Entry `count:\"N\"`\n}\n\n" +
+		"type Plain struct {\n\tA uint8\n}\n"
+	os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0o644)
+	var buf bytes.Buffer
+
+	// Exercise
+	problems, checked, err := vetDir(&buf, dir)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, 3, problems)
+	assert.Equal(t, 4, checked)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 4) {
+		assert.Contains(t, lines[0], "p.go:15:6: Wide: bitfield: bit size must be within range 1 to its type size (A uint8")
+		assert.Contains(t, lines[1], "p.go:19:6: Overlap: bitfield: ")
+		assert.Contains(t, lines[1], "(b uint8")
+		assert.Contains(t, lines[2], "p.go:25:6: Sized: size is 1 bytes, not 2 as asserted")
+		assert.Contains(t, lines[3], "p.go:29:6: Entry: skipped: recursive type Entry is not supported")
+	}
+}
+
+func TestVet(t *testing.T) {
+	// Setup
+	var buf bytes.Buffer
+
+	// Exercise
+	problems, err := vet(&buf, []string{"./internal/sample"})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, 0, problems)
+	assert.Equal(t, "bitfieldgen vet: 0 problems in 2 struct types of 1 packages\n", buf.String())
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmatsuzawa/go-bitfield"
+)
+
+// layoutTags are the struct tags of the package bitfield which mark a struct
+// type as a layout to vet.
+var layoutTags = []string{"bit", "bytes", "count", "len", "tlv", "offset", "skip", "endian", "byteorder", "access", "enum", "const", "default"}
+
+// sizeDirective is the comment asserting the size of a layout in bytes, such
+// as "//bitfield:size 20" in the doc comment of the type.
+const sizeDirective = "//bitfield:size "
+
+// vet checks the struct types with bit-fields in the packages matching the
+// patterns, as listed by go list, and writes a report of the problems to w.
+// It returns the number of problems.
+func vet(w io.Writer, patterns []string) (int, error) {
+	out, err := exec.Command("go", append([]string{"list", "-f", "{{.Dir}}"}, patterns...)...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return 0, fmt.Errorf("go list: %s", bytes.TrimSpace(exitErr.Stderr))
+		}
+		return 0, err
+	}
+	var problems, checked int
+	dirs := strings.Fields(string(out))
+	for _, dir := range dirs {
+		p, c, err := vetDir(w, dir)
+		if err != nil {
+			return problems, err
+		}
+		problems += p
+		checked += c
+	}
+	fmt.Fprintf(w, "bitfieldgen vet: %d problems in %d struct types of %d packages\n", problems, checked, len(dirs))
+	return problems, nil
+}
+
+// vetDir checks the struct types with bit-fields declared in the Go files of
+// dir, excluding tests, and writes a line for each problem to w. It returns
+// the number of problems and the number of struct types checked. Types with
+// fields of types unknown to vet are reported as skipped, but are not
+// problems.
+func vetDir(w io.Writer, dir string) (problems, checked int, err error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return 0, 0, err
+	}
+	pkg := &vetPackage{fset: token.NewFileSet(), decls: map[string]ast.Expr{}}
+	var specs []*ast.TypeSpec
+	docs := map[*ast.TypeSpec]*ast.CommentGroup{}
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(pkg.fset, path, nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				spec := spec.(*ast.TypeSpec)
+				pkg.decls[spec.Name.Name] = spec.Type
+				if st, ok := spec.Type.(*ast.StructType); ok && hasLayoutTags(st) && spec.TypeParams == nil {
+					specs = append(specs, spec)
+					docs[spec] = spec.Doc
+					if docs[spec] == nil {
+						docs[spec] = gen.Doc
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Pos() < specs[j].Pos() })
+
+	for _, spec := range specs {
+		pos := pkg.fset.Position(spec.Pos())
+		if rel, err := filepath.Rel(".", pos.Filename); err == nil && !strings.HasPrefix(rel, "..") {
+			pos.Filename = rel
+		}
+		msgs, err := pkg.vetType(spec, docs[spec])
+		if err != nil {
+			fmt.Fprintf(w, "%s: %s: skipped: %v\n", pos, spec.Name.Name, err)
+			continue
+		}
+		checked++
+		for _, msg := range msgs {
+			fmt.Fprintf(w, "%s: %s: %s\n", pos, spec.Name.Name, msg)
+			problems++
+		}
+	}
+	return problems, checked, nil
+}
+
+// hasLayoutTags reports whether a field of the struct has a tag of the
+// package bitfield.
+func hasLayoutTags(st *ast.StructType) bool {
+	for _, f := range st.Fields.List {
+		if f.Tag == nil {
+			continue
+		}
+		s, _ := strconv.Unquote(f.Tag.Value)
+		for _, key := range layoutTags {
+			if _, ok := reflect.StructTag(s).Lookup(key); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// vetPackage resolves the types declared in a package to reflect types, with
+// which the layouts are validated by the package bitfield.
+type vetPackage struct {
+	fset  *token.FileSet
+	decls map[string]ast.Expr // types of the type declarations by name
+	// renamed maps the names given to unexported fields, as StructOf does
+	// not accept them, to their names in the source
+	renamed map[string]string
+	// resolving holds the names of the declarations being resolved, to stop
+	// at recursive types
+	resolving map[string]bool
+}
+
+// vetType returns the problems of a struct type. err is not nil if the type
+// cannot be checked.
+func (p *vetPackage) vetType(spec *ast.TypeSpec, doc *ast.CommentGroup) (msgs []string, err error) {
+	p.renamed, p.resolving = map[string]string{}, map[string]bool{spec.Name.Name: true}
+	rt, err := p.reflectType(spec.Type)
+	if err != nil {
+		return nil, err
+	}
+	v := reflect.New(rt).Interface()
+	report := func(err error) {
+		msg := err.Error()
+		for i := len(p.renamed) - 1; i >= 0; i-- {
+			// From the last, so that Unexported1 does not match Unexported10
+			key := "Unexported" + strconv.Itoa(i)
+			msg = strings.ReplaceAll(msg, key, p.renamed[key])
+		}
+		for _, m := range msgs {
+			if m == msg {
+				return
+			}
+		}
+		msgs = append(msgs, msg)
+	}
+	if _, err := bitfield.PlanOf(v); err != nil {
+		report(err)
+	}
+	if _, err := bitfield.Marshal(v); err != nil {
+		report(err)
+	}
+	if want, ok, err := sizeAssertion(doc); err != nil {
+		report(err)
+	} else if ok {
+		if size, err := bitfield.Size(v); err == nil && size != want {
+			report(fmt.Errorf("size is %d bytes, not %d as asserted", size, want))
+		}
+	}
+	return msgs, nil
+}
+
+// sizeAssertion returns the size in bytes asserted by a directive in the doc
+// comment of a type.
+func sizeAssertion(doc *ast.CommentGroup) (size int, ok bool, err error) {
+	if doc == nil {
+		return 0, false, nil
+	}
+	for _, c := range doc.List {
+		if arg, found := strings.CutPrefix(c.Text, sizeDirective); found {
+			size, err := strconv.Atoi(strings.TrimSpace(arg))
+			if err != nil || size < 0 {
+				return 0, false, fmt.Errorf("invalid size directive %q", c.Text)
+			}
+			return size, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// reflectType returns the reflect type of a type expression of the package.
+// Named types are resolved to their underlying types.
+func (p *vetPackage) reflectType(expr ast.Expr) (rt reflect.Type, err error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if rt, ok := basicTypes[t.Name]; ok {
+			return rt, nil
+		}
+		switch t.Name {
+		case "float32":
+			return reflect.TypeOf(float32(0)), nil
+		case "float64":
+			return reflect.TypeOf(float64(0)), nil
+		}
+		decl, ok := p.decls[t.Name]
+		if !ok {
+			break
+		}
+		if p.resolving[t.Name] {
+			return nil, fmt.Errorf("recursive type %s is not supported", t.Name)
+		}
+		p.resolving[t.Name] = true
+		defer delete(p.resolving, t.Name)
+		return p.reflectType(decl)
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "bitfield" {
+			switch t.Sel.Name {
+			case "Raw":
+				return reflect.TypeOf(bitfield.Raw(nil)), nil
+			case "TLV":
+				return reflect.TypeOf(bitfield.TLV{}), nil
+			}
+		}
+	case *ast.StarExpr:
+		elem, err := p.reflectType(t.X)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PointerTo(elem), nil
+	case *ast.ArrayType:
+		elem, err := p.reflectType(t.Elt)
+		if err != nil {
+			return nil, err
+		}
+		if t.Len == nil {
+			return reflect.SliceOf(elem), nil
+		}
+		if lit, ok := t.Len.(*ast.BasicLit); ok && lit.Kind == token.INT {
+			if n, err := strconv.Atoi(lit.Value); err == nil {
+				return reflect.ArrayOf(n, elem), nil
+			}
+		}
+	case *ast.StructType:
+		return p.structType(t)
+	}
+	var b bytes.Buffer
+	format.Node(&b, p.fset, expr)
+	return nil, fmt.Errorf("field type %s is not supported", b.String())
+}
+
+func (p *vetPackage) structType(st *ast.StructType) (rt reflect.Type, err error) {
+	var fields []reflect.StructField
+	for _, f := range st.Fields.List {
+		ft, err := p.reflectType(f.Type)
+		if err != nil {
+			return nil, err
+		}
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			s, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(s)
+		}
+		if enum := tag.Get("enum"); enum != "" && !strings.ContainsAny(enum[:1], "0123456789+-") {
+			// Sets named by enum tags are registered when the package runs,
+			// so only lists of values are checked
+			bitfield.RegisterEnumSet(enum)
+		}
+		if name, _, _ := strings.Cut(tag.Get("check"), ","); name != "" {
+			// Likewise for checksums named by check tags
+			bitfield.RegisterChecksum(name, func([]byte) uint64 { return 0 })
+		}
+		if len(f.Names) == 0 {
+			name := ft.Name()
+			if ident, ok := f.Type.(*ast.Ident); ok {
+				name = ident.Name
+			}
+			if !token.IsExported(name) {
+				return nil, fmt.Errorf("embedded field %s is not supported", name)
+			}
+			fields = append(fields, reflect.StructField{Name: name, Type: ft, Tag: tag, Anonymous: true})
+			continue
+		}
+		for _, n := range f.Names {
+			name := n.Name
+			if !token.IsExported(name) {
+				// Placeholders and unexported fields are renamed, as StructOf
+				// does not accept unexported fields
+				name = "Unexported" + strconv.Itoa(len(p.renamed))
+				p.renamed[name] = n.Name
+			}
+			fields = append(fields, reflect.StructField{Name: name, Type: ft, Tag: tag})
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("struct type is not supported: %v", r)
+		}
+	}()
+	return reflect.StructOf(fields), nil
+}
+
+// vetMain runs the vet mode with the arguments following "vet", and returns
+// the exit code.
+func vetMain(args []string) int {
+	if len(args) == 0 {
+		args = []string{"./..."}
+	}
+	problems, err := vet(os.Stdout, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bitfieldgen vet:", err)
+		return 1
+	}
+	if problems > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,54 @@
+// Command bitproto2go generates Go structs with bit tags from a bitproto file.
+//
+// Usage:
+//
+//	bitproto2go [-pkg name] [-o output.go] input.bitproto
+//
+// It can be used with go:generate:
+//
+//	//go:generate go run github.com/jmatsuzawa/go-bitfield/cmd/bitproto2go -pkg drone -o drone_bp.go drone.bitproto
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jmatsuzawa/go-bitfield/bitproto"
+)
+
+func main() {
+	pkg := flag.String("pkg", "main", "package name of the generated code")
+	out := flag.String("o", "", "output file (default stdout)")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: bitproto2go [-pkg name] [-o output.go] input.bitproto")
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *pkg, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "bitproto2go:", err)
+		os.Exit(1)
+	}
+}
+
+func run(input, pkg, output string) error {
+	f, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	file, err := bitproto.Parse(f)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := bitproto.Generate(&buf, file, pkg); err != nil {
+		return err
+	}
+	if output == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(output, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,60 @@
+// Command rfc2go generates a Go struct with bit tags from an RFC-style ASCII
+// packet diagram.
+//
+// Usage:
+//
+//	rfc2go [-pkg name] [-type name] [-o output.go] [input.txt]
+//
+// The diagram is read from the input file, or from the standard input if no
+// file is given. Text before the diagram, such as the bit numbers, is skipped.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jmatsuzawa/go-bitfield/rfcdiagram"
+)
+
+func main() {
+	pkg := flag.String("pkg", "main", "package name of the generated code")
+	typeName := flag.String("type", "Header", "name of the generated struct")
+	out := flag.String("o", "", "output file (default stdout)")
+	flag.Parse()
+	if flag.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "usage: rfc2go [-pkg name] [-type name] [-o output.go] [input.txt]")
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *pkg, *typeName, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "rfc2go:", err)
+		os.Exit(1)
+	}
+}
+
+func run(input, pkg, typeName, output string) error {
+	var r io.Reader = os.Stdin
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	d, err := rfcdiagram.Parse(r)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := rfcdiagram.Generate(&buf, d, pkg, typeName); err != nil {
+		return err
+	}
+	if output == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(output, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,222 @@
+package bitfield
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// condition is the condition of a field given by its struct tag "if", such
+// as if:"Flags&0x1!=0", under which the field is present. It compares the
+// value of a preceding field of the same struct, optionally masked, with a
+// constant. A condition naming only the field, such as if:"HasExtension",
+// holds if the value is not zero. The struct tag "presentif", such as
+// presentif:"Flags.3", gives the condition that a bit of a preceding flags
+// field is set, which Marshal sets if the field is not zero.
+type condition struct {
+	field   int    // index of the field whose value is compared
+	bitSize int    // bit size of the field
+	signed  bool   // whether the values are compared as signed integers
+	mask    uint64 // mask applied to the value if masked
+	masked  bool
+	op      string
+	value   uint64
+	flag    bool // whether the condition is given by a presentif tag
+}
+
+// conditionOps are the comparison operators of conditions, the operators of
+// two characters first.
+var conditionOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// isConditional reports whether the field has a condition.
+func isConditional(field reflect.StructField) bool {
+	_, ok := field.Tag.Lookup("if")
+	_, flagged := field.Tag.Lookup("presentif")
+	return ok || flagged
+}
+
+// parseCondition parses the if or presentif tag of the i-th field of rt.
+// problem describes an invalid condition.
+func parseCondition(rt reflect.Type, i int) (c *condition, problem string) {
+	if tag, ok := rt.Field(i).Tag.Lookup("presentif"); ok {
+		return parsePresentIf(rt, i, tag)
+	}
+	tag := rt.Field(i).Tag.Get("if")
+	left, right, op := tag, "0", "!="
+	for _, o := range conditionOps {
+		if l, r, found := strings.Cut(tag, o); found {
+			left, right, op = l, r, o
+			break
+		}
+	}
+	name, mask, masked := strings.Cut(left, "&")
+	c = &condition{field: -1, masked: masked, op: op}
+	for j := 0; j < i; j++ {
+		if rt.Field(j).Name == strings.TrimSpace(name) {
+			c.field = j
+		}
+	}
+	if c.field < 0 || !isConditionType(rt.Field(c.field)) {
+		return nil, "if must compare preceding integer or bool field"
+	}
+	field := rt.Field(c.field)
+	c.bitSize = conditionBitSize(field)
+	c.signed = isSignedInteger(field.Type.Kind()) && !masked
+	var err error
+	if masked {
+		if c.mask, err = strconv.ParseUint(strings.TrimSpace(mask), 0, 64); err != nil {
+			return nil, "if must mask field with unsigned integer"
+		}
+	}
+	right = strings.TrimSpace(right)
+	if c.signed {
+		var v int64
+		v, err = strconv.ParseInt(right, 0, 64)
+		c.value = uint64(v)
+	} else {
+		c.value, err = strconv.ParseUint(right, 0, 64)
+	}
+	if err != nil {
+		return nil, "if must compare field with integer"
+	}
+	return c, ""
+}
+
+// parsePresentIf parses the presentif tag of the i-th field of rt, the name
+// of a preceding unsigned integer field followed by a dot and the index of a
+// bit, counted from the least significant bit.
+func parsePresentIf(rt reflect.Type, i int, tag string) (c *condition, problem string) {
+	const invalid = "presentif must name bit of preceding unsigned integer field such as Flags.3"
+	name, index, found := strings.Cut(tag, ".")
+	bit, err := strconv.Atoi(index)
+	if !found || err != nil || bit < 0 {
+		return nil, invalid
+	}
+	c = &condition{field: -1, masked: true, mask: 1 << bit, op: "!=", flag: true}
+	for j := 0; j < i; j++ {
+		if rt.Field(j).Name == name {
+			c.field = j
+		}
+	}
+	if c.field < 0 || !isCountType(rt.Field(c.field)) || isSignedInteger(rt.Field(c.field).Type.Kind()) {
+		return nil, invalid
+	}
+	if c.bitSize = conditionBitSize(rt.Field(c.field)); bit >= c.bitSize {
+		return nil, invalid
+	}
+	return c, ""
+}
+
+// conditionBitSize returns the bit size of the values of a field compared by
+// a condition. A varint field holds values as wide as its type, whatever the
+// size of its encoding.
+func conditionBitSize(field reflect.StructField) int {
+	if isVarint(field) {
+		return field.Type.Bits()
+	}
+	bitSize, _, _ := fieldBitSize(field)
+	return bitSize
+}
+
+// presenceFlags returns the flag bits which Marshal sets in the fields of a
+// struct value rv whose plan is plan, indexed by field, for the fields with
+// presentif tags which are not zero. It is nil if there are none.
+func presenceFlags(rv reflect.Value, plan *structPlan) []uint64 {
+	var flags []uint64
+	for i := range plan.fields {
+		c := plan.fields[i].cond
+		if c == nil || !c.flag || rv.Field(i).IsZero() {
+			continue
+		}
+		if flags == nil {
+			flags = make([]uint64, len(plan.fields))
+		}
+		flags[c.field] |= c.mask
+	}
+	return flags
+}
+
+// isConditionType reports whether a condition can compare the value of the
+// field.
+func isConditionType(field reflect.StructField) bool {
+	if field.Type.Kind() == reflect.Bool {
+		_, _, ok := fieldBitSize(field)
+		return ok
+	}
+	return isCountType(field)
+}
+
+// validateCondition validates the if tag of the i-th field of rt, if any.
+func validateCondition(rt reflect.Type, i int) error {
+	field := rt.Field(i)
+	if !isConditional(field) {
+		return nil
+	}
+	_, ok := field.Tag.Lookup("if")
+	if _, flagged := field.Tag.Lookup("presentif"); ok && flagged {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "if and presentif tags must not be used together",
+		}
+	}
+	if _, problem := parseCondition(rt, i); problem != "" {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: problem,
+		}
+	}
+	return nil
+}
+
+// holds reports whether the condition holds for val, the bits of the
+// compared field.
+func (c *condition) holds(val uint64) bool {
+	if c.masked {
+		val &= c.mask
+	}
+	if c.signed {
+		a, b := signed(val, c.bitSize), int64(c.value)
+		return compare(a < b, a == b, c.op)
+	}
+	return compare(val < c.value, val == c.value, c.op)
+}
+
+// holdsFor is like holds, but for the value of the compared field of the
+// struct value rv.
+func (c *condition) holdsFor(rv reflect.Value) bool {
+	v := rv.Field(c.field)
+	var val uint64
+	switch {
+	case v.Kind() == reflect.Bool:
+		if v.Bool() {
+			val = 1
+		}
+	case v.CanInt():
+		val = uint64(v.Int())
+		if c.bitSize < 64 {
+			val &= 1<<c.bitSize - 1
+		}
+	default:
+		val = v.Uint()
+	}
+	return c.holds(val)
+}
+
+func compare(less, equal bool, op string) bool {
+	switch op {
+	case "==":
+		return equal
+	case "!=":
+		return !equal
+	case "<":
+		return less
+	case "<=":
+		return less || equal
+	case ">":
+		return !less && !equal
+	default:
+		return !less
+	}
+}
@@ -0,0 +1,87 @@
+package bitfield
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// tagBits returns the bits of the value of a field given by its struct tag
+// key, such as const:"0x4D5A" for magic bytes. bits is nil if the field has no
+// such tag, and ok is false if the tag is not an integer, or a bool for a bool
+// field, which fits in the field.
+func tagBits(field reflect.StructField, key string) (bits *uint64, ok bool) {
+	tag, found := field.Tag.Lookup(key)
+	if !found {
+		return nil, true
+	}
+	v := reflect.New(field.Type).Elem()
+	if v.Kind() == reflect.Bool {
+		b, err := strconv.ParseBool(tag)
+		if err != nil {
+			return nil, false
+		}
+		v.SetBool(b)
+	} else if v.CanUint() {
+		n, err := strconv.ParseUint(tag, 0, 64)
+		if err != nil || v.OverflowUint(n) {
+			return nil, false
+		}
+		v.SetUint(n)
+	} else {
+		n, err := strconv.ParseInt(tag, 0, 64)
+		if err != nil || v.OverflowInt(n) {
+			return nil, false
+		}
+		v.SetInt(n)
+	}
+	bitSize, _, _ := fieldBitSize(field)
+	val, err := fieldValue(field, v, bitSize)
+	if err != nil {
+		return nil, false
+	}
+	return &val, true
+}
+
+// validateConstTag validates the const tag of a field, if any.
+func validateConstTag(field reflect.StructField) error {
+	if _, found := field.Tag.Lookup("const"); !found {
+		return nil
+	}
+	if !isFixedInteger(field.Type.Kind()) {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "const tag requires integer field",
+		}
+	}
+	if _, ok := tagBits(field, "const"); !ok {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "const must be integer which fits in bit size of field",
+		}
+	}
+	return nil
+}
+
+// validateDefaultTag validates the default tag of a field, if any.
+func validateDefaultTag(field reflect.StructField) error {
+	if _, found := field.Tag.Lookup("default"); !found {
+		return nil
+	}
+	if !isFixedInteger(field.Type.Kind()) && field.Type.Kind() != reflect.Bool {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "default tag requires integer or bool field",
+		}
+	}
+	if _, ok := tagBits(field, "default"); !ok {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "default must be value which fits in bit size of field",
+		}
+	}
+	return nil
+}
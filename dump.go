@@ -0,0 +1,74 @@
+package bitfield
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"text/tabwriter"
+)
+
+// Dump decodes data into the struct pointed by v as [Unmarshal] does, and
+// writes an annotated decode of data to w: a line for each field with its
+// range of bits in data, its raw bits and its decoded value, such as
+//
+//	FIELD     BITS    RAW                VALUE
+//	Version   0-3     0101               5
+//	IHL       4-7     0100               4
+//	Length    16-31   0000000000010100   20
+//
+// The raw bits of an integer field are those of its value, from the most
+// significant bit, and the bits of a [Raw] field are written in hexadecimal.
+// Bits missing from short input are marked. Values are shown as decoded,
+// before any [DecodeHook].
+//
+// Fields decoded before an error are written, followed by the error, which
+// Dump returns. Dump also returns the error of writing to w.
+func Dump(data []byte, v any, w io.Writer, opts ...Option) error {
+	options, err := collectOptions(opts)
+	if err != nil {
+		return err
+	}
+	if err := validateUnmarshalType(v, options); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	options.dump = tw
+	fmt.Fprintf(tw, "FIELD\tBITS\tRAW\tVALUE\n")
+	_, err = unmarshal(data, len(data)*8, v, options)
+	if err != nil {
+		fmt.Fprintf(tw, "error: %v\n", err)
+	}
+	if flushErr := tw.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// dumpField writes the line of a decoded field to the writer of [Dump]. raw is
+// the text of the bits of the field, and value its decoded value.
+func dumpField(w io.Writer, name string, offset, bitSize, nbits int, raw string, value any) {
+	if offset+bitSize > nbits {
+		raw += " (missing)"
+	}
+	fmt.Fprintf(w, "%s\t%d-%d\t%s\t%v\n", name, offset, offset+bitSize-1, raw, value)
+}
+
+// decodedValue returns the value of a field of type rt decoded from the bits
+// val of a field of bitSize bits.
+func decodedValue(rt reflect.Type, val uint64, bitSize int) any {
+	v := reflect.New(rt).Elem()
+	switch {
+	case v.CanUint():
+		v.SetUint(val)
+	case v.CanInt():
+		v.SetInt(signed(val, bitSize))
+	case v.Kind() == reflect.Bool:
+		v.SetBool(val != 0)
+	case v.Kind() == reflect.Float32:
+		v.SetFloat(float64(math.Float32frombits(uint32(val))))
+	case v.CanFloat():
+		v.SetFloat(math.Float64frombits(val))
+	}
+	return v.Interface()
+}
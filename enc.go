@@ -0,0 +1,169 @@
+package bitfield
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// validateEncTag validates the enc tag of a field, if any, which gives the
+// encoding of an unsigned integer field: "bcd" for packed binary-coded
+// decimal, a decimal digit in each 4 bits, or "gray" for the reflected binary
+// Gray code. "float16" is the encoding of a float field as an IEEE 754
+// half-precision number.
+func validateEncTag(field reflect.StructField) error {
+	tag, found := field.Tag.Lookup("enc")
+	if !found {
+		return nil
+	}
+	if tag == "float16" {
+		if bitSize, _, _ := fieldBitSize(field); !isFloat(field.Type.Kind()) || bitSize != 16 {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "float16 requires float field with bit size 16",
+			}
+		}
+		if _, ok := field.Tag.Lookup("scale"); ok {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "float16 and scale tag must not be used together",
+			}
+		}
+		return nil
+	}
+	if !isFixedInteger(field.Type.Kind()) || isSignedInteger(field.Type.Kind()) {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "enc tag requires unsigned integer field",
+		}
+	}
+	switch tag {
+	case "bcd":
+		if bitSize, _, _ := fieldBitSize(field); bitSize%4 != 0 {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "bcd requires bit size of multiple of 4",
+			}
+		}
+	case "gray":
+	default:
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "enc must be bcd, gray or float16",
+		}
+	}
+	return nil
+}
+
+// decodeBits returns the value of the bits of a field in the encoding given
+// by its enc tag, if any. The value of a float16 field is the bits of its
+// float type.
+func (f *fieldPlan) decodeBits(bits uint64) (uint64, error) {
+	switch f.enc {
+	case "float16":
+		v := fromFloat16(uint16(bits))
+		if f.Type.Kind() == reflect.Float32 {
+			return uint64(math.Float32bits(float32(v))), nil
+		}
+		return math.Float64bits(v), nil
+	case "bcd":
+		var val uint64
+		for shift := f.bitSize - 4; shift >= 0; shift -= 4 {
+			digit := bits >> shift & 0xF
+			if digit > 9 {
+				return 0, fmt.Errorf("bitfield: nibble %#x of BCD %#x is not decimal digit", digit, bits)
+			}
+			val = val*10 + digit
+		}
+		return val, nil
+	case "gray":
+		// Each bit is the parity of the bits above it
+		val := bits
+		for shift := 1; shift < 64; shift <<= 1 {
+			val ^= val >> shift
+		}
+		return val, nil
+	}
+	return bits, nil
+}
+
+// encodeBits returns the bits of a value of a field in the encoding given by
+// its enc tag, if any. ok is false if the value does not fit in the field.
+func (f *fieldPlan) encodeBits(val uint64) (bits uint64, ok bool) {
+	switch f.enc {
+	case "float16":
+		if f.Type.Kind() == reflect.Float32 {
+			return uint64(toFloat16(float64(math.Float32frombits(uint32(val))))), true
+		}
+		return uint64(toFloat16(math.Float64frombits(val))), true
+	case "bcd":
+		for shift := 0; val > 0; shift += 4 {
+			if shift >= f.bitSize {
+				return 0, false
+			}
+			bits |= val % 10 << shift
+			val /= 10
+		}
+		return bits, true
+	case "gray":
+		return val ^ val>>1, true
+	}
+	return val, true
+}
+
+// fromFloat16 returns the value of the bits of an IEEE 754 half-precision
+// number.
+func fromFloat16(h uint16) float64 {
+	exp, frac := int(h>>10&0x1F), float64(h&0x3FF)
+	var v float64
+	switch exp {
+	case 0:
+		// Subnormal
+		v = math.Ldexp(frac, -24)
+	case 0x1F:
+		v = math.Inf(1)
+		if frac != 0 {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(frac+0x400, exp-25)
+	}
+	if h>>15 != 0 {
+		v = -v
+	}
+	return v
+}
+
+// toFloat16 returns the bits of the IEEE 754 half-precision number nearest
+// to v, rounding halves to even. Values beyond the range are infinities.
+func toFloat16(v float64) uint16 {
+	var sign uint16
+	if math.Signbit(v) {
+		sign = 0x8000
+	}
+	v = math.Abs(v)
+	switch {
+	case math.IsNaN(v):
+		return sign | 0x7E00
+	case math.IsInf(v, 0):
+		return sign | 0x7C00
+	case v < 0x1p-14:
+		// Subnormal, or the smallest normal number if rounded up to it
+		return sign | uint16(math.RoundToEven(v*0x1p24))
+	}
+	frac, exp := math.Frexp(v)
+	// 11 significant bits with the implicit leading one
+	mant, biased := math.RoundToEven(frac*0x800), exp+14
+	if mant == 0x800 {
+		mant, biased = 0x400, biased+1
+	}
+	if biased >= 0x1F {
+		return sign | 0x7C00
+	}
+	return sign | uint16(biased)<<10 | uint16(mant-0x400)
+}
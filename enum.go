@@ -0,0 +1,206 @@
+package bitfield
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// enums maps a named integer type to the set of its registered values,
+	// keyed by enumKey.
+	enums      sync.Map
+	enumsMu    sync.Mutex // serializes RegisterEnum
+	enumsExist atomic.Bool
+)
+
+// RegisterEnum registers the valid values of a named integer type, such as
+// the constants of an enumeration:
+//
+//	type Opcode uint8
+//
+//	const (
+//		OpRead  Opcode = 1
+//		OpWrite Opcode = 2
+//	)
+//
+//	func init() {
+//		bitfield.RegisterEnum(OpRead, OpWrite)
+//	}
+//
+// Unmarshal then returns [EnumError] for a field of the type whose decoded
+// value is not registered. The value is checked after any [DecodeHook], so a
+// hook can map unknown values to registered ones. Marshal does not check the
+// values. Registering values of a type again adds to its valid values.
+//
+// RegisterEnum panics if no value is given, or if the values are not of the
+// same named integer type. It is meant to be called from init functions, but
+// is safe for concurrent use.
+func RegisterEnum(values ...any) {
+	if len(values) == 0 {
+		panic("bitfield: RegisterEnum requires at least one value")
+	}
+	rt := reflect.TypeOf(values[0])
+	if rt.PkgPath() == "" || !isFixedInteger(rt.Kind()) {
+		panic(fmt.Sprintf("bitfield: RegisterEnum requires values of named integer type, not %T", values[0]))
+	}
+	enumsMu.Lock()
+	defer enumsMu.Unlock()
+	set := map[uint64]bool{}
+	if old, ok := enums.Load(rt); ok {
+		for k := range old.(map[uint64]bool) {
+			set[k] = true
+		}
+	}
+	for _, v := range values {
+		if reflect.TypeOf(v) != rt {
+			panic(fmt.Sprintf("bitfield: RegisterEnum requires values of the same type, %T and %v", values[0], reflect.TypeOf(v)))
+		}
+		set[enumKey(reflect.ValueOf(v))] = true
+	}
+	enums.Store(rt, set)
+	enumsExist.Store(true)
+}
+
+// enumKey returns the key of an integer value in the set of registered values.
+func enumKey(v reflect.Value) uint64 {
+	if v.CanInt() {
+		return uint64(v.Int())
+	}
+	return v.Uint()
+}
+
+// checkEnum returns [EnumError] if a decoded field of a registered type does
+// not hold a registered value.
+func checkEnum(field reflect.StructField, vf reflect.Value, path string) error {
+	if !enumsExist.Load() {
+		return nil
+	}
+	set, ok := enums.Load(vf.Type())
+	if !ok || set.(map[uint64]bool)[enumKey(vf)] {
+		return nil
+	}
+	return &EnumError{Field: field, Path: path, Value: vf.Interface()}
+}
+
+// enumSets maps the name of a set of values registered by RegisterEnumSet to
+// the set of its values, keyed by enumKey.
+var enumSets sync.Map
+
+// RegisterEnumSet registers a named set of valid values, which integer fields
+// of any type can refer to with a struct tag "enum" naming the set:
+//
+//	func init() {
+//		bitfield.RegisterEnumSet("opcode", 0x01, 0x02, 0x07)
+//	}
+//
+//	type header struct {
+//		Opcode uint8 `bit:"4" enum:"opcode"`
+//	}
+//
+// The set must be registered before a struct referring to it is first used.
+// Registering a set again replaces its values. RegisterEnumSet panics if the
+// name is empty or starts with a digit or a sign, as it could not be told
+// from a list of values in the tag.
+func RegisterEnumSet(name string, values ...int64) {
+	if name == "" || strings.ContainsAny(name[:1], "0123456789+-") {
+		panic(fmt.Sprintf("bitfield: invalid enum set name %q", name))
+	}
+	set := map[uint64]bool{}
+	for _, v := range values {
+		set[uint64(v)] = true
+	}
+	enumSets.Store(name, set)
+}
+
+// enumTag returns the set of valid values of a field given by its struct tag
+// "enum", either a comma-separated list of integers such as enum:"0,1,2,7" or
+// the name of a set registered by RegisterEnumSet. set is nil if the field
+// has no enum tag, and ok is false if the tag is invalid.
+func enumTag(field reflect.StructField) (set map[uint64]bool, ok bool) {
+	tag, found := field.Tag.Lookup("enum")
+	if !found {
+		return nil, true
+	}
+	if tag != "" && !strings.ContainsAny(tag[:1], "0123456789+-") {
+		registered, ok := enumSets.Load(tag)
+		if !ok {
+			return nil, false
+		}
+		return registered.(map[uint64]bool), true
+	}
+	set = map[uint64]bool{}
+	for _, s := range strings.Split(tag, ",") {
+		s = strings.TrimSpace(s)
+		if v, err := strconv.ParseInt(s, 0, 64); err == nil {
+			set[uint64(v)] = true
+		} else if v, err := strconv.ParseUint(s, 0, 64); err == nil {
+			set[v] = true
+		} else {
+			return nil, false
+		}
+	}
+	return set, true
+}
+
+// validateEnumTag validates the enum tag of a field, if any.
+func validateEnumTag(field reflect.StructField) error {
+	if _, found := field.Tag.Lookup("enum"); !found {
+		return nil
+	}
+	if !isFixedInteger(field.Type.Kind()) {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "enum tag requires integer field",
+		}
+	}
+	if _, ok := enumTag(field); !ok {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "enum must be list of integers or name of set registered by RegisterEnumSet",
+		}
+	}
+	return nil
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// stringAttr returns an attribute logging a decoded integer val as text, if
+// the integer or bool type of the field implements [fmt.Stringer], or an empty
+// attribute otherwise. The text is produced only if the record is handled.
+func stringAttr(rt reflect.Type, val uint64, bitSize int) slog.Attr {
+	if !isFixedInteger(rt.Kind()) && rt.Kind() != reflect.Bool {
+		return slog.Attr{}
+	}
+	if !rt.Implements(stringerType) && !reflect.PointerTo(rt).Implements(stringerType) {
+		return slog.Attr{}
+	}
+	return slog.Any("string", stringerValue{rt, val, bitSize})
+}
+
+// stringerValue is an integer of a type implementing [fmt.Stringer], which is
+// logged as the text returned by its String method.
+type stringerValue struct {
+	rt      reflect.Type
+	val     uint64
+	bitSize int
+}
+
+func (s stringerValue) LogValue() slog.Value {
+	v := reflect.New(s.rt)
+	switch {
+	case v.Elem().CanUint():
+		v.Elem().SetUint(s.val)
+	case v.Elem().CanInt():
+		v.Elem().SetInt(signed(s.val, s.bitSize))
+	case v.Elem().Kind() == reflect.Bool:
+		v.Elem().SetBool(s.val != 0)
+	}
+	return slog.StringValue(v.Interface().(fmt.Stringer).String())
+}
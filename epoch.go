@@ -0,0 +1,138 @@
+package bitfield
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// epochs are the epochs of the timestamps given by epoch tags. GPS time is
+// counted from its epoch without leap seconds.
+var epochs = map[string]time.Time{
+	"unix": time.Unix(0, 0).UTC(),
+	"ntp":  time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC),
+	"gps":  time.Date(1980, time.January, 6, 0, 0, 0, 0, time.UTC),
+}
+
+// precisions are the units of the timestamps given by epoch tags, in
+// nanoseconds.
+var precisions = map[string]uint64{
+	"s":  1e9,
+	"ms": 1e6,
+	"us": 1e3,
+	"ns": 1,
+}
+
+// epochSpec is the encoding of a time.Time field given by its struct tag
+// "epoch", such as epoch:"unix,ms": the number of units elapsed since the
+// epoch. With frac, the low 32 bits are the binary fraction of a second
+// instead, as in epoch:"ntp,frac32" for 64-bit NTP timestamps.
+type epochSpec struct {
+	epoch time.Time
+	unit  uint64 // nanoseconds of a unit
+	frac  bool
+}
+
+// epochTag returns the encoding of a time.Time field given by its epoch tag.
+// spec is nil if the field has no epoch tag, and ok is false if the tag is
+// invalid.
+func epochTag(field reflect.StructField) (spec *epochSpec, ok bool) {
+	tag, found := field.Tag.Lookup("epoch")
+	if !found {
+		return nil, true
+	}
+	name, precision, found := strings.Cut(tag, ",")
+	if !found {
+		precision = "s"
+	}
+	epoch, ok := epochs[name]
+	if !ok {
+		return nil, false
+	}
+	if precision == "frac32" {
+		return &epochSpec{epoch: epoch, frac: true}, true
+	}
+	unit, ok := precisions[precision]
+	if !ok {
+		return nil, false
+	}
+	return &epochSpec{epoch: epoch, unit: unit}, true
+}
+
+// validateEpochTag validates the epoch tag of a field, which a time.Time
+// field with a bit tag must have.
+func validateEpochTag(field reflect.StructField) error {
+	_, hasBit := field.Tag.Lookup("bit")
+	if _, found := field.Tag.Lookup("epoch"); !found {
+		if field.Type == timeType && hasBit {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "time.Time field must have epoch tag",
+			}
+		}
+		return nil
+	}
+	if field.Type != timeType || !hasBit {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "epoch tag requires time.Time field with bit tag",
+		}
+	}
+	spec, ok := epochTag(field)
+	if !ok {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "epoch must be unix, ntp or gps, optionally followed by s, ms, us, ns or frac32",
+		}
+	}
+	if bitSize, _, _ := fieldBitSize(field); spec.frac && bitSize <= 32 {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "frac32 requires bit size over 32",
+		}
+	}
+	return nil
+}
+
+// time returns the time of the bits val of a timestamp, in UTC.
+func (s *epochSpec) time(val uint64) time.Time {
+	var sec, nsec uint64
+	if s.frac {
+		sec, nsec = val>>32, (val&(1<<32-1)*1e9)>>32
+	} else {
+		sec, nsec = val/(1e9/s.unit), val%(1e9/s.unit)*s.unit
+	}
+	return time.Unix(s.epoch.Unix()+int64(sec), int64(nsec)).UTC()
+}
+
+// bits returns the bits of a timestamp of bitSize bits for t, truncated to
+// the unit. ok is false if t is before the epoch or does not fit.
+func (s *epochSpec) bits(t time.Time, bitSize int) (val uint64, ok bool) {
+	sec := t.Unix() - s.epoch.Unix()
+	if sec < 0 {
+		return 0, false
+	}
+	nsec := uint64(t.Nanosecond())
+	if s.frac {
+		if sec >= 1<<(bitSize-32) {
+			return 0, false
+		}
+		// Rounded to the nearest fraction
+		return uint64(sec)<<32 | (nsec<<32+5e8)/1e9, true
+	}
+	perSecond := 1e9 / s.unit
+	if uint64(sec) > (1<<64-1)/perSecond {
+		return 0, false
+	}
+	val = uint64(sec)*perSecond + nsec/s.unit
+	if bitSize < 64 && val>>bitSize != 0 {
+		return 0, false
+	}
+	return val, true
+}
@@ -1,10 +1,14 @@
 package bitfield
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
-// TypeError describes an invalid type passed to [Unmarshal].
+// TypeError describes an invalid type passed to [Unmarshal] or [LayoutOf].
 // (The argument to [Unmarshal] must be a non-nil pointer to a struct.)
 type TypeError struct {
 	Type    reflect.Type
@@ -15,12 +19,250 @@ func (e *TypeError) Error() string {
 	return "bitfield: " + e.problem
 }
 
-// FieldError describes an invalid bit-field in a struct passed to [Unmarshal].
+// FieldError describes an invalid bit-field in a struct passed to [Unmarshal]
+// or [LayoutOf].
+//
+// The message of FieldError, and of the other errors about a field, ends with
+// the text of the struct tag "hint" of the field if any. The tag can carry
+// guidance for the operator reading the error:
+//
+//	Protocol uint8 `bit:"3" hint:"check DIP switch 3 sets protocol v2"`
 type FieldError struct {
-	Field   reflect.StructField
+	Field reflect.StructField
+	// Path is the path of the field from the struct passed to the function,
+	// such as "Header.Version" for a field of a nested struct.
+	Path    string
 	problem string
 }
 
 func (e *FieldError) Error() string {
-	return "bitfield: " + e.problem + " (" + e.Field.Name + " " + e.Field.Type.String() + " `" + string(e.Field.Tag) + "`)"
+	return "bitfield: " + e.problem + " (" + e.path() + " " + e.Field.Type.String() + " `" + string(e.Field.Tag) + "`)" + hint(e.Field)
+}
+
+func (e *FieldError) path() string {
+	if e.Path == "" {
+		return e.Field.Name
+	}
+	return e.Path
+}
+
+// FrameError describes a frame which cannot be parsed by [UnmarshalFramed] or
+// by a [Decoder] with [WithFrameLength], or an invalid [Frame].
+type FrameError struct {
+	problem string
+}
+
+func (e *FrameError) Error() string {
+	return "bitfield: " + e.problem
+}
+
+// LengthError describes a slice whose length is inconsistent with the field
+// giving its length: a count or a byte length read by [Unmarshal] which
+// exceeds the rest of the input, or a slice passed to [Marshal] whose length
+// differs from its count or len field.
+type LengthError struct {
+	Field reflect.StructField
+	// Path is the path of the field from the struct passed to the function,
+	// such as "Entries[0].Children" for a field of an element of a slice.
+	Path    string
+	problem string
+}
+
+func (e *LengthError) Error() string {
+	return "bitfield: " + e.problem + " (" + e.Path + " " + e.Field.Type.String() + " `" + string(e.Field.Tag) + "`)" + hint(e.Field)
+}
+
+// ByteOrderError describes a byte order mark, a field with a struct tag
+// "byteorder", whose value selects no byte order.
+type ByteOrderError struct {
+	Field reflect.StructField
+	// Path is the path of the field from the struct passed to the function,
+	// such as "Header.Mark" for a field of a nested struct.
+	Path  string
+	Value uint64
+}
+
+func (e *ByteOrderError) Error() string {
+	return fmt.Sprintf("bitfield: value %#x of byte order mark selects no byte order (%s %s `%s`)%s", e.Value, e.Path, e.Field.Type, e.Field.Tag, hint(e.Field))
+}
+
+// DepthError describes a struct nested deeper than the limit set by
+// [WithMaxDepth], either in the data read by [Unmarshal] or in the value
+// passed to [Marshal].
+type DepthError struct {
+	// Path is the path of the struct from the struct passed to the function,
+	// such as "Children[0].Children[1]".
+	Path     string
+	MaxDepth int
+}
+
+func (e *DepthError) Error() string {
+	return "bitfield: struct " + e.Path + " is nested deeper than " + strconv.Itoa(e.MaxDepth)
+}
+
+// EnumError describes a field decoded by [Unmarshal] whose value is not among
+// the values registered for its type by [RegisterEnum], or the values given
+// by its struct tag "enum".
+type EnumError struct {
+	Field reflect.StructField
+	// Path is the path of the field from the struct passed to the function,
+	// such as "Header.Opcode" for a field of a nested struct.
+	Path  string
+	Value any
+}
+
+func (e *EnumError) Error() string {
+	if enum, ok := e.Field.Tag.Lookup("enum"); ok {
+		return fmt.Sprintf("bitfield: value %v is not in enum %q (%s %s `%s`)%s", e.Value, enum, e.Path, e.Field.Type, e.Field.Tag, hint(e.Field))
+	}
+	return fmt.Sprintf("bitfield: value %v is not registered for %s (%s %s `%s`)%s", e.Value, e.Field.Type, e.Path, e.Field.Type, e.Field.Tag, hint(e.Field))
+}
+
+// OverflowError describes a field value passed to [Marshal] which does not fit
+// in the bit size of the field.
+type OverflowError struct {
+	Field reflect.StructField
+	// Path is the path of the field from the struct passed to the function,
+	// such as "Header.Version" for a field of a nested struct.
+	Path  string
+	Value any
+}
+
+func (e *OverflowError) Error() string {
+	return fmt.Sprintf("bitfield: value %v overflows bit size of field (%s %s `%s`)%s", e.Value, e.Path, e.Field.Type, e.Field.Tag, hint(e.Field))
+}
+
+// ConstError describes a field with a const tag whose value is not the
+// constant, decoded by [Unmarshal] or passed to [Marshal].
+type ConstError struct {
+	Field reflect.StructField
+	// Path is the path of the field from the struct passed to the function,
+	// such as "Header.Magic" for a field of a nested struct.
+	Path  string
+	Value any
+	Const any
+}
+
+func (e *ConstError) Error() string {
+	return fmt.Sprintf("bitfield: value %v is not constant %v (%s %s `%s`)%s", e.Value, e.Const, e.Path, e.Field.Type, e.Field.Tag, hint(e.Field))
+}
+
+// ReservedBitsError describes a placeholder field decoded by [Unmarshal] with
+// [WithReservedMustBeZero] which has a bit set.
+type ReservedBitsError struct {
+	// Path is the path of the field from the struct passed to the function,
+	// such as "Header._" for a placeholder of a nested struct.
+	Path string
+	// Offset is the offset of the field in bits from the start of the input,
+	// and Bits its bit size.
+	Offset int
+	Bits   int
+}
+
+func (e *ReservedBitsError) Error() string {
+	return fmt.Sprintf("bitfield: reserved bits %d to %d (%s) are not zero", e.Offset, e.Offset+e.Bits, e.Path)
+}
+
+// VariantError describes a field with a switch tag whose variant does not
+// match the value of its discriminator: no variant is registered by
+// [RegisterVariant] for the value decoded by [Unmarshal], or the value passed
+// to [Marshal] is not of the variant registered for it.
+type VariantError struct {
+	Field reflect.StructField
+	// Path is the path of the field from the struct passed to the function,
+	// such as "Packet.Body" for a field of a nested struct.
+	Path string
+	// Value is the value of the discriminator.
+	Value uint64
+	// Type is the type of the value of the field passed to Marshal, or nil.
+	Type     reflect.Type
+	encoding bool
+}
+
+func (e *VariantError) Error() string {
+	if e.encoding {
+		return fmt.Sprintf("bitfield: %v is not variant of %s registered for value %d (%s %s `%s`)%s", e.Type, e.Field.Type, e.Value, e.Path, e.Field.Type, e.Field.Tag, hint(e.Field))
+	}
+	return fmt.Sprintf("bitfield: no variant of %s registered for value %d (%s %s `%s`)%s", e.Field.Type, e.Value, e.Path, e.Field.Type, e.Field.Tag, hint(e.Field))
+}
+
+// ChecksumError describes a field with a check tag decoded by [Unmarshal]
+// whose value is not the checksum of its range of bytes.
+type ChecksumError struct {
+	Field reflect.StructField
+	// Path is the path of the field from the struct passed to the function,
+	// such as "Frame.CRC" for a field of a nested struct.
+	Path string
+	// Value is the decoded value of the field, and Sum the checksum of the
+	// bytes, truncated to the bit size of the field.
+	Value uint64
+	Sum   uint64
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("bitfield: value %#x is not checksum %#x (%s %s `%s`)%s", e.Value, e.Sum, e.Path, e.Field.Type, e.Field.Tag, hint(e.Field))
+}
+
+// DecodeError locates an error of [Unmarshal] in the input, wrapping the error
+// decoding a field, such as [EnumError] or the error of a [BitUnmarshaler].
+// Use [errors.As] to retrieve the wrapped error:
+//
+//	var decodeErr *bitfield.DecodeError
+//	if errors.As(err, &decodeErr) {
+//		log.Printf("%s at byte %d", decodeErr.Path, decodeErr.Byte)
+//	}
+type DecodeError struct {
+	Field reflect.StructField
+	// Path is the path of the field from the struct passed to the function,
+	// such as "Header.Opcode" for a field of a nested struct.
+	Path string
+	// Byte is the index of the byte of the input at which the field starts,
+	// and Bit the index of the bit in the byte, counted in the bit order.
+	Byte int
+	Bit  int
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("bitfield: at byte %d bit %d: %s", e.Byte, e.Bit, strings.TrimPrefix(e.Err.Error(), "bitfield: "))
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeError wraps err decoding a field starting at offset in [DecodeError],
+// unless err already locates a field nested in it.
+func decodeError(field reflect.StructField, path string, offset int, err error) error {
+	var located *DecodeError
+	if errors.As(err, &located) {
+		return err
+	}
+	return &DecodeError{Field: field, Path: path, Byte: offset / 8, Bit: offset % 8, Err: err}
+}
+
+// TrailingDataError describes input parsed by [Unmarshal] with
+// [WithStrictSize] which is longer than the struct, as when the struct lacks
+// fields added to the format.
+type TrailingDataError struct {
+	// Type is the type of the struct, or of the slice of [UnmarshalSlice].
+	Type reflect.Type
+	// Size is the number of bytes occupied by the struct, and Len the number
+	// of bytes of the input.
+	Size int
+	Len  int
+}
+
+func (e *TrailingDataError) Error() string {
+	return fmt.Sprintf("bitfield: %d bytes left over after %s of %d bytes in input of %d bytes", e.Len-e.Size, e.Type, e.Size, e.Len)
+}
+
+// hint returns the text of the struct tag "hint" of a field to append to the
+// message of an error about the field, such as remediation guidance for
+// operators, or an empty string if the field has no hint.
+func hint(field reflect.StructField) string {
+	if text := field.Tag.Get("hint"); text != "" {
+		return "; " + text
+	}
+	return ""
 }
@@ -19,6 +19,40 @@ func ExampleUnmarshal() {
 	// Output: A=0x2, B=0x41, C=0x78563
 }
 
+func ExampleMarshal() {
+	type bitFields struct {
+		A uint8 `bit:"1"`
+		B uint8 `bit:"2"`
+		_ uint8 `bit:"1"`
+		C uint8 `bit:"4"`
+	}
+
+	data, _ := bitfield.Marshal(bitFields{A: 0b1, B: 0b10, C: 0b1010})
+	fmt.Printf("%#x\n", data)
+	// Output: 0xa5
+}
+
+func ExampleLayoutOf() {
+	type header struct {
+		Version uint8  `bit:"4"`
+		IHL     uint8  `bit:"4"`
+		TOS     uint8  `bit:"8"`
+		Length  uint16 `bit:"16"`
+		Offset  int16  `bit:"13"`
+	}
+
+	layout, _ := bitfield.LayoutOf(header{}, bitfield.WithByteOrder(bitfield.BigEndian))
+	for _, f := range layout.Fields {
+		fmt.Printf("%-7s offset=%-2d bits=%-2d signed=%-5t %v\n", f.Name, f.Offset, f.Bits, f.Signed, f.ByteOrder)
+	}
+	// Output:
+	// Version offset=0  bits=4  signed=false BigEndian
+	// IHL     offset=4  bits=4  signed=false BigEndian
+	// TOS     offset=8  bits=8  signed=false BigEndian
+	// Length  offset=16 bits=16 signed=false BigEndian
+	// Offset  offset=32 bits=13 signed=true  BigEndian
+}
+
 // func ExampleUnmarshal() {
 // 	var out struct {
 // 		A uint8 `bit:"4"`
@@ -0,0 +1,151 @@
+package export
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/jmatsuzawa/go-bitfield"
+)
+
+// diagramRowBits is the number of bits drawn in a row of a bit diagram.
+const diagramRowBits = 32
+
+type segmentKind int
+
+const (
+	segmentField segmentKind = iota
+	segmentPlaceholder
+	segmentGap
+)
+
+// segment is a part of a field, or of a gap between fields, in a row of a
+// bit diagram.
+type segment struct {
+	start int // bit offset in the row
+	bits  int
+	label string
+	kind  segmentKind
+}
+
+// diagramRows splits a layout into rows of segments. Gaps between fields and
+// the bits up to the next byte boundary after the last field are included as
+// segments without labels.
+func diagramRows(l *bitfield.Layout) [][]segment {
+	var rows [][]segment
+	add := func(offset, bits int, label string, kind segmentKind) {
+		for bits > 0 {
+			row, start := offset/diagramRowBits, offset%diagramRowBits
+			n := min(bits, diagramRowBits-start)
+			for len(rows) <= row {
+				rows = append(rows, nil)
+			}
+			rows[row] = append(rows[row], segment{start: start, bits: n, label: label, kind: kind})
+			offset, bits = offset+n, bits-n
+		}
+	}
+	next := 0
+	for _, f := range l.Fields {
+		if f.Offset > next {
+			add(next, f.Offset-next, "", segmentGap)
+		}
+		if isPlaceholder(f) {
+			add(f.Offset, f.Bits, f.Name, segmentPlaceholder)
+		} else {
+			add(f.Offset, f.Bits, f.Name, segmentField)
+		}
+		next = f.Offset + f.Bits
+	}
+	if end := (next + 7) / 8 * 8; end > next {
+		add(next, end-next, "", segmentGap)
+	}
+	return rows
+}
+
+func diagramTitle(l *bitfield.Layout) string {
+	name := l.Name
+	if name == "" {
+		name = "struct"
+	}
+	order := "little-endian"
+	if l.ByteOrder == bitfield.BigEndian {
+		order = "big-endian"
+	}
+	if l.BitOrder == bitfield.MSBFirst {
+		order += ", MSB first"
+	}
+	return fmt.Sprintf("%s (%d bits, %s)", name, l.BitSize, order)
+}
+
+// DOT writes a Graphviz graph drawing the layout as a grid of bits, 32 bits
+// per row. Columns are numbered by bit offset from the least significant bit
+// of the first byte in the row, or from the most significant bit for
+// MSB-first layouts, and rows by byte offset. Placeholders and bits skipped
+// before plain integer fields are shaded.
+func DOT(w io.Writer, l *bitfield.Layout) error {
+	var b strings.Builder
+	b.WriteString("digraph bitfield {\n")
+	b.WriteString("  node [shape=plaintext, fontname=\"monospace\"];\n")
+	b.WriteString("  layout [label=<\n")
+	b.WriteString("<TABLE BORDER=\"0\" CELLBORDER=\"1\" CELLSPACING=\"0\">\n")
+	fmt.Fprintf(&b, "<TR><TD COLSPAN=\"%d\"><B>%s</B></TD></TR>\n", diagramRowBits+1, html.EscapeString(diagramTitle(l)))
+	b.WriteString("<TR><TD></TD>")
+	for i := 0; i < diagramRowBits; i++ {
+		fmt.Fprintf(&b, "<TD>%d</TD>", i)
+	}
+	b.WriteString("</TR>\n")
+	for i, row := range diagramRows(l) {
+		fmt.Fprintf(&b, "<TR><TD>%d</TD>", i*diagramRowBits/8)
+		for _, s := range row {
+			attrs := fmt.Sprintf(" COLSPAN=\"%d\"", s.bits)
+			if s.kind != segmentField {
+				attrs += " BGCOLOR=\"lightgray\""
+			}
+			fmt.Fprintf(&b, "<TD%s>%s</TD>", attrs, html.EscapeString(s.label))
+		}
+		b.WriteString("</TR>\n")
+	}
+	b.WriteString("</TABLE>>];\n")
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// SVG writes an SVG image drawing the layout in the same grid as [DOT].
+func SVG(w io.Writer, l *bitfield.Layout) error {
+	const (
+		cellWidth  = 24
+		cellHeight = 32
+		labelWidth = 40
+	)
+	rows := diagramRows(l)
+	width := labelWidth + diagramRowBits*cellWidth + 1
+	height := (len(rows)+2)*cellHeight + 1
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"monospace\" font-size=\"12\">\n", width, height)
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\" font-weight=\"bold\">%s</text>\n", 0, cellHeight/2+4, html.EscapeString(diagramTitle(l)))
+	for i := 0; i < diagramRowBits; i++ {
+		x := labelWidth + i*cellWidth + cellWidth/2
+		fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\" text-anchor=\"middle\">%d</text>\n", x, cellHeight*3/2+4, i)
+	}
+	for i, row := range rows {
+		y := (i + 2) * cellHeight
+		fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\" text-anchor=\"end\">%d</text>\n", labelWidth-4, y+cellHeight/2+4, i*diagramRowBits/8)
+		for _, s := range row {
+			x := labelWidth + s.start*cellWidth
+			fill := "white"
+			if s.kind != segmentField {
+				fill = "lightgray"
+			}
+			fmt.Fprintf(&b, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\" stroke=\"black\"/>\n", x, y, s.bits*cellWidth, cellHeight, fill)
+			if s.label != "" {
+				fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\" text-anchor=\"middle\">%s</text>\n", x+s.bits*cellWidth/2, y+cellHeight/2+4, html.EscapeString(s.label))
+			}
+		}
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
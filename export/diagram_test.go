@@ -0,0 +1,60 @@
+package export_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jmatsuzawa/go-bitfield"
+	"github.com/jmatsuzawa/go-bitfield/export"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDOT(t *testing.T) {
+	// Setup
+	layout, _ := bitfield.LayoutOf(message{})
+	header := "<TR><TD></TD>"
+	for i := 0; i < 32; i++ {
+		header += "<TD>" + strconv.Itoa(i) + "</TD>"
+	}
+	header += "</TR>\n"
+	want := "digraph bitfield {\n" +
+		"  node [shape=plaintext, fontname=\"monospace\"];\n" +
+		"  layout [label=<\n" +
+		"<TABLE BORDER=\"0\" CELLBORDER=\"1\" CELLSPACING=\"0\">\n" +
+		"<TR><TD COLSPAN=\"33\"><B>message (68 bits, little-endian)</B></TD></TR>\n" +
+		header +
+		"<TR><TD>0</TD>" +
+		"<TD COLSPAN=\"4\">Type</TD><TD COLSPAN=\"3\">Priority</TD><TD COLSPAN=\"9\">Length</TD>" +
+		"<TD COLSPAN=\"4\" BGCOLOR=\"lightgray\">_</TD><TD COLSPAN=\"4\" BGCOLOR=\"lightgray\"></TD>" +
+		"<TD COLSPAN=\"8\">ID</TD></TR>\n" +
+		"<TR><TD>4</TD><TD COLSPAN=\"24\">ID</TD><TD COLSPAN=\"8\">Payload</TD></TR>\n" +
+		"<TR><TD>8</TD><TD COLSPAN=\"4\">Payload</TD><TD COLSPAN=\"4\" BGCOLOR=\"lightgray\"></TD></TR>\n" +
+		"</TABLE>>];\n" +
+		"}\n"
+
+	// Exercise
+	var got strings.Builder
+	err := export.DOT(&got, layout)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.String())
+}
+
+func TestSVG(t *testing.T) {
+	// Setup
+	layout, _ := bitfield.LayoutOf(message{}, bitfield.WithByteOrder(bitfield.BigEndian))
+
+	// Exercise
+	var got strings.Builder
+	err := export.SVG(&got, layout)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(got.String(), "<svg "))
+	assert.True(t, strings.HasSuffix(got.String(), "</svg>\n"))
+	assert.Contains(t, got.String(), "message (68 bits, big-endian)")
+	assert.Equal(t, 10, strings.Count(got.String(), "<rect "))
+	assert.Equal(t, 2, strings.Count(got.String(), ">Payload</text>"))
+}
@@ -0,0 +1,97 @@
+// Package export generates definitions for other languages and tools from the
+// layout of a struct with bit-fields, so that the same format can be parsed
+// outside Go without specifying it again by hand.
+//
+// The input of every generator is a [bitfield.Layout] obtained from
+// [bitfield.LayoutOf]:
+//
+//	layout, err := bitfield.LayoutOf(&Header{}, bitfield.WithByteOrder(bitfield.BigEndian))
+//	if err != nil {
+//		return err
+//	}
+//	err = export.Python(os.Stdout, layout)
+package export
+
+import (
+	"errors"
+	"go/token"
+	"reflect"
+	"strings"
+
+	"github.com/jmatsuzawa/go-bitfield"
+)
+
+// chunk is a run of bits of a field within a single byte.
+type chunk struct {
+	offset int
+	bits   int
+}
+
+// chunks splits a field into runs of bits per byte, in the order in which
+// they appear in the data.
+func chunks(f bitfield.FieldLayout) []chunk {
+	var cs []chunk
+	for offset, end := f.Offset, f.Offset+f.Bits; offset < end; {
+		bits := 8 - offset%8
+		if end-offset < bits {
+			bits = end - offset
+		}
+		cs = append(cs, chunk{offset: offset, bits: bits})
+		offset += bits
+	}
+	return cs
+}
+
+// isPlaceholder reports whether the field is parsed but not stored by
+// Unmarshal, which is the case if the field or a nested struct containing it
+// is unexported.
+func isPlaceholder(f bitfield.FieldLayout) bool {
+	for _, name := range strings.Split(f.Name, ".") {
+		if !token.IsExported(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// identifier returns the name of a field usable as an identifier, which has
+// the dots in the path of a nested field and the brackets around the index of
+// an array element replaced with underscores.
+func identifier(f bitfield.FieldLayout) string {
+	return identifierReplacer.Replace(f.Name)
+}
+
+var identifierReplacer = strings.NewReplacer(".", "_", "[", "_", "]", "")
+
+// isRaw reports whether the field is a [bitfield.Raw], which is not affected
+// by the byte order.
+func isRaw(f bitfield.FieldLayout) bool {
+	return f.Type == reflect.TypeOf(bitfield.Raw(nil))
+}
+
+// isBool reports whether the field is a bool flag.
+func isBool(f bitfield.FieldLayout) bool {
+	return f.Type.Kind() == reflect.Bool
+}
+
+func checkName(l *bitfield.Layout) error {
+	if l.Name == "" {
+		return errors.New("export: layout of anonymous struct has no name")
+	}
+	return nil
+}
+
+func checkBitOrder(l *bitfield.Layout) error {
+	if l.BitOrder != bitfield.LSBFirst {
+		return errors.New("export: layout " + l.Name + " is not LSB-first")
+	}
+	for _, f := range l.Fields {
+		if f.BitOrder != bitfield.LSBFirst {
+			return errors.New("export: field " + f.Name + " of layout " + l.Name + " is not LSB-first")
+		}
+		if f.Encoding != "" {
+			return errors.New("export: field " + f.Name + " of layout " + l.Name + " is encoded as " + f.Encoding)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jmatsuzawa/go-bitfield"
+)
+
+// Python writes a Python module declaring a [construct] definition for each
+// layout. The definitions are named after the layouts.
+//
+// Each definition parses the whole struct as a single integer with
+// ByteSwapped(BitStruct(...)), so the fields are listed from the most
+// significant bit. In big-endian layouts, and for fields overridden to
+// big-endian by an endian tag, a field spanning multiple bytes is parsed as
+// hidden per-byte parts which are combined into the field by Computed.
+// [bitfield.Raw] fields are parsed as unsigned integers whose little-endian
+// bytes are the raw bytes. Layouts with [bitfield.MSBFirst] are not supported.
+//
+// [construct]: https://construct.readthedocs.io/
+func Python(w io.Writer, layouts ...*bitfield.Layout) error {
+	for _, l := range layouts {
+		if err := checkName(l); err != nil {
+			return err
+		}
+		if err := checkBitOrder(l); err != nil {
+			return err
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("# Code generated by go-bitfield. DO NOT EDIT.\n\n")
+	b.WriteString("from construct import BitStruct, BitsInteger, ByteSwapped, Computed, Padding\n\n\n")
+	b.WriteString("def _signed(value, bits):\n")
+	b.WriteString("    return value - (1 << bits) if value >> (bits - 1) else value\n")
+	for _, l := range layouts {
+		b.WriteString("\n\n")
+		writePythonLayout(&b, l)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writePythonLayout(b *strings.Builder, l *bitfield.Layout) {
+	var items []string
+	next := 0
+	add := func(offset, bits int, line string) {
+		// Bits skipped before a plain integer field
+		if offset > next {
+			items = append(items, fmt.Sprintf("Padding(%d)", offset-next))
+		}
+		items = append(items, line)
+		next = offset + bits
+	}
+	var computed []string
+	for _, f := range l.Fields {
+		if isPlaceholder(f) {
+			add(f.Offset, f.Bits, fmt.Sprintf("Padding(%d)", f.Bits))
+			continue
+		}
+		cs := chunks(f)
+		if f.ByteOrder == bitfield.LittleEndian || len(cs) == 1 || isRaw(f) {
+			add(f.Offset, f.Bits, fmt.Sprintf("%q / BitsInteger(%d%s)", identifier(f), f.Bits, pythonSigned(f.Signed)))
+			continue
+		}
+		// Big-endian: the part in the earlier byte is more significant
+		var terms []string
+		shift := f.Bits
+		for i, c := range cs {
+			name := fmt.Sprintf("_%s_%d", identifier(f), i)
+			add(c.offset, c.bits, fmt.Sprintf("%q / BitsInteger(%d)", name, c.bits))
+			shift -= c.bits
+			if shift > 0 {
+				terms = append(terms, fmt.Sprintf("(this.%s << %d)", name, shift))
+			} else {
+				terms = append(terms, "this."+name)
+			}
+		}
+		value := strings.Join(terms, " | ")
+		if f.Signed {
+			value = fmt.Sprintf("_signed(%s, %d)", value, f.Bits)
+		}
+		computed = append(computed, fmt.Sprintf("%q / Computed(lambda this: %s)", identifier(f), value))
+	}
+
+	fmt.Fprintf(b, "%s = ByteSwapped(BitStruct(\n", l.Name)
+	if pad := (8 - l.BitSize%8) % 8; pad > 0 {
+		fmt.Fprintf(b, "    Padding(%d),\n", pad)
+	}
+	for i := len(items) - 1; i >= 0; i-- {
+		fmt.Fprintf(b, "    %s,\n", items[i])
+	}
+	for _, c := range computed {
+		fmt.Fprintf(b, "    %s,\n", c)
+	}
+	b.WriteString("))\n")
+}
+
+func pythonSigned(signed bool) string {
+	if signed {
+		return ", signed=True"
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package export_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmatsuzawa/go-bitfield"
+	"github.com/jmatsuzawa/go-bitfield/export"
+	"github.com/stretchr/testify/assert"
+)
+
+type header struct {
+	Version   uint8 `bit:"4"`
+	Class     uint8 `bit:"8"`
+	FlowLabel int32 `bit:"20"`
+	Length    uint16
+	_         uint8        `bit:"3"`
+	Extra     bitfield.Raw `bit:"2"`
+}
+
+const pythonPrelude = `# Code generated by go-bitfield. DO NOT EDIT.
+
+from construct import BitStruct, BitsInteger, ByteSwapped, Computed, Padding
+
+
+def _signed(value, bits):
+    return value - (1 << bits) if value >> (bits - 1) else value
+
+
+`
+
+func TestPython_LittleEndian(t *testing.T) {
+	// Setup
+	layout, _ := bitfield.LayoutOf(header{})
+	want := pythonPrelude + `header = ByteSwapped(BitStruct(
+    Padding(3),
+    "Extra" / BitsInteger(2),
+    Padding(3),
+    "Length" / BitsInteger(16),
+    "FlowLabel" / BitsInteger(20, signed=True),
+    "Class" / BitsInteger(8),
+    "Version" / BitsInteger(4),
+))
+`
+
+	// Exercise
+	var got strings.Builder
+	err := export.Python(&got, layout)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.String())
+}
+
+func TestPython_BigEndian(t *testing.T) {
+	// Setup
+	layout, _ := bitfield.LayoutOf(header{}, bitfield.WithByteOrder(bitfield.BigEndian))
+	want := pythonPrelude + `header = ByteSwapped(BitStruct(
+    Padding(3),
+    "Extra" / BitsInteger(2),
+    Padding(3),
+    "_Length_1" / BitsInteger(8),
+    "_Length_0" / BitsInteger(8),
+    "_FlowLabel_2" / BitsInteger(8),
+    "_FlowLabel_1" / BitsInteger(8),
+    "_FlowLabel_0" / BitsInteger(4),
+    "_Class_1" / BitsInteger(4),
+    "_Class_0" / BitsInteger(4),
+    "Version" / BitsInteger(4),
+    "Class" / Computed(lambda this: (this._Class_0 << 4) | this._Class_1),
+    "FlowLabel" / Computed(lambda this: _signed((this._FlowLabel_0 << 16) | (this._FlowLabel_1 << 8) | this._FlowLabel_2, 20)),
+    "Length" / Computed(lambda this: (this._Length_0 << 8) | this._Length_1),
+))
+`
+
+	// Exercise
+	var got strings.Builder
+	err := export.Python(&got, layout)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.String())
+}
+
+func TestPython_AnonymousStruct(t *testing.T) {
+	// Setup
+	layout, _ := bitfield.LayoutOf(struct{ A uint8 }{})
+
+	// Exercise
+	err := export.Python(&strings.Builder{}, layout)
+
+	// Verify
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,194 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/jmatsuzawa/go-bitfield"
+)
+
+// Rust writes a Rust module declaring a struct with [deku] attributes for each
+// layout. The structs are named after the layouts.
+//
+// The structs are read with bit_order = "lsb", so bit-fields are filled from
+// the least significant bit as in Go. In big-endian layouts, a field spanning
+// multiple bytes is declared as private per-byte parts, and a method named
+// after the field combines them into its value. [bitfield.Raw] fields are
+// declared as unsigned integers whose little-endian bytes are the raw bytes;
+// Raw fields wider than 128 bits are not supported. 1-bit bool fields are
+// declared as bool, and wider ones as unsigned integers. Fields whose byte
+// order is overridden by an endian tag are declared in the same way as in a
+// layout of that byte order. Layouts with [bitfield.MSBFirst] are not
+// supported.
+//
+// [deku]: https://docs.rs/deku
+func Rust(w io.Writer, layouts ...*bitfield.Layout) error {
+	var b strings.Builder
+	b.WriteString("// Code generated by go-bitfield. DO NOT EDIT.\n\n")
+	b.WriteString("use deku::prelude::*;\n")
+	for _, l := range layouts {
+		if err := checkName(l); err != nil {
+			return err
+		}
+		if err := checkBitOrder(l); err != nil {
+			return err
+		}
+		b.WriteString("\n")
+		if err := writeRustLayout(&b, l); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeRustLayout(b *strings.Builder, l *bitfield.Layout) error {
+	name := rustTypeName(l.Name)
+	endian := "little"
+	if l.ByteOrder == bitfield.BigEndian {
+		endian = "big"
+	}
+	fmt.Fprintf(b, "#[derive(Debug, PartialEq, DekuRead, DekuWrite)]\n")
+	fmt.Fprintf(b, "#[deku(endian = %q, bit_order = \"lsb\")]\n", endian)
+	fmt.Fprintf(b, "pub struct %s {\n", name)
+
+	var methods strings.Builder
+	next := 0
+	reserved := 0
+	add := func(offset, bits int, fieldName, typ string, full bool, attrs ...string) {
+		if offset > next {
+			attrs = append(attrs, fmt.Sprintf("pad_bits_before = \"%d\"", offset-next))
+		}
+		if !full {
+			attrs = append(attrs, fmt.Sprintf("bits = %d", bits))
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(b, "    #[deku(%s)]\n", strings.Join(attrs, ", "))
+		}
+		fmt.Fprintf(b, "    %s: %s,\n", fieldName, typ)
+		next = offset + bits
+	}
+	for _, f := range l.Fields {
+		typ, ok := rustType(f)
+		if !ok {
+			return fmt.Errorf("export: field %s of %s is too wide for Rust", f.Name, l.Name)
+		}
+		full := !isRaw(f) && !isBool(f) && f.Bits == f.Type.Bits()
+		if f.Name == "_" || strings.HasSuffix(f.Name, "._") {
+			add(f.Offset, f.Bits, fmt.Sprintf("_reserved%d", reserved), typ, full)
+			reserved++
+			continue
+		}
+		fieldName := rustIdent(snakeCase(identifier(f)))
+		if isPlaceholder(f) {
+			add(f.Offset, f.Bits, fieldName, typ, full)
+			continue
+		}
+		if isRaw(f) && l.ByteOrder == bitfield.BigEndian {
+			add(f.Offset, f.Bits, "pub "+fieldName, typ, false, "endian = \"little\"")
+			continue
+		}
+		cs := chunks(f)
+		if f.ByteOrder == bitfield.LittleEndian || len(cs) == 1 || isRaw(f) {
+			var attrs []string
+			if l.ByteOrder == bitfield.BigEndian && f.ByteOrder == bitfield.LittleEndian && len(cs) > 1 && !isRaw(f) {
+				// Overridden by an endian tag
+				attrs = append(attrs, "endian = \"little\"")
+			}
+			add(f.Offset, f.Bits, "pub "+fieldName, typ, full, attrs...)
+			continue
+		}
+		// Big-endian: the part in the earlier byte is more significant
+		unsigned := "u" + strings.TrimLeft(typ, "iu")
+		var terms []string
+		shift := f.Bits
+		for i, c := range cs {
+			part := fmt.Sprintf("%s_%d", strings.TrimPrefix(fieldName, "r#"), i)
+			add(c.offset, c.bits, part, "u8", c.bits == 8)
+			shift -= c.bits
+			term := fmt.Sprintf("(self.%s as %s)", part, unsigned)
+			if shift > 0 {
+				term = fmt.Sprintf("(%s << %d)", term, shift)
+			}
+			terms = append(terms, term)
+		}
+		fmt.Fprintf(&methods, "    pub fn %s(&self) -> %s {\n", fieldName, typ)
+		value := strings.Join(terms, " | ")
+		if f.Signed && f.Type.Bits() > f.Bits {
+			unused := f.Type.Bits() - f.Bits
+			fmt.Fprintf(&methods, "        let value = %s;\n", value)
+			fmt.Fprintf(&methods, "        ((value << %d) as %s) >> %d\n", unused, typ, unused)
+		} else if f.Signed {
+			fmt.Fprintf(&methods, "        (%s) as %s\n", value, typ)
+		} else {
+			fmt.Fprintf(&methods, "        %s\n", value)
+		}
+		fmt.Fprintf(&methods, "    }\n")
+	}
+	b.WriteString("}\n")
+	if methods.Len() > 0 {
+		fmt.Fprintf(b, "\nimpl %s {\n%s}\n", name, methods.String())
+	}
+	return nil
+}
+
+func rustType(f bitfield.FieldLayout) (string, bool) {
+	if isBool(f) && f.Bits == 1 {
+		return "bool", true
+	}
+	if isRaw(f) || isBool(f) {
+		for _, size := range []int{8, 16, 32, 64, 128} {
+			if f.Bits <= size {
+				return fmt.Sprintf("u%d", size), true
+			}
+		}
+		return "", false
+	}
+	if f.Signed {
+		return fmt.Sprintf("i%d", f.Type.Bits()), true
+	}
+	return fmt.Sprintf("u%d", f.Type.Bits()), true
+}
+
+func rustTypeName(name string) string {
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// snakeCase converts a Go identifier such as "FlowLabel" or "IPVersion" into
+// "flow_label" or "ip_version".
+func snakeCase(name string) string {
+	r := []rune(name)
+	var b strings.Builder
+	for i, c := range r {
+		if i > 0 && unicode.IsUpper(c) {
+			prev := r[i-1]
+			nextLower := i+1 < len(r) && unicode.IsLower(r[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(c))
+	}
+	return b.String()
+}
+
+var rustKeywords = map[string]bool{
+	"as": true, "async": true, "await": true, "break": true, "const": true,
+	"continue": true, "dyn": true, "else": true, "enum": true, "extern": true,
+	"false": true, "fn": true, "for": true, "if": true, "impl": true,
+	"in": true, "let": true, "loop": true, "match": true, "mod": true,
+	"move": true, "mut": true, "pub": true, "ref": true, "return": true,
+	"static": true, "struct": true, "trait": true, "true": true, "type": true,
+	"unsafe": true, "use": true, "where": true, "while": true,
+}
+
+func rustIdent(name string) string {
+	if rustKeywords[name] {
+		return "r#" + name
+	}
+	return name
+}
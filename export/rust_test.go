@@ -0,0 +1,224 @@
+package export_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmatsuzawa/go-bitfield"
+	"github.com/jmatsuzawa/go-bitfield/export"
+	"github.com/stretchr/testify/assert"
+)
+
+type message struct {
+	Type     uint8  `bit:"4"`
+	Priority int8   `bit:"3"`
+	Length   uint16 `bit:"9"`
+	_        uint8  `bit:"4"`
+	ID       int32
+	Payload  bitfield.Raw `bit:"12"`
+}
+
+func TestRust_LittleEndian(t *testing.T) {
+	// Setup
+	layout, _ := bitfield.LayoutOf(message{})
+	want := `// Code generated by go-bitfield. DO NOT EDIT.
+
+use deku::prelude::*;
+
+#[derive(Debug, PartialEq, DekuRead, DekuWrite)]
+#[deku(endian = "little", bit_order = "lsb")]
+pub struct Message {
+    #[deku(bits = 4)]
+    pub r#type: u8,
+    #[deku(bits = 3)]
+    pub priority: i8,
+    #[deku(bits = 9)]
+    pub length: u16,
+    #[deku(bits = 4)]
+    _reserved0: u8,
+    #[deku(pad_bits_before = "4")]
+    pub id: i32,
+    #[deku(bits = 12)]
+    pub payload: u16,
+}
+`
+
+	// Exercise
+	var got strings.Builder
+	err := export.Rust(&got, layout)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.String())
+}
+
+func TestRust_BigEndian(t *testing.T) {
+	// Setup
+	layout, _ := bitfield.LayoutOf(message{}, bitfield.WithByteOrder(bitfield.BigEndian))
+	want := `// Code generated by go-bitfield. DO NOT EDIT.
+
+use deku::prelude::*;
+
+#[derive(Debug, PartialEq, DekuRead, DekuWrite)]
+#[deku(endian = "big", bit_order = "lsb")]
+pub struct Message {
+    #[deku(bits = 4)]
+    pub r#type: u8,
+    #[deku(bits = 3)]
+    pub priority: i8,
+    #[deku(bits = 1)]
+    length_0: u8,
+    length_1: u8,
+    #[deku(bits = 4)]
+    _reserved0: u8,
+    #[deku(pad_bits_before = "4")]
+    id_0: u8,
+    id_1: u8,
+    id_2: u8,
+    id_3: u8,
+    #[deku(endian = "little", bits = 12)]
+    pub payload: u16,
+}
+
+impl Message {
+    pub fn length(&self) -> u16 {
+        ((self.length_0 as u16) << 8) | (self.length_1 as u16)
+    }
+    pub fn id(&self) -> i32 {
+        (((self.id_0 as u32) << 24) | ((self.id_1 as u32) << 16) | ((self.id_2 as u32) << 8) | (self.id_3 as u32)) as i32
+    }
+}
+`
+
+	// Exercise
+	var got strings.Builder
+	err := export.Rust(&got, layout)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.String())
+}
+
+func TestRust_TooWideRaw(t *testing.T) {
+	// Setup
+	type wide struct {
+		A bitfield.Raw `bit:"129"`
+	}
+	layout, _ := bitfield.LayoutOf(wide{})
+
+	// Exercise
+	err := export.Rust(&strings.Builder{}, layout)
+
+	// Verify
+	assert.NotNil(t, err)
+}
+
+func TestRust_MSBFirst(t *testing.T) {
+	// Setup
+	layout, _ := bitfield.LayoutOf(message{}, bitfield.WithBitOrder(bitfield.MSBFirst))
+
+	// Exercise
+	err := export.Rust(&strings.Builder{}, layout)
+	errPython := export.Python(&strings.Builder{}, layout)
+
+	// Verify
+	assert.NotNil(t, err)
+	assert.NotNil(t, errPython)
+}
+
+func TestRust_EncTag(t *testing.T) {
+	// Setup
+	type gray struct {
+		Pos uint16 `bit:"10" enc:"gray"`
+	}
+	layout, _ := bitfield.LayoutOf(gray{})
+
+	// Exercise
+	err := export.Rust(&strings.Builder{}, layout)
+	errPython := export.Python(&strings.Builder{}, layout)
+
+	// Verify
+	assert.ErrorContains(t, err, "encoded as gray")
+	assert.ErrorContains(t, errPython, "encoded as gray")
+}
+
+func TestRust_NestedStruct(t *testing.T) {
+	// Setup
+	type Flags struct {
+		SynAck uint8 `bit:"2"`
+		_      uint8 `bit:"2"`
+	}
+	type segment struct {
+		Flags Flags
+		Port  uint8 `bit:"4"`
+	}
+	layout, _ := bitfield.LayoutOf(segment{})
+	want := `// Code generated by go-bitfield. DO NOT EDIT.
+
+use deku::prelude::*;
+
+#[derive(Debug, PartialEq, DekuRead, DekuWrite)]
+#[deku(endian = "little", bit_order = "lsb")]
+pub struct Segment {
+    #[deku(bits = 2)]
+    pub flags_syn_ack: u8,
+    #[deku(bits = 2)]
+    _reserved0: u8,
+    #[deku(bits = 4)]
+    pub port: u8,
+}
+`
+
+	// Exercise
+	var got strings.Builder
+	err := export.Rust(&got, layout)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.String())
+}
+
+func TestRust_EndianTag(t *testing.T) {
+	// Setup
+	type mixed struct {
+		Length uint16
+		Port   uint16 `endian:"big"`
+	}
+	layout, _ := bitfield.LayoutOf(mixed{})
+	layoutBig, _ := bitfield.LayoutOf(struct {
+		Length uint16 `endian:"little"`
+	}{}, bitfield.WithByteOrder(bitfield.BigEndian))
+	want := `// Code generated by go-bitfield. DO NOT EDIT.
+
+use deku::prelude::*;
+
+#[derive(Debug, PartialEq, DekuRead, DekuWrite)]
+#[deku(endian = "little", bit_order = "lsb")]
+pub struct Mixed {
+    pub length: u16,
+    port_0: u8,
+    port_1: u8,
+}
+
+impl Mixed {
+    pub fn port(&self) -> u16 {
+        ((self.port_0 as u16) << 8) | (self.port_1 as u16)
+    }
+}
+`
+	wantBig := `    #[deku(endian = "little")]
+    pub length: u16,
+`
+
+	// Exercise
+	var got, gotBig strings.Builder
+	err := export.Rust(&got, layout)
+	layoutBig.Name = "big"
+	errBig := export.Rust(&gotBig, layoutBig)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.String())
+	assert.Nil(t, errBig)
+	assert.Contains(t, gotBig.String(), wantBig)
+}
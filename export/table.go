@@ -0,0 +1,54 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/jmatsuzawa/go-bitfield"
+)
+
+// tableHeader is the first record of a table written by CSV and TSV.
+var tableHeader = []string{"field", "byte", "bit", "width", "order", "description"}
+
+// CSV writes the layout as comma-separated values, one record per field in
+// order of offset, for hardware and QA teams to open in a spreadsheet. The
+// first record names the columns:
+//
+//	field,byte,bit,width,order,description
+//	Version,0,0,4,little,Protocol version
+//
+// byte is the offset of the first byte of the field, and bit is the offset of
+// its first bit within that byte, counted from the least significant bit, or
+// from the most significant bit for MSB-first layouts. width is the bit size,
+// and order is the byte order of the field, little or big. description is the
+// text of the desc tag of the field.
+func CSV(w io.Writer, l *bitfield.Layout) error {
+	return writeTable(csv.NewWriter(w), l)
+}
+
+// TSV is like [CSV] but writes tab-separated values.
+func TSV(w io.Writer, l *bitfield.Layout) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = '\t'
+	return writeTable(cw, l)
+}
+
+func writeTable(cw *csv.Writer, l *bitfield.Layout) error {
+	records := [][]string{tableHeader}
+	for _, f := range l.Fields {
+		order := "little"
+		if f.ByteOrder == bitfield.BigEndian {
+			order = "big"
+		}
+		records = append(records, []string{
+			f.Name,
+			strconv.Itoa(f.Offset / 8),
+			strconv.Itoa(f.Offset % 8),
+			strconv.Itoa(f.Bits),
+			order,
+			f.Description,
+		})
+	}
+	return cw.WriteAll(records)
+}
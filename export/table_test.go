@@ -0,0 +1,53 @@
+package export_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmatsuzawa/go-bitfield"
+	"github.com/jmatsuzawa/go-bitfield/export"
+	"github.com/stretchr/testify/assert"
+)
+
+type register struct {
+	Enable  bool   `bit:"1" desc:"Enables the channel"`
+	Mode    uint8  `bit:"3" desc:"Mode, see table 4"`
+	_       uint8  `bit:"4"`
+	Counter uint16 `endian:"big"`
+}
+
+func TestCSV(t *testing.T) {
+	// Setup
+	layout, _ := bitfield.LayoutOf(register{})
+	want := "field,byte,bit,width,order,description\n" +
+		"Enable,0,0,1,little,Enables the channel\n" +
+		"Mode,0,1,3,little,\"Mode, see table 4\"\n" +
+		"_,0,4,4,little,\n" +
+		"Counter,1,0,16,big,\n"
+
+	// Exercise
+	var got strings.Builder
+	err := export.CSV(&got, layout)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.String())
+}
+
+func TestTSV(t *testing.T) {
+	// Setup
+	layout, _ := bitfield.LayoutOf(register{}, bitfield.WithByteOrder(bitfield.BigEndian))
+	want := "field\tbyte\tbit\twidth\torder\tdescription\n" +
+		"Enable\t0\t0\t1\tbig\tEnables the channel\n" +
+		"Mode\t0\t1\t3\tbig\tMode, see table 4\n" +
+		"_\t0\t4\t4\tbig\t\n" +
+		"Counter\t1\t0\t16\tbig\t\n"
+
+	// Exercise
+	var got strings.Builder
+	err := export.TSV(&got, layout)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.String())
+}
@@ -0,0 +1,164 @@
+package bitfield
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Frame describes an envelope around a struct with bit-fields, as used by
+// many serial protocols. A frame consists of the following parts, in order:
+//
+//   - Preamble: fixed bytes marking the start of a frame
+//   - Length: an unsigned integer holding the size of the body in bytes
+//   - Body: the struct with bit-fields
+//   - Checksum: an unsigned integer computed over the length and the body
+//
+// Each part except the body can be omitted.
+type Frame struct {
+	// Preamble is the fixed bytes at the start of a frame.
+	Preamble []byte
+	// LengthSize is the size of the length field in bytes, from 0 to 8. If it
+	// is 0, the frame has no length field and the body extends up to the
+	// checksum at the end of the data.
+	LengthSize int
+	// Checksum computes the checksum of a frame from the length field and the
+	// body. If it is nil, the frame has no checksum.
+	Checksum func(data []byte) uint64
+	// ChecksumSize is the size of the checksum in bytes, from 1 to 8.
+	ChecksumSize int
+	// ByteOrder is the byte order of the length and the checksum.
+	ByteOrder ByteOrder
+}
+
+// UnmarshalFramed parses a frame at the start of data and stores its body in
+// the struct pointed by out. The preamble and the checksum are verified before
+// the body is parsed. The opts are applied to the body as in [Unmarshal].
+//
+// Returns:
+//
+//   - the number of bytes of the frame and nil if the frame is successfully
+//     parsed; bytes following the frame are not parsed
+//   - [FrameError] if the frame is truncated, the frame description is
+//     invalid, or the preamble or the checksum does not match
+//   - any error returned by [Unmarshal] for the body
+func UnmarshalFramed(data []byte, frame Frame, out any, opts ...Option) (n int, err error) {
+	if err := frame.validate(); err != nil {
+		return 0, err
+	}
+	if len(data) < len(frame.Preamble) || !bytes.Equal(data[:len(frame.Preamble)], frame.Preamble) {
+		return 0, &FrameError{problem: "preamble mismatch"}
+	}
+	rest := data[len(frame.Preamble):]
+
+	checksumSize := 0
+	if frame.Checksum != nil {
+		checksumSize = frame.ChecksumSize
+	}
+	var bodySize int
+	if frame.LengthSize > 0 {
+		if len(rest) < frame.LengthSize {
+			return 0, &FrameError{problem: "frame truncated in length field"}
+		}
+		if len(rest) < frame.LengthSize+checksumSize {
+			return 0, &FrameError{problem: "frame truncated in checksum"}
+		}
+		length := frame.readUint(rest[:frame.LengthSize])
+		// The available size is not negative, so that it converts to uint64
+		if available := len(rest) - frame.LengthSize - checksumSize; length > uint64(available) {
+			return 0, &FrameError{problem: "frame truncated: length field " + strconv.FormatUint(length, 10) + " exceeds data"}
+		}
+		bodySize = int(length)
+	} else {
+		bodySize = len(rest) - checksumSize
+		if bodySize < 0 {
+			return 0, &FrameError{problem: "frame truncated in checksum"}
+		}
+	}
+	covered := rest[:frame.LengthSize+bodySize]
+	body := covered[frame.LengthSize:]
+
+	if frame.Checksum != nil {
+		want := frame.readUint(rest[len(covered) : len(covered)+checksumSize])
+		mask := uint64(1)<<(8*checksumSize) - 1
+		if checksumSize == 8 {
+			mask = ^uint64(0)
+		}
+		if got := frame.Checksum(covered) & mask; got != want {
+			return 0, &FrameError{problem: "checksum mismatch: computed " + strconv.FormatUint(got, 16) + ", stored " + strconv.FormatUint(want, 16)}
+		}
+	}
+
+	if err := Unmarshal(body, out, opts...); err != nil {
+		return 0, err
+	}
+	return len(frame.Preamble) + len(covered) + checksumSize, nil
+}
+
+// MarshalFramed encodes v with [Marshal] and wraps the result in a frame. The
+// length field holds the size of the encoded body, and the checksum is computed
+// over the length field and the body. The opts are applied to the body as in
+// Marshal.
+//
+// Returns:
+//
+//   - the bytes of the frame and nil if the frame is successfully encoded
+//   - [FrameError] if the frame description is invalid or the body is too
+//     large for the length field
+//   - any error returned by [Marshal] for the body
+func MarshalFramed(v any, frame Frame, opts ...Option) ([]byte, error) {
+	if err := frame.validate(); err != nil {
+		return nil, err
+	}
+	body, err := Marshal(v, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	data := append([]byte(nil), frame.Preamble...)
+	start := len(data)
+	if frame.LengthSize > 0 {
+		if frame.LengthSize < 8 && uint64(len(body))>>(8*frame.LengthSize) != 0 {
+			return nil, &FrameError{problem: "body size " + strconv.Itoa(len(body)) + " overflows length field"}
+		}
+		data = frame.appendUint(data, uint64(len(body)), frame.LengthSize)
+	}
+	data = append(data, body...)
+	if frame.Checksum != nil {
+		data = frame.appendUint(data, frame.Checksum(data[start:]), frame.ChecksumSize)
+	}
+	return data, nil
+}
+
+func (f *Frame) validate() error {
+	if f.LengthSize < 0 || f.LengthSize > 8 {
+		return &FrameError{problem: "length size must be within range 0 to 8"}
+	}
+	if f.Checksum != nil && (f.ChecksumSize < 1 || f.ChecksumSize > 8) {
+		return &FrameError{problem: "checksum size must be within range 1 to 8"}
+	}
+	return nil
+}
+
+func (f *Frame) readUint(b []byte) uint64 {
+	var v uint64
+	for i := range b {
+		if f.ByteOrder == BigEndian {
+			v = v<<8 | uint64(b[i])
+		} else {
+			v |= uint64(b[i]) << (8 * i)
+		}
+	}
+	return v
+}
+
+// appendUint appends the low size bytes of v to b.
+func (f *Frame) appendUint(b []byte, v uint64, size int) []byte {
+	for i := 0; i < size; i++ {
+		if f.ByteOrder == BigEndian {
+			b = append(b, byte(v>>(8*(size-1-i))))
+		} else {
+			b = append(b, byte(v>>(8*i)))
+		}
+	}
+	return b
+}
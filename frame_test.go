@@ -0,0 +1,155 @@
+package bitfield
+
+import (
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalFramed(t *testing.T) {
+	// Setup
+	type body struct {
+		A uint8 `bit:"4"`
+		B uint8 `bit:"4"`
+		C uint16
+	}
+	crc8 := func(data []byte) uint64 {
+		var sum byte
+		for _, b := range data {
+			sum += b
+		}
+		return uint64(sum)
+	}
+	testCases := map[string]struct {
+		frame Frame
+		data  []byte
+		want  int
+	}{
+		"Full envelope": {
+			frame: Frame{Preamble: []byte{0xAA, 0x55}, LengthSize: 1, Checksum: crc8, ChecksumSize: 1},
+			data:  []byte{0xAA, 0x55, 0x03, 0x21, 0x34, 0x12, 0x6A, 0xFF},
+			want:  7,
+		},
+		"Big-endian length and CRC-32": {
+			frame: Frame{LengthSize: 2, Checksum: func(b []byte) uint64 { return uint64(crc32.ChecksumIEEE(b)) }, ChecksumSize: 4, ByteOrder: BigEndian},
+			data:  []byte{0x00, 0x03, 0x21, 0x34, 0x12, 0xA5, 0x8B, 0xF6, 0x9B},
+			want:  9,
+		},
+		"No length field": {
+			frame: Frame{Preamble: []byte{0x7E}, Checksum: crc8, ChecksumSize: 2},
+			data:  []byte{0x7E, 0x21, 0x34, 0x12, 0x67, 0x00},
+			want:  6,
+		},
+		"Body only": {
+			frame: Frame{},
+			data:  []byte{0x21, 0x34, 0x12},
+			want:  3,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var got body
+			n, err := UnmarshalFramed(tc.data, tc.frame, &got)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, n)
+			assert.Equal(t, body{A: 1, B: 2, C: 0x1234}, got)
+		})
+	}
+}
+
+func TestUnmarshalFramedError(t *testing.T) {
+	// Setup
+	var out struct{ A uint8 }
+	sum := func(data []byte) uint64 { return uint64(len(data)) }
+	testCases := map[string]struct {
+		frame Frame
+		data  []byte
+	}{
+		"Preamble mismatch":         {Frame{Preamble: []byte{0xAA}}, []byte{0xAB, 0x00}},
+		"Short preamble":            {Frame{Preamble: []byte{0xAA, 0x55}}, []byte{0xAA}},
+		"Short length":              {Frame{LengthSize: 2}, []byte{0x01}},
+		"Length exceeds":            {Frame{LengthSize: 1}, []byte{0x02, 0x00}},
+		"Checksum mismatch":         {Frame{Checksum: sum, ChecksumSize: 1}, []byte{0x00, 0x02}},
+		"Short checksum":            {Frame{Checksum: sum, ChecksumSize: 2}, []byte{0x00}},
+		"Short length and checksum": {Frame{LengthSize: 1, Checksum: sum, ChecksumSize: 2}, []byte{0x05}},
+		"Short body and checksum":   {Frame{LengthSize: 1, Checksum: sum, ChecksumSize: 2}, []byte{0x01, 0x00, 0x00}},
+		"Invalid length":            {Frame{LengthSize: 9}, []byte{0x00}},
+		"Invalid checksum":          {Frame{Checksum: sum}, []byte{0x00}},
+		"Negative length":           {Frame{LengthSize: -1}, []byte{0x00}},
+		"Checksum too large":        {Frame{Checksum: sum, ChecksumSize: 9}, []byte{0x00}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			_, err := UnmarshalFramed(tc.data, tc.frame, &out)
+
+			// Verify
+			var frameError *FrameError
+			assert.ErrorAs(t, err, &frameError)
+		})
+	}
+}
+
+func TestMarshalFramed(t *testing.T) {
+	// Setup
+	type body struct {
+		A uint8 `bit:"4"`
+		B uint8 `bit:"4"`
+		C uint16
+	}
+	in := body{A: 1, B: 2, C: 0x1234}
+	testCases := map[string]struct {
+		frame Frame
+		want  []byte
+	}{
+		"Full envelope": {
+			frame: Frame{Preamble: []byte{0xAA, 0x55}, LengthSize: 1, Checksum: func(data []byte) uint64 {
+				var sum byte
+				for _, b := range data {
+					sum += b
+				}
+				return uint64(sum)
+			}, ChecksumSize: 1},
+			want: []byte{0xAA, 0x55, 0x03, 0x21, 0x34, 0x12, 0x6A},
+		},
+		"Big-endian length and CRC-32": {
+			frame: Frame{LengthSize: 2, Checksum: func(b []byte) uint64 { return uint64(crc32.ChecksumIEEE(b)) }, ChecksumSize: 4, ByteOrder: BigEndian},
+			want:  []byte{0x00, 0x03, 0x21, 0x34, 0x12, 0xA5, 0x8B, 0xF6, 0x9B},
+		},
+		"Body only": {
+			frame: Frame{},
+			want:  []byte{0x21, 0x34, 0x12},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := MarshalFramed(in, tc.frame)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestMarshalFramedError(t *testing.T) {
+	// Setup
+	big := struct {
+		A Raw `bit:"2048"`
+	}{}
+
+	// Exercise
+	got, err := MarshalFramed(big, Frame{LengthSize: 1})
+
+	// Verify
+	assert.Nil(t, got)
+	assert.IsType(t, &FrameError{}, err)
+}
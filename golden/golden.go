@@ -0,0 +1,229 @@
+// Package golden generates and verifies golden test vectors for structs with
+// bit-fields, so that implementations of the same formats in other languages
+// can be validated against the semantics of package bitfield.
+//
+// A vector file is a JSON document holding, for each struct, its layout, the
+// bytes encoded by [bitfield.Marshal], and the field values which
+// [bitfield.Unmarshal] decodes from the bytes:
+//
+//	{
+//	  "vectors": [
+//	    {
+//	      "name": "header",
+//	      "layout": {
+//	        "name": "header",
+//	        "byte_order": "big",
+//	        "bit_size": 12,
+//	        "fields": [
+//	          {"name": "Version", "type": "uint8", "offset": 0, "bits": 4},
+//	          {"name": "Class", "type": "int8", "offset": 4, "bits": 8}
+//	        ]
+//	      },
+//	      "input": "f30f",
+//	      "values": {"Version": "3", "Class": "-1"}
+//	    }
+//	  ]
+//	}
+//
+// Offsets count bits from the least significant bit of the first byte, as in
+// [bitfield.FieldLayout]. Field types are the Go integer type names, "bool" for
+// flags, or "raw" for [bitfield.Raw]. Fields of nested structs are named by
+// their path, such as "Header.Version". Placeholders are named "_" and have no
+// value. Input and raw values are hex strings, bool values are "true" or
+// "false", and integer values are decimal strings so that 64-bit values
+// survive JSON parsers using floating-point numbers.
+//
+// A runner in another language decodes input with the layout and compares the
+// result with values. [Verify] is the runner for this package.
+package golden
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jmatsuzawa/go-bitfield"
+)
+
+// File is the root of a vector file.
+type File struct {
+	Vectors []Vector `json:"vectors"`
+}
+
+// Vector is a test vector for a struct.
+type Vector struct {
+	Name   string            `json:"name"`
+	Layout Layout            `json:"layout"`
+	Input  string            `json:"input"`
+	Values map[string]string `json:"values"`
+}
+
+// Layout is the JSON form of [bitfield.Layout]. BitOrder is "msb" for
+// layouts with [bitfield.MSBFirst], and omitted otherwise. WordSize is the
+// word size given by [bitfield.WithWordSize], and omitted without it.
+type Layout struct {
+	Name      string  `json:"name"`
+	ByteOrder string  `json:"byte_order"`
+	BitOrder  string  `json:"bit_order,omitempty"`
+	WordSize  int     `json:"word_size,omitempty"`
+	BitSize   int     `json:"bit_size"`
+	Fields    []Field `json:"fields"`
+}
+
+// Field is the JSON form of [bitfield.FieldLayout]. ByteOrder and BitOrder are
+// set only for fields whose byte or bit order differs from that of the layout.
+// Encoding is that of fields with an enc tag, "bcd" or "gray", or the
+// representation of signed fields, "magnitude" for sign-magnitude, "zigzag" or
+// "ones".
+type Field struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Offset    int    `json:"offset"`
+	Bits      int    `json:"bits"`
+	ByteOrder string `json:"byte_order,omitempty"`
+	BitOrder  string `json:"bit_order,omitempty"`
+	Encoding  string `json:"encoding,omitempty"`
+}
+
+// Case is a struct from which a vector is generated.
+type Case struct {
+	// Name is the name of the vector. The name of the struct type is used if
+	// it is empty.
+	Name string
+	// Value is a struct, or a pointer to a struct, holding the field values.
+	Value any
+	// Options are passed to bitfield.LayoutOf and bitfield.Marshal.
+	Options []bitfield.Option
+}
+
+// Generate writes a vector file with a vector for each case.
+func Generate(w io.Writer, cases ...Case) error {
+	var f File
+	for _, c := range cases {
+		v, err := vectorOf(c)
+		if err != nil {
+			return err
+		}
+		f.Vectors = append(f.Vectors, v)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func vectorOf(c Case) (Vector, error) {
+	layout, err := bitfield.LayoutOf(c.Value, c.Options...)
+	if err != nil {
+		return Vector{}, err
+	}
+	data, err := bitfield.Marshal(c.Value, c.Options...)
+	if err != nil {
+		return Vector{}, err
+	}
+	// The expected values are those decoded from the data, with the bits
+	// which are not encoded cleared
+	rt := reflect.TypeOf(c.Value)
+	if rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	out := reflect.New(rt)
+	if err := bitfield.Unmarshal(data, out.Interface(), c.Options...); err != nil {
+		return Vector{}, err
+	}
+
+	v := Vector{
+		Name:   c.Name,
+		Layout: Layout{Name: layout.Name, ByteOrder: byteOrderName(layout.ByteOrder), WordSize: layout.WordSize, BitSize: layout.BitSize},
+		Input:  hex.EncodeToString(data),
+		Values: map[string]string{},
+	}
+	if v.Name == "" {
+		v.Name = layout.Name
+	}
+	if layout.BitOrder == bitfield.MSBFirst {
+		v.Layout.BitOrder = "msb"
+	}
+	for _, f := range layout.Fields {
+		typ, err := typeName(f)
+		if err != nil {
+			return Vector{}, err
+		}
+		if isPlaceholder(f.Name) {
+			v.Layout.Fields = append(v.Layout.Fields, Field{Name: "_", Type: typ, Offset: f.Offset, Bits: f.Bits})
+			continue
+		}
+		field := Field{Name: f.Name, Type: typ, Offset: f.Offset, Bits: f.Bits}
+		if f.ByteOrder != layout.ByteOrder && typ != "raw" {
+			field.ByteOrder = byteOrderName(f.ByteOrder)
+		}
+		if f.BitOrder != layout.BitOrder {
+			field.BitOrder = "lsb"
+			if f.BitOrder == bitfield.MSBFirst {
+				field.BitOrder = "msb"
+			}
+		}
+		field.Encoding = f.Encoding
+		v.Layout.Fields = append(v.Layout.Fields, field)
+		fv := fieldByPath(out.Elem(), f.Name)
+		switch typ {
+		case "raw":
+			v.Values[f.Name] = hex.EncodeToString(fv.Bytes())
+		case "bool":
+			v.Values[f.Name] = strconv.FormatBool(fv.Bool())
+		case "int8", "int16", "int32", "int64":
+			v.Values[f.Name] = strconv.FormatInt(fv.Int(), 10)
+		default:
+			v.Values[f.Name] = strconv.FormatUint(fv.Uint(), 10)
+		}
+	}
+	return v, nil
+}
+
+func byteOrderName(order bitfield.ByteOrder) string {
+	if order == bitfield.BigEndian {
+		return "big"
+	}
+	return "little"
+}
+
+// fieldByPath returns the field of a struct at a path such as "Header.Addr[2]".
+func fieldByPath(v reflect.Value, path string) reflect.Value {
+	for _, name := range strings.Split(path, ".") {
+		name, index, isElement := strings.Cut(name, "[")
+		v = v.FieldByName(name)
+		if isElement {
+			i, _ := strconv.Atoi(strings.TrimSuffix(index, "]"))
+			v = v.Index(i)
+		}
+	}
+	return v
+}
+
+// isPlaceholder reports whether a field is not stored by Unmarshal, which is
+// the case if the field or a nested struct containing it is unexported.
+func isPlaceholder(path string) bool {
+	for _, name := range strings.Split(path, ".") {
+		if !token.IsExported(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func typeName(f bitfield.FieldLayout) (string, error) {
+	if f.Type == reflect.TypeOf(bitfield.Raw(nil)) {
+		return "raw", nil
+	}
+	if _, ok := fieldTypes[f.Type.Kind().String()]; !ok {
+		return "", fmt.Errorf("golden: field %s has unsupported type %s", f.Name, f.Type)
+	}
+	return f.Type.Kind().String(), nil
+}
@@ -0,0 +1,148 @@
+package golden_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jmatsuzawa/go-bitfield"
+	"github.com/jmatsuzawa/go-bitfield/golden"
+	"github.com/stretchr/testify/assert"
+)
+
+type header struct {
+	Version uint8 `bit:"4"`
+	Class   int8  `bit:"8"`
+}
+
+type packet struct {
+	Kind     uint8        `bit:"2"`
+	Urgent   bool         `bit:"1"`
+	_        uint8        `bit:"2"`
+	Reserved bitfield.Raw `bit:"11"`
+	Length   uint16
+	Offset   int64    `bit:"40"`
+	Tags     [2]uint8 `bit:"3"`
+}
+
+type encoded struct {
+	Pos  uint16 `bit:"10" enc:"gray"`
+	Year uint16 `bit:"16" enc:"bcd"`
+	D    int8   `bit:"6" signed:"zigzag"`
+	E    int8   `bit:"5" signed:"ones"`
+	M    int8   `bit:"4,sm"`
+}
+
+type word struct {
+	Length uint16 `bit:"12"`
+	Type   uint8  `bit:"4"`
+}
+
+type mixed struct {
+	Length uint16
+	Port   uint16 `endian:"big"`
+}
+
+func TestGenerate(t *testing.T) {
+	// Setup
+	want := `{
+  "vectors": [
+    {
+      "name": "header",
+      "layout": {
+        "name": "header",
+        "byte_order": "big",
+        "bit_size": 12,
+        "fields": [
+          {
+            "name": "Version",
+            "type": "uint8",
+            "offset": 0,
+            "bits": 4
+          },
+          {
+            "name": "Class",
+            "type": "int8",
+            "offset": 4,
+            "bits": 8
+          }
+        ]
+      },
+      "input": "f30f",
+      "values": {
+        "Class": "-1",
+        "Version": "3"
+      }
+    }
+  ]
+}
+`
+
+	// Exercise
+	var got strings.Builder
+	err := golden.Generate(&got, golden.Case{
+		Value:   header{Version: 3, Class: -1},
+		Options: []bitfield.Option{bitfield.WithByteOrder(bitfield.BigEndian)},
+	})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.String())
+}
+
+func TestVerify(t *testing.T) {
+	// Setup
+	var buf bytes.Buffer
+	err := golden.Generate(&buf,
+		golden.Case{Name: "header", Value: header{Version: 0xA, Class: -100}},
+		golden.Case{Name: "packet little-endian", Value: &packet{Kind: 1, Urgent: true, Reserved: bitfield.Raw{0xFF, 0x07}, Length: 0x1234, Offset: -0x123456789, Tags: [2]uint8{7, 2}}},
+		golden.Case{
+			Name:    "packet big-endian",
+			Value:   packet{Kind: 2, Reserved: bitfield.Raw{0x01, 0x04}, Length: 0xBEEF, Offset: 0x7FFFFFFFFF},
+			Options: []bitfield.Option{bitfield.WithByteOrder(bitfield.BigEndian)},
+		},
+		golden.Case{Name: "mixed", Value: mixed{Length: 0x1234, Port: 0x5678}},
+		golden.Case{
+			Name:    "word",
+			Value:   word{Length: 0x123, Type: 5},
+			Options: []bitfield.Option{bitfield.WithByteOrder(bitfield.BigEndian), bitfield.WithWordSize(16)},
+		},
+		golden.Case{Name: "encoded", Value: encoded{Pos: 5, Year: 2024, D: -3, E: -7, M: -5}},
+		golden.Case{
+			Name:    "packet MSB-first",
+			Value:   packet{Kind: 2, Urgent: true, Reserved: bitfield.Raw{0x01, 0x04}, Length: 0xBEEF, Offset: -2, Tags: [2]uint8{1, 6}},
+			Options: []bitfield.Option{bitfield.WithBitOrder(bitfield.MSBFirst)},
+		},
+	)
+	assert.Nil(t, err)
+
+	// Exercise
+	errPass := golden.Verify(bytes.NewReader(buf.Bytes()))
+	errFail := golden.Verify(strings.NewReader(strings.Replace(buf.String(), `"Length": "48879"`, `"Length": "48878"`, 1)))
+
+	// Verify
+	assert.Nil(t, errPass)
+	assert.ErrorContains(t, errFail, "vector packet big-endian: field Length: decoded 48879, want 48878")
+}
+
+func TestVerifyError(t *testing.T) {
+	// Setup
+	testCases := map[string]string{
+		"Invalid JSON":       `{`,
+		"Unknown byte order": `{"vectors": [{"name": "a", "layout": {"byte_order": "middle"}}]}`,
+		"Unknown type":       `{"vectors": [{"name": "a", "layout": {"byte_order": "little", "fields": [{"name": "A", "type": "float", "bits": 4}]}}]}`,
+		"Invalid word size":  `{"vectors": [{"name": "a", "layout": {"byte_order": "little", "word_size": 12}}]}`,
+		"Unknown encoding":   `{"vectors": [{"name": "a", "layout": {"byte_order": "little", "fields": [{"name": "A", "type": "uint8", "bits": 4, "encoding": "excess-3"}]}}]}`,
+		"Invalid input":      `{"vectors": [{"name": "a", "layout": {"byte_order": "little"}, "input": "x"}]}`,
+	}
+
+	for name, file := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := golden.Verify(strings.NewReader(file))
+
+			// Verify
+			assert.NotNil(t, err)
+		})
+	}
+}
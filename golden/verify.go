@@ -0,0 +1,183 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/jmatsuzawa/go-bitfield"
+)
+
+// Verify reads a vector file and checks each vector against this package:
+// Unmarshal must decode the values from the input, and Marshal must encode
+// the values back into the input. The struct of each vector is built from its
+// layout at run time.
+//
+// Returns nil if all vectors pass, or an error describing every failing
+// vector.
+func Verify(r io.Reader) error {
+	var f File
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return fmt.Errorf("golden: %w", err)
+	}
+	var errs []error
+	for _, v := range f.Vectors {
+		if err := verify(v); err != nil {
+			errs = append(errs, fmt.Errorf("golden: vector %s: %w", v.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func verify(v Vector) error {
+	rt, err := structOf(v.Layout)
+	if err != nil {
+		return err
+	}
+	input, err := hex.DecodeString(v.Input)
+	if err != nil {
+		return fmt.Errorf("input: %w", err)
+	}
+	var opts []bitfield.Option
+	switch v.Layout.ByteOrder {
+	case "little":
+	case "big":
+		opts = append(opts, bitfield.WithByteOrder(bitfield.BigEndian))
+	default:
+		return fmt.Errorf("unknown byte order %q", v.Layout.ByteOrder)
+	}
+	switch v.Layout.BitOrder {
+	case "", "lsb":
+	case "msb":
+		opts = append(opts, bitfield.WithBitOrder(bitfield.MSBFirst))
+	default:
+		return fmt.Errorf("unknown bit order %q", v.Layout.BitOrder)
+	}
+	if v.Layout.WordSize != 0 {
+		opts = append(opts, bitfield.WithWordSize(v.Layout.WordSize))
+	}
+
+	out := reflect.New(rt)
+	if err := bitfield.Unmarshal(input, out.Interface(), opts...); err != nil {
+		return err
+	}
+	var errs []error
+	for i, f := range v.Layout.Fields {
+		if f.Name == "_" {
+			continue
+		}
+		fv := out.Elem().FieldByName(goName(i))
+		var got string
+		switch f.Type {
+		case "raw":
+			got = hex.EncodeToString(fv.Bytes())
+		case "bool":
+			got = strconv.FormatBool(fv.Bool())
+		case "int8", "int16", "int32", "int64":
+			got = strconv.FormatInt(fv.Int(), 10)
+		default:
+			got = strconv.FormatUint(fv.Uint(), 10)
+		}
+		if want := v.Values[f.Name]; got != want {
+			errs = append(errs, fmt.Errorf("field %s: decoded %s, want %s", f.Name, got, want))
+		}
+	}
+	data, err := bitfield.Marshal(out.Interface(), opts...)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(data, input) {
+		errs = append(errs, fmt.Errorf("encoded %x, want %s", data, v.Input))
+	}
+	return errors.Join(errs...)
+}
+
+// structOf returns a struct type with the layout. All fields are declared as
+// bit-fields, and the bits not covered by named fields as Raw padding, so that
+// the offsets do not depend on the alignment rules for plain integer fields.
+// The i-th field of the layout is named by goName(i).
+func structOf(l Layout) (reflect.Type, error) {
+	var fields []reflect.StructField
+	next := 0
+	pad := func(bits int) {
+		fields = append(fields, reflect.StructField{
+			Name: "Pad" + strconv.Itoa(len(fields)),
+			Type: reflect.TypeOf(bitfield.Raw(nil)),
+			Tag:  reflect.StructTag(`bit:"` + strconv.Itoa(bits) + `"`),
+		})
+	}
+	for i, f := range l.Fields {
+		if f.Offset < next {
+			return nil, fmt.Errorf("field %s overlaps the previous field", f.Name)
+		}
+		if f.Offset > next {
+			pad(f.Offset - next)
+		}
+		next = f.Offset + f.Bits
+		if f.Name == "_" {
+			pad(f.Bits)
+			continue
+		}
+		typ, ok := fieldTypes[f.Type]
+		if f.Type == "raw" {
+			typ, ok = reflect.TypeOf(bitfield.Raw(nil)), true
+		}
+		if !ok {
+			return nil, fmt.Errorf("field %s has unknown type %q", f.Name, f.Type)
+		}
+		tag := `bit:"` + strconv.Itoa(f.Bits)
+		switch f.BitOrder {
+		case "":
+		case "lsb", "msb":
+			tag += "," + f.BitOrder
+			// The following field starts from the next byte
+			next = (next + 7) / 8 * 8
+		default:
+			return nil, fmt.Errorf("field %s has unknown bit order %q", f.Name, f.BitOrder)
+		}
+		tag += `"`
+		switch f.ByteOrder {
+		case "":
+		case "little", "big":
+			tag += ` endian:"` + f.ByteOrder + `"`
+		default:
+			return nil, fmt.Errorf("field %s has unknown byte order %q", f.Name, f.ByteOrder)
+		}
+		switch f.Encoding {
+		case "":
+		case "bcd", "gray":
+			tag += ` enc:"` + f.Encoding + `"`
+		case "magnitude", "zigzag", "ones":
+			tag += ` signed:"` + f.Encoding + `"`
+		default:
+			return nil, fmt.Errorf("field %s has unknown encoding %q", f.Name, f.Encoding)
+		}
+		fields = append(fields, reflect.StructField{
+			Name: goName(i),
+			Type: typ,
+			Tag:  reflect.StructTag(tag),
+		})
+	}
+	return reflect.StructOf(fields), nil
+}
+
+func goName(i int) string {
+	return "F" + strconv.Itoa(i)
+}
+
+var fieldTypes = map[string]reflect.Type{
+	"bool":   reflect.TypeOf(false),
+	"uint8":  reflect.TypeOf(uint8(0)),
+	"uint16": reflect.TypeOf(uint16(0)),
+	"uint32": reflect.TypeOf(uint32(0)),
+	"uint64": reflect.TypeOf(uint64(0)),
+	"int8":   reflect.TypeOf(int8(0)),
+	"int16":  reflect.TypeOf(int16(0)),
+	"int32":  reflect.TypeOf(int32(0)),
+	"int64":  reflect.TypeOf(int64(0)),
+}
@@ -0,0 +1,404 @@
+package bitfield
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strconv"
+)
+
+// FieldLayout describes the position of a field in the data parsed by
+// [Unmarshal].
+type FieldLayout struct {
+	// Name is the name of the struct field. Placeholders are named "_".
+	// Fields of nested structs are named by their path, such as
+	// "Header.Version". Fields of embedded structs are named as promoted
+	// fields, without the name of the embedded struct. Elements of array
+	// fields are named with their index, such as "Addr[0]".
+	Name string
+	// Type is the type of the struct field.
+	Type reflect.Type
+	// Offset is the bit offset of the field from the least significant bit of
+	// the first byte, or from the most significant bit with [MSBFirst].
+	Offset int
+	// Bits is the bit size of the field.
+	Bits int
+	// Signed reports whether the field is a signed integer.
+	Signed bool
+	// Encoding is the encoding of the bits of the field, or empty for plain
+	// binary and two's complement. It is "magnitude", "ones" or "zigzag" for
+	// a signed field given by the modifier sm of its bit tag or a signed tag,
+	// the value of an enc tag such as "bcd", "gray" or "float16", or the
+	// scale or qformat tag of a float field with its value, such as
+	// "scale:0.5,offset=-40" or "qformat:8.8".
+	Encoding string
+	// Access is the access mode of the field given by a struct tag
+	// "access", for fields of hardware registers.
+	Access Access
+	// ByteOrder is the byte order in which the field is parsed. It is the
+	// byte order of the layout unless overridden by a struct tag "endian" or
+	// a modifier of the bit tag.
+	ByteOrder ByteOrder
+	// BitOrder is the order in which the field fills its bytes. It is the bit
+	// order of the layout unless overridden by a modifier of the bit tag.
+	BitOrder BitOrder
+	// Description is the text of a struct tag "desc", which documents the
+	// field in tables generated from the layout. It does not affect parsing.
+	Description string
+}
+
+// Access is the access mode of a field of a hardware register, as annotated
+// in datasheets and SVD files. It is given by a struct tag "access":
+//
+//	type status struct {
+//		Ready   bool  `bit:"1" access:"ro"`
+//		Reset   bool  `bit:"1" access:"wo"`
+//		Error   bool  `bit:"1" access:"w1c"`
+//		Channel uint8 `bit:"5"`
+//	}
+//
+// The access mode is recorded in the [Layout] for tools working with
+// registers. It does not affect Unmarshal and Marshal.
+type Access int
+
+const (
+	// ReadWrite is the access mode of fields without an access tag, or with
+	// access:"rw".
+	ReadWrite Access = iota
+	// ReadOnly is the access mode of fields with access:"ro".
+	ReadOnly
+	// WriteOnly is the access mode of fields with access:"wo".
+	WriteOnly
+	// WriteOneToClear is the access mode of fields with access:"w1c", which
+	// are cleared by writing 1 and left unchanged by writing 0.
+	WriteOneToClear
+)
+
+var accessTags = map[string]Access{
+	"rw":  ReadWrite,
+	"ro":  ReadOnly,
+	"wo":  WriteOnly,
+	"w1c": WriteOneToClear,
+}
+
+// String returns the tag value of the access mode, such as "ro".
+func (a Access) String() string {
+	switch a {
+	case ReadWrite:
+		return "rw"
+	case ReadOnly:
+		return "ro"
+	case WriteOnly:
+		return "wo"
+	case WriteOneToClear:
+		return "w1c"
+	default:
+		return "Access(" + strconv.Itoa(int(a)) + ")"
+	}
+}
+
+// fieldAccess returns the access mode of a field, and false if its access tag
+// is invalid.
+func fieldAccess(field reflect.StructField) (Access, bool) {
+	tag, ok := field.Tag.Lookup("access")
+	if !ok {
+		return ReadWrite, true
+	}
+	access, ok := accessTags[tag]
+	return access, ok
+}
+
+// Layout describes how a struct with bit-fields is mapped onto a byte slice.
+type Layout struct {
+	// Name is the name of the struct type. It is empty for an anonymous struct.
+	Name string
+	// ByteOrder is the byte order in which multi-byte fields are parsed.
+	ByteOrder ByteOrder
+	// BitOrder is the order in which the fields fill each byte.
+	BitOrder BitOrder
+	// WordSize is the size in bits of the words in which the fields are
+	// grouped, given by [WithWordSize], or 0 without the option. With words
+	// larger than a byte, the offsets of the fields are positions within the
+	// values of the words.
+	WordSize int
+	// Fields lists the fields which occupy bits, in order of their offset.
+	// Fields ignored by Unmarshal are not included.
+	Fields []FieldLayout
+	// BitSize is the total number of bits covered by the fields.
+	BitSize int
+}
+
+// LayoutOf returns the layout of a struct with bit-fields. v must be a struct
+// or a pointer to a struct. The pointer may be nil, as only its type is used.
+//
+// The options are the same as those for [Unmarshal].
+//
+// Returns:
+//
+//   - the layout of the struct and nil if the struct is valid
+//   - [FieldError] if the struct has an invalid bit-field, a variable-length
+//     field such as a slice with a count, len or tlv tag, or a byte order mark
+//   - [TypeError] if v is neither a struct nor a pointer to a struct
+func LayoutOf(v any, opts ...Option) (*Layout, error) {
+	rt, err := structTypeOf(v)
+	if err != nil {
+		return nil, err
+	}
+	options, err := collectOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateStruct(rt, options); err != nil {
+		return nil, err
+	}
+	if field, path, ok := variableField(rt, ""); ok {
+		return nil, &FieldError{
+			Field:   field,
+			Path:    path,
+			problem: "variable-length field has no fixed layout",
+		}
+	}
+	if field, path, ok := byteOrderMark(rt, ""); ok {
+		return nil, &FieldError{
+			Field:   field,
+			Path:    path,
+			problem: "byte order mark makes byte order depend on data",
+		}
+	}
+	return layoutOf(rt, options), nil
+}
+
+// BitSize returns the number of bits occupied by the encoding of a struct,
+// which is the bit size of its [Layout]. v must be a struct or a pointer to a
+// struct. Use it to allocate buffers, or to check a struct against the length
+// stated by a specification.
+//
+// For a struct with variable-length fields, the size depends on the lengths
+// of the slices of v, and the pointer must not be nil. The size is then that
+// of the encoding by [Marshal], whose errors are returned.
+//
+// The options are the same as those for [Unmarshal].
+//
+// Returns:
+//
+//   - the bit size of the struct and nil if the struct is valid
+//   - [FieldError] if the struct has an invalid bit-field, or a
+//     variable-length field and v is a nil pointer
+//   - [LengthError] or [DepthError] as returned by Marshal for a struct with
+//     variable-length fields
+//   - [TypeError] if v is neither a struct nor a pointer to a struct
+func BitSize(v any, opts ...Option) (int, error) {
+	rt, err := structTypeOf(v)
+	if err != nil {
+		return 0, err
+	}
+	options, err := collectOptions(opts)
+	if err != nil {
+		return 0, err
+	}
+	if err := validateStruct(rt, options); err != nil {
+		return 0, err
+	}
+	field, path, ok := variableField(rt, "")
+	if !ok {
+		return options.wordEnd(encodedBitSize(rt)), nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return 0, &FieldError{
+				Field:   field,
+				Path:    path,
+				problem: "variable-length field has no size without value",
+			}
+		}
+		rv = rv.Elem()
+	}
+	w := &bitWriter{bitOrder: options.bitOrder, wordSize: options.wordSize}
+	if err := marshal(w, addressable(rv, options), "", true, options); err != nil {
+		return 0, err
+	}
+	return options.wordEnd(w.iData*8 + w.iBitInData), nil
+}
+
+// Size is like [BitSize] but returns the number of bytes occupied by the
+// encoding of a struct. A struct ending in the middle of a byte occupies the
+// whole byte.
+func Size(v any, opts ...Option) (int, error) {
+	bitSize, err := BitSize(v, opts...)
+	return (bitSize + 7) / 8, err
+}
+
+// Hash returns a fingerprint of the wire format described by the layout. Peers
+// can exchange it to check that they use the same version of a format.
+//
+// The fingerprint covers the byte and bit orders, the word size, the bit size,
+// and the offset, the bit size, the signedness, the encoding and any byte or
+// bit order override of each field. Names and Go types of the fields do not
+// affect it, except that [Raw] fields differ from integer fields, since the
+// byte order does not apply to them. The value is stable across builds and
+// platforms.
+func (l *Layout) Hash() uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d:%d", l.ByteOrder, l.BitSize)
+	if l.BitOrder == MSBFirst {
+		h.Write([]byte(":msb"))
+	}
+	if l.WordSize > 8 {
+		fmt.Fprintf(h, ":w%d", l.WordSize)
+	}
+	for _, f := range l.Fields {
+		kind := "u"
+		switch {
+		case f.Type == rawType || isAddrType(f.Type):
+			kind = "r"
+		case f.Signed:
+			kind = "s"
+		}
+		switch f.Encoding {
+		case "":
+		case "magnitude":
+			kind += "m"
+		default:
+			kind += "(" + f.Encoding + ")"
+		}
+		if f.ByteOrder != l.ByteOrder && f.Type != rawType && !isAddrType(f.Type) {
+			// Mark fields overriding the byte order of the layout
+			kind += "e"
+		}
+		if f.BitOrder != l.BitOrder {
+			kind += "b"
+		}
+		fmt.Fprintf(h, ";%d+%d%s", f.Offset, f.Bits, kind)
+	}
+	return h.Sum64()
+}
+
+// layoutOf returns the layout of a validated struct type. For a struct with a
+// variable-length field, the layout covers the fields before it.
+func layoutOf(rt reflect.Type, options options) *Layout {
+	layout := &Layout{
+		Name:      rt.Name(),
+		ByteOrder: options.byteOrder,
+		BitOrder:  options.bitOrder,
+		WordSize:  options.wordSize,
+	}
+	layout.BitSize, _ = layout.addFields(rt, "", 0)
+	return layout
+}
+
+// addFields adds the fields of a struct starting at offset, and returns the
+// offset following them. prefix is the path of the struct followed by a dot.
+// fixed is false if a variable-length field stopped the layout.
+func (l *Layout) addFields(rt reflect.Type, prefix string, offset int) (end int, fixed bool) {
+	if hasOffsets(rt) {
+		start := (offset + 7) / 8 * 8
+		for _, f := range offsetFields(rt) {
+			l.addField(rt.Field(f.index), prefix, start+f.offset)
+		}
+		return offsetsEnd(rt, offset), true
+	}
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if isArray(field) {
+			for j := 0; j < field.Type.Len(); j++ {
+				if offset, fixed = l.addField(arrayElement(field, j), prefix, offset); !fixed {
+					return offset, false
+				}
+			}
+			continue
+		}
+		if offset, fixed = l.addField(field, prefix, offset); !fixed {
+			return offset, false
+		}
+	}
+	return offset, true
+}
+
+// addField adds a field of a struct, or an element of an array field.
+func (l *Layout) addField(field reflect.StructField, prefix string, offset int) (end int, fixed bool) {
+	if isVariable(field) || isTLV(field) || isRest(field) || isVarint(field) || isSwitch(field) || isSized(field) || isConditional(field) {
+		return offset, false
+	}
+	offset += fieldSkip(field)
+	if isNestedStruct(field) {
+		return l.addFields(field.Type, nestedPrefix(prefix, field), offset)
+	}
+	bitSize, byteAligned, ok := fieldBitSize(field)
+	if !ok {
+		return offset, true
+	}
+	if byteAligned {
+		// Plain integer fields start from the next byte
+		offset = (offset + 7) / 8 * 8
+	}
+	access, _ := fieldAccess(field)
+	modifiers := fieldModifiers(field)
+	bitOrder := l.BitOrder
+	if modifiers.ownBitOrder {
+		bitOrder = modifiers.bitOrder
+	}
+	l.Fields = append(l.Fields, FieldLayout{
+		Name:        prefix + field.Name,
+		Type:        field.Type,
+		Offset:      offset,
+		Bits:        bitSize,
+		Signed:      isSignedInteger(optionalElement(field).Type.Kind()),
+		Encoding:    fieldEncoding(field, modifiers),
+		Access:      access,
+		ByteOrder:   fieldByteOrder(field, l.ByteOrder),
+		BitOrder:    bitOrder,
+		Description: field.Tag.Get("desc"),
+	})
+	if modifiers.ownBitOrder {
+		// The following field starts from the next byte
+		return (offset + bitSize + 7) / 8 * 8, true
+	}
+	return offset + bitSize, true
+}
+
+// fieldEncoding returns the encoding of a field recorded in its layout.
+func fieldEncoding(field reflect.StructField, modifiers bitModifiers) string {
+	if modifiers.signMagnitude {
+		return "magnitude"
+	}
+	if signed, ok := field.Tag.Lookup("signed"); ok {
+		return signed
+	}
+	for _, tag := range []string{"scale", "qformat"} {
+		if value, ok := field.Tag.Lookup(tag); ok {
+			return tag + ":" + value
+		}
+	}
+	return field.Tag.Get("enc")
+}
+
+func isSignedInteger(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	default:
+		return false
+	}
+}
+
+func structTypeOf(v any) (reflect.Type, error) {
+	errMsg := "layout object must be struct or pointer to struct"
+	rt := reflect.TypeOf(v)
+	if rt == nil {
+		return nil, &TypeError{
+			Type:    rt,
+			problem: errMsg + " (nil passed)",
+		}
+	}
+	if rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return nil, &TypeError{
+			Type:    reflect.TypeOf(v),
+			problem: errMsg + " (" + reflect.TypeOf(v).String() + " passed)",
+		}
+	}
+	return rt, nil
+}
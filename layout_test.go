@@ -0,0 +1,454 @@
+package bitfield
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayoutOf(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8 `bit:"4"`
+		B int16 `bit:"10"`
+		C uint16
+		_ uint8 `bit:"3"`
+		D Raw   `bit:"9"`
+		E string
+	}
+	want := &Layout{
+		Name:      "a",
+		ByteOrder: BigEndian,
+		Fields: []FieldLayout{
+			{Name: "A", Type: reflect.TypeOf(uint8(0)), Offset: 0, Bits: 4, ByteOrder: BigEndian},
+			{Name: "B", Type: reflect.TypeOf(int16(0)), Offset: 4, Bits: 10, Signed: true, ByteOrder: BigEndian},
+			{Name: "C", Type: reflect.TypeOf(uint16(0)), Offset: 16, Bits: 16, ByteOrder: BigEndian},
+			{Name: "_", Type: reflect.TypeOf(uint8(0)), Offset: 32, Bits: 3, ByteOrder: BigEndian},
+			{Name: "D", Type: reflect.TypeOf(Raw{}), Offset: 35, Bits: 9, ByteOrder: BigEndian},
+		},
+		BitSize: 44,
+	}
+
+	testCases := map[string]any{
+		"Struct":      a{},
+		"Pointer":     &a{},
+		"Nil pointer": (*a)(nil),
+	}
+	for name, v := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := LayoutOf(v, WithByteOrder(BigEndian))
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestLayoutOf_NestedStruct(t *testing.T) {
+	// Setup
+	type Inner struct {
+		B uint8 `bit:"3"`
+		_ uint8 `bit:"2"`
+	}
+	type outer struct {
+		A uint8 `bit:"4"`
+		Inner
+		hid Inner
+		C   uint8
+	}
+	u8 := reflect.TypeOf(uint8(0))
+	want := &Layout{
+		Name: "outer",
+		Fields: []FieldLayout{
+			{Name: "A", Type: u8, Offset: 0, Bits: 4},
+			{Name: "B", Type: u8, Offset: 4, Bits: 3},
+			{Name: "_", Type: u8, Offset: 7, Bits: 2},
+			{Name: "hid.B", Type: u8, Offset: 9, Bits: 3},
+			{Name: "hid._", Type: u8, Offset: 12, Bits: 2},
+			{Name: "C", Type: u8, Offset: 16, Bits: 8},
+		},
+		BitSize: 24,
+	}
+
+	// Exercise
+	got, err := LayoutOf(outer{})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestLayoutOf_Array(t *testing.T) {
+	// Setup
+	type Point struct {
+		X uint8 `bit:"4"`
+	}
+	type a struct {
+		Addr   [2]byte
+		Points [2]Point
+	}
+	u8 := reflect.TypeOf(uint8(0))
+	want := []FieldLayout{
+		{Name: "Addr[0]", Type: u8, Offset: 0, Bits: 8},
+		{Name: "Addr[1]", Type: u8, Offset: 8, Bits: 8},
+		{Name: "Points[0].X", Type: u8, Offset: 16, Bits: 4},
+		{Name: "Points[1].X", Type: u8, Offset: 20, Bits: 4},
+	}
+
+	// Exercise
+	got, err := LayoutOf(a{})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.Fields)
+	assert.Equal(t, 24, got.BitSize)
+}
+
+func TestLayoutOf_OffsetTag(t *testing.T) {
+	// Setup
+	type a struct {
+		Flags   uint8  `bit:"4" offset:"28"`
+		Version uint8  `bit:"4" offset:"0"`
+		Length  uint16 `offset:"8"`
+	}
+	u8 := reflect.TypeOf(uint8(0))
+	want := []FieldLayout{
+		{Name: "Version", Type: u8, Offset: 0, Bits: 4},
+		{Name: "Length", Type: reflect.TypeOf(uint16(0)), Offset: 8, Bits: 16},
+		{Name: "Flags", Type: u8, Offset: 28, Bits: 4},
+	}
+
+	// Exercise
+	got, err := LayoutOf(a{})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.Fields)
+	assert.Equal(t, 32, got.BitSize)
+}
+
+func TestLayoutOf_BitTagModifiers(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8  `bit:"3"`
+		B uint8  `bit:"3,msb"`
+		C uint16 `bit:"12,be"`
+		D int8   `bit:"4,sm"`
+		E int8   `bit:"4" signed:"magnitude"`
+	}
+	u8 := reflect.TypeOf(uint8(0))
+	want := []FieldLayout{
+		{Name: "A", Type: u8, Offset: 0, Bits: 3},
+		{Name: "B", Type: u8, Offset: 8, Bits: 3, BitOrder: MSBFirst},
+		{Name: "C", Type: reflect.TypeOf(uint16(0)), Offset: 16, Bits: 12, ByteOrder: BigEndian},
+		{Name: "D", Type: reflect.TypeOf(int8(0)), Offset: 28, Bits: 4, Signed: true, Encoding: "magnitude"},
+		{Name: "E", Type: reflect.TypeOf(int8(0)), Offset: 32, Bits: 4, Signed: true, Encoding: "magnitude"},
+	}
+
+	// Exercise
+	got, err := LayoutOf(a{})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.Fields)
+	assert.Equal(t, 36, got.BitSize)
+}
+
+func TestLayoutOf_Encoding(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8   `bit:"4"`
+		B uint8   `bit:"4" enc:"gray"`
+		C uint16  `bit:"12" enc:"bcd"`
+		D int8    `bit:"6" signed:"zigzag"`
+		E int8    `bit:"5" signed:"ones"`
+		F float32 `bit:"16" enc:"float16"`
+		G float64 `bit:"8" scale:"0.5,offset=-40"`
+		H float64 `qformat:"8.8"`
+	}
+
+	// Exercise
+	got, err := LayoutOf(a{})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, "", got.Fields[0].Encoding)
+	assert.Equal(t, "gray", got.Fields[1].Encoding)
+	assert.Equal(t, "bcd", got.Fields[2].Encoding)
+	assert.Equal(t, "zigzag", got.Fields[3].Encoding)
+	assert.Equal(t, "ones", got.Fields[4].Encoding)
+	assert.Equal(t, "float16", got.Fields[5].Encoding)
+	assert.Equal(t, "scale:0.5,offset=-40", got.Fields[6].Encoding)
+	assert.Equal(t, "qformat:8.8", got.Fields[7].Encoding)
+}
+
+func TestLayoutOf_Access(t *testing.T) {
+	// Setup
+	type status struct {
+		Ready   bool  `bit:"1" access:"ro"`
+		Reset   bool  `bit:"1" access:"wo"`
+		Error   bool  `bit:"1" access:"w1c"`
+		Channel uint8 `bit:"5" access:"rw"`
+		Mode    uint8
+	}
+	var invalid struct {
+		A uint8 `bit:"4" access:"rc"`
+	}
+
+	// Exercise
+	got, err := LayoutOf(status{})
+	_, errInvalid := LayoutOf(invalid)
+
+	// Verify
+	assert.Nil(t, err)
+	var access []Access
+	for _, f := range got.Fields {
+		access = append(access, f.Access)
+	}
+	assert.Equal(t, []Access{ReadOnly, WriteOnly, WriteOneToClear, ReadWrite, ReadWrite}, access)
+	assert.Equal(t, "w1c", WriteOneToClear.String())
+	var fieldError *FieldError
+	assert.ErrorAs(t, errInvalid, &fieldError)
+	assert.Equal(t, "A", fieldError.Path)
+}
+
+func TestLayoutOfError(t *testing.T) {
+	// Setup
+	var integer int
+	var invalidField struct {
+		A uint8 `bit:"9"`
+	}
+	var variable struct {
+		N       uint8
+		Entries []*struct{ A uint8 } `count:"N"`
+	}
+
+	// Exercise
+	_, errNil := LayoutOf(nil)
+	_, errInt := LayoutOf(&integer)
+	_, errField := LayoutOf(invalidField)
+	_, errVariable := LayoutOf(variable)
+
+	// Verify
+	var typeError *TypeError
+	var fieldError *FieldError
+	assert.ErrorAs(t, errNil, &typeError)
+	assert.ErrorAs(t, errInt, &typeError)
+	assert.ErrorAs(t, errField, &fieldError)
+	assert.ErrorAs(t, errVariable, &fieldError)
+	assert.Equal(t, "Entries", fieldError.Path)
+}
+
+func TestSize(t *testing.T) {
+	// Setup
+	type header struct {
+		Version uint8 `bit:"4"`
+		IHL     uint8 `bit:"4"`
+		Length  uint16
+		Flags   uint8 `bit:"3"`
+	}
+	type record struct {
+		Len     uint8
+		Payload []byte `len:"Len"`
+	}
+	testCases := map[string]struct {
+		argV        any
+		wantBitSize int
+		wantSize    int
+	}{
+		"Fixed": {
+			argV:        header{},
+			wantBitSize: 27,
+			wantSize:    4,
+		},
+		"NilPointer": {
+			argV:        (*header)(nil),
+			wantBitSize: 27,
+			wantSize:    4,
+		},
+		"Variable": {
+			argV:        &record{Len: 3, Payload: []byte{1, 2, 3}},
+			wantBitSize: 32,
+			wantSize:    4,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			gotBitSize, errBitSize := BitSize(tc.argV)
+			gotSize, errSize := Size(tc.argV)
+
+			// Verify
+			assert.Nil(t, errBitSize)
+			assert.Nil(t, errSize)
+			assert.Equal(t, tc.wantBitSize, gotBitSize)
+			assert.Equal(t, tc.wantSize, gotSize)
+		})
+	}
+}
+
+func TestSizeError(t *testing.T) {
+	// Setup
+	type record struct {
+		Len     uint8
+		Payload []byte `len:"Len"`
+	}
+
+	// Exercise
+	_, errType := Size(1)
+	_, errNil := Size((*record)(nil))
+	_, errLength := Size(record{Len: 2})
+
+	// Verify
+	var typeError *TypeError
+	var fieldError *FieldError
+	var lengthError *LengthError
+	assert.ErrorAs(t, errType, &typeError)
+	assert.ErrorAs(t, errNil, &fieldError)
+	assert.ErrorAs(t, errLength, &lengthError)
+}
+
+func TestLayoutHash(t *testing.T) {
+	// Setup
+	type base struct {
+		A uint8 `bit:"4"`
+		B int8  `bit:"4"`
+		C uint16
+	}
+	type renamed struct {
+		X uint16 `bit:"4"`
+		Y int32  `bit:"4"`
+		Z uint16
+	}
+	baseLayout, _ := LayoutOf(base{})
+	testCases := map[string]struct {
+		argV    any
+		argOpts []Option
+		same    bool
+	}{
+		"Same layout with other names and types": {
+			argV: renamed{},
+			same: true,
+		},
+		"Different byte order": {
+			argV:    base{},
+			argOpts: []Option{WithByteOrder(BigEndian)},
+		},
+		"Different bit order": {
+			argV:    base{},
+			argOpts: []Option{WithBitOrder(MSBFirst)},
+		},
+		"Different signedness": {
+			argV: struct {
+				A uint8 `bit:"4"`
+				B uint8 `bit:"4"`
+				C uint16
+			}{},
+		},
+		"Different width": {
+			argV: struct {
+				A uint8 `bit:"4"`
+				B int8  `bit:"4"`
+				C uint32
+			}{},
+		},
+		"Byte order override": {
+			argV: struct {
+				A uint8  `bit:"4"`
+				B int8   `bit:"4"`
+				C uint16 `endian:"big"`
+			}{},
+		},
+		"Tag matching byte order": {
+			argV: struct {
+				A uint8  `bit:"4"`
+				B int8   `bit:"4"`
+				C uint16 `endian:"little"`
+			}{},
+			same: true,
+		},
+		"Sign-magnitude": {
+			argV: struct {
+				A uint8 `bit:"4"`
+				B int8  `bit:"4,sm"`
+				C uint16
+			}{},
+		},
+		"Ones' complement": {
+			argV: struct {
+				A uint8 `bit:"4"`
+				B int8  `bit:"4" signed:"ones"`
+				C uint16
+			}{},
+		},
+		"Zigzag": {
+			argV: struct {
+				A uint8 `bit:"4"`
+				B int8  `bit:"4" signed:"zigzag"`
+				C uint16
+			}{},
+		},
+		"Gray code": {
+			argV: struct {
+				A uint8 `bit:"4" enc:"gray"`
+				B int8  `bit:"4"`
+				C uint16
+			}{},
+		},
+		"Scaled float": {
+			argV: struct {
+				A uint8   `bit:"4"`
+				B int8    `bit:"4"`
+				C float64 `bit:"16" scale:"0.5"`
+			}{},
+		},
+		"Raw instead of integer": {
+			argV: struct {
+				A Raw  `bit:"4"`
+				B int8 `bit:"4"`
+				C uint16
+			}{},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			layout, err := LayoutOf(tc.argV, tc.argOpts...)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.same, baseLayout.Hash() == layout.Hash())
+		})
+	}
+}
+
+func TestLayoutHash_Encoding(t *testing.T) {
+	// Setup
+	half, _ := LayoutOf(struct {
+		A float64 `bit:"16" scale:"0.5"`
+	}{})
+	quarter, _ := LayoutOf(struct {
+		A float64 `bit:"16" scale:"0.25"`
+	}{})
+	q88, _ := LayoutOf(struct {
+		A float64 `qformat:"8.8"`
+	}{})
+	q115, _ := LayoutOf(struct {
+		A float64 `qformat:"1.15"`
+	}{})
+
+	// Exercise
+	hashes := []uint64{half.Hash(), quarter.Hash(), q88.Hash(), q115.Hash()}
+
+	// Verify
+	for i := range hashes {
+		for j := i + 1; j < len(hashes); j++ {
+			assert.NotEqual(t, hashes[i], hashes[j])
+		}
+	}
+}
@@ -0,0 +1,625 @@
+package bitfield
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Marshal encodes a struct with bit-fields into a byte slice. It is the
+// inverse of [Unmarshal]: the bit tags and the rules for plain integer fields
+// are the same, so that Unmarshal restores the struct from the result of
+// Marshal.
+//
+//	type bitFields struct {
+//		A uint8 `bit:"1"`
+//		B uint8 `bit:"2"`
+//		_ uint8 `bit:"1"`
+//		C uint8 `bit:"4"`
+//	}
+//	data, _ := bitfield.Marshal(bitFields{A: 0b1, B: 0b10, C: 0b1010})
+//	fmt.Printf("%#x\n", data)
+//	// Output: 0xa5
+//
+// Placeholders and other unexported fields are encoded as zero, and fields with
+// a const tag as the constant if they are zero. Fields with an auto tag are
+// encoded as their counts in the [Sequence] given by [WithSequence], if any,
+// whatever their value. Fields with a check tag are encoded as the checksum of
+// their range of bytes, whatever their value, so that a decoded frame is
+// encoded to the same bytes. Bits skipped before a plain integer field are
+// zero, and so are the unused bits of the last byte. Fields with an if tag
+// whose condition does not hold are not encoded. Nil pointers to integers or
+// bools are encoded as zero, and the flag bits named by the presentif tags of
+// fields which are not zero are set. The length of the result is the number of
+// bytes needed for all fields. Field types encoding their own bits implement
+// [BitMarshaler].
+//
+// The encoding is canonical: a value is always encoded to the same bytes,
+// which depend neither on the previous contents of the buffer passed to
+// [MarshalAppend] or [MarshalInto] nor on options which only affect decoding,
+// save for the counts of a Sequence.
+// Bits of a [Raw] value beyond the bit size of the field and the values of
+// ignored fields never reach the output. Encoded structs can thus be signed
+// or hashed, and compared byte for byte.
+//
+// Multi-byte data is encoded in little-endian by default. [WithByteOrder]
+// changes the byte order as for Unmarshal. Options which only affect decoding,
+// such as [WithDecodeHook], are ignored.
+//
+// Returns:
+//
+//   - the encoded bytes and nil if the struct is successfully encoded
+//   - [FieldError] if the struct has an invalid bit-field
+//   - [OverflowError] if the value of a field does not fit in its bit size
+//   - [LengthError] if the range of bytes of a field with a check tag ends
+//     beyond the encoding of its struct
+//   - [ConstError] if a field with a const tag holds a value other than zero
+//     or the constant
+//   - [VariantError] if a field with a switch tag does not hold the variant
+//     registered for its discriminator
+//   - [LengthError] if the length of a slice differs from its count or len
+//     field
+//   - [DepthError] if structs are nested deeper than the limit, as with a
+//     cycle of pointers
+//   - [TypeError] if v is neither a struct nor a non-nil pointer to a struct
+func Marshal(v any, opts ...Option) ([]byte, error) {
+	return marshalAppend(nil, v, opts)
+}
+
+// MarshalAppend is like [Marshal] but appends the encoded bytes to dst and
+// returns the extended slice. It does not allocate if dst has enough capacity.
+//
+// On error, dst is returned unchanged along with the same errors as Marshal.
+func MarshalAppend(dst []byte, v any, opts ...Option) ([]byte, error) {
+	return marshalAppend(dst, v, opts)
+}
+
+// MarshalInto is like [Marshal] but writes the encoded bytes to the start of
+// buf and returns the number of bytes written. It never allocates a buffer.
+//
+// Returns:
+//
+//   - the number of bytes written and nil if the struct is successfully
+//     encoded
+//   - an error wrapping [io.ErrShortBuffer] if buf is shorter than the
+//     encoded bytes; buf is not modified
+//   - the same errors as Marshal
+func MarshalInto(buf []byte, v any, opts ...Option) (n int, err error) {
+	rv, err := marshaledValue(v)
+	if err != nil {
+		return 0, err
+	}
+	options, err := collectOptions(opts)
+	if err != nil {
+		return 0, err
+	}
+	if err := validateMarshalType(rv.Type(), options); err != nil {
+		return 0, err
+	}
+	// A value nested too deep is left to marshalAppend to report
+	end, ok := valueEnd(rv, 0, options.depthLimit())
+	if size := (options.wordEnd(end) + 7) / 8; ok && len(buf) < size {
+		return 0, fmt.Errorf("bitfield: buffer of %d bytes is too short for %d bytes: %w", len(buf), size, io.ErrShortBuffer)
+	}
+	data, err := marshalAppend(buf[:0], v, opts)
+	return len(data), err
+}
+
+func marshalAppend(dst []byte, v any, opts []Option) ([]byte, error) {
+	rv, err := marshaledValue(v)
+	if err != nil {
+		return dst, err
+	}
+	options, err := collectOptions(opts)
+	if err != nil {
+		return dst, err
+	}
+	if err := validateMarshalType(rv.Type(), options); err != nil {
+		return dst, err
+	}
+	return appendStruct(dst, rv, options)
+}
+
+// appendStruct appends the encoding of a validated struct value to dst. On
+// error, dst is returned unchanged.
+func appendStruct(dst []byte, rv reflect.Value, options options) ([]byte, error) {
+	rv = addressable(rv, options)
+	w := &bitWriter{data: dst, iData: len(dst), origin: len(dst), bitOrder: options.bitOrder, wordSize: options.wordSize}
+	if err := marshal(w, rv, "", true, options); err != nil {
+		return dst, err
+	}
+	w.alignToWord()
+	if options.sequence != nil {
+		options.sequence.advance(w.autos)
+	}
+	return w.data, nil
+}
+
+// marshaledValue returns the struct to be encoded from a struct or a pointer
+// to a struct.
+func marshaledValue(v any) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Struct {
+		return rv, nil
+	}
+	if err := ensureNonNilPointerToStruct(v); err != nil {
+		return reflect.Value{}, err
+	}
+	return rv.Elem(), nil
+}
+
+// addressable returns an addressable copy of a struct passed by value if its
+// unexported fields are to be read, which is done through their address.
+func addressable(rv reflect.Value, options options) reflect.Value {
+	if options.unexported != SetUnexported || rv.CanAddr() {
+		return rv
+	}
+	p := reflect.New(rv.Type())
+	p.Elem().Set(rv)
+	return p.Elem()
+}
+
+// validateMarshalType validates a struct type to encode. Options only for
+// decoding do not apply.
+func validateMarshalType(rt reflect.Type, options options) error {
+	options.decodeHook = nil
+	options.encoding = true
+	return validateStruct(rt, options)
+}
+
+// marshal writes the fields of a struct. prefix is the path of the struct
+// followed by a dot, or empty for the top-level struct, and exported reports
+// whether the fields of the struct are accessible.
+func marshal(w *bitWriter, rv reflect.Value, prefix string, exported bool, options options) error {
+	plan := planOf(rv.Type())
+	if plan.offsets {
+		return marshalOffsets(w, rv, plan, prefix, exported, options)
+	}
+	var counts []uint64 // values of the fields, kept for variable-length fields
+	if plan.variable {
+		counts = make([]uint64, len(plan.fields))
+	}
+	var flags []uint64
+	if exported && plan.variable {
+		flags = presenceFlags(rv, plan)
+	}
+	start := w.iData
+	var checked []checkedField // fields with check tags, written at the end
+	for iField := range plan.fields {
+		f := &plan.fields[iField]
+		vf, accessible := settableField(f.StructField, rv.Field(iField), exported, options)
+		if f.cond != nil && !f.cond.holds(counts[f.cond.field]) {
+			continue
+		}
+		if flags != nil && flags[iField] != 0 && accessible {
+			// The flag bits of the present fields with presentif tags
+			vf = reflect.ValueOf(vf.Uint() | flags[iField]).Convert(vf.Type())
+		}
+		if f.elem != nil {
+			elem := *f.elem
+			if f.guid && accessible {
+				guid := reflect.New(vf.Type()).Elem()
+				guid.Set(vf)
+				swapGUID(guid.Slice(0, guid.Len()).Bytes())
+				vf = guid
+			}
+			for i := 0; i < vf.Len(); i++ {
+				elem.Name = f.names[i]
+				if err := marshalField(w, &elem, vf.Index(i), prefix, accessible, exported, options); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if f.variant {
+			if err := marshalVariant(w, f.StructField, vf, prefix, counts[f.count], accessible, options); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.sized {
+			if err := marshalSized(w, f.StructField, vf, prefix, counts[f.count], accessible, options); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.variable {
+			if err := marshalSlice(w, f.StructField, vf, prefix, counts[f.count], accessible, options); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.tlv {
+			if err := marshalTLVs(w, f.StructField, vf, prefix, accessible, options); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.rest {
+			marshalRest(w, f.StructField, vf, accessible)
+			continue
+		}
+		if f.varint {
+			if err := marshalVarint(w, f, vf, prefix, accessible); err != nil {
+				return err
+			}
+			if counts != nil {
+				counts[iField] = w.last
+			}
+			continue
+		}
+		if err := marshalField(w, f, vf, prefix, accessible, exported, options); err != nil {
+			return err
+		}
+		if f.mark {
+			if err := switchByteOrder(f.StructField, prefix, w.last, &options); err != nil {
+				return err
+			}
+		}
+		if f.check != nil {
+			checked = append(checked, checkedField{f: f, start: start, offset: f.fieldStart(w.iData*8 + w.iBitInData)})
+		}
+		if counts != nil {
+			counts[iField] = w.last
+		}
+	}
+	for _, c := range checked {
+		if err := c.fill(w, prefix, options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// marshalNested writes a nested struct, or an element of a slice, whose path
+// is path. It stops at the depth limit, which also stops cycles of pointers.
+func marshalNested(w *bitWriter, rv reflect.Value, prefix, path string, exported bool, options options) error {
+	if w.depth >= options.depthLimit() {
+		return &DepthError{Path: path, MaxDepth: options.depthLimit()}
+	}
+	w.depth++
+	err := marshal(w, rv, prefix, exported, options)
+	w.depth--
+	return err
+}
+
+// marshalField writes a field of a struct, or an element of an array field.
+// accessible reports whether the field can be read, and exported whether the
+// fields of the struct containing the field can be read.
+func marshalField(w *bitWriter, f *fieldPlan, vf reflect.Value, prefix string, accessible, exported bool, options options) error {
+	if f.skip > 0 {
+		w.skip(f.skip)
+	}
+	if f.nested {
+		if f.Anonymous {
+			// Exported fields of an unexported embedded struct are promoted,
+			// and can be read
+			accessible = accessible || exported
+		}
+		return marshalNested(w, vf, nestedPrefix(prefix, f.StructField), prefix+f.Name, accessible, options)
+	}
+	if !f.occupies {
+		return nil
+	}
+	if f.optional && accessible {
+		// A nil optional field is written as zero
+		if vf.IsNil() {
+			vf = reflect.Zero(f.Type)
+		} else {
+			vf = vf.Elem()
+		}
+	}
+	if f.byteAligned {
+		w.alignToByte()
+	}
+	if f.modifiers.ownBitOrder {
+		bitOrder := w.bitOrder
+		w.bitOrder = f.modifiers.bitOrder
+		defer func() {
+			w.bitOrder = bitOrder
+			w.alignToByte()
+		}()
+	}
+	if f.raw {
+		var raw Raw
+		if accessible {
+			raw = vf.Bytes()
+		}
+		w.writeRaw(raw, f.bitSize)
+		return nil
+	}
+	if f.addr {
+		var addr []byte
+		if accessible {
+			var ok bool
+			if addr, ok = addrBytes(vf, f.bitSize); !ok {
+				return &OverflowError{Field: f.StructField, Path: prefix + f.Name, Value: vf.Interface()}
+			}
+		}
+		w.writeRaw(addr, f.bitSize)
+		return nil
+	}
+	if f.wide {
+		val := new(big.Int)
+		if accessible {
+			var ok bool
+			if val, ok = wideValue(vf, f.bitSize); !ok {
+				return &OverflowError{Field: f.StructField, Path: prefix + f.Name, Value: vf.Interface()}
+			}
+		}
+		w.writeBig(val, f.bitSize, f.byteOrder(options.byteOrder))
+		return nil
+	}
+
+	var val uint64
+	if f.auto && options.sequence != nil {
+		val = options.sequence.value(prefix+f.Name, f.bitSize)
+		w.autos = append(w.autos, prefix+f.Name)
+	} else if accessible && f.marshaler {
+		var err error
+		if val, err = marshalBits(f.StructField, vf, f.bitSize); err != nil {
+			if overflow, ok := err.(*OverflowError); ok {
+				overflow.Path = prefix + f.Name
+				return err
+			}
+			return fmt.Errorf("bitfield: MarshalBits failed for %s: %w", prefix+f.Name, err)
+		}
+	} else if accessible && f.scale != nil {
+		var ok bool
+		if val, ok = f.scale.bits(vf.Float(), f.bitSize); !ok {
+			return &OverflowError{Field: f.StructField, Path: prefix + f.Name, Value: vf.Interface()}
+		}
+	} else if accessible && f.epoch != nil {
+		var ok bool
+		if val, ok = f.epoch.bits(vf.Interface().(time.Time), f.bitSize); !ok {
+			return &OverflowError{Field: f.StructField, Path: prefix + f.Name, Value: vf.Interface()}
+		}
+	} else if accessible {
+		var err error
+		if val, err = fieldValue(f.StructField, vf, f.bitSize); err != nil {
+			err.(*OverflowError).Path = prefix + f.Name
+			return err
+		}
+	}
+	if f.constant != nil {
+		if val != 0 && val != *f.constant {
+			return &ConstError{
+				Field: f.StructField,
+				Path:  prefix + f.Name,
+				Value: vf.Interface(),
+				Const: decodedValue(f.Type, *f.constant, f.bitSize),
+			}
+		}
+		val = *f.constant
+	}
+	if f.check != nil {
+		// The checksum is written once the bytes it covers are
+		val = 0
+	}
+	if f.modifiers.signMagnitude {
+		var ok bool
+		if val, ok = toSignMagnitude(val, f.bitSize); !ok {
+			return &OverflowError{Field: f.StructField, Path: prefix + f.Name, Value: vf.Interface()}
+		}
+	}
+	if f.signed != "" {
+		var ok bool
+		if val, ok = f.toSigned(val, f.bitSize); !ok {
+			return &OverflowError{Field: f.StructField, Path: prefix + f.Name, Value: vf.Interface()}
+		}
+	}
+	if f.enc != "" {
+		var ok bool
+		if val, ok = f.encodeBits(val); !ok {
+			return &OverflowError{Field: f.StructField, Path: prefix + f.Name, Value: vf.Interface()}
+		}
+	}
+	w.writeValue(val, f.bitSize, f.byteOrder(options.byteOrder))
+	w.last = val
+	return nil
+}
+
+// BitMarshaler is implemented by field types which encode their own bits,
+// the counterpart of [BitUnmarshaler]. If a pointer to the type of a field
+// with a bit tag implements it, Marshal calls MarshalBits of the field instead
+// of encoding the field as an integer. The returned value is written as an
+// unsigned integer field of bitLen bits, in the byte order of the field, and
+// must fit in bitLen bits.
+//
+// A field of a non-integer type implementing BitMarshaler must have a bit
+// size within the range of 1 to 64.
+type BitMarshaler interface {
+	MarshalBits(bitLen int) (uint64, error)
+}
+
+var bitMarshalerType = reflect.TypeOf((*BitMarshaler)(nil)).Elem()
+
+// bitMarshalers caches whether a pointer to a type implements BitMarshaler.
+var bitMarshalers sync.Map
+
+// isBitMarshaler reports whether a pointer to rt implements BitMarshaler.
+func isBitMarshaler(rt reflect.Type) bool {
+	if ok, found := bitMarshalers.Load(rt); found {
+		return ok.(bool)
+	}
+	ok := reflect.PointerTo(rt).Implements(bitMarshalerType)
+	bitMarshalers.Store(rt, ok)
+	return ok
+}
+
+// marshalBits returns the bits of a field encoded by its MarshalBits method.
+func marshalBits(field reflect.StructField, vf reflect.Value, bitSize int) (uint64, error) {
+	var m BitMarshaler
+	if vf.CanAddr() {
+		m = vf.Addr().Interface().(BitMarshaler)
+	} else {
+		// A struct passed by value is not addressable
+		p := reflect.New(vf.Type())
+		p.Elem().Set(vf)
+		m = p.Interface().(BitMarshaler)
+	}
+	val, err := m.MarshalBits(bitSize)
+	if err != nil {
+		return 0, err
+	}
+	if bitSize < 64 && val>>bitSize != 0 {
+		return 0, &OverflowError{Field: field, Value: val}
+	}
+	return val, nil
+}
+
+// fieldValue returns the bits to encode for an integer or floating-point field.
+func fieldValue(field reflect.StructField, vf reflect.Value, bitSize int) (uint64, error) {
+	if vf.Kind() == reflect.Bool {
+		if vf.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	if vf.Kind() == reflect.Float32 {
+		return uint64(math.Float32bits(float32(vf.Float()))), nil
+	}
+	if vf.CanFloat() {
+		return math.Float64bits(vf.Float()), nil
+	}
+	if vf.CanUint() {
+		val := vf.Uint()
+		if bitSize < 64 && val>>bitSize != 0 {
+			return 0, &OverflowError{Field: field, Value: vf.Interface()}
+		}
+		return val, nil
+	}
+	val := vf.Int()
+	if bitSize < 64 {
+		min, max := int64(-1)<<(bitSize-1), int64(1)<<(bitSize-1)-1
+		if val < min || max < val {
+			return 0, &OverflowError{Field: field, Value: vf.Interface()}
+		}
+		return uint64(val) & (1<<bitSize - 1), nil
+	}
+	return uint64(val), nil
+}
+
+// bitWriter writes bits into a growing byte slice, starting from the least
+// significant bit of each byte, or from the most significant bit with
+// MSBFirst. It is the counterpart of bitReader.
+type bitWriter struct {
+	data       []byte
+	iData      int
+	iBitInData int // number of bits already written in data[iData]
+	origin     int // index of the byte from which words are counted
+	bitOrder   BitOrder
+	wordSize   int      // bits of the words grouping the bits, if given by WithWordSize
+	last       uint64   // value of the last integer field written
+	depth      int      // depth of the struct being written
+	autos      []string // paths of the fields written from the sequence
+}
+
+func (w *bitWriter) alignToByte() {
+	if w.iBitInData > 0 {
+		w.iData++
+		w.iBitInData = 0
+	}
+}
+
+// writeBits writes the low n bits of b, where n does not exceed the bits
+// remaining in the current byte. With MSBFirst, the most significant of the n
+// bits is written first.
+func (w *bitWriter) writeBits(b byte, n int) {
+	for len(w.data) <= w.iData {
+		w.data = append(w.data, 0)
+	}
+	if w.bitOrder == MSBFirst {
+		w.data[w.iData] |= (b & (0xff >> (8 - n))) << (8 - w.iBitInData - n)
+	} else {
+		w.data[w.iData] |= (b & (0xff >> (8 - n))) << w.iBitInData
+	}
+	w.iBitInData += n
+	if w.iBitInData >= 8 {
+		w.iData++
+		w.iBitInData = 0
+	}
+}
+
+// skip writes n zero bits, as a placeholder field would.
+func (w *bitWriter) skip(n int) {
+	for ; n > 64; n -= 64 {
+		w.writeValue(0, 64, LittleEndian)
+	}
+	w.writeValue(0, n, LittleEndian)
+}
+
+func (w *bitWriter) writeValue(val uint64, bitSize int, byteOrder ByteOrder) {
+	if w.wordSize > 8 {
+		w.writeWordValue(val, bitSize, byteOrder)
+		return
+	}
+	for written := 0; written < bitSize; {
+		n := 8 - w.iBitInData
+		if bitSize-written < n {
+			n = bitSize - written
+		}
+		if byteOrder == LittleEndian {
+			w.writeBits(byte(val>>written), n)
+		} else {
+			// The earlier bytes hold the more significant bits
+			w.writeBits(byte(val>>(bitSize-written-n)), n)
+		}
+		written += n
+	}
+}
+
+// writeWordValue is writeValue for data grouped in words, the counterpart of
+// readWordValue. The whole words holding the bits are appended to the data.
+func (w *bitWriter) writeWordValue(val uint64, bitSize int, byteOrder ByteOrder) {
+	for written := 0; written < bitSize; {
+		offset := w.iData*8 + w.iBitInData
+		inWord := (offset - w.origin*8) % w.wordSize
+		n := min(w.wordSize-inWord, bitSize-written)
+		var chunk uint64
+		if byteOrder == LittleEndian {
+			chunk = val >> written
+		} else {
+			chunk = val >> (bitSize - written - n)
+		}
+		shift := inWord
+		if w.bitOrder == MSBFirst {
+			shift = w.wordSize - inWord - n
+		}
+		w.orWord(offset-inWord, chunk&(1<<n-1)<<shift, byteOrder)
+		written += n
+		w.seek(offset + n)
+	}
+}
+
+// alignToWord moves the writer to the start of the next word, if the data is
+// grouped in words, and pads the data with zeros up to there.
+func (w *bitWriter) alignToWord() {
+	if w.wordSize <= 8 {
+		return
+	}
+	offset := (w.iData-w.origin)*8 + w.iBitInData
+	w.seek(w.origin*8 + (offset+w.wordSize-1)/w.wordSize*w.wordSize)
+	for len(w.data) < w.iData {
+		w.data = append(w.data, 0)
+	}
+}
+
+// orWord sets the bits of word in the word starting at the bit offset.
+func (w *bitWriter) orWord(offset int, word uint64, byteOrder ByteOrder) {
+	nbytes := w.wordSize / 8
+	for len(w.data) < offset/8+nbytes {
+		w.data = append(w.data, 0)
+	}
+	for i := 0; i < nbytes; i++ {
+		if byteOrder == LittleEndian {
+			w.data[offset/8+i] |= byte(word >> (i * 8))
+		} else {
+			w.data[offset/8+i] |= byte(word >> ((nbytes - 1 - i) * 8))
+		}
+	}
+}
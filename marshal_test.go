@@ -0,0 +1,1666 @@
+package bitfield
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshal(t *testing.T) {
+	// Setup
+	type composite struct {
+		A uint8 `bit:"4"`
+		B uint8 `bit:"8"`
+		_ uint8 `bit:"2"`
+		C int8  `bit:"2"`
+		D uint16
+		E int16 `bit:"10"`
+		f uint8 `bit:"6"`
+	}
+	type raw struct {
+		A uint8 `bit:"4"`
+		B Raw   `bit:"12"`
+		C uint8
+	}
+	testCases := map[string]struct {
+		argV    any
+		argOpts []Option
+		want    []byte
+	}{
+		"LittleEndian": {
+			argV: composite{A: 0x2, B: 0x41, C: -1, D: 0x2301, E: -512, f: 0x3F},
+			want: []byte{0x12, 0xC4, 0x01, 0x23, 0x00, 0x02},
+		},
+		"BigEndian": {
+			argV:    &composite{A: 0x2, B: 0x41, C: -1, D: 0x2301, E: -512},
+			argOpts: []Option{WithByteOrder(BigEndian)},
+			want:    []byte{0x42, 0xC1, 0x23, 0x01, 0x80, 0x00},
+		},
+		"Raw": {
+			argV: raw{A: 0x1, B: Raw{0x32, 0x04}, C: 0x56},
+			want: []byte{0x21, 0x43, 0x56},
+		},
+		"Short Raw": {
+			argV: raw{A: 0x1, B: Raw{0x32}, C: 0x56},
+			want: []byte{0x21, 0x03, 0x56},
+		},
+		"Trailing bits": {
+			argV: struct {
+				A uint8 `bit:"3"`
+			}{A: 0b101},
+			want: []byte{0b101},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(tc.argV, tc.argOpts...)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestMarshal_RoundTrip(t *testing.T) {
+	// Setup
+	type header struct {
+		Version  uint8  `bit:"4"`
+		Flags    uint8  `bit:"3"`
+		Signed   int32  `bit:"19"`
+		Length   uint16 `bit:"14"`
+		Reserved Raw    `bit:"10"`
+		Checksum uint32
+		Wide     int64 `bit:"64"`
+	}
+	in := header{Version: 0xA, Flags: 0x5, Signed: -0x12345, Length: 0x3FFE, Reserved: Raw{0xFF, 0x02}, Checksum: 0xDEADBEEF, Wide: -2}
+	for _, byteOrder := range []ByteOrder{LittleEndian, BigEndian} {
+		// Exercise
+		data, err := Marshal(&in, WithByteOrder(byteOrder))
+		var out header
+		err2 := Unmarshal(data, &out, WithByteOrder(byteOrder))
+
+		// Verify
+		assert.Nil(t, err)
+		assert.Nil(t, err2)
+		assert.Equal(t, in, out)
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	// Setup
+	var nilPtr *struct{ A uint8 }
+	testCases := map[string]struct {
+		argV any
+		want error
+	}{
+		"Not a struct": {
+			argV: 1,
+			want: &TypeError{},
+		},
+		"Nil pointer": {
+			argV: nilPtr,
+			want: &TypeError{},
+		},
+		"Invalid field": {
+			argV: struct {
+				A uint8 `bit:"9"`
+			}{},
+			want: &FieldError{},
+		},
+		"Unsigned overflow": {
+			argV: struct {
+				A uint8 `bit:"3"`
+			}{A: 8},
+			want: &OverflowError{},
+		},
+		"Signed overflow": {
+			argV: struct {
+				A int8 `bit:"3"`
+			}{A: -5},
+			want: &OverflowError{},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(tc.argV)
+
+			// Verify
+			assert.Nil(t, got)
+			assert.IsType(t, tc.want, err)
+		})
+	}
+}
+
+func TestMarshalAppend(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8 `bit:"4"`
+		B uint8 `bit:"4"`
+		C uint16
+	}
+	in := &a{A: 1, B: 2, C: 0x1234}
+	dst := make([]byte, 1, 8)
+	dst[0] = 0xFF
+
+	// Exercise
+	got, err := MarshalAppend(dst, in)
+	allocs := testing.AllocsPerRun(10, func() {
+		_, _ = MarshalAppend(dst, in)
+	})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xFF, 0x21, 0x34, 0x12}, got)
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestMarshalInto(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8 `bit:"4"`
+		B uint8 `bit:"4"`
+		C uint16
+	}
+	testCases := map[string]struct {
+		argBuf  []byte
+		want    int
+		wantBuf []byte
+		wantErr error
+	}{
+		"Exact size": {
+			argBuf:  []byte{0xFF, 0xFF, 0xFF},
+			want:    3,
+			wantBuf: []byte{0x21, 0x34, 0x12},
+		},
+		"Larger buffer": {
+			argBuf:  []byte{0xFF, 0xFF, 0xFF, 0xFF},
+			want:    3,
+			wantBuf: []byte{0x21, 0x34, 0x12, 0xFF},
+		},
+		"Short buffer": {
+			argBuf:  []byte{0xFF, 0xFF},
+			want:    0,
+			wantBuf: []byte{0xFF, 0xFF},
+			wantErr: io.ErrShortBuffer,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			n, err := MarshalInto(tc.argBuf, a{A: 1, B: 2, C: 0x1234})
+
+			// Verify
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.want, n)
+			assert.Equal(t, tc.wantBuf, tc.argBuf)
+		})
+	}
+}
+
+func TestMarshal_Canonical(t *testing.T) {
+	// Setup
+	type s struct {
+		A     uint8 `bit:"3"`
+		_     uint8 `bit:"2"`
+		b     uint8 `bit:"3"`
+		Flag  bool  `bit:"4"`
+		Extra Raw   `bit:"4"`
+		C     uint16
+		Note  string
+	}
+	in := s{A: 0x5, b: 0x7, Flag: true, Extra: Raw{0xF3, 0xFF}, C: 0x1234, Note: "ignored"}
+	want := []byte{0x05, 0x31, 0x34, 0x12}
+	dirty := func(n int) []byte {
+		return bytes.Repeat([]byte{0xFF}, n)
+	}
+
+	// Exercise
+	got := map[string][]byte{}
+	got["Marshal"], _ = Marshal(in)
+	got["Marshal with decode options"], _ = Marshal(&in, WithZeroBeforeDecode(true), WithPresence(map[string]bool{}))
+	got["Marshal without ignored values"], _ = Marshal(s{A: 0x5, Flag: true, Extra: Raw{0x03}, C: 0x1234})
+	appended, _ := MarshalAppend(dirty(8)[:1], in)
+	got["MarshalAppend"] = appended[1:]
+	buf := dirty(8)
+	n, _ := MarshalInto(buf, in)
+	got["MarshalInto"] = buf[:n]
+	var encoded bytes.Buffer
+	e := NewEncoder(&encoded)
+	_ = e.Encode(in)
+	_ = e.Flush()
+	got["Encoder"] = encoded.Bytes()
+
+	// Verify
+	for name, data := range got {
+		assert.Equal(t, want, data, name)
+	}
+}
+
+func TestMarshal_NestedStruct(t *testing.T) {
+	// Setup
+	type Inner struct {
+		B uint8 `bit:"4"`
+		C int8  `bit:"3"`
+	}
+	type outer struct {
+		A   uint8 `bit:"1"`
+		In  Inner
+		hid Inner
+		D   uint8
+	}
+	in := outer{A: 1, In: Inner{B: 0xA, C: -1}, hid: Inner{B: 0xF, C: 3}, D: 0x5A}
+
+	// Exercise
+	data, err := Marshal(in)
+	var out outer
+	errOut := Unmarshal(data, &out)
+	_, errOverflow := Marshal(outer{In: Inner{B: 0x10}})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Nil(t, errOut)
+	assert.Equal(t, []byte{0b1111_0101, 0x00, 0x5A}, data)
+	assert.Equal(t, outer{A: 1, In: Inner{B: 0xA, C: -1}, D: 0x5A}, out)
+	var overflowError *OverflowError
+	assert.ErrorAs(t, errOverflow, &overflowError)
+	assert.Equal(t, "In.B", overflowError.Path)
+}
+
+func TestMarshal_WithUnexported(t *testing.T) {
+	// Setup
+	type inner struct {
+		c uint8 `bit:"4"`
+	}
+	type s struct {
+		A  uint8 `bit:"4"`
+		b  uint8 `bit:"4"`
+		in inner
+	}
+	in := s{A: 0x1, b: 0x2, in: inner{c: 0x3}}
+	testCases := map[string]struct {
+		argV    any
+		argOpts []Option
+		want    []byte
+	}{
+		"Default": {
+			argV: in,
+			want: []byte{0x01, 0x00},
+		},
+		"Set by value": {
+			argV:    in,
+			argOpts: []Option{WithUnexported(SetUnexported)},
+			want:    []byte{0x21, 0x03},
+		},
+		"Set by pointer": {
+			argV:    &in,
+			argOpts: []Option{WithUnexported(SetUnexported)},
+			want:    []byte{0x21, 0x03},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(tc.argV, tc.argOpts...)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestMarshal_EmbeddedStruct(t *testing.T) {
+	// Setup
+	type Header struct {
+		Version uint8 `bit:"4"`
+	}
+	type extra struct {
+		Flag   uint8 `bit:"1"`
+		hidden uint8 `bit:"3"`
+	}
+	type message struct {
+		Header
+		extra
+	}
+
+	// Exercise
+	got, err := Marshal(message{Header: Header{Version: 0x2}, extra: extra{Flag: 1, hidden: 7}})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x12}, got)
+}
+
+func TestMarshal_Bool(t *testing.T) {
+	// Setup
+	type flags struct {
+		A bool `bit:"1"`
+		B bool `bit:"1"`
+		C bool `bit:"6"`
+	}
+
+	// Exercise
+	got, err := Marshal(flags{A: true, C: true})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0b000001_0_1}, got)
+}
+
+func TestMarshal_Array(t *testing.T) {
+	// Setup
+	type s struct {
+		A     uint8 `bit:"4"`
+		Addr  [2]byte
+		Words [2]int16 `bit:"12"`
+		Flags [2]bool  `bit:"1"`
+	}
+	in := s{A: 0xF, Addr: [2]byte{0x12, 0x34}, Words: [2]int16{-1, 0x123}, Flags: [2]bool{false, true}}
+
+	// Exercise
+	data, err := Marshal(in)
+	var out s
+	errOut := Unmarshal(data, &out)
+	_, errOverflow := Marshal(s{Words: [2]int16{0, 0x800}})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Nil(t, errOut)
+	assert.Equal(t, []byte{0x0F, 0x12, 0x34, 0xFF, 0x3F, 0x12, 0x02}, data)
+	assert.Equal(t, in, out)
+	var overflowError *OverflowError
+	assert.ErrorAs(t, errOverflow, &overflowError)
+	assert.Equal(t, "Words[1]", overflowError.Path)
+}
+
+func TestMarshal_Slice(t *testing.T) {
+	// Setup
+	type entry struct {
+		Kind uint8 `bit:"4"`
+		Size uint8 `bit:"4"`
+	}
+	type s struct {
+		NumEntries uint8
+		Entries    []*entry `count:"NumEntries"`
+		Trailer    uint8
+	}
+	in := s{NumEntries: 2, Entries: []*entry{{Kind: 1, Size: 2}, {Kind: 3}}, Trailer: 0xFF}
+
+	// Exercise
+	data, err := Marshal(in)
+	var out s
+	errOut := Unmarshal(data, &out)
+	buf := make([]byte, 3)
+	_, errInto := MarshalInto(buf, in)
+	nilElement, errNil := Marshal(s{NumEntries: 1, Entries: []*entry{nil}})
+	_, errLength := Marshal(s{NumEntries: 1})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Nil(t, errOut)
+	assert.Equal(t, []byte{0x02, 0x21, 0x03, 0xFF}, data)
+	assert.Equal(t, in, out)
+	assert.ErrorIs(t, errInto, io.ErrShortBuffer)
+	assert.Nil(t, errNil)
+	assert.Equal(t, []byte{0x01, 0x00, 0x00}, nilElement)
+	var lengthError *LengthError
+	assert.ErrorAs(t, errLength, &lengthError)
+	assert.Equal(t, "Entries", lengthError.Path)
+}
+
+func TestMarshal_WithMaxDepth(t *testing.T) {
+	// Setup
+	type node struct {
+		N        uint8
+		Children []*node `count:"N"`
+	}
+	deep := &node{N: 1, Children: []*node{{N: 1, Children: []*node{{}}}}}
+	cycle := &node{N: 1}
+	cycle.Children = []*node{cycle}
+
+	// Exercise
+	data, err := Marshal(deep, WithMaxDepth(2))
+	_, errDeep := Marshal(deep, WithMaxDepth(1))
+	_, errCycle := Marshal(cycle)
+	_, errInto := MarshalInto(make([]byte, 1), cycle, WithMaxDepth(4))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x01, 0x01, 0x00}, data)
+	var depthError *DepthError
+	if assert.ErrorAs(t, errDeep, &depthError) {
+		assert.Equal(t, "Children[0].Children[0]", depthError.Path)
+	}
+	if assert.ErrorAs(t, errCycle, &depthError) {
+		assert.Equal(t, 10000, depthError.MaxDepth)
+	}
+	if assert.ErrorAs(t, errInto, &depthError) {
+		assert.Equal(t, "Children[0].Children[0].Children[0].Children[0].Children[0]", depthError.Path)
+	}
+}
+
+func TestMarshal_LenTag(t *testing.T) {
+	// Setup
+	type record struct {
+		Type       uint8  `bit:"4"`
+		PayloadLen uint8  `bit:"8"`
+		Payload    []byte `len:"PayloadLen"`
+	}
+	in := record{Type: 0xF, PayloadLen: 2, Payload: []byte{0x12, 0x34}}
+
+	// Exercise
+	data, err := Marshal(in)
+	var out record
+	errOut := Unmarshal(data, &out)
+	_, errLength := Marshal(record{PayloadLen: 1})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Nil(t, errOut)
+	assert.Equal(t, []byte{0x2F, 0x00, 0x12, 0x34}, data)
+	assert.Equal(t, in, out)
+	var lengthError *LengthError
+	assert.ErrorAs(t, errLength, &lengthError)
+}
+
+func TestMarshal_ByteOrderMark(t *testing.T) {
+	// Setup
+	type s struct {
+		Mark  uint16 `byteorder:"little=0x4949,big=0x4D4D"`
+		Magic uint16
+	}
+
+	// Exercise
+	little, errLittle := Marshal(s{Mark: 0x4949, Magic: 42})
+	big, errBig := Marshal(s{Mark: 0x4D4D, Magic: 42})
+	_, errMark := Marshal(s{Mark: 1})
+
+	// Verify
+	assert.Nil(t, errLittle)
+	assert.Nil(t, errBig)
+	assert.Equal(t, []byte{0x49, 0x49, 0x2A, 0x00}, little)
+	assert.Equal(t, []byte{0x4D, 0x4D, 0x00, 0x2A}, big)
+	var byteOrderError *ByteOrderError
+	assert.ErrorAs(t, errMark, &byteOrderError)
+}
+
+func TestMarshal_SkipTag(t *testing.T) {
+	// Setup
+	type s struct {
+		A uint8 `bit:"4"`
+		B uint8 `bit:"4" skip:"100"`
+	}
+
+	// Exercise
+	data, err := Marshal(s{A: 0xF, B: 0xF})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, append(append([]byte{0x0F}, make([]byte, 12)...), 0x0F), data)
+}
+
+func TestMarshal_BitMarshaler(t *testing.T) {
+	// Setup
+	type s struct {
+		A    uint8     `bit:"4"`
+		Temp testFixed `bit:"8"`
+		B    uint8     `bit:"4"`
+	}
+	testCases := map[string]struct {
+		arg     s
+		want    []byte
+		wantErr bool
+	}{
+		"Encoded": {
+			arg:  s{A: 1, Temp: 2.5, B: 5},
+			want: []byte{0x81, 0x52},
+		},
+		"Error": {
+			arg:     s{Temp: 16},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			data, err := Marshal(tc.arg)
+
+			// Verify
+			if tc.wantErr {
+				assert.ErrorContains(t, err, "MarshalBits failed for Temp: out of range")
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, data)
+			var got s
+			assert.Nil(t, Unmarshal(data, &got))
+			assert.Equal(t, tc.arg, got)
+		})
+	}
+}
+
+func TestMarshal_Float(t *testing.T) {
+	// Setup
+	type s struct {
+		Valid bool `bit:"1"`
+		F32   float32
+		F64   float64 `endian:"big"`
+	}
+
+	// Exercise
+	data, err := Marshal(s{Valid: true, F32: 2.5, F64: -2.5})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{
+		0x01,
+		0x00, 0x00, 0x20, 0x40,
+		0xC0, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}, data)
+}
+
+func TestMarshal_CountTagBools(t *testing.T) {
+	// Setup
+	type s struct {
+		N     uint8  `bit:"4"`
+		Flags []bool `count:"N"`
+		Tail  uint8  `bit:"4"`
+	}
+	in := s{N: 4, Flags: []bool{true, false, true, true}, Tail: 0xF}
+
+	// Exercise
+	data, err := Marshal(in)
+	dataMSB, errMSB := Marshal(in, WithBitOrder(MSBFirst))
+	_, errLength := Marshal(s{N: 1})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xD4, 0x0F}, data)
+	assert.Nil(t, errMSB)
+	assert.Equal(t, []byte{0x4B, 0xF0}, dataMSB)
+	assert.ErrorContains(t, errLength, "slice of 0 elements does not match count 1")
+}
+
+func TestMarshal_TLVTag(t *testing.T) {
+	// Setup
+	type s struct {
+		Version uint8
+		Options []TLV `tlv:"type=uint8,len=uint8"`
+	}
+	in := s{Version: 1, Options: []TLV{
+		{Type: 3, Value: []byte{0xAA, 0xBB}},
+		{Type: 7},
+	}}
+
+	// Exercise
+	data, err := Marshal(in)
+	size, errSize := Size(&in)
+	_, errType := Marshal(s{Options: []TLV{{Type: 0x100}}})
+	_, errLength := Marshal(s{Options: []TLV{{Value: make([]byte, 256)}}})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x01, 0x03, 0x02, 0xAA, 0xBB, 0x07, 0x00}, data)
+	assert.Nil(t, errSize)
+	assert.Equal(t, len(data), size)
+	var overflowError *OverflowError
+	assert.ErrorAs(t, errType, &overflowError)
+	var lengthError *LengthError
+	assert.ErrorAs(t, errLength, &lengthError)
+}
+
+func TestMarshal_LenTagUnit(t *testing.T) {
+	// Setup
+	type header struct {
+		Words   uint8
+		Options []byte `len:"Words,unit=4"`
+	}
+	in := header{Words: 1, Options: []byte{0x01, 0x02, 0x03, 0x04}}
+
+	// Exercise
+	data, err := Marshal(in)
+	_, errLength := Marshal(header{Words: 1, Options: []byte{0x01, 0x02}})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x01, 0x01, 0x02, 0x03, 0x04}, data)
+	assert.ErrorContains(t, errLength, "slice of 2 elements does not match len 1 of 4-byte units")
+}
+
+func TestMarshal_EndianTag(t *testing.T) {
+	// Setup
+	type s struct {
+		A uint16
+		B uint16 `endian:"big"`
+		C uint16 `bit:"12" endian:"little"`
+	}
+	in := s{A: 0x3412, B: 0x5678, C: 0xC9A}
+
+	// Exercise
+	data, err := Marshal(in)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x12, 0x34, 0x56, 0x78, 0x9A, 0x0C}, data)
+}
+
+func TestMarshal_WithWordSize(t *testing.T) {
+	// Setup
+	type s struct {
+		A uint8  `bit:"4"`
+		B uint16 `bit:"16"`
+	}
+	in := s{A: 0x1, B: 0x2345}
+	opts := []Option{WithByteOrder(BigEndian), WithWordSize(16)}
+
+	// Exercise
+	data, err := Marshal(in, opts...)
+	appended, errAppend := MarshalAppend([]byte{0xFF}, in, opts...)
+	size, errSize := Size(in, opts...)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x23, 0x41, 0x00, 0x05}, data)
+	assert.Nil(t, errAppend)
+	assert.Equal(t, append([]byte{0xFF}, data...), appended)
+	assert.Nil(t, errSize)
+	assert.Equal(t, 4, size)
+}
+
+func TestMarshal_OffsetTag(t *testing.T) {
+	// Setup
+	type s struct {
+		Flags   uint8  `bit:"4" offset:"28"`
+		Version uint8  `bit:"4" offset:"0"`
+		Length  uint16 `offset:"8"`
+	}
+	in := s{Flags: 0xA, Version: 5, Length: 0x1234}
+
+	// Exercise
+	data, err := Marshal(in)
+	buf := make([]byte, 4)
+	n, errInto := MarshalInto(buf, in)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x05, 0x34, 0x12, 0xA0}, data)
+	assert.Nil(t, errInto)
+	assert.Equal(t, 4, n)
+}
+
+func TestMarshal_ConstTag(t *testing.T) {
+	// Setup
+	type s struct {
+		_       uint16 `const:"0x4D5A"`
+		Version int8   `bit:"4" const:"-2"`
+		Flags   uint8  `bit:"4"`
+	}
+
+	// Exercise
+	zero, errZero := Marshal(s{Flags: 3})
+	same, errSame := Marshal(s{Version: -2, Flags: 3})
+	_, errOther := Marshal(s{Version: 1})
+
+	// Verify
+	assert.Nil(t, errZero)
+	assert.Equal(t, []byte{0x5A, 0x4D, 0x3E}, zero)
+	assert.Nil(t, errSame)
+	assert.Equal(t, zero, same)
+	var constError *ConstError
+	assert.ErrorAs(t, errOther, &constError)
+	assert.EqualError(t, errOther, "bitfield: value 1 is not constant -2 (Version int8 `bit:\"4\" const:\"-2\"`)")
+}
+
+func TestMarshal_AutoTag(t *testing.T) {
+	// Setup
+	type s struct {
+		Seq     uint8 `bit:"2" auto:"increment"`
+		Command uint8 `bit:"6"`
+	}
+	var seq Sequence
+	var got []byte
+
+	// Exercise
+	for i := 0; i < 5; i++ {
+		data, err := Marshal(s{Seq: 3, Command: 1}, WithSequence(&seq))
+		assert.Nil(t, err)
+		got = append(got, data...)
+	}
+	_, errOverflow := Marshal(s{Command: 64}, WithSequence(&seq))
+	next, errNext := Marshal(s{Command: 1}, WithSequence(&seq))
+	seq.Reset()
+	reset, errReset := Marshal(s{Command: 1}, WithSequence(&seq))
+	plain, errPlain := Marshal(s{Seq: 3, Command: 1})
+	_, errNil := Marshal(s{}, WithSequence(nil))
+
+	// Verify
+	assert.Equal(t, []byte{0x04, 0x05, 0x06, 0x07, 0x04}, got)
+	var overflowError *OverflowError
+	assert.ErrorAs(t, errOverflow, &overflowError)
+	assert.Nil(t, errNext)
+	assert.Equal(t, []byte{0x05}, next)
+	assert.Nil(t, errReset)
+	assert.Equal(t, []byte{0x04}, reset)
+	assert.Nil(t, errPlain)
+	assert.Equal(t, []byte{0x07}, plain)
+	assert.NotNil(t, errNil)
+}
+
+func TestMarshal_AutoTagPath(t *testing.T) {
+	// Setup
+	type inner struct {
+		Seq uint8 `auto:"increment"`
+	}
+	type s struct {
+		A   inner
+		B   [2]inner
+		Len uint8    `bit:"8"`
+		C   []*inner `count:"Len"`
+	}
+	var seq Sequence
+	codec := MustCompile(reflect.TypeOf(s{}), WithSequence(&seq))
+
+	// Exercise
+	size, errSize := codec.Size(s{Len: 1, C: []*inner{{}}})
+	first, errFirst := codec.Marshal(s{Len: 1, C: []*inner{{}}})
+	second, errSecond := codec.Marshal(s{Len: 2, C: []*inner{{}, {}}})
+
+	// Verify
+	assert.Nil(t, errSize)
+	assert.Equal(t, 5, size)
+	assert.Nil(t, errFirst)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x00}, first)
+	assert.Nil(t, errSecond)
+	assert.Equal(t, []byte{0x01, 0x01, 0x01, 0x02, 0x01, 0x00}, second)
+}
+
+func TestMarshal_AutoTagError(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"NotInteger": struct {
+			A bool `bit:"1" auto:"increment"`
+		}{},
+		"Signed": struct {
+			A int8 `auto:"increment"`
+		}{},
+		"NotIncrement": struct {
+			A uint8 `auto:"decrement"`
+		}{},
+		"Const": struct {
+			A uint8 `auto:"increment" const:"1"`
+		}{},
+		"Check": struct {
+			A uint8 `auto:"increment" check:"sum8,0:"`
+		}{},
+	}
+
+	for name, v := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			_, err := Marshal(v, WithSequence(new(Sequence)))
+
+			// Verify
+			assertFieldError("A")(t, err)
+		})
+	}
+}
+
+func TestMarshal_BitTagModifiers(t *testing.T) {
+	// Setup
+	type s struct {
+		A uint8  `bit:"3"`
+		B uint8  `bit:"3,msb"`
+		C uint16 `bit:"12,be"`
+		D int8   `bit:"4,sm"`
+	}
+
+	// Exercise
+	data, err := Marshal(s{A: 5, B: 5, C: 0x123, D: -3})
+	_, errOverflow := Marshal(s{D: -8})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x05, 0xA0, 0x12, 0xB3}, data)
+	var overflowError *OverflowError
+	assert.ErrorAs(t, errOverflow, &overflowError)
+	assert.Equal(t, "D", overflowError.Path)
+}
+
+func TestMarshal_IfTag(t *testing.T) {
+	// Setup
+	testCases := map[string]struct {
+		arg  testOptional
+		want []byte
+	}{
+		"Absent": {
+			arg:  testOptional{Level: 1, Extension: 0x1234, Checksum: 0xAB, Note: 0xCD},
+			want: []byte{0x10},
+		},
+		"Present": {
+			arg:  testOptional{Flags: 3, Level: -1, Extension: 0x1234, Checksum: 0xAB, Note: 0xCD},
+			want: []byte{0xF3, 0x34, 0x12, 0xAB, 0xCD},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(tc.arg)
+			size, errSize := Size(tc.arg)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Nil(t, errSize)
+			assert.Equal(t, len(tc.want), size)
+		})
+	}
+}
+
+func TestMarshal_Pointer(t *testing.T) {
+	// Setup
+	enabled, extension, level := true, uint16(0x1234), int8(-2)
+	testCases := map[string]struct {
+		arg  testPointers
+		want []byte
+	}{
+		"Present": {
+			arg:  testPointers{Flags: 1, Enabled: &enabled, Extension: &extension, Level: &level},
+			want: []byte{0x11, 0x34, 0x12, 0xFE},
+		},
+		"Nil": {
+			arg:  testPointers{Flags: 1},
+			want: []byte{0x01, 0x00, 0x00, 0x00},
+		},
+		"Absent": {
+			arg:  testPointers{Extension: &extension},
+			want: []byte{0x00, 0x00},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(tc.arg)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestMarshal_PresentIfTag(t *testing.T) {
+	// Setup
+	checksum := uint16(0xBEEF)
+	testCases := map[string]struct {
+		arg  testPresentIf
+		want []byte
+	}{
+		"Both": {
+			arg:  testPresentIf{Checksum: &checksum, Key: 0x01020304},
+			want: []byte{0x0A, 0xEF, 0xBE, 0x04, 0x03, 0x02, 0x01},
+		},
+		"KeyOnly": {
+			arg:  testPresentIf{Version: 1, Key: 0x01020304},
+			want: []byte{0x12, 0x04, 0x03, 0x02, 0x01},
+		},
+		"FlagSet": {
+			arg:  testPresentIf{Flags: 0x8},
+			want: []byte{0x08, 0x00, 0x00},
+		},
+		"None": {
+			arg:  testPresentIf{},
+			want: []byte{0x00},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(tc.arg)
+			size, errSize := Size(tc.arg)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Nil(t, errSize)
+			assert.Equal(t, len(tc.want), size)
+		})
+	}
+}
+
+func TestMarshal_EpochTag(t *testing.T) {
+	// Setup
+	newYear := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+	arg := testTimestamps{
+		Unix:   newYear,
+		Millis: newYear.Add(123*time.Millisecond + 456*time.Microsecond),
+		NTP:    newYear.Add(500 * time.Millisecond),
+		GPS:    newYear,
+	}
+
+	// Exercise
+	got, err := Marshal(arg)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, testTimestampsData, got)
+}
+
+func TestMarshal_EpochTagOverflow(t *testing.T) {
+	// Setup
+	type timestamp struct {
+		Time time.Time `bit:"32" epoch:"unix"`
+	}
+	testCases := map[string]time.Time{
+		"BeforeEpoch": time.Date(1969, time.December, 31, 0, 0, 0, 0, time.UTC),
+		"TooLate":     time.Date(2107, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	for name, arg := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			_, err := Marshal(timestamp{Time: arg})
+
+			// Verify
+			var overflowErr *OverflowError
+			assert.ErrorAs(t, err, &overflowErr)
+		})
+	}
+}
+
+func TestMarshal_Addr(t *testing.T) {
+	// Setup
+	type mapped struct {
+		Addr netip.Addr `bit:"128"`
+		IP   net.IP     `bit:"32"`
+	}
+	testCases := map[string]struct {
+		arg  any
+		opts []Option
+		want []byte
+	}{
+		"Header": {
+			arg: testAddrs{
+				Version: 4,
+				Src:     netip.MustParseAddr("192.168.0.10"),
+				Dst:     net.ParseIP("10.0.0.1"),
+				Via:     netip.MustParseAddr("2001:db8::1"),
+			},
+			opts: []Option{WithBitOrder(MSBFirst)},
+			want: testAddrsData,
+		},
+		"Mapped": {
+			arg:  mapped{Addr: netip.MustParseAddr("10.0.0.1"), IP: net.ParseIP("::ffff:10.0.0.2")},
+			want: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xFF, 0xFF, 10, 0, 0, 1, 10, 0, 0, 2},
+		},
+		"Zero": {
+			arg:  mapped{},
+			want: make([]byte, 20),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(tc.arg, tc.opts...)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestMarshal_AddrOverflow(t *testing.T) {
+	// Setup
+	testCases := map[string]any{
+		"Addr": struct {
+			Addr netip.Addr `bit:"32"`
+		}{Addr: netip.MustParseAddr("2001:db8::1")},
+		"IP": struct {
+			Addr net.IP `bit:"32"`
+		}{Addr: net.ParseIP("2001:db8::1")},
+	}
+
+	for name, arg := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			_, err := Marshal(arg)
+
+			// Verify
+			var overflowErr *OverflowError
+			assert.ErrorAs(t, err, &overflowErr)
+		})
+	}
+}
+
+func TestMarshal_UUIDTag(t *testing.T) {
+	// Setup
+	type ids struct {
+		RFC  [16]byte `uuid:"rfc4122"`
+		GUID [16]byte `uuid:"guid"`
+	}
+	id := [16]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
+	arg := ids{RFC: id, GUID: id}
+	want := []byte{
+		0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF,
+		0x33, 0x22, 0x11, 0x00, 0x55, 0x44, 0x77, 0x66, 0x88, 0x99, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF,
+	}
+
+	// Exercise
+	got, err := Marshal(arg)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, id, arg.GUID)
+}
+
+func TestMarshal_Wide(t *testing.T) {
+	// Setup
+	type counters struct {
+		A      uint8    `bit:"4"`
+		Big    *big.Int `bit:"68"`
+		Little *big.Int `bit:"72" endian:"little"`
+		Array  [9]byte  `bit:"72" endian:"big"`
+	}
+	arg := counters{
+		A:      0xA,
+		Big:    new(big.Int).Lsh(big.NewInt(0x5), 64),
+		Little: big.NewInt(0x0102),
+		Array:  [9]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09},
+	}
+	want := []byte{
+		0x0A, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x50,
+		0x02, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09,
+	}
+
+	// Exercise
+	got, err := Marshal(arg)
+	var back counters
+	errBack := Unmarshal(got, &back)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+	assert.Nil(t, errBack)
+	assert.Equal(t, arg, back)
+}
+
+func TestMarshal_WideOverflow(t *testing.T) {
+	// Setup
+	type counter struct {
+		Wide *big.Int `bit:"68"`
+	}
+	testCases := map[string]*big.Int{
+		"Negative": big.NewInt(-1),
+		"TooLarge": new(big.Int).Lsh(big.NewInt(1), 68),
+	}
+
+	for name, arg := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			_, err := Marshal(counter{Wide: arg})
+
+			// Verify
+			var overflowErr *OverflowError
+			assert.ErrorAs(t, err, &overflowErr)
+		})
+	}
+}
+
+func TestMarshal_Varint(t *testing.T) {
+	// Setup
+	arg := testVarints{
+		Unsigned: 300,
+		Signed:   -2,
+		Negative: -1,
+		QUIC:     151288809941952652,
+		Length:   2,
+		Payload:  []byte{0xAB, 0xCD},
+	}
+
+	// Exercise
+	got, err := Marshal(arg)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, testVarintsData, got)
+}
+
+func TestMarshal_VarintCondition(t *testing.T) {
+	// Setup
+	type compared struct {
+		N int16 `varint:"zigzag"`
+		X uint8 `if:"N>100"`
+	}
+	type flagged struct {
+		Flags uint16 `varint:"leb128"`
+		X     uint8  `presentif:"Flags.10"`
+	}
+
+	// Exercise
+	gotCompared, errCompared := Marshal(compared{N: 200, X: 7})
+	gotNegative, errNegative := Marshal(compared{N: -200, X: 7})
+	gotFlagged, errFlagged := Marshal(flagged{X: 7})
+
+	// Verify
+	assert.Nil(t, errCompared)
+	assert.Equal(t, []byte{0x90, 0x03, 0x07}, gotCompared)
+	assert.Nil(t, errNegative)
+	assert.Equal(t, []byte{0x8F, 0x03}, gotNegative)
+	assert.Nil(t, errFlagged)
+	assert.Equal(t, []byte{0x80, 0x08, 0x07}, gotFlagged)
+}
+
+func TestMarshal_VarintQUIC(t *testing.T) {
+	// Setup
+	type quic struct {
+		V uint64 `varint:"quic"`
+	}
+	testCases := map[string]struct {
+		arg  uint64
+		want []byte
+	}{
+		"1Byte":  {arg: 37, want: []byte{0x25}},
+		"2Bytes": {arg: 15293, want: []byte{0x7B, 0xBD}},
+		"4Bytes": {arg: 494878333, want: []byte{0x9D, 0x7F, 0x3E, 0x7D}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(quic{V: tc.arg})
+			_, errOverflow := Marshal(quic{V: 1 << 62})
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+			var overflowErr *OverflowError
+			assert.ErrorAs(t, errOverflow, &overflowErr)
+		})
+	}
+}
+
+func TestMarshal_EncTag(t *testing.T) {
+	// Setup
+	arg := testBCD{Seconds: 59, Digit: 7, Year: 2024, IMSI: [2]uint8{12, 34}}
+
+	// Exercise
+	got, err := Marshal(arg)
+	_, errOverflow := Marshal(testBCD{Seconds: 100})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, testBCDData, got)
+	var overflowErr *OverflowError
+	assert.ErrorAs(t, errOverflow, &overflowErr)
+}
+
+func TestMarshal_SignedTag(t *testing.T) {
+	// Setup
+	type deltas struct {
+		A int8  `bit:"4" signed:"zigzag"`
+		B int8  `bit:"4" signed:"zigzag"`
+		C int16 `bit:"12" signed:"zigzag"`
+	}
+	testCases := map[string]struct {
+		arg     deltas
+		want    []byte
+		wantErr bool
+	}{
+		"Small":    {arg: deltas{A: -1, B: 1, C: -2}, want: []byte{0x21, 0x03, 0x00}},
+		"Extremes": {arg: deltas{A: -8, B: 7, C: 2047}, want: []byte{0xEF, 0xFE, 0x0F}},
+		"Overflow": {arg: deltas{A: 8}, wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(tc.arg)
+
+			// Verify
+			if tc.wantErr {
+				var overflowErr *OverflowError
+				assert.ErrorAs(t, err, &overflowErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestMarshal_SignedTagOnesAndMagnitude(t *testing.T) {
+	// Setup
+	type legacy struct {
+		Ones      int8 `bit:"4" signed:"ones"`
+		Magnitude int8 `bit:"4" signed:"magnitude"`
+	}
+	testCases := map[string]struct {
+		arg     legacy
+		want    []byte
+		wantErr bool
+	}{
+		"Positive":          {arg: legacy{Ones: 7, Magnitude: 5}, want: []byte{0x57}},
+		"Negative":          {arg: legacy{Ones: -1, Magnitude: -1}, want: []byte{0x9E}},
+		"Extremes":          {arg: legacy{Ones: -7, Magnitude: -7}, want: []byte{0xF8}},
+		"OnesOverflow":      {arg: legacy{Ones: -8}, wantErr: true},
+		"MagnitudeOverflow": {arg: legacy{Magnitude: -8}, wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(tc.arg)
+
+			// Verify
+			if tc.wantErr {
+				var overflowErr *OverflowError
+				assert.ErrorAs(t, err, &overflowErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestMarshal_ScaleTag(t *testing.T) {
+	// Setup
+	testCases := map[string]struct {
+		arg     testScaled
+		want    []byte
+		wantErr bool
+	}{
+		"Exact":     {arg: testScaled{Temp: -6.9375, Humidity: 8, Ambient: 20}, want: testScaledData},
+		"Rounded":   {arg: testScaled{Temp: -6.94, Humidity: 8.2, Ambient: 19.6}, want: testScaledData},
+		"Overflow":  {arg: testScaled{Temp: 128}, wantErr: true},
+		"Negative":  {arg: testScaled{Humidity: -1}, wantErr: true},
+		"BelowBase": {arg: testScaled{Ambient: -41}, wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(tc.arg)
+
+			// Verify
+			if tc.wantErr {
+				var overflowErr *OverflowError
+				assert.ErrorAs(t, err, &overflowErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestMarshal_QFormatTag(t *testing.T) {
+	// Setup
+	testCases := map[string]struct {
+		arg     testQFormat
+		want    []byte
+		wantErr bool
+	}{
+		"Exact":       {arg: testQFormat{Gain: -1.5, Coef: 0.5, Step: -0.25}, want: testQFormatData},
+		"Rounded":     {arg: testQFormat{Gain: -1.499, Coef: 0.50001, Step: -0.2}, want: testQFormatData},
+		"Overflow":    {arg: testQFormat{Gain: 128}, wantErr: true},
+		"FractionOne": {arg: testQFormat{Coef: 1}, wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(tc.arg)
+
+			// Verify
+			if tc.wantErr {
+				var overflowErr *OverflowError
+				assert.ErrorAs(t, err, &overflowErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestMarshal_QFormatTagRounding(t *testing.T) {
+	// Setup
+	type nearest struct {
+		V float64 `qformat:"4.4"`
+	}
+	type even struct {
+		V float64 `qformat:"4.4,round=even"`
+	}
+	type floor struct {
+		V float64 `qformat:"4.4,round=floor"`
+	}
+	type zero struct {
+		V float64 `qformat:"4.4,round=zero"`
+	}
+	testCases := map[string]struct {
+		arg  any
+		want []byte
+	}{
+		"Nearest":         {arg: nearest{V: 2.5 / 16}, want: []byte{0x03}},
+		"NearestNegative": {arg: nearest{V: -2.5 / 16}, want: []byte{0xFD}},
+		"Even":            {arg: even{V: 2.5 / 16}, want: []byte{0x02}},
+		"EvenNegative":    {arg: even{V: -2.5 / 16}, want: []byte{0xFE}},
+		"Floor":           {arg: floor{V: 2.5 / 16}, want: []byte{0x02}},
+		"FloorNegative":   {arg: floor{V: -2.5 / 16}, want: []byte{0xFD}},
+		"Zero":            {arg: zero{V: 2.5 / 16}, want: []byte{0x02}},
+		"ZeroNegative":    {arg: zero{V: -2.5 / 16}, want: []byte{0xFE}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(tc.arg)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestMarshal_EncTagFloat16(t *testing.T) {
+	// Setup
+	type half struct {
+		V float32 `bit:"16" enc:"float16"`
+	}
+	testCases := map[string]struct {
+		arg  float32
+		want []byte
+	}{
+		"One":           {arg: 1, want: []byte{0x00, 0x3C}},
+		"Negative":      {arg: -2, want: []byte{0x00, 0xC0}},
+		"NegativeZero":  {arg: float32(math.Copysign(0, -1)), want: []byte{0x00, 0x80}},
+		"Rounded":       {arg: 0.1, want: []byte{0x66, 0x2E}},
+		"Max":           {arg: 65504, want: []byte{0xFF, 0x7B}},
+		"RoundedToInf":  {arg: 65520, want: []byte{0x00, 0x7C}},
+		"Overflow":      {arg: -1e6, want: []byte{0x00, 0xFC}},
+		"Subnormal":     {arg: 0x1p-24, want: []byte{0x01, 0x00}},
+		"TieToEvenZero": {arg: 0x1p-25, want: []byte{0x00, 0x00}},
+		"NaN":           {arg: float32(math.NaN()), want: []byte{0x00, 0x7E}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(half{V: tc.arg})
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestMarshal_CheckTag(t *testing.T) {
+	// Setup
+	type trailer struct {
+		Length  uint8
+		Payload [2]byte
+		Sum     uint8 `check:"sum8,0:"`
+	}
+	type leading struct {
+		CRC  uint16 `check:"crc16-ccitt,2:11" endian:"big"`
+		Data [9]byte
+	}
+	type header struct {
+		Version  uint16 `endian:"big"`
+		Checksum uint16 `check:"internet,0:6" endian:"big"`
+		ID       uint16 `endian:"big"`
+	}
+	type beyond struct {
+		Sum uint8 `check:"sum8,0:2"`
+	}
+	testCases := map[string]struct {
+		arg     any
+		out     any
+		want    []byte
+		wantErr string
+	}{
+		"Trailer": {
+			arg:  trailer{Length: 2, Payload: [2]byte{0x10, 0x20}, Sum: 0xFF},
+			out:  &trailer{},
+			want: []byte{0x02, 0x10, 0x20, 0x32},
+		},
+		"Leading": {
+			arg:  leading{Data: [9]byte([]byte("123456789"))},
+			out:  &leading{},
+			want: append([]byte{0x29, 0xB1}, "123456789"...),
+		},
+		"Covered": {
+			arg:  header{Version: 0x4500, ID: 0x1234},
+			out:  &header{},
+			want: []byte{0x45, 0x00, 0xA8, 0xCB, 0x12, 0x34},
+		},
+		"Beyond": {
+			arg:     beyond{},
+			wantErr: "bitfield: checksum range ends at byte 2 beyond the encoding (Sum uint8 `check:\"sum8,0:2\"`)",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(tc.arg)
+
+			// Verify
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Nil(t, Unmarshal(got, tc.out))
+			again, err := Marshal(tc.out)
+			assert.Nil(t, err)
+			assert.Equal(t, got, again)
+		})
+	}
+}
+
+func TestMarshal_RestTag(t *testing.T) {
+	// Setup
+	type message struct {
+		Type    uint8 `bit:"4"`
+		Payload Raw   `rest:"true"`
+	}
+	arg := message{Type: 1, Payload: Raw{0xAB, 0xCD}}
+
+	// Exercise
+	got, err := Marshal(arg)
+	size, errSize := Size(arg)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x01, 0xAB, 0xCD}, got)
+	assert.Nil(t, errSize)
+	assert.Equal(t, 3, size)
+}
+
+func TestMarshal_RestTagBools(t *testing.T) {
+	// Setup
+	type status struct {
+		Type  uint8  `bit:"4"`
+		Flags []bool `rest:"true"`
+	}
+	arg := status{Type: 1, Flags: []bool{true, false, true, false, false, false, false, false, false, false, false, true}}
+
+	// Exercise
+	got, err := Marshal(arg)
+	size, errSize := BitSize(arg)
+	var decoded status
+	errDecoded := Unmarshal(got, &decoded)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x51, 0x80}, got)
+	assert.Nil(t, errSize)
+	assert.Equal(t, 16, size)
+	assert.Nil(t, errDecoded)
+	assert.Equal(t, arg, decoded)
+}
+
+func TestMarshal_RestTagWithWordSize(t *testing.T) {
+	// Setup
+	type rest struct {
+		A    uint8
+		Rest []byte `rest:"true"`
+	}
+	type tlv struct {
+		A       uint8
+		Options []TLV `tlv:"type=uint8,len=uint8"`
+	}
+	testCases := map[string]struct {
+		arg, out  any
+		wantField string
+	}{
+		"Rest": {
+			arg:       rest{A: 1, Rest: []byte{2, 3}},
+			out:       &rest{},
+			wantField: "Rest",
+		},
+		"TLV": {
+			arg:       tlv{A: 1, Options: []TLV{{Type: 2, Value: []byte{3}}}},
+			out:       &tlv{},
+			wantField: "Options",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			data, err := Marshal(tc.arg, WithWordSize(8))
+			errUnmarshal := Unmarshal(data, tc.out, WithWordSize(8))
+			_, errWord := Marshal(tc.arg, WithWordSize(16))
+			errUnmarshalWord := Unmarshal(append(data, 0), tc.out, WithWordSize(16))
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Nil(t, errUnmarshal)
+			assert.Equal(t, tc.arg, reflect.ValueOf(tc.out).Elem().Interface())
+			assertFieldError(tc.wantField)(t, errWord)
+			assertFieldError(tc.wantField)(t, errUnmarshalWord)
+		})
+	}
+}
+
+func TestMarshal_SizeFromTag(t *testing.T) {
+	// Setup
+	testCases := map[string]struct {
+		arg     testSized
+		want    []byte
+		wantErr string
+	}{
+		"Exact": {
+			arg: testSized{
+				Length:  2,
+				Body:    testSizedBody{A: 0x11, B: 0x22},
+				Words:   1,
+				Records: []*testSizedRecord{{X: 0x1234}, {X: 0x5678}},
+				Tail:    0xFF,
+			},
+			want: []byte{0x02, 0x11, 0x22, 0x01, 0x34, 0x12, 0x78, 0x56, 0xFF},
+		},
+		"Padded": {
+			arg:  testSized{Length: 3, Body: testSizedBody{A: 0x11, B: 0x22}, Words: 1, Tail: 0xFF},
+			want: []byte{0x03, 0x11, 0x22, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0xFF},
+		},
+		"Exceeds": {
+			arg:     testSized{Length: 1, Body: testSizedBody{A: 0x11, B: 0x22}},
+			wantErr: "bitfield: encoding of 2 bytes exceeds size 1 (Body bitfield.testSizedBody `sizefrom:\"Length\"`)",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(tc.arg)
+			size, errSize := Size(tc.arg)
+
+			// Verify
+			if tc.wantErr != "" {
+				var lengthError *LengthError
+				assert.ErrorAs(t, err, &lengthError)
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Nil(t, errSize)
+			assert.Equal(t, len(tc.want), size)
+		})
+	}
+}
+
+func TestMarshal_SwitchTag(t *testing.T) {
+	// Setup
+	testCases := map[string]struct {
+		arg     testPacket
+		want    []byte
+		wantErr string
+	}{
+		"Struct": {
+			arg:  testPacket{Type: 1, Version: 2, Body: testPing{Seq: 0x1234}},
+			want: []byte{0x21, 0x34, 0x12},
+		},
+		"Pointer": {
+			arg:  testPacket{Type: 2, Version: 2, Body: &testData{Length: 2, Payload: []byte{0xAB, 0xCD}}},
+			want: []byte{0x22, 0x02, 0xAB, 0xCD},
+		},
+		"Mismatch": {
+			arg:     testPacket{Type: 2, Body: testPing{}},
+			wantErr: "bitfield: bitfield.testPing is not variant of bitfield.testBody registered for value 2 (Body bitfield.testBody `switch:\"Type\"`)",
+		},
+		"Nil": {
+			arg:     testPacket{Type: 1},
+			wantErr: "bitfield: <nil> is not variant of bitfield.testBody registered for value 1 (Body bitfield.testBody `switch:\"Type\"`)",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := Marshal(tc.arg)
+			size, errSize := Size(tc.arg)
+
+			// Verify
+			if tc.wantErr != "" {
+				var variantError *VariantError
+				assert.ErrorAs(t, err, &variantError)
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Nil(t, errSize)
+			assert.Equal(t, len(tc.want), size)
+		})
+	}
+}
@@ -1,5 +1,15 @@
 package bitfield
 
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
 type ByteOrder int
 
 // ByteOrder is an enumeration type that represents the byte order of binary data.
@@ -9,11 +19,161 @@ const (
 	BigEndian
 )
 
+// String returns the name of the byte order, such as "BigEndian".
+func (b ByteOrder) String() string {
+	switch b {
+	case LittleEndian:
+		return "LittleEndian"
+	case BigEndian:
+		return "BigEndian"
+	default:
+		return "ByteOrder(" + strconv.Itoa(int(b)) + ")"
+	}
+}
+
+// BitOrder is an enumeration type that represents the order in which bits are
+// filled within each byte.
+type BitOrder int
+
+const (
+	// LSBFirst fills each byte from the least significant bit. This is the
+	// default.
+	LSBFirst BitOrder = iota
+	// MSBFirst fills each byte from the most significant bit, as bits are
+	// numbered in RFC diagrams and many radio protocols.
+	MSBFirst
+)
+
+// String returns the name of the bit order, such as "MSBFirst".
+func (b BitOrder) String() string {
+	switch b {
+	case LSBFirst:
+		return "LSBFirst"
+	case MSBFirst:
+		return "MSBFirst"
+	default:
+		return "BitOrder(" + strconv.Itoa(int(b)) + ")"
+	}
+}
+
+// UnexportedPolicy is an enumeration type that represents how named unexported
+// fields occupying bits are handled. Placeholders named "_" always consume
+// bits without being stored, regardless of the policy.
+type UnexportedPolicy int
+
+const (
+	// IgnoreUnexported makes unexported fields consume bits without being
+	// stored by Unmarshal, and encoded as zero by Marshal. This is the default.
+	IgnoreUnexported UnexportedPolicy = iota
+	// RejectUnexported makes unexported fields an error, reported as
+	// [FieldError].
+	RejectUnexported
+	// SetUnexported makes Unmarshal store unexported fields, and Marshal encode
+	// their values, in the same way as exported fields. The fields are
+	// accessed with package unsafe.
+	SetUnexported
+)
+
+// String returns the name of the policy, such as "RejectUnexported".
+func (p UnexportedPolicy) String() string {
+	switch p {
+	case IgnoreUnexported:
+		return "IgnoreUnexported"
+	case RejectUnexported:
+		return "RejectUnexported"
+	case SetUnexported:
+		return "SetUnexported"
+	default:
+		return "UnexportedPolicy(" + strconv.Itoa(int(p)) + ")"
+	}
+}
+
 type options struct {
-	byteOrder ByteOrder
+	byteOrder  ByteOrder
+	bitOrder   BitOrder
+	wordSize   int
+	presence   map[string]bool
+	partial    *Partial
+	decodeHook DecodeHook
+	zero       bool
+	strict     bool
+	strictSize bool
+	reserved   bool // set by WithReservedMustBeZero
+	scratch    *Scratch
+	sequence   *Sequence
+	unexported UnexportedPolicy
+	frame      *frameLength
+	logger     *slog.Logger
+	dump       io.Writer // set by Dump
+	factory    ElementFactory
+	maxDepth   int
+	batch      int
+	count      int
+	counted    bool // set by WithCount
+	encoding   bool // set when validating a struct to encode
+}
+
+type frameLength struct {
+	field      string
+	adjustment int
+}
+
+// Option is an option of Unmarshal, Marshal and the other functions of the
+// package, created by the functions named With such as [WithByteOrder].
+//
+// Options can be inspected, so that frameworks can log them, and merge sets of
+// options by name, such as defaults overridden by options given at the call
+// site. When several options of the same name are given, the last one wins.
+type Option interface {
+	// Name returns the name of the function which created the option, such
+	// as "WithByteOrder".
+	Name() string
+	// Value returns the argument passed to the function which created the
+	// option, or a []any of the arguments for a function taking several.
+	Value() any
+	// String returns a description of the option, such as
+	// "WithByteOrder(BigEndian)".
+	String() string
+	apply(o *options) error
+}
+
+type option struct {
+	name  string
+	value any
+	fn    func(o *options) error
+}
+
+func newOption(name string, value any, fn func(o *options) error) Option {
+	return &option{name: name, value: value, fn: fn}
+}
+
+func (o *option) Name() string {
+	return o.name
+}
+
+func (o *option) Value() any {
+	return o.value
+}
+
+func (o *option) String() string {
+	args, ok := o.value.([]any)
+	if !ok {
+		args = []any{o.value}
+	}
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		if str, ok := arg.(string); ok {
+			strs[i] = strconv.Quote(str)
+		} else {
+			strs[i] = fmt.Sprint(arg)
+		}
+	}
+	return o.name + "(" + strings.Join(strs, ", ") + ")"
 }
 
-type Option func(*options) error
+func (o *option) apply(options *options) error {
+	return o.fn(options)
+}
 
 // WithByteOrder specifies the byte order in which Unmarshal parses multi-byte data in a byte slice
 //
@@ -27,18 +187,443 @@ type Option func(*options) error
 //	// For big-endian:
 //	Unmarshal(data, out, WithByteOrder(BigEndian))
 func WithByteOrder(order ByteOrder) Option {
-	return func(o *options) error {
+	return newOption("WithByteOrder", order, func(o *options) error {
 		o.byteOrder = order
 		return nil
-	}
+	})
+}
+
+// WithBitOrder specifies the order in which bit-fields fill each byte. By
+// default, fields are filled from the least significant bit (LSBFirst). With
+// MSBFirst, they are filled from the most significant bit, and the bit read
+// first is the most significant bit of the field within each byte:
+//
+//	var out struct {
+//		Version uint8 `bit:"4"`
+//		IHL     uint8 `bit:"4"`
+//	}
+//	_ = bitfield.Unmarshal([]byte{0x45}, &out, bitfield.WithBitOrder(bitfield.MSBFirst))
+//	// out.Version is 4 and out.IHL is 5
+//
+// The bit order composes with the byte order: a field spanning several bytes
+// is split into its parts in each byte, which are combined according to
+// [WithByteOrder]. MSBFirst with BigEndian reads fields as numbered in RFC
+// diagrams. The bit order applies to Unmarshal, Marshal, LayoutOf and the
+// Decoder and Encoder alike.
+func WithBitOrder(order BitOrder) Option {
+	return newOption("WithBitOrder", order, func(o *options) error {
+		o.bitOrder = order
+		return nil
+	})
 }
 
 func collectOptions(opts []Option) (options, error) {
+	if len(opts) == 0 {
+		// Avoid allocating options for the common case
+		return options{}, nil
+	}
 	var options options
 	for _, opt := range opts {
-		if err := opt(&options); err != nil {
+		if err := opt.apply(&options); err != nil {
 			return options, err
 		}
 	}
 	return options, nil
 }
+
+// WithWordSize specifies the size in bits of the words, or storage units, in
+// which bit-fields are grouped: 8, 16 or 32. The default is 8, grouping the
+// bits per byte.
+//
+// Specifications define "big-endian bit-fields" relative to different word
+// sizes. With a word size of 16 or 32, the data is a sequence of words, each
+// stored in the byte order of the field. The bits of a word are numbered from
+// the least significant bit of its value, or from the most significant bit
+// with [MSBFirst], and a bit-field occupies the bits following the previous
+// field within the value of the word. A field continuing into the next word
+// takes its more significant bits from the earlier word in big-endian, as it
+// does from the earlier byte without words. For example, a register of 16-bit
+// words numbered from the least significant bit, sent in big-endian:
+//
+//	// 15      12 11                  0
+//	// +---------+--------------------+
+//	// |  Type   |       Length       |
+//	// +---------+--------------------+
+//	var out struct {
+//		Length uint16 `bit:"12"`
+//		Type   uint8  `bit:"4"`
+//	}
+//	data := []byte{0x51, 0x23} // Type=5, Length=0x123
+//	err := bitfield.Unmarshal(data, &out, bitfield.WithByteOrder(bitfield.BigEndian), bitfield.WithWordSize(16))
+//
+// Words are counted from the start of the struct passed to Unmarshal or
+// Marshal, and Marshal and [BitSize] cover whole words. Data is expected to
+// consist of whole words; the missing bytes of a partial word at the end of
+// the data are taken as zero. The word size does not affect [Raw] fields and
+// slices of bytes, nor the positions of the fields given by [LayoutOf], which
+// are positions within the words. Fields with a rest or tlv tag are rejected
+// with words larger than a byte, as they would take the padding of the last
+// word for data.
+func WithWordSize(n int) Option {
+	return newOption("WithWordSize", n, func(o *options) error {
+		if n != 8 && n != 16 && n != 32 {
+			return errors.New("bitfield: word size must be 8, 16 or 32")
+		}
+		o.wordSize = n
+		return nil
+	})
+}
+
+// wordEnd returns the bit offset rounded up to whole words given by
+// [WithWordSize], which data grouped in words consists of.
+func (o *options) wordEnd(offset int) int {
+	if o.wordSize <= 8 {
+		return offset
+	}
+	return (offset + o.wordSize - 1) / o.wordSize * o.wordSize
+}
+
+// WithPresence makes Unmarshal record whether each field received data into
+// presence. After decoding, presence[name] is true if all bits of the field
+// named name were contained in the input, and false if the field was fully or
+// partially zero-filled because the input was too short. Placeholders and
+// unexported fields are not recorded.
+//
+// This lets applications distinguish fields which are absent from fields
+// whose value is zero. Example of usage:
+//
+//	presence := map[string]bool{}
+//	err := Unmarshal(data, &out, WithPresence(presence))
+//	if !presence["Extension"] {
+//		// Extension was not in data
+//	}
+//
+// presence must not be nil. Existing entries for other names are kept.
+func WithPresence(presence map[string]bool) Option {
+	return newOption("WithPresence", presence, func(o *options) error {
+		if presence == nil {
+			return errors.New("bitfield: presence map must not be nil")
+		}
+		o.presence = presence
+		return nil
+	})
+}
+
+// WithZeroBeforeDecode specifies whether Unmarshal sets the whole target struct
+// to its zero value before decoding.
+//
+// By default, Unmarshal overwrites only the fields which are decoded, and
+// leaves the other fields, such as fields of non-integer types, untouched. This
+// allows merge-style decoding into a struct holding values from elsewhere.
+// With WithZeroBeforeDecode(true), no value from a previous use of the struct
+// survives decoding.
+func WithZeroBeforeDecode(zero bool) Option {
+	return newOption("WithZeroBeforeDecode", zero, func(o *options) error {
+		o.zero = zero
+		return nil
+	})
+}
+
+// WithStrictInput specifies whether Unmarshal requires the input to contain
+// all bits of the struct.
+//
+// By default, fields beyond the end of the input are zero-filled, as
+// recorded by [WithPresence]. With WithStrictInput(true), Unmarshal instead
+// returns an error wrapping [io.ErrUnexpectedEOF] for the first field which is
+// not fully contained in the input. Fields which are not stored, such as
+// placeholders, must also be contained in the input. For a [Decoder] with
+// [WithFrameLength], the fields must be contained in the frame.
+func WithStrictInput(strict bool) Option {
+	return newOption("WithStrictInput", strict, func(o *options) error {
+		o.strict = strict
+		return nil
+	})
+}
+
+// WithStrictSize specifies whether Unmarshal rejects input longer than the
+// struct.
+//
+// By default, bytes following the last field are ignored. With
+// WithStrictSize(true), Unmarshal instead returns [TrailingDataError] if any
+// byte of the input is not occupied by the struct, which catches structs
+// silently ignoring fields added to a format. The unused bits of the last
+// byte are not reported. For [UnmarshalSlice], bytes following the last
+// record are reported.
+func WithStrictSize(strict bool) Option {
+	return newOption("WithStrictSize", strict, func(o *options) error {
+		o.strictSize = strict
+		return nil
+	})
+}
+
+// WithReservedMustBeZero makes Unmarshal check that the bits of placeholder
+// fields, the fields named _, are zero, as standards require of reserved bits
+// in conforming messages. Unmarshal returns [ReservedBitsError] identifying
+// the first placeholder holding a bit set. Bits skipped by skip and offset
+// tags are not checked.
+func WithReservedMustBeZero() Option {
+	return newOption("WithReservedMustBeZero", []any{}, func(o *options) error {
+		o.reserved = true
+		return nil
+	})
+}
+
+// WithAllowPartial makes Unmarshal accept input shorter than the struct, and
+// zero-fill the fields beyond the end of the input. Fields partially contained
+// in the input keep the bits which are contained, and the missing bits are
+// zero. Fields with a default tag instead receive the default value.
+//
+// This is the default behavior, which the option states explicitly and
+// guarantees, overriding [WithStrictInput] given before it. Use
+// [UnmarshalPartial] or [WithPresence] to find which fields were populated.
+func WithAllowPartial() Option {
+	return newOption("WithAllowPartial", []any{}, func(o *options) error {
+		o.strict = false
+		return nil
+	})
+}
+
+// WithScratch makes Unmarshal allocate the storage of [Raw] fields and byte
+// slices with a len tag from scratch instead of the heap. Call [Scratch.Reset]
+// between messages to reuse the memory:
+//
+//	var scratch bitfield.Scratch
+//	for {
+//		scratch.Reset()
+//		if err := dec.Decode(&out); err != nil {
+//			break
+//		}
+//		handle(out)
+//	}
+//
+// where dec is a [Decoder] created with WithScratch(&scratch). scratch must
+// not be nil.
+func WithScratch(scratch *Scratch) Option {
+	return newOption("WithScratch", scratch, func(o *options) error {
+		if scratch == nil {
+			return errors.New("bitfield: scratch must not be nil")
+		}
+		o.scratch = scratch
+		return nil
+	})
+}
+
+// WithSequence makes Marshal encode the fields with an auto tag, such as
+// sequence numbers, from the counts of seq, which advance after every
+// successful encode:
+//
+//	var seq bitfield.Sequence
+//	for _, msg := range msgs {
+//		data, err := bitfield.Marshal(msg, bitfield.WithSequence(&seq))
+//		...
+//	}
+//
+// Without a Sequence, Marshal encodes such fields as their values, and an
+// [Encoder] counts them itself. seq must not be nil.
+func WithSequence(seq *Sequence) Option {
+	return newOption("WithSequence", seq, func(o *options) error {
+		if seq == nil {
+			return errors.New("bitfield: sequence must not be nil")
+		}
+		o.sequence = seq
+		return nil
+	})
+}
+
+// WithUnexported specifies how named unexported fields occupying bits, such as
+// b uint8 `bit:"3"`, are handled by Unmarshal, Marshal and LayoutOf. By
+// default, they are treated as placeholders, which silently discards the
+// decoded data. See [UnexportedPolicy] for the available policies.
+//
+// The policy also applies to nested structs in unexported fields.
+func WithUnexported(policy UnexportedPolicy) Option {
+	return newOption("WithUnexported", policy, func(o *options) error {
+		o.unexported = policy
+		return nil
+	})
+}
+
+// WithFrameLength makes a [Decoder] read length-prefixed frames. For each
+// struct, the Decoder first reads the header of the frame, which is the bytes
+// up to the end of the length field named field. The frame then continues with
+// the value of the length field plus adjustment bytes, which are read before
+// the struct is decoded from the whole frame.
+//
+// For example, if the length field counts the bytes of the whole frame
+// including a 4-byte header, adjustment is -4. Fields beyond the end of the
+// frame are zero-filled, and the bytes of the frame beyond the fields are
+// skipped. Frames whose length is negative or more than 1 GiB are rejected.
+//
+// field is the name of an integer field, or its path for a field of a nested
+// struct, as in [FieldLayout]. The option does not affect Unmarshal.
+func WithFrameLength(field string, adjustment int) Option {
+	return newOption("WithFrameLength", []any{field, adjustment}, func(o *options) error {
+		o.frame = &frameLength{field: field, adjustment: adjustment}
+		return nil
+	})
+}
+
+// WithBatchSize makes an [Encoder] write to the underlying writer once for
+// every n structs, instead of once for each struct. Batching amortizes the
+// cost of writes, such as system calls, for high-rate producers. Call
+// [Encoder.Flush] to write an incomplete batch without waiting for the next
+// structs, and [Encoder.Close] to write it when done. n must be positive. The
+// option does not affect Marshal.
+func WithBatchSize(n int) Option {
+	return newOption("WithBatchSize", n, func(o *options) error {
+		if n <= 0 {
+			return errors.New("bitfield: batch size must be positive")
+		}
+		o.batch = n
+		return nil
+	})
+}
+
+// WithCount makes [UnmarshalSlice] decode n records, instead of as many
+// complete records as the data contains. Records beyond the end of the data
+// are zero-filled, or reported as an error with [WithStrictInput], in the same
+// way as fields beyond the end of the data are by Unmarshal. n must not be
+// negative. The option does not affect the other functions.
+func WithCount(n int) Option {
+	return newOption("WithCount", n, func(o *options) error {
+		if n < 0 {
+			return errors.New("bitfield: count must not be negative")
+		}
+		o.count, o.counted = n, true
+		return nil
+	})
+}
+
+// WithLogger makes Unmarshal log a record for each decoded field to logger at
+// [slog.LevelDebug], so that decoding can be traced in a structured logging
+// pipeline. Each record has the following attributes:
+//
+//   - field: the name of the field, as in [FieldLayout]
+//   - offset: the bit offset of the field
+//   - bits: the bit size of the field
+//   - value: the bits parsed for the field as an unsigned integer, or the
+//     bytes of a [Raw] field
+//   - string: the text returned by the String method of the field, only for
+//     fields of a type implementing [fmt.Stringer]
+//   - present: whether all bits of the field were contained in the input
+//
+// Placeholders and other fields which are not stored are also logged. Nothing
+// is logged if the logger is not enabled for the debug level. logger must not
+// be nil.
+func WithLogger(logger *slog.Logger) Option {
+	return newOption("WithLogger", logger, func(o *options) error {
+		if logger == nil {
+			return errors.New("bitfield: logger must not be nil")
+		}
+		o.logger = logger
+		return nil
+	})
+}
+
+// ElementFactory is a function called by Unmarshal to allocate each element of
+// a slice of pointers to structs. rt is the struct type of the elements, and
+// the function returns a non-nil pointer to a new value of rt, into which the
+// element is decoded. Fields which are not decoded keep the values set by the
+// factory.
+type ElementFactory func(rt reflect.Type) (any, error)
+
+// WithElementFactory makes Unmarshal allocate the elements of slices of
+// pointers to structs with factory instead of reflect.New. Use it for element
+// types which need to be constructed, such as types holding an interface
+// value or a reference to shared state. Example of usage:
+//
+//	factory := func(rt reflect.Type) (any, error) {
+//		if rt == reflect.TypeOf(Entry{}) {
+//			return &Entry{Handler: defaultHandler}, nil
+//		}
+//		return reflect.New(rt).Interface(), nil
+//	}
+//	err := bitfield.Unmarshal(data, &out, bitfield.WithElementFactory(factory))
+//
+// If the factory returns an error, Unmarshal stops and returns it.
+func WithElementFactory(factory ElementFactory) Option {
+	return newOption("WithElementFactory", factory, func(o *options) error {
+		o.factory = factory
+		return nil
+	})
+}
+
+// defaultMaxDepth is the maximum depth of nested structs without
+// [WithMaxDepth]. It is far beyond the depth of real formats, but keeps
+// malicious input from exhausting the stack.
+const defaultMaxDepth = 10000
+
+// WithMaxDepth limits how deep Unmarshal and Marshal descend into nested
+// structs, including the elements of slices of pointers to structs. The
+// struct passed to the function is at depth 0, and its nested structs and
+// slice elements at depth 1. A struct nested deeper than n is reported as
+// [DepthError].
+//
+// The depth of self-referential types, such as a tree whose nodes hold a
+// slice of child nodes, is given by the data. The limit keeps deeply nested
+// input, or a cycle of pointers passed to Marshal, from overflowing the stack.
+// The default limit is 10000. n must be positive.
+func WithMaxDepth(n int) Option {
+	return newOption("WithMaxDepth", n, func(o *options) error {
+		if n <= 0 {
+			return errors.New("bitfield: max depth must be positive")
+		}
+		o.maxDepth = n
+		return nil
+	})
+}
+
+// depthLimit returns the maximum depth of nested structs.
+func (o *options) depthLimit() int {
+	if o.maxDepth == 0 {
+		return defaultMaxDepth
+	}
+	return o.maxDepth
+}
+
+// FieldInfo describes a field passed to a [DecodeHook].
+type FieldInfo struct {
+	FieldLayout
+	// Tag is the struct tag of the field. Hooks can define their own tag keys.
+	Tag reflect.StructTag
+}
+
+// DecodeHook is a function called by Unmarshal for each exported bit-field and
+// plain integer field after the field is decoded. v is the field, which can be
+// set by the hook, and raw is the bits parsed for the field as an unsigned
+// integer. Integer fields already hold the decoded value when the hook is
+// called, so the hook only needs to handle the fields it wants to replace. If
+// the hook returns an error, Unmarshal stops and returns it.
+//
+// [Raw] fields are not passed to the hook.
+type DecodeHook func(field FieldInfo, v reflect.Value, raw uint64) error
+
+// WithDecodeHook specifies a hook which converts or replaces decoded values,
+// for formats needing conversions not covered by this package.
+//
+// With a decode hook, a bit tag may also be put on a field of a non-integer
+// type, with a bit size within the range of 1 to 64. Unmarshal leaves such a
+// field to the hook. Hooks should define tag keys of their own, since the tags
+// of this package such as scale are handled by Unmarshal. Example of usage:
+//
+//	type reading struct {
+//		Power float64 `bit:"8" dbm:"0.5"` // in steps of 0.5 dBm
+//	}
+//	hook := func(f bitfield.FieldInfo, v reflect.Value, raw uint64) error {
+//		if s, ok := f.Tag.Lookup("dbm"); ok {
+//			step, err := strconv.ParseFloat(s, 64)
+//			if err != nil {
+//				return err
+//			}
+//			// Milliwatts
+//			v.SetFloat(math.Pow(10, float64(raw)*step/10))
+//		}
+//		return nil
+//	}
+//	err := bitfield.Unmarshal(data, &out, bitfield.WithDecodeHook(hook))
+func WithDecodeHook(hook DecodeHook) Option {
+	return newOption("WithDecodeHook", hook, func(o *options) error {
+		o.decodeHook = hook
+		return nil
+	})
+}
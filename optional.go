@@ -0,0 +1,39 @@
+package bitfield
+
+import "reflect"
+
+// isOptional reports whether the field is a pointer to an integer or bool,
+// which is nil if the field is absent from the input.
+func isOptional(field reflect.StructField) bool {
+	if field.Type.Kind() != reflect.Pointer {
+		return false
+	}
+	kind := field.Type.Elem().Kind()
+	return isFixedInteger(kind) || kind == reflect.Bool
+}
+
+// optionalElement returns the field with the type pointed by an optional
+// field, which is parsed in place of the pointer, or the field itself
+// otherwise.
+func optionalElement(field reflect.StructField) reflect.StructField {
+	if isOptional(field) {
+		field.Type = field.Type.Elem()
+	}
+	return field
+}
+
+// validateOptional validates an optional field, if the field is one. The
+// value of an absent optional field is nil rather than a default.
+func validateOptional(field reflect.StructField) error {
+	if !isOptional(field) {
+		return nil
+	}
+	if _, ok := field.Tag.Lookup("default"); ok {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "optional field must not have default tag",
+		}
+	}
+	return nil
+}
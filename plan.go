@@ -0,0 +1,364 @@
+package bitfield
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// Plan is a struct type validated for decoding with a set of options,
+// returned by [PlanOf]. It separates the validation of the struct tags from
+// decoding: a plan can be built in package initialization, failing fast on
+// invalid tags, and then passed to [UnmarshalWithPlan] on the hot path:
+//
+//	var headerPlan = bitfield.MustPlanOf(Header{}, bitfield.WithByteOrder(bitfield.BigEndian))
+//
+//	func parse(data []byte) (Header, error) {
+//		var h Header
+//		err := bitfield.UnmarshalWithPlan(headerPlan, data, &h)
+//		return h, err
+//	}
+//
+// A Plan is safe for concurrent use, unless its options are written by
+// decoding, as the map of [WithPresence] and the [Scratch] of [WithScratch] are.
+type Plan struct {
+	rt      reflect.Type
+	options options
+}
+
+// PlanOf validates a struct type for decoding with the options, and returns
+// its plan. v must be a struct or a pointer to a struct. The pointer may be
+// nil, as only its type is used.
+//
+// Returns:
+//
+//   - the plan of the struct and nil if the struct is valid
+//   - [FieldError] if the struct has an invalid bit-field
+//   - [TypeError] if v is neither a struct nor a pointer to a struct
+//   - an error if an option is invalid
+func PlanOf(v any, opts ...Option) (*Plan, error) {
+	rt, err := structTypeOf(v)
+	if err != nil {
+		return nil, err
+	}
+	options, err := collectOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateStruct(rt, options); err != nil {
+		return nil, err
+	}
+	planOf(rt)
+	return &Plan{rt: rt, options: options}, nil
+}
+
+// MustPlanOf is like [PlanOf] but panics if the struct or an option is
+// invalid. It simplifies the initialization of global variables holding
+// plans.
+func MustPlanOf(v any, opts ...Option) *Plan {
+	plan, err := PlanOf(v, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return plan
+}
+
+// UnmarshalWithPlan is like [Unmarshal] with the struct type and the options
+// of the plan, but skips validating them again. out must be a non-nil pointer
+// to a struct of the type of the plan, otherwise UnmarshalWithPlan returns
+// [TypeError].
+func UnmarshalWithPlan(plan *Plan, data []byte, out any) error {
+	if err := ensureNonNilPointerToStruct(out); err != nil {
+		return err
+	}
+	if rt := reflect.TypeOf(out).Elem(); rt != plan.rt {
+		return &TypeError{
+			Type:    reflect.TypeOf(out),
+			problem: "plan for " + plan.rt.String() + " cannot decode into " + rt.String(),
+		}
+	}
+	_, err := unmarshal(data, len(data)*8, out, plan.options)
+	return err
+}
+
+// Codec is a struct type validated for both decoding and encoding with a set
+// of options, returned by [Compile]. Like [Plan], it parses and validates the
+// struct tags once, and its methods take no options, as those given to
+// Compile apply to every call:
+//
+//	var headerCodec = bitfield.MustCompile(reflect.TypeOf(Header{}), bitfield.WithByteOrder(bitfield.BigEndian))
+//
+//	data, err := headerCodec.Marshal(h)
+//
+// A Codec is safe for concurrent use under the same conditions as a Plan, and
+// unless it encodes with the [Sequence] of [WithSequence].
+type Codec struct {
+	rt      reflect.Type
+	options options
+	bits    int // bit size of the encoding, or -1 if it depends on the value
+}
+
+// Compile validates a struct type, or a pointer type to a struct, for
+// decoding and encoding with the options, and returns its codec.
+//
+// Returns:
+//
+//   - the codec of the struct and nil if the struct is valid
+//   - [FieldError] if the struct has an invalid bit-field
+//   - [TypeError] if rt is neither a struct nor a pointer to a struct
+//   - an error if an option is invalid
+func Compile(rt reflect.Type, opts ...Option) (*Codec, error) {
+	if rt != nil && rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return nil, &TypeError{
+			Type:    rt,
+			problem: "codec type must be struct or pointer to struct",
+		}
+	}
+	options, err := collectOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateStruct(rt, options); err != nil {
+		return nil, err
+	}
+	if err := validateMarshalType(rt, options); err != nil {
+		return nil, err
+	}
+	planOf(rt)
+	c := &Codec{rt: rt, options: options, bits: -1}
+	if _, _, variable := variableField(rt, ""); !variable {
+		c.bits = options.wordEnd(encodedBitSize(rt))
+	}
+	return c, nil
+}
+
+// MustCompile is like [Compile] but panics if the struct or an option is
+// invalid.
+func MustCompile(rt reflect.Type, opts ...Option) *Codec {
+	c, err := Compile(rt, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Type returns the struct type of the codec.
+func (c *Codec) Type() reflect.Type {
+	return c.rt
+}
+
+// Unmarshal is like [Unmarshal] with the options of the codec. out must be a
+// non-nil pointer to a struct of the type of the codec, otherwise Unmarshal
+// returns [TypeError].
+func (c *Codec) Unmarshal(data []byte, out any) error {
+	return UnmarshalWithPlan(&Plan{rt: c.rt, options: c.options}, data, out)
+}
+
+// Marshal is like [Marshal] with the options of the codec. v must be a struct
+// of the type of the codec or a non-nil pointer to one, otherwise Marshal
+// returns [TypeError].
+func (c *Codec) Marshal(v any) ([]byte, error) {
+	return c.MarshalAppend(nil, v)
+}
+
+// MarshalAppend is like [MarshalAppend] with the options of the codec.
+func (c *Codec) MarshalAppend(dst []byte, v any) ([]byte, error) {
+	rv, err := c.value(v)
+	if err != nil {
+		return dst, err
+	}
+	return appendStruct(dst, rv, c.options)
+}
+
+// Size is like [Size] with the options of the codec. For a struct without
+// variable-length fields, v is not used and may be nil.
+func (c *Codec) Size(v any) (int, error) {
+	if c.bits >= 0 {
+		return (c.bits + 7) / 8, nil
+	}
+	rv, err := c.value(v)
+	if err != nil {
+		return 0, err
+	}
+	// Sizing does not count as an encode of the fields with an auto tag
+	options := c.options
+	options.sequence = nil
+	data, err := appendStruct(nil, rv, options)
+	return len(data), err
+}
+
+// value returns the struct to be encoded from a struct or a pointer to a
+// struct of the type of the codec.
+func (c *Codec) value(v any) (reflect.Value, error) {
+	rv, err := marshaledValue(v)
+	if err != nil {
+		return rv, err
+	}
+	if rv.Type() != c.rt {
+		return rv, &TypeError{
+			Type:    reflect.TypeOf(v),
+			problem: "codec for " + c.rt.String() + " cannot encode " + rv.Type().String(),
+		}
+	}
+	return rv, nil
+}
+
+// structPlan is the metadata of a validated struct type, parsed from its
+// struct tags once, so that decoding and encoding the type again do not parse
+// the tags again. Plans are cached per type, as encoding/json caches its
+// encoders.
+type structPlan struct {
+	fields    []fieldPlan
+	offsets   bool // whether the fields are placed by offset tags
+	variable  bool // whether the struct has a variable-length or conditional field of its own
+	bits      int  // bits occupied by the fields, as given by structEnd
+	validator bool // whether a pointer to the struct implements Validator
+}
+
+// fieldPlan is the metadata of a field of a struct, or of an element of an
+// array field.
+type fieldPlan struct {
+	reflect.StructField
+	bitSize     int
+	byteAligned bool // whether the field starts from the next byte
+	occupies    bool // whether the field occupies bits, as ok of fieldBitSize
+	skip        int
+	nested      bool
+	variable    bool
+	tlv         bool
+	raw         bool
+	addr        bool            // whether the field is net.IP or netip.Addr
+	guid        bool            // whether the field is a GUID given by the uuid tag
+	wide        bool            // whether the field is a *big.Int or a wide byte array
+	varint      bool            // whether the field is an integer given by the varint tag
+	enc         string          // encoding of the bits given by the enc tag
+	signed      string          // representation of a signed integer given by the signed tag
+	mark        bool            // whether the field is a byte order mark
+	endian      string          // byte order given by the endian tag or bit tag
+	modifiers   bitModifiers    // modifiers of the bit tag
+	unmarshaler bool            // whether the field implements BitUnmarshaler
+	marshaler   bool            // whether the field implements BitMarshaler
+	count       int             // index of the count field of a variable-length field, of the discriminator of a switch field, or of the size field of a sized field
+	variant     bool            // whether the field is a switch field
+	sized       bool            // whether the field has a sizefrom tag
+	rest        bool            // whether the field holds the rest of the input
+	cond        *condition      // condition given by the if tag, under which the field is present
+	enum        map[uint64]bool // valid values given by the enum tag, keyed by enumKey
+	constant    *uint64         // bits of the value given by the const tag
+	auto        bool            // whether the field is a counter given by the auto tag
+	fallback    *uint64         // bits of the value given by the default tag
+	check       *checkSpec      // checksum given by the check tag
+	epoch       *epochSpec      // encoding of a time.Time field given by the epoch tag
+	scale       *scaleSpec      // conversion of a float field given by the scale tag
+	optional    bool            // whether the field is a pointer to the type of StructField
+
+	// elem is the plan of the elements of an array field, named by names
+	elem  *fieldPlan
+	names []string
+}
+
+// plans maps a validated struct type to its *structPlan.
+var plans sync.Map
+
+// planOf returns the plan of a validated struct type.
+func planOf(rt reflect.Type) *structPlan {
+	if plan, ok := plans.Load(rt); ok {
+		return plan.(*structPlan)
+	}
+	plan := &structPlan{
+		fields:    make([]fieldPlan, rt.NumField()),
+		offsets:   hasOffsets(rt),
+		variable:  hasVariable(rt),
+		bits:      structEnd(rt, 0),
+		validator: reflect.PointerTo(rt).Implements(validatorType),
+	}
+	for i := range plan.fields {
+		field := rt.Field(i)
+		plan.fields[i] = newFieldPlan(field)
+		if isVariable(field) {
+			plan.fields[i].count = countIndex(rt, field)
+		} else if isSwitch(field) {
+			plan.fields[i].count = switchIndex(rt, field)
+		} else if isSized(field) {
+			plan.fields[i].count = sizeIndex(rt, field)
+		}
+		if isConditional(field) {
+			plan.fields[i].cond, _ = parseCondition(rt, i)
+		}
+		if isArray(field) {
+			elem := newFieldPlan(arrayElement(field, 0))
+			names := make([]string, field.Type.Len())
+			for j := range names {
+				names[j] = field.Name + "[" + strconv.Itoa(j) + "]"
+			}
+			plan.fields[i].elem, plan.fields[i].names = &elem, names
+		}
+	}
+	actual, _ := plans.LoadOrStore(rt, plan)
+	return actual.(*structPlan)
+}
+
+func newFieldPlan(field reflect.StructField) fieldPlan {
+	optional := isOptional(field)
+	field = optionalElement(field)
+	bitSize, byteAligned, occupies := fieldBitSize(field)
+	_, mark := field.Tag.Lookup("byteorder")
+	enum, _ := enumTag(field)
+	constant, _ := tagBits(field, "const")
+	fallback, _ := tagBits(field, "default")
+	check, _ := checkTag(field)
+	epoch, _ := epochTag(field)
+	scale, _ := scaleTag(field)
+	if q, _, _ := qformatTag(field); q != nil {
+		scale = q
+	}
+	return fieldPlan{
+		StructField: field,
+		bitSize:     bitSize,
+		byteAligned: byteAligned,
+		occupies:    occupies,
+		skip:        fieldSkip(field),
+		nested:      isNestedStruct(field),
+		variable:    isVariable(field),
+		tlv:         isTLV(field),
+		variant:     isSwitch(field),
+		sized:       isSized(field),
+		rest:        isRest(field),
+		raw:         field.Type == rawType,
+		addr:        isAddrType(field.Type),
+		guid:        isGUID(field),
+		wide:        isWide(field),
+		varint:      isVarint(field),
+		enc:         field.Tag.Get("enc"),
+		signed:      field.Tag.Get("signed"),
+		mark:        mark,
+		endian:      fieldEndian(field),
+		modifiers:   fieldModifiers(field),
+		unmarshaler: isBitUnmarshaler(field.Type),
+		marshaler:   isBitMarshaler(field.Type),
+		enum:        enum,
+		constant:    constant,
+		auto:        isAuto(field),
+		fallback:    fallback,
+		check:       check,
+		epoch:       epoch,
+		scale:       scale,
+		optional:    optional,
+	}
+}
+
+// byteOrder returns the byte order of the field, which is given by its endian
+// tag if any, or byteOrder otherwise.
+func (f *fieldPlan) byteOrder(byteOrder ByteOrder) ByteOrder {
+	switch f.endian {
+	case "little":
+		return LittleEndian
+	case "big":
+		return BigEndian
+	default:
+		return byteOrder
+	}
+}
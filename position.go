@@ -0,0 +1,193 @@
+package bitfield
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// offsetField is a field of a struct with offset tags, which is placed at the
+// bit offset given by its tag rather than after the preceding field.
+type offsetField struct {
+	index  int // index of the field in the struct
+	offset int // bit offset of the field from the start of the struct
+	bits   int
+}
+
+// offsetCache maps a struct type with offset tags to its []offsetField.
+var offsetCache sync.Map
+
+// hasOffsets reports whether the fields of a struct are placed by offset tags.
+func hasOffsets(rt reflect.Type) bool {
+	for i := 0; i < rt.NumField(); i++ {
+		if _, ok := rt.Field(i).Tag.Lookup("offset"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// offsetFields returns the fields occupying bits of a struct with offset tags,
+// sorted by offset.
+func offsetFields(rt reflect.Type) []offsetField {
+	if fields, ok := offsetCache.Load(rt); ok {
+		return fields.([]offsetField)
+	}
+	var fields []offsetField
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		bitSize, _, ok := fieldBitSize(field)
+		if !ok {
+			continue
+		}
+		offset, _ := strconv.Atoi(field.Tag.Get("offset"))
+		fields = append(fields, offsetField{index: i, offset: offset, bits: bitSize})
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].offset < fields[j].offset
+	})
+	offsetCache.Store(rt, fields)
+	return fields
+}
+
+// validateOffsets validates the offset tags of a struct whose fields are
+// otherwise valid. Every field occupying bits must have an offset tag, and
+// the fields must not overlap. Nested structs, arrays and variable-length
+// fields cannot be placed by offset.
+func validateOffsets(rt reflect.Type) error {
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, hasTag := field.Tag.Lookup("offset")
+		if field.Type.Kind() == reflect.Array || isNestedStruct(field) || isVariable(field) || isTLV(field) || isRest(field) || isVarint(field) || isSwitch(field) || isSized(field) || isConditional(field) {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "nested struct, array or variable-length field cannot be placed by offset",
+			}
+		}
+		if _, ok := field.Tag.Lookup("skip"); ok {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "skip tag cannot be used with offset tags",
+			}
+		}
+		if _, ok := field.Tag.Lookup("check"); ok {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "check tag cannot be used with offset tags",
+			}
+		}
+		_, byteAligned, ok := fieldBitSize(field)
+		switch {
+		case !ok && hasTag:
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "offset tag on field occupying no bits",
+			}
+		case !ok:
+			continue
+		case !hasTag:
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "field of struct with offset tags must have offset",
+			}
+		}
+		offset, err := strconv.Atoi(tag)
+		if err != nil || offset < 0 {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "offset must be non-negative integer",
+			}
+		}
+		if byteAligned && offset%8 != 0 {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "offset of plain integer field must be multiple of 8",
+			}
+		}
+	}
+	fields := offsetFields(rt)
+	for i := 1; i < len(fields); i++ {
+		if prev := fields[i-1]; prev.offset+prev.bits > fields[i].offset {
+			field := rt.Field(fields[i].index)
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "field overlaps " + rt.Field(prev.index).Name,
+			}
+		}
+	}
+	return nil
+}
+
+// offsetsEnd returns the offset following the fields of a struct with offset
+// tags starting at offset. Such a struct starts from the next byte.
+func offsetsEnd(rt reflect.Type, offset int) int {
+	start := (offset + 7) / 8 * 8
+	fields := offsetFields(rt)
+	if len(fields) == 0 {
+		return start
+	}
+	last := fields[len(fields)-1]
+	return start + last.offset + last.bits
+}
+
+// seek moves the reader to the bit offset from the start of the data.
+func (r *bitReader) seek(offset int) {
+	r.iData, r.iBitInData = offset/8, offset%8
+}
+
+// seek moves the writer to the bit offset from the start of the data. Bits
+// skipped over are left zero.
+func (w *bitWriter) seek(offset int) {
+	w.iData, w.iBitInData = offset/8, offset%8
+}
+
+// unmarshalOffsets reads the fields of a struct with offset tags in order of
+// their offsets.
+func (r *bitReader) unmarshalOffsets(rv reflect.Value, plan *structPlan, prefix string, settable bool, options options) error {
+	r.alignToByte()
+	start := r.iData * 8
+	for _, o := range offsetFields(rv.Type()) {
+		f := &plan.fields[o.index]
+		vf, exported := settableField(f.StructField, rv.Field(o.index), settable, options)
+		r.seek(start + o.offset)
+		if err := r.unmarshalField(f, vf, prefix, exported, settable, options); err != nil {
+			return err
+		}
+		if f.mark {
+			if err := switchByteOrder(f.StructField, prefix, r.last, &options); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// marshalOffsets writes the fields of a struct with offset tags in order of
+// their offsets, leaving the gaps between them zero.
+func marshalOffsets(w *bitWriter, rv reflect.Value, plan *structPlan, prefix string, exported bool, options options) error {
+	w.alignToByte()
+	start := w.iData * 8
+	for _, o := range offsetFields(rv.Type()) {
+		f := &plan.fields[o.index]
+		vf, accessible := settableField(f.StructField, rv.Field(o.index), exported, options)
+		w.seek(start + o.offset)
+		if err := marshalField(w, f, vf, prefix, accessible, exported, options); err != nil {
+			return err
+		}
+		if f.mark {
+			if err := switchByteOrder(f.StructField, prefix, w.last, &options); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
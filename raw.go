@@ -0,0 +1,117 @@
+package bitfield
+
+import "reflect"
+
+// Raw is a field type that captures a span of bits verbatim. Use it for
+// regions of a format that the application does not interpret (yet) so that
+// they can be passed through unchanged.
+//
+// A Raw field must have a bit tag. Unlike integer bit-fields, its bit size is
+// not limited to 64 bits:
+//
+//	type header struct {
+//		Version  uint8        `bit:"4"`
+//		Reserved bitfield.Raw `bit:"20"`
+//		Length   uint8        `bit:"8"`
+//	}
+//
+// The extracted bits are stored in the same order as they appear in the input,
+// starting from the least significant bit of the first byte, or from the most
+// significant bit with [MSBFirst]. The byte order option does not affect Raw
+// fields. If the bit size is not a multiple of 8, the unused bits at the end
+// of the last byte are zero.
+//
+// [Marshal] writes the bits of a Raw field back in the same order. If the Raw
+// value is shorter than the bit size, the missing bits are zero; bits beyond
+// the bit size are ignored.
+type Raw []byte
+
+var rawType = reflect.TypeOf(Raw(nil))
+
+// Scratch is a reusable buffer from which Unmarshal allocates the storage of
+// [Raw] fields when given by [WithScratch]. Reusing a Scratch for every message
+// keeps a steady stream of decodes free of allocations.
+//
+// Raw fields decoded with a Scratch refer to its memory, and are valid only
+// until the next call of [Scratch.Reset]. Copy them to keep them longer.
+//
+// A Scratch must not be used by concurrent decodes. The zero value is an empty
+// Scratch ready to use.
+type Scratch struct {
+	buf []byte
+}
+
+// Reset makes the memory of the Scratch available for the following decodes.
+// Raw fields decoded before Reset must no longer be used.
+func (s *Scratch) Reset() {
+	s.buf = s.buf[:0]
+}
+
+// alloc returns n zeroed bytes from the Scratch. When the buffer runs short,
+// a larger one is allocated; slices returned before stay valid.
+func (s *Scratch) alloc(n int) []byte {
+	if cap(s.buf)-len(s.buf) < n {
+		s.buf = make([]byte, 0, max(2*cap(s.buf), n))
+	}
+	b := s.buf[len(s.buf) : len(s.buf)+n]
+	s.buf = s.buf[:len(s.buf)+n]
+	clear(b)
+	return b
+}
+
+func (r *bitReader) readRaw(bitSize int, scratch *Scratch) Raw {
+	var raw Raw
+	if scratch != nil {
+		raw = scratch.alloc((bitSize + 7) / 8)
+	} else {
+		raw = make(Raw, (bitSize+7)/8)
+	}
+	for i := 0; i < bitSize && r.hasBits(); i++ {
+		if r.bitOrder == MSBFirst {
+			bit := (r.data[r.iData] >> (7 - r.iBitInData)) & 1
+			raw[i/8] |= bit << (7 - i%8)
+		} else {
+			bit := (r.data[r.iData] >> r.iBitInData) & 1
+			raw[i/8] |= bit << (i % 8)
+		}
+		r.iBitInData++
+		if r.iBitInData >= 8 {
+			r.iData++
+			r.iBitInData = 0
+		}
+	}
+	return raw
+}
+
+// isZero reports whether all bits of a Raw value are zero.
+func isZero(raw Raw) bool {
+	for _, b := range raw {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *bitWriter) writeRaw(raw Raw, bitSize int) {
+	for i := 0; i < bitSize; {
+		n := 8 - w.iBitInData
+		if bitSize-i < n {
+			n = bitSize - i
+		}
+		// Bits of raw in the current byte of raw and the next one
+		var cur, next uint16
+		if i/8 < len(raw) {
+			cur = uint16(raw[i/8])
+		}
+		if i/8+1 < len(raw) {
+			next = uint16(raw[i/8+1])
+		}
+		if w.bitOrder == MSBFirst {
+			w.writeBits(byte((cur<<8|next)>>(16-i%8-n)), n)
+		} else {
+			w.writeBits(byte((next<<8|cur)>>(i%8)), n)
+		}
+		i += n
+	}
+}
@@ -0,0 +1,99 @@
+package bitfield
+
+import (
+	"log/slog"
+	"reflect"
+)
+
+// isRest reports whether the field holds the bytes remaining in the input.
+func isRest(field reflect.StructField) bool {
+	_, ok := field.Tag.Lookup("rest")
+	return ok
+}
+
+// validateRest validates a field holding the rest of the input, the i-th field
+// of rt. As the bytes extend to the end of the input, it must be the last
+// field, and words larger than a byte, whose padding would be read as bytes,
+// are rejected.
+func validateRest(rt reflect.Type, i int, options options) error {
+	field := rt.Field(i)
+	if field.Tag.Get("rest") != "true" {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: `rest must be given as rest:"true"`,
+		}
+	}
+	if field.Type.Kind() != reflect.Slice || field.Type.Elem().Kind() != reflect.Uint8 && field.Type.Elem().Kind() != reflect.Bool {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "rest field must be byte or bool slice",
+		}
+	}
+	for _, tag := range []string{"bit", "bytes", "count", "len", "tlv", "sizefrom", "offset", "skip"} {
+		if _, ok := field.Tag.Lookup(tag); ok {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "rest and " + tag + " tags must not be used together",
+			}
+		}
+	}
+	if i != rt.NumField()-1 {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "rest field must be last field of struct",
+		}
+	}
+	if options.wordSize > 8 {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "rest field does not apply with words larger than a byte",
+		}
+	}
+	return nil
+}
+
+// unmarshalRest reads the bytes from the next byte to the end of the input.
+// Bits of a last partial byte are not read. A bool slice is read from the next
+// bit, one bit per element, to the end of the input.
+func (r *bitReader) unmarshalRest(field reflect.StructField, vf reflect.Value, prefix string, exported bool, options options) error {
+	if isBools(field) {
+		count := max(r.nbits-r.iData*8-r.iBitInData, 0)
+		return r.unmarshalBools(field, vf, prefix, uint64(count), exported, options)
+	}
+	r.alignToByte()
+	offset := r.iData * 8
+	b := r.readRaw(max(r.nbits-offset, 0)/8*8, options.scratch)
+	if options.logger != nil {
+		logField(options.logger, prefix+field.Name, offset, len(b)*8, r.nbits, slog.Any("value", b))
+	}
+	if !exported {
+		return nil
+	}
+	if len(b) == 0 {
+		vf.SetZero()
+	} else {
+		vf.SetBytes(b)
+	}
+	return nil
+}
+
+// marshalRest writes the bytes of a field holding the rest of the input
+// verbatim from the next byte, or the bits of a bool slice from the next bit.
+// accessible reports whether the field can be read.
+func marshalRest(w *bitWriter, field reflect.StructField, vf reflect.Value, accessible bool) {
+	if isBools(field) {
+		if accessible {
+			marshalBools(w, vf)
+		}
+		return
+	}
+	w.alignToByte()
+	if accessible && vf.Len() > 0 {
+		w.writeRaw(vf.Bytes(), vf.Len()*8)
+	}
+}
@@ -0,0 +1,175 @@
+package rfcdiagram
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Generate writes Go source declaring a struct named typeName in package pkg
+// for the diagram.
+//
+// The struct must be decoded with bitfield.WithByteOrder(bitfield.BigEndian),
+// as diagrams are drawn in network byte order. Because bit-fields are filled
+// from the least significant bit, fields sharing a byte are listed in reverse
+// order. A field which starts in the middle of a byte and spans multiple bytes
+// is split into one struct field per byte, named with an index suffix, and a
+// method named after the field combines them into its value. Fields wider than
+// 64 bits must be byte-aligned; they become [bitfield.Raw] fields holding the
+// bytes as they appear in the input.
+func Generate(w io.Writer, d *Diagram, pkg, typeName string) error {
+	pieces, methods, err := split(d)
+	if err != nil {
+		return err
+	}
+	usesRaw := false
+	for _, p := range pieces {
+		usesRaw = usesRaw || p.bits > 64
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by rfc2go. DO NOT EDIT.\n\npackage %s\n", pkg)
+	if usesRaw {
+		fmt.Fprintf(&b, "\nimport \"github.com/jmatsuzawa/go-bitfield\"\n")
+	}
+	fmt.Fprintf(&b, "\n// %s must be decoded with bitfield.WithByteOrder(bitfield.BigEndian).\n", typeName)
+	if d.Truncated {
+		fmt.Fprintf(&b, "// Variable-length fields following the fixed part are not included.\n")
+	}
+	fmt.Fprintf(&b, "type %s struct {\n", typeName)
+	next := 0
+	for _, p := range pieces {
+		// Bits not covered by the diagram
+		if offset := streamOffset(p.offset, p.bits); offset > next {
+			fmt.Fprintf(&b, "_ uint8 `bit:\"%d\"`\n", offset-next)
+		}
+		fmt.Fprintf(&b, "%s %s `bit:\"%d\"`\n", p.name, p.typ, p.bits)
+		next = streamOffset(p.offset, p.bits) + p.bits
+	}
+	fmt.Fprintf(&b, "}\n")
+	for _, m := range methods {
+		fmt.Fprintf(&b, "\n// %s returns the value of the %q field, which spans multiple bytes.\n", m.name, m.label)
+		fmt.Fprintf(&b, "func (v *%s) %s() %s {\n", typeName, m.name, m.typ)
+		var terms []string
+		shift := 0
+		for i := len(m.parts) - 1; i >= 0; i-- {
+			term := fmt.Sprintf("%s(v.%s)", m.typ, m.parts[i].name)
+			if shift > 0 {
+				term = fmt.Sprintf("%s<<%d", term, shift)
+			}
+			terms = append([]string{term}, terms...)
+			shift += m.parts[i].bits
+		}
+		fmt.Fprintf(&b, "return %s\n}\n", strings.Join(terms, " | "))
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("rfcdiagram: format generated code: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// piece is a struct field generated for a field of a diagram.
+type piece struct {
+	name   string
+	offset int // RFC bit number of the most significant bit
+	bits   int
+	typ    string
+}
+
+// method combines the pieces of a field which spans multiple bytes.
+type method struct {
+	name, label, typ string
+	parts            []piece
+}
+
+// split converts the fields of a diagram into struct fields, sorted in the
+// order in which bit-fields are filled.
+func split(d *Diagram) ([]piece, []method, error) {
+	var pieces []piece
+	var methods []method
+	names := map[string]int{}
+	for _, f := range d.Fields {
+		name := uniqueName(names, goName(f.Label))
+		switch {
+		case f.Offset%8 == 0 && f.Bits%8 == 0 || f.Offset/8 == (f.Offset+f.Bits-1)/8:
+			typ := goType(f.Bits)
+			if f.Bits > 64 {
+				typ = "bitfield.Raw"
+			}
+			pieces = append(pieces, piece{name, f.Offset, f.Bits, typ})
+		case f.Bits > 64:
+			return nil, nil, fmt.Errorf("rfcdiagram: field %q is wider than 64 bits and not byte-aligned", f.Label)
+		default:
+			m := method{name: name, label: f.Label, typ: goType(f.Bits)}
+			for offset, end := f.Offset, f.Offset+f.Bits; offset < end; {
+				bits := min(8-offset%8, end-offset)
+				m.parts = append(m.parts, piece{name + strconv.Itoa(len(m.parts)), offset, bits, "uint8"})
+				offset += bits
+			}
+			pieces = append(pieces, m.parts...)
+			methods = append(methods, m)
+		}
+	}
+	// Bit-fields are filled from the least significant bit of each byte
+	sort.SliceStable(pieces, func(i, j int) bool {
+		return streamOffset(pieces[i].offset, pieces[i].bits) < streamOffset(pieces[j].offset, pieces[j].bits)
+	})
+	return pieces, methods, nil
+}
+
+// streamOffset converts the RFC bit number of the most significant bit of a
+// field within a byte, or of a byte-aligned field, into the offset counted
+// from the least significant bit of the first byte.
+func streamOffset(offset, bits int) int {
+	if offset%8 == 0 && bits%8 == 0 {
+		return offset
+	}
+	return offset/8*8 + 8 - (offset%8 + bits)
+}
+
+func goType(bits int) string {
+	size := 8
+	for size < bits {
+		size *= 2
+	}
+	return "uint" + strconv.Itoa(size)
+}
+
+// goName converts a label such as "Type of Service" into "TypeOfService".
+func goName(label string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(label, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		r := []rune(word)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	name := b.String()
+	if name == "" {
+		return "_"
+	}
+	if unicode.IsDigit(rune(name[0])) {
+		name = "F" + name
+	}
+	return name
+}
+
+func uniqueName(names map[string]int, name string) string {
+	if name == "_" {
+		return name
+	}
+	names[name]++
+	if n := names[name]; n > 1 {
+		return name + strconv.Itoa(n)
+	}
+	return name
+}
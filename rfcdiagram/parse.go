@@ -0,0 +1,204 @@
+// Package rfcdiagram parses the ASCII art packet diagrams used in RFCs and
+// generates Go structs with bit tags for them.
+//
+// A diagram looks like the following. Each bit occupies two columns, and
+// fields are separated by "|". A field spans multiple rows when the border
+// line below it has no "-" under the field.
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|Version|  IHL  |Type of Service|          Total Length         |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//
+// Rows drawn with ":" instead of "|" denote variable-length fields. They, and
+// everything after them, are not parsed.
+package rfcdiagram
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Diagram is a parsed packet diagram. Bits are numbered as in RFCs: bit 0 is
+// the most significant bit of the first byte.
+type Diagram struct {
+	// Fields lists the fields in order of their offset.
+	Fields []Field
+	// BitSize is the total number of bits covered by the fields.
+	BitSize int
+	// Truncated reports whether parsing stopped at a variable-length field.
+	Truncated bool
+}
+
+// Field is a field of a diagram.
+type Field struct {
+	// Label is the text in the diagram, with lines joined by spaces.
+	Label  string
+	Offset int
+	Bits   int
+}
+
+// SyntaxError describes a malformed diagram.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("rfcdiagram: line %d: %s", e.Line, e.Msg)
+}
+
+// segment is a field, or a part of a multi-row field, in a row.
+type segment struct {
+	start, end int // bit positions in the row
+	labels     []string
+	field      *Field
+}
+
+// parser holds the state of parsing a diagram.
+type parser struct {
+	fields  []*Field
+	rowBits int
+	row     int        // index of the current row
+	current []*segment // segments of the current row
+	open    []*segment // segments continued from the previous row
+}
+
+// Parse parses the first packet diagram in r. Lines before the first border
+// line, such as the bit numbers, are skipped, and parsing ends at the first
+// line which is not a part of the diagram.
+func Parse(r io.Reader) (*Diagram, error) {
+	scanner := bufio.NewScanner(r)
+	p := &parser{}
+	origin := -1 // column of the left edge
+	lineNo := 0
+	truncated := false
+scan:
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), " \t")
+		if origin < 0 {
+			if i := strings.Index(line, "+-"); i >= 0 && strings.Trim(line[i:], "+-") == "" {
+				origin = i
+				p.rowBits = (len(line) - i - 1) / 2
+			}
+			continue
+		}
+		if len(line) <= origin {
+			break
+		}
+		body := line[origin:]
+		var err error
+		switch body[0] {
+		case ':':
+			truncated = true
+			break scan
+		case '|':
+			err = p.content(body)
+		case '+':
+			err = p.border(body)
+		default:
+			break scan
+		}
+		if err != nil {
+			return nil, &SyntaxError{Line: lineNo, Msg: err.Error()}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if origin < 0 {
+		return nil, &SyntaxError{Line: lineNo, Msg: "no diagram found"}
+	}
+
+	d := &Diagram{Truncated: truncated}
+	for _, f := range p.fields {
+		d.Fields = append(d.Fields, *f)
+		d.BitSize = max(d.BitSize, f.Offset+f.Bits)
+	}
+	return d, nil
+}
+
+// content parses a line with labels.
+func (p *parser) content(body string) error {
+	var segs []*segment
+	start := 0
+	for i := 2; i < len(body); i += 2 {
+		if body[i] != '|' {
+			continue
+		}
+		label := strings.TrimSpace(body[2*start+1 : i])
+		s := &segment{start: start, end: i / 2}
+		if label != "" {
+			if strings.Contains(label, "...") {
+				return fmt.Errorf("variable-length field %q must be drawn with \":\"", label)
+			}
+			s.labels = []string{label}
+		}
+		segs = append(segs, s)
+		start = i / 2
+	}
+	if start == 0 || start > p.rowBits || 2*start+1 != len(body) {
+		return fmt.Errorf("malformed field boundaries")
+	}
+	if p.current == nil {
+		p.current = segs
+		return nil
+	}
+
+	// Another line of labels in the same row
+	if len(segs) != len(p.current) {
+		return fmt.Errorf("field boundaries differ from the previous line")
+	}
+	for i, s := range segs {
+		if s.start != p.current[i].start || s.end != p.current[i].end {
+			return fmt.Errorf("field boundaries differ from the previous line")
+		}
+		p.current[i].labels = append(p.current[i].labels, s.labels...)
+	}
+	return nil
+}
+
+// border parses a border line below a row. It attaches the segments of the
+// row to fields and keeps the segments which continue to the next row.
+func (p *parser) border(body string) error {
+	if p.current == nil {
+		return fmt.Errorf("border without fields")
+	}
+	var next []*segment
+	for _, s := range p.current {
+		for _, o := range p.open {
+			if o.start == s.start && o.end == s.end {
+				s.field = o.field
+				s.labels = append(o.labels, s.labels...)
+			}
+		}
+		if s.field == nil {
+			s.field = &Field{Offset: p.row*p.rowBits + s.start}
+			p.fields = append(p.fields, s.field)
+		}
+		s.field.Bits += s.end - s.start
+		s.field.Label = strings.Join(s.labels, " ")
+
+		inner := ""
+		if 2*s.start+1 < len(body) {
+			inner = body[2*s.start+1 : min(2*s.end, len(body))]
+		}
+		if inner == "" || strings.Contains(inner, "-") {
+			continue
+		}
+		// No border under the segment: the field continues to the next row
+		if label := strings.Trim(inner, " +"); label != "" {
+			s.labels = append(s.labels, label)
+			s.field.Label = strings.Join(s.labels, " ")
+		}
+		next = append(next, s)
+	}
+	p.open = next
+	p.current = nil
+	p.row++
+	return nil
+}
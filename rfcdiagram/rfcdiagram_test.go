@@ -0,0 +1,223 @@
+package rfcdiagram
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jmatsuzawa/go-bitfield"
+	"github.com/stretchr/testify/assert"
+)
+
+const ipv4Diagram = `
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |Version|  IHL  |Type of Service|          Total Length         |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |         Identification        |Flags|      Fragment Offset    |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |  Time to Live |    Protocol   |         Header Checksum       |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                       Source Address                          |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                    Destination Address                        |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   :                    Options                    :    Padding    :
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+`
+
+func TestParse_IPv4(t *testing.T) {
+	// Setup
+	want := &Diagram{
+		Fields: []Field{
+			{Label: "Version", Offset: 0, Bits: 4},
+			{Label: "IHL", Offset: 4, Bits: 4},
+			{Label: "Type of Service", Offset: 8, Bits: 8},
+			{Label: "Total Length", Offset: 16, Bits: 16},
+			{Label: "Identification", Offset: 32, Bits: 16},
+			{Label: "Flags", Offset: 48, Bits: 3},
+			{Label: "Fragment Offset", Offset: 51, Bits: 13},
+			{Label: "Time to Live", Offset: 64, Bits: 8},
+			{Label: "Protocol", Offset: 72, Bits: 8},
+			{Label: "Header Checksum", Offset: 80, Bits: 16},
+			{Label: "Source Address", Offset: 96, Bits: 32},
+			{Label: "Destination Address", Offset: 128, Bits: 32},
+		},
+		BitSize:   160,
+		Truncated: true,
+	}
+
+	// Exercise
+	got, err := Parse(strings.NewReader(ipv4Diagram))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestParse_MultiLineLabelsAndRows(t *testing.T) {
+	// Setup
+	input := `
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |  Data |       |C|E|U|A|P|R|S|F|
+   | Offset| Rsrvd |W|C|R|C|S|S|Y|I|
+   |       |       |R|E|G|K|H|T|N|N|
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                               |
+   +            Address            +
+   |                               |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+`
+	want := &Diagram{
+		Fields: []Field{
+			{Label: "Data Offset", Offset: 0, Bits: 4},
+			{Label: "Rsrvd", Offset: 4, Bits: 4},
+			{Label: "C W R", Offset: 8, Bits: 1},
+			{Label: "E C E", Offset: 9, Bits: 1},
+			{Label: "U R G", Offset: 10, Bits: 1},
+			{Label: "A C K", Offset: 11, Bits: 1},
+			{Label: "P S H", Offset: 12, Bits: 1},
+			{Label: "R S T", Offset: 13, Bits: 1},
+			{Label: "S Y N", Offset: 14, Bits: 1},
+			{Label: "F I N", Offset: 15, Bits: 1},
+			{Label: "Address", Offset: 16, Bits: 32},
+		},
+		BitSize: 48,
+	}
+
+	// Exercise
+	got, err := Parse(strings.NewReader(input))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestParseError(t *testing.T) {
+	testCases := map[string]string{
+		"No diagram":          "text only\n",
+		"Odd boundary":        "+-+-+-+-+\n|ab |    |\n+-+-+-+-+\n",
+		"Ellipsis in field":   "+-+-+-+-+\n|  ...  |\n+-+-+-+-+\n",
+		"Differing boundary":  "+-+-+-+-+\n| A | B |\n|   C   |\n+-+-+-+-+\n",
+		"Border without body": "+-+-+-+-+\n+-+-+-+-+\n",
+	}
+
+	for name, input := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			_, err := Parse(strings.NewReader(input))
+
+			// Verify
+			var syntaxError *SyntaxError
+			assert.ErrorAs(t, err, &syntaxError)
+		})
+	}
+}
+
+func TestGenerate_IPv4(t *testing.T) {
+	// Setup
+	d, _ := Parse(strings.NewReader(ipv4Diagram))
+	want := "// Code generated by rfc2go. DO NOT EDIT.\n" +
+		"\n" +
+		"package ipv4\n" +
+		"\n" +
+		"// IPv4 must be decoded with bitfield.WithByteOrder(bitfield.BigEndian).\n" +
+		"// Variable-length fields following the fixed part are not included.\n" +
+		"type IPv4 struct {\n" +
+		"\tIHL                uint8  `bit:\"4\"`\n" +
+		"\tVersion            uint8  `bit:\"4\"`\n" +
+		"\tTypeOfService      uint8  `bit:\"8\"`\n" +
+		"\tTotalLength        uint16 `bit:\"16\"`\n" +
+		"\tIdentification     uint16 `bit:\"16\"`\n" +
+		"\tFragmentOffset0    uint8  `bit:\"5\"`\n" +
+		"\tFlags              uint8  `bit:\"3\"`\n" +
+		"\tFragmentOffset1    uint8  `bit:\"8\"`\n" +
+		"\tTimeToLive         uint8  `bit:\"8\"`\n" +
+		"\tProtocol           uint8  `bit:\"8\"`\n" +
+		"\tHeaderChecksum     uint16 `bit:\"16\"`\n" +
+		"\tSourceAddress      uint32 `bit:\"32\"`\n" +
+		"\tDestinationAddress uint32 `bit:\"32\"`\n" +
+		"}\n" +
+		"\n" +
+		"// FragmentOffset returns the value of the \"Fragment Offset\" field, which spans multiple bytes.\n" +
+		"func (v *IPv4) FragmentOffset() uint16 {\n" +
+		"\treturn uint16(v.FragmentOffset0)<<8 | uint16(v.FragmentOffset1)\n" +
+		"}\n"
+
+	// Exercise
+	var got strings.Builder
+	err := Generate(&got, d, "ipv4", "IPv4")
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.String())
+}
+
+// ipv4 is the struct generated by TestGenerate_IPv4.
+type ipv4 struct {
+	IHL                uint8  `bit:"4"`
+	Version            uint8  `bit:"4"`
+	TypeOfService      uint8  `bit:"8"`
+	TotalLength        uint16 `bit:"16"`
+	Identification     uint16 `bit:"16"`
+	FragmentOffset0    uint8  `bit:"5"`
+	Flags              uint8  `bit:"3"`
+	FragmentOffset1    uint8  `bit:"8"`
+	TimeToLive         uint8  `bit:"8"`
+	Protocol           uint8  `bit:"8"`
+	HeaderChecksum     uint16 `bit:"16"`
+	SourceAddress      uint32 `bit:"32"`
+	DestinationAddress uint32 `bit:"32"`
+}
+
+func TestGenerate_DecodesIPv4(t *testing.T) {
+	// Setup
+	input := []byte{
+		0x45, 0x00, 0x00, 0x54, 0x1c, 0x46, 0x5f, 0xa1, 0x40, 0x01, 0xb1, 0xe6,
+		0xc0, 0xa8, 0x00, 0x68, 0xc0, 0xa8, 0x00, 0x01,
+	}
+
+	// Exercise
+	var got ipv4
+	err := bitfield.Unmarshal(input, &got, bitfield.WithByteOrder(bitfield.BigEndian))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, uint8(4), got.Version)
+	assert.Equal(t, uint8(5), got.IHL)
+	assert.Equal(t, uint16(84), got.TotalLength)
+	assert.Equal(t, uint8(0b010), got.Flags)
+	assert.Equal(t, uint16(0x1fa1), uint16(got.FragmentOffset0)<<8|uint16(got.FragmentOffset1))
+	assert.Equal(t, uint8(64), got.TimeToLive)
+	assert.Equal(t, uint32(0xc0a80068), got.SourceAddress)
+}
+
+func TestGenerate_WideFields(t *testing.T) {
+	// Setup
+	aligned := &Diagram{Fields: []Field{{Label: "Address", Offset: 0, Bits: 128}}, BitSize: 128}
+	unaligned := &Diagram{Fields: []Field{{Label: "Flag", Offset: 0, Bits: 1}, {Label: "Key", Offset: 1, Bits: 71}}, BitSize: 72}
+
+	// Exercise
+	var got strings.Builder
+	errAligned := Generate(&got, aligned, "p", "T")
+	errUnaligned := Generate(&strings.Builder{}, unaligned, "p", "T")
+
+	// Verify
+	assert.Nil(t, errAligned)
+	assert.Contains(t, got.String(), "import \"github.com/jmatsuzawa/go-bitfield\"")
+	assert.Contains(t, got.String(), "Address bitfield.Raw `bit:\"128\"`")
+	assert.NotNil(t, errUnaligned)
+}
+
+func TestStructOf(t *testing.T) {
+	// Setup
+	d, _ := Parse(strings.NewReader(ipv4Diagram))
+
+	// Exercise
+	got, err := StructOf(d)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.True(t, got.ConvertibleTo(reflect.TypeOf(ipv4{})))
+}
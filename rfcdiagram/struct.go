@@ -0,0 +1,46 @@
+package rfcdiagram
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/jmatsuzawa/go-bitfield"
+)
+
+var goTypes = map[string]reflect.Type{
+	"uint8":        reflect.TypeOf(uint8(0)),
+	"uint16":       reflect.TypeOf(uint16(0)),
+	"uint32":       reflect.TypeOf(uint32(0)),
+	"uint64":       reflect.TypeOf(uint64(0)),
+	"bitfield.Raw": reflect.TypeOf(bitfield.Raw(nil)),
+}
+
+// StructOf returns a struct type with the same fields as the struct generated
+// by [Generate], for decoding layouts which are loaded at run time. The
+// methods combining the parts of fields spanning multiple bytes are not
+// available.
+func StructOf(d *Diagram) (reflect.Type, error) {
+	pieces, _, err := split(d)
+	if err != nil {
+		return nil, err
+	}
+	var fields []reflect.StructField
+	next := 0
+	for _, p := range pieces {
+		if offset := streamOffset(p.offset, p.bits); offset > next {
+			fields = append(fields, reflect.StructField{
+				Name:    "_",
+				PkgPath: "github.com/jmatsuzawa/go-bitfield/rfcdiagram",
+				Type:    goTypes["uint8"],
+				Tag:     reflect.StructTag(`bit:"` + strconv.Itoa(offset-next) + `"`),
+			})
+		}
+		fields = append(fields, reflect.StructField{
+			Name: p.name,
+			Type: goTypes[p.typ],
+			Tag:  reflect.StructTag(`bit:"` + strconv.Itoa(p.bits) + `"`),
+		})
+		next = streamOffset(p.offset, p.bits) + p.bits
+	}
+	return reflect.StructOf(fields), nil
+}
@@ -0,0 +1,168 @@
+package bitfield
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// scaleSpec is the conversion of the bits of a float field into engineering
+// units given by its struct tag "scale", such as scale:"0.0625,signed" or
+// scale:"0.5,offset=-40", or by its struct tag "qformat": the value is the
+// raw integer times scale plus offset.
+type scaleSpec struct {
+	scale, offset float64
+	signed        bool   // whether the raw integer is in two's complement
+	round         string // rounding of qformat tags, or empty for nearest
+}
+
+// roundings are the rounding modes of qformat tags, which round a value to
+// the nearest raw integer by default.
+var roundings = map[string]func(float64) float64{
+	"nearest": math.Round,
+	"even":    math.RoundToEven,
+	"floor":   math.Floor,
+	"zero":    math.Trunc,
+}
+
+// scaleTag returns the conversion given by the scale tag of a field. spec is
+// nil if the field has no scale tag, and ok is false if the tag is invalid.
+func scaleTag(field reflect.StructField) (spec *scaleSpec, ok bool) {
+	tag, found := field.Tag.Lookup("scale")
+	if !found {
+		return nil, true
+	}
+	scale, rest, _ := strings.Cut(tag, ",")
+	spec = &scaleSpec{}
+	var err error
+	if spec.scale, err = strconv.ParseFloat(scale, 64); err != nil || spec.scale == 0 || math.IsInf(spec.scale, 0) {
+		return nil, false
+	}
+	if rest == "" {
+		return spec, true
+	}
+	for _, option := range strings.Split(rest, ",") {
+		switch name, value, _ := strings.Cut(option, "="); name {
+		case "signed":
+			spec.signed = true
+		case "offset":
+			if spec.offset, err = strconv.ParseFloat(value, 64); err != nil || math.IsInf(spec.offset, 0) {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return spec, true
+}
+
+// qformatTag returns the conversion given by the qformat tag of a field, such
+// as qformat:"8.8" or qformat:"1.15,round=even", and the bit size m+n of the
+// format Qm.n, whose m integer bits include the sign bit. spec is nil if the
+// field has no qformat tag, and ok is false if the tag is invalid.
+func qformatTag(field reflect.StructField) (spec *scaleSpec, bitSize int, ok bool) {
+	tag, found := field.Tag.Lookup("qformat")
+	if !found {
+		return nil, 0, true
+	}
+	format, rest, _ := strings.Cut(tag, ",")
+	integer, fraction, found := strings.Cut(format, ".")
+	m, errM := strconv.Atoi(integer)
+	n, errN := strconv.Atoi(fraction)
+	if !found || errM != nil || errN != nil || m < 1 || n < 0 || m+n > 64 {
+		return nil, 0, false
+	}
+	spec = &scaleSpec{scale: math.Ldexp(1, -n), signed: true}
+	if rest != "" {
+		name, value, _ := strings.Cut(rest, "=")
+		if _, ok := roundings[value]; name != "round" || !ok {
+			return nil, 0, false
+		}
+		spec.round = value
+	}
+	return spec, m + n, true
+}
+
+// validateQFormatTag validates the qformat tag of a field, if any.
+func validateQFormatTag(field reflect.StructField) error {
+	if _, found := field.Tag.Lookup("qformat"); !found {
+		return nil
+	}
+	if !isFloat(field.Type.Kind()) {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "qformat tag requires float field",
+		}
+	}
+	for _, tag := range []string{"bit", "bytes", "scale"} {
+		if _, ok := field.Tag.Lookup(tag); ok {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "qformat and " + tag + " tags must not be used together",
+			}
+		}
+	}
+	if _, _, ok := qformatTag(field); !ok {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "qformat must be m.n of up to 64 bits optionally followed by round=nearest, even, floor or zero",
+		}
+	}
+	return nil
+}
+
+// validateScaleTag validates the scale tag of a field, if any.
+func validateScaleTag(field reflect.StructField) error {
+	if _, found := field.Tag.Lookup("scale"); !found {
+		return nil
+	}
+	if _, hasBit := field.Tag.Lookup("bit"); !isFloat(field.Type.Kind()) || !hasBit {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "scale tag requires float field with bit tag",
+		}
+	}
+	if _, ok := scaleTag(field); !ok {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "scale must be nonzero number optionally followed by signed and offset such as 0.5,offset=-40",
+		}
+	}
+	return nil
+}
+
+// value returns the value in engineering units of the raw integer val of
+// bitSize bits.
+func (s *scaleSpec) value(val uint64, bitSize int) float64 {
+	if s.signed {
+		return float64(signed(val, bitSize))*s.scale + s.offset
+	}
+	return float64(val)*s.scale + s.offset
+}
+
+// bits returns the raw integer of bitSize bits nearest to the value v in
+// engineering units. ok is false if it does not fit.
+func (s *scaleSpec) bits(v float64, bitSize int) (val uint64, ok bool) {
+	round := math.Round
+	if s.round != "" {
+		round = roundings[s.round]
+	}
+	raw := round((v - s.offset) / s.scale)
+	if s.signed {
+		limit := math.Ldexp(1, bitSize-1)
+		if !(-limit <= raw && raw < limit) {
+			return 0, false
+		}
+		return uint64(int64(raw)) & (math.MaxUint64 >> (64 - bitSize)), true
+	}
+	if !(0 <= raw && raw < math.Ldexp(1, bitSize)) {
+		return 0, false
+	}
+	return uint64(raw), true
+}
@@ -0,0 +1,83 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmatsuzawa/go-bitfield"
+)
+
+// Document is a schema stored as JSON or YAML, so that the definition of a
+// format can be shared with tools in other languages and reloaded without
+// recompiling:
+//
+//	{
+//	  "name": "status",
+//	  "byteOrder": "big",
+//	  "fields": [
+//	    {"name": "ver", "bits": 4},
+//	    {"name": "state", "bits": 4, "enum": [
+//	      {"name": "idle", "value": 0},
+//	      {"name": "busy", "value": 1}
+//	    ]},
+//	    {"bits": 8},
+//	    {"name": "temp", "bits": 16, "signed": true, "endian": "little"}
+//	  ]
+//	}
+//
+// The fields are those of [Field], and the entries without a name are
+// padding. The byte order is "little" (the default) or "big", and the bit
+// order is "lsb" (the default) or "msb".
+type Document struct {
+	Name      string  `json:"name,omitempty" yaml:"name,omitempty"`
+	ByteOrder string  `json:"byteOrder,omitempty" yaml:"byteOrder,omitempty"`
+	BitOrder  string  `json:"bitOrder,omitempty" yaml:"bitOrder,omitempty"`
+	Fields    []Field `json:"fields" yaml:"fields"`
+}
+
+// Parse returns the schema of a document decoded by unmarshal, such as
+// json.Unmarshal or the Unmarshal function of a YAML package, which this
+// package does not depend on:
+//
+//	s, err := schema.Parse(data, yaml.Unmarshal)
+//
+// The byte and bit orders of the document are the options of the schema.
+func Parse(data []byte, unmarshal func([]byte, any) error) (*Schema, error) {
+	var doc Document
+	if err := unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	return doc.Schema()
+}
+
+// ParseJSON returns the schema of a JSON document.
+func ParseJSON(data []byte) (*Schema, error) {
+	return Parse(data, json.Unmarshal)
+}
+
+// Schema returns the schema described by the document.
+func (d *Document) Schema() (*Schema, error) {
+	s := New()
+	s.name = d.Name
+	switch d.ByteOrder {
+	case "", "little":
+	case "big":
+		s.opts = append(s.opts, bitfield.WithByteOrder(bitfield.BigEndian))
+	default:
+		return nil, fmt.Errorf("schema: unknown byte order %q", d.ByteOrder)
+	}
+	switch d.BitOrder {
+	case "", "lsb":
+	case "msb":
+		s.opts = append(s.opts, bitfield.WithBitOrder(bitfield.MSBFirst))
+	default:
+		return nil, fmt.Errorf("schema: unknown bit order %q", d.BitOrder)
+	}
+	for _, f := range d.Fields {
+		s.Add(f)
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s, nil
+}
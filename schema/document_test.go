@@ -0,0 +1,104 @@
+package schema_test
+
+import (
+	"testing"
+
+	"github.com/jmatsuzawa/go-bitfield"
+	"github.com/jmatsuzawa/go-bitfield/schema"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const statusJSON = `{
+  "name": "status",
+  "byteOrder": "big",
+  "fields": [
+    {"name": "ver", "bits": 4},
+    {"name": "state", "bits": 4, "enum": [
+      {"name": "idle", "value": 0},
+      {"name": "busy", "value": 1}
+    ]},
+    {"bits": 8},
+    {"name": "temp", "bits": 16, "signed": true, "endian": "little"},
+    {"name": "length", "bits": 16}
+  ]
+}`
+
+const statusYAML = `name: status
+byteOrder: big
+fields:
+  - name: ver
+    bits: 4
+  - name: state
+    bits: 4
+    enum:
+      - {name: idle, value: 0}
+      - {name: busy, value: 1}
+  - bits: 8
+  - {name: temp, bits: 16, signed: true, endian: little}
+  - {name: length, bits: 16}
+`
+
+func TestParse(t *testing.T) {
+	// Setup
+	testCases := map[string]func() (*schema.Schema, error){
+		"JSON": func() (*schema.Schema, error) { return schema.ParseJSON([]byte(statusJSON)) },
+		"YAML": func() (*schema.Schema, error) { return schema.Parse([]byte(statusYAML), yaml.Unmarshal) },
+	}
+	want := []schema.Value{
+		{Name: "ver", Value: uint64(2)},
+		{Name: "state", Value: uint64(1), Enum: "busy"},
+		{Name: "temp", Value: int64(-2)},
+		{Name: "length", Value: uint64(0x0102)},
+	}
+
+	for name, parse := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			s, err := parse()
+			got, errDecode := s.Decode([]byte{0x12, 0x00, 0xFE, 0xFF, 0x01, 0x02})
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, "status", s.Name())
+			assert.Nil(t, errDecode)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestParse_EnumError(t *testing.T) {
+	// Setup
+	s, _ := schema.ParseJSON([]byte(statusJSON))
+
+	// Exercise
+	_, err := s.Decode([]byte{0x32, 0x00, 0xFE, 0xFF, 0x01, 0x02})
+
+	// Verify
+	var enumErr *bitfield.EnumError
+	assert.ErrorAs(t, err, &enumErr)
+}
+
+func TestParseError(t *testing.T) {
+	// Setup
+	testCases := map[string]string{
+		"Syntax":    `{"fields": [`,
+		"ByteOrder": `{"byteOrder": "middle", "fields": [{"name": "a", "bits": 8}]}`,
+		"BitOrder":  `{"bitOrder": "msb-first", "fields": [{"name": "a", "bits": 8}]}`,
+		"Bits":      `{"fields": [{"name": "a", "bits": 0}]}`,
+		"EnumRange": `{"fields": [{"name": "a", "bits": 2, "enum": [{"name": "big", "value": 4}]}]}`,
+		"EnumSign":  `{"fields": [{"name": "a", "bits": 2, "enum": [{"name": "neg", "value": -1}]}]}`,
+		"EnumBool":  `{"fields": [{"name": "a", "bits": 1, "bool": true, "enum": [{"name": "on", "value": 1}]}]}`,
+	}
+
+	for name, doc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			s, err := schema.ParseJSON([]byte(doc))
+
+			// Verify
+			assert.Error(t, err)
+			assert.Nil(t, s)
+		})
+	}
+}
@@ -0,0 +1,274 @@
+// Package schema builds bit-field layouts at run time, for tools which load
+// field definitions from user configuration rather than declaring structs
+// with struct tags:
+//
+//	s := schema.New().Uint("ver", 4).Uint("flags", 12).Int("temp", 16)
+//	values, err := s.Decode(data, bitfield.WithByteOrder(bitfield.BigEndian))
+//
+// The fields are packed as bit-fields one after another, from the first bit
+// of the data. Decoding builds a struct type with the same fields and parses
+// it with [bitfield.Unmarshal], whose options apply. Schemas can also be
+// loaded from JSON or YAML documents with [Parse].
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jmatsuzawa/go-bitfield"
+)
+
+// Field is a field of a schema.
+type Field struct {
+	// Name is the name of the field, unique in the schema. It is empty for
+	// padding, whose bits are skipped.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Bits is the bit size of the field, from 1 to 64, or any positive size
+	// for padding.
+	Bits int `json:"bits" yaml:"bits"`
+	// Signed reports whether the field is a two's complement integer.
+	Signed bool `json:"signed,omitempty" yaml:"signed,omitempty"`
+	// Bool reports whether the field is a bool, true if any bit is set.
+	Bool bool `json:"bool,omitempty" yaml:"bool,omitempty"`
+	// Endian is the byte order of the field, "little" or "big", or empty for
+	// the byte order of the options.
+	Endian string `json:"endian,omitempty" yaml:"endian,omitempty"`
+	// Enum lists the valid values of an integer field, if restricted.
+	// Decoding returns [bitfield.EnumError] for other values.
+	Enum []EnumValue `json:"enum,omitempty" yaml:"enum,omitempty"`
+}
+
+// EnumValue is a named value of an integer field.
+type EnumValue struct {
+	Name  string `json:"name" yaml:"name"`
+	Value int64  `json:"value" yaml:"value"`
+}
+
+// Schema is a layout of fields built at run time. The methods adding fields
+// return the schema for chaining. An invalid field makes the schema invalid,
+// and the first problem is returned by the methods using the schema.
+type Schema struct {
+	name   string
+	fields []Field
+	opts   []bitfield.Option // options applied before those of each call
+	err    error
+}
+
+// New returns an empty schema.
+func New() *Schema {
+	return &Schema{}
+}
+
+// Uint adds an unsigned integer field of bits bits.
+func (s *Schema) Uint(name string, bits int) *Schema {
+	return s.Add(Field{Name: name, Bits: bits})
+}
+
+// Int adds a signed integer field of bits bits.
+func (s *Schema) Int(name string, bits int) *Schema {
+	return s.Add(Field{Name: name, Bits: bits, Signed: true})
+}
+
+// Bool adds a bool field of a single bit.
+func (s *Schema) Bool(name string) *Schema {
+	return s.Add(Field{Name: name, Bits: 1, Bool: true})
+}
+
+// Pad adds bits bits of padding.
+func (s *Schema) Pad(bits int) *Schema {
+	return s.Add(Field{Bits: bits})
+}
+
+// Add adds a field.
+func (s *Schema) Add(f Field) *Schema {
+	if s.err == nil {
+		if err := s.check(f); err != nil {
+			s.err = fmt.Errorf("schema: field %d %q: %w", len(s.fields), f.Name, err)
+		}
+	}
+	s.fields = append(s.fields, f)
+	return s
+}
+
+// check returns the problem of a field to be added, if any.
+func (s *Schema) check(f Field) error {
+	if f.Name == "" {
+		if f.Bits < 1 {
+			return fmt.Errorf("padding of %d bits", f.Bits)
+		}
+		return nil
+	}
+	for _, g := range s.fields {
+		if g.Name == f.Name {
+			return fmt.Errorf("duplicate name")
+		}
+	}
+	if !(1 <= f.Bits && f.Bits <= 64) {
+		return fmt.Errorf("bit size %d out of range [1, 64]", f.Bits)
+	}
+	if f.Bool && f.Signed {
+		return fmt.Errorf("bool field cannot be signed")
+	}
+	if f.Bool && len(f.Enum) > 0 {
+		return fmt.Errorf("bool field cannot have enum")
+	}
+	for _, e := range f.Enum {
+		min, max := int64(0), uint64(1)<<f.Bits-1
+		if f.Signed {
+			min, max = -1<<(f.Bits-1), 1<<(f.Bits-1)-1
+		}
+		if e.Value < min || e.Value >= 0 && uint64(e.Value) > max {
+			return fmt.Errorf("enum value %s %d does not fit in field", e.Name, e.Value)
+		}
+	}
+	switch f.Endian {
+	case "", "little", "big":
+	default:
+		return fmt.Errorf("unknown byte order %q", f.Endian)
+	}
+	return nil
+}
+
+// Options sets the options applied to every decoding before the options
+// passed to the call, such as the byte order of the format.
+func (s *Schema) Options(opts ...bitfield.Option) *Schema {
+	s.opts = opts
+	return s
+}
+
+// Name returns the name of the schema given by its document, if any.
+func (s *Schema) Name() string {
+	return s.name
+}
+
+// Fields returns the fields of the schema.
+func (s *Schema) Fields() []Field {
+	return s.fields
+}
+
+// Err returns the problem of the first invalid field, or nil.
+func (s *Schema) Err() error {
+	return s.err
+}
+
+// StructOf returns a struct type with the fields of the schema, which
+// [bitfield.Unmarshal], [bitfield.Marshal] and [bitfield.LayoutOf] accept. The
+// i-th field of the schema is the i-th struct field, named F followed by i,
+// and padding is [bitfield.Raw]. All fields are bit-fields, so that the
+// offsets do not depend on the alignment of plain integer fields.
+func (s *Schema) StructOf() (reflect.Type, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	fields := make([]reflect.StructField, len(s.fields))
+	for i, f := range s.fields {
+		tag := `bit:"` + strconv.Itoa(f.Bits) + `"`
+		if f.Endian != "" {
+			tag += ` endian:"` + f.Endian + `"`
+		}
+		if len(f.Enum) > 0 {
+			values := make([]string, len(f.Enum))
+			for j, e := range f.Enum {
+				values[j] = strconv.FormatInt(e.Value, 10)
+			}
+			tag += ` enum:"` + strings.Join(values, ",") + `"`
+		}
+		fields[i] = reflect.StructField{
+			Name: goName(i),
+			Type: fieldType(f),
+			Tag:  reflect.StructTag(tag),
+		}
+	}
+	return reflect.StructOf(fields), nil
+}
+
+// Value is the decoded value of a field: uint64 for an unsigned integer,
+// int64 for a signed integer, or bool.
+type Value struct {
+	Name  string
+	Value any
+	// Enum is the name of the value in the enum of the field, if any.
+	Enum string
+}
+
+// Decode parses data with the schema, and returns the values of the fields
+// in order, without padding. The options are those of [bitfield.Unmarshal].
+func (s *Schema) Decode(data []byte, opts ...bitfield.Option) ([]Value, error) {
+	rt, err := s.StructOf()
+	if err != nil {
+		return nil, err
+	}
+	out := reflect.New(rt)
+	if err := bitfield.Unmarshal(data, out.Interface(), append(s.opts[:len(s.opts):len(s.opts)], opts...)...); err != nil {
+		return nil, err
+	}
+	var values []Value
+	for i, f := range s.fields {
+		if f.Name == "" {
+			continue
+		}
+		fv := out.Elem().Field(i)
+		value := Value{Name: f.Name}
+		switch {
+		case f.Bool:
+			value.Value = fv.Bool()
+		case f.Signed:
+			value.Value = fv.Int()
+		default:
+			value.Value = fv.Uint()
+		}
+		for _, e := range f.Enum {
+			if f.Signed && e.Value == fv.Int() || !f.Signed && uint64(e.Value) == fv.Uint() {
+				value.Enum = e.Name
+			}
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// DecodeMap is like Decode, but returns the values keyed by name.
+func (s *Schema) DecodeMap(data []byte, opts ...bitfield.Option) (map[string]any, error) {
+	values, err := s.Decode(data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]any, len(values))
+	for _, v := range values {
+		m[v.Name] = v.Value
+	}
+	return m, nil
+}
+
+func goName(i int) string {
+	return "F" + strconv.Itoa(i)
+}
+
+// fieldType returns the Go type of the struct field of a schema field, the
+// smallest integer type holding its bits.
+func fieldType(f Field) reflect.Type {
+	switch {
+	case f.Name == "":
+		return reflect.TypeOf(bitfield.Raw(nil))
+	case f.Bool:
+		return reflect.TypeOf(false)
+	case f.Bits <= 8 && f.Signed:
+		return reflect.TypeOf(int8(0))
+	case f.Bits <= 8:
+		return reflect.TypeOf(uint8(0))
+	case f.Bits <= 16 && f.Signed:
+		return reflect.TypeOf(int16(0))
+	case f.Bits <= 16:
+		return reflect.TypeOf(uint16(0))
+	case f.Bits <= 32 && f.Signed:
+		return reflect.TypeOf(int32(0))
+	case f.Bits <= 32:
+		return reflect.TypeOf(uint32(0))
+	case f.Signed:
+		return reflect.TypeOf(int64(0))
+	default:
+		return reflect.TypeOf(uint64(0))
+	}
+}
@@ -0,0 +1,100 @@
+package schema_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jmatsuzawa/go-bitfield"
+	"github.com/jmatsuzawa/go-bitfield/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSchema() *schema.Schema {
+	return schema.New().
+		Uint("ver", 4).
+		Uint("flags", 12).
+		Int("temp", 16).
+		Bool("ok").
+		Pad(7).
+		Add(schema.Field{Name: "len", Bits: 16, Endian: "big"})
+}
+
+var testData = []byte{0xC4, 0xAB, 0xFE, 0xFF, 0x01, 0x01, 0x02}
+
+func TestDecode(t *testing.T) {
+	// Setup
+	want := []schema.Value{
+		{Name: "ver", Value: uint64(4)},
+		{Name: "flags", Value: uint64(0xABC)},
+		{Name: "temp", Value: int64(-2)},
+		{Name: "ok", Value: true},
+		{Name: "len", Value: uint64(0x0102)},
+	}
+
+	// Exercise
+	got, err := newTestSchema().Decode(testData)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestDecodeMap(t *testing.T) {
+	// Setup
+	want := map[string]any{"ver": uint64(4), "flags": uint64(0xABC), "temp": int64(-2), "ok": true, "len": uint64(0x0102)}
+
+	// Exercise
+	got, err := newTestSchema().DecodeMap(testData)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestDecode_Options(t *testing.T) {
+	// Setup
+	s := schema.New().Uint("id", 11).Uint("rtr", 1).Pad(4)
+
+	// Exercise
+	got, err := s.Decode([]byte{0x12, 0x38}, bitfield.WithBitOrder(bitfield.MSBFirst), bitfield.WithByteOrder(bitfield.BigEndian))
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, []schema.Value{{Name: "id", Value: uint64(0x091)}, {Name: "rtr", Value: uint64(1)}}, got)
+}
+
+func TestStructOf(t *testing.T) {
+	// Exercise
+	rt, err := newTestSchema().StructOf()
+	layout, errLayout := bitfield.LayoutOf(reflect.New(rt).Interface())
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Nil(t, errLayout)
+	assert.Equal(t, 56, layout.BitSize)
+	assert.Equal(t, "F5", layout.Fields[5].Name)
+	assert.Equal(t, bitfield.BigEndian, layout.Fields[5].ByteOrder)
+}
+
+func TestSchemaError(t *testing.T) {
+	// Setup
+	testCases := map[string]*schema.Schema{
+		"Duplicate":  schema.New().Uint("a", 1).Uint("a", 1),
+		"Zero":       schema.New().Uint("a", 0),
+		"Wide":       schema.New().Int("a", 65),
+		"SignedBool": schema.New().Add(schema.Field{Name: "a", Bits: 1, Signed: true, Bool: true}),
+		"Endian":     schema.New().Add(schema.Field{Name: "a", Bits: 8, Endian: "middle"}),
+		"Padding":    schema.New().Pad(0),
+	}
+
+	for name, s := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			_, err := s.Decode(make([]byte, 16))
+
+			// Verify
+			assert.Error(t, err)
+			assert.Equal(t, s.Err(), err)
+		})
+	}
+}
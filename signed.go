@@ -0,0 +1,78 @@
+package bitfield
+
+import "reflect"
+
+// validateSignedTag validates the signed tag of a field, if any, which gives
+// the representation of a signed integer field other than two's complement:
+// "zigzag" for the zigzag encoding of Protocol Buffers, in which 0, -1, 1, -2
+// and so on are 0, 1, 2, 3, "ones" for ones' complement, or "magnitude" for
+// sign-magnitude as the sm modifier of the bit tag.
+func validateSignedTag(field reflect.StructField) error {
+	tag, found := field.Tag.Lookup("signed")
+	if !found {
+		return nil
+	}
+	if !isSignedInteger(field.Type.Kind()) {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "signed tag requires signed integer field",
+		}
+	}
+	if fieldModifiers(field).signMagnitude {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "signed tag and sm modifier must not be used together",
+		}
+	}
+	if tag != "zigzag" && tag != "ones" && tag != "magnitude" {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "signed must be zigzag, ones or magnitude",
+		}
+	}
+	return nil
+}
+
+// fromSigned returns the two's complement bits of a value of bitSize bits in
+// the representation given by the signed tag of a field. The negative zero of
+// ones' complement and sign-magnitude is zero.
+func (f *fieldPlan) fromSigned(bits uint64, bitSize int) uint64 {
+	switch f.signed {
+	case "zigzag":
+		return (bits>>1 ^ -(bits & 1)) & (1<<bitSize - 1)
+	case "ones":
+		if bits>>(bitSize-1) == 0 {
+			return bits
+		}
+		return (bits + 1) & (1<<bitSize - 1)
+	case "magnitude":
+		return fromSignMagnitude(bits, bitSize)
+	}
+	return bits
+}
+
+// toSigned returns the bits of a two's complement value of bitSize bits in
+// the representation given by the signed tag of a field. ok is false for the
+// most negative value, which ones' complement and sign-magnitude lack.
+func (f *fieldPlan) toSigned(val uint64, bitSize int) (bits uint64, ok bool) {
+	v := signed(val, bitSize)
+	switch f.signed {
+	case "zigzag":
+		return uint64(v<<1^v>>63) & (1<<bitSize - 1), true
+	case "ones":
+		switch {
+		case v >= 0:
+			return val, true
+		case v == -1<<(bitSize-1):
+			return 0, false
+		default:
+			return (val - 1) & (1<<bitSize - 1), true
+		}
+	case "magnitude":
+		return toSignMagnitude(val, bitSize)
+	}
+	return val, true
+}
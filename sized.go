@@ -0,0 +1,210 @@
+package bitfield
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// isSized reports whether the field is decoded from a region whose size is
+// given by another field.
+func isSized(field reflect.StructField) bool {
+	_, ok := field.Tag.Lookup("sizefrom")
+	return ok
+}
+
+// sizeTag returns the name of the field giving the size of the region of a
+// field with a sizefrom tag, and the number of bytes counted by each unit of
+// the size. As with len tags, the unit may be given after the name, as in
+// sizefrom:"IHL,unit=4". ok is false if the unit is invalid.
+func sizeTag(field reflect.StructField) (name string, unit int, ok bool) {
+	name, opt, found := strings.Cut(field.Tag.Get("sizefrom"), ",")
+	if !found {
+		return name, 1, true
+	}
+	value, found := strings.CutPrefix(opt, "unit=")
+	if !found {
+		return name, 0, false
+	}
+	unit, err := strconv.Atoi(value)
+	return name, unit, err == nil && unit > 0
+}
+
+// sizeIndex returns the index of the field giving the size of the region of a
+// field with a sizefrom tag.
+func sizeIndex(rt reflect.Type, field reflect.StructField) int {
+	name, _, _ := sizeTag(field)
+	for i := 0; i < rt.NumField(); i++ {
+		if rt.Field(i).Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// validateSized validates a field with a sizefrom tag, the i-th field of rt.
+// The field must be a nested struct or a slice of pointers to structs, and the
+// size field an integer field declared before it in the same struct.
+// visiting lists the element types being validated, as for validateVariable.
+func validateSized(rt reflect.Type, i int, options options, visiting []reflect.Type) error {
+	field := rt.Field(i)
+	for _, tag := range []string{"bit", "bytes", "count", "len", "tlv", "switch", "offset", "skip"} {
+		if _, ok := field.Tag.Lookup(tag); ok {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "sizefrom and " + tag + " tags must not be used together",
+			}
+		}
+	}
+	if _, _, ok := sizeTag(field); !ok {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "sizefrom unit must be given as unit=N with positive N",
+		}
+	}
+	j := sizeIndex(rt, field)
+	if j < 0 || j > i || !isCountType(rt.Field(j)) {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "sizefrom must name preceding integer field",
+		}
+	}
+	elem := field.Type
+	if elem.Kind() == reflect.Slice && elem.Elem().Kind() == reflect.Pointer {
+		elem = elem.Elem().Elem()
+	}
+	if elem.Kind() != reflect.Struct || elem == rawType {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "sizefrom field must be struct or slice of pointers to structs",
+		}
+	}
+	if slices.Contains(visiting, elem) {
+		return nil
+	}
+	if err := validateFields(elem, options, append(visiting, elem)); err != nil {
+		var fieldErr *FieldError
+		if errors.As(err, &fieldErr) {
+			fieldErr.Path = field.Name + "." + fieldErr.Path
+		}
+		return err
+	}
+	return nil
+}
+
+// unmarshalSized reads a field with a sizefrom tag from a region of size units,
+// starting from the next byte. The fields of a nested struct beyond the region
+// are read as beyond the end of the input, and the elements of a slice are
+// read until the region ends. The reader then continues from the end of the
+// region, however many bits the field consumed. exported reports whether the
+// field can be set.
+func (r *bitReader) unmarshalSized(field reflect.StructField, vf reflect.Value, prefix string, size uint64, exported bool, options options) error {
+	r.alignToByte()
+	_, unit, _ := sizeTag(field)
+	start := r.iData * 8
+	if remaining := max(r.nbits-start, 0) / 8; size > uint64(remaining/unit) {
+		return &LengthError{
+			Field:   field,
+			Path:    prefix + field.Name,
+			problem: "size " + strconv.FormatUint(size, 10) + " exceeds the rest of the input",
+		}
+	}
+	end, nbits := start+int(size)*unit*8, r.nbits
+	r.nbits = end
+	defer func() {
+		r.iData, r.iBitInData, r.nbits = end/8, 0, nbits
+	}()
+	if field.Type.Kind() == reflect.Struct {
+		return r.unmarshalNested(vf, nestedPrefix(prefix, field), prefix+field.Name, exported, options)
+	}
+	elem := field.Type.Elem().Elem()
+	elems := reflect.MakeSlice(field.Type, 0, 0)
+	for i := 0; r.hasBits(); i++ {
+		elemPrefix := prefix + field.Name + "[" + strconv.Itoa(i) + "]."
+		elemPath := elemPrefix[:len(elemPrefix)-1]
+		p := reflect.New(elem)
+		if exported {
+			var err error
+			if p, err = newElement(elem, options); err != nil {
+				return fmt.Errorf("bitfield: cannot allocate %s: %w", elemPath, err)
+			}
+		}
+		offset := r.iData*8 + r.iBitInData
+		if err := r.unmarshalNested(p.Elem(), elemPrefix, elemPath, exported, options); err != nil {
+			return err
+		}
+		elems = reflect.Append(elems, p)
+		if r.iData*8+r.iBitInData == offset {
+			// An element occupying no bits would never reach the end
+			break
+		}
+	}
+	if exported {
+		if elems.Len() == 0 {
+			vf.SetZero()
+		} else {
+			vf.Set(elems)
+		}
+	}
+	return nil
+}
+
+// marshalSized writes a field with a sizefrom tag into a region of size units,
+// starting from the next byte, and pads the region with zeros. accessible
+// reports whether the field can be read; the region is zero otherwise.
+func marshalSized(w *bitWriter, field reflect.StructField, vf reflect.Value, prefix string, size uint64, accessible bool, options options) error {
+	w.alignToByte()
+	_, unit, _ := sizeTag(field)
+	start := w.iData * 8
+	end := start + int(size)*unit*8
+	if field.Type.Kind() == reflect.Struct {
+		if err := marshalNested(w, vf, nestedPrefix(prefix, field), prefix+field.Name, accessible, options); err != nil {
+			return err
+		}
+	} else if accessible {
+		for i := 0; i < vf.Len(); i++ {
+			elemPrefix := prefix + field.Name + "[" + strconv.Itoa(i) + "]."
+			p := vf.Index(i)
+			if p.IsNil() {
+				// A nil element is encoded as the zero value
+				p = reflect.New(p.Type().Elem())
+			}
+			if err := marshalNested(w, p.Elem(), elemPrefix, elemPrefix[:len(elemPrefix)-1], true, options); err != nil {
+				return err
+			}
+		}
+	}
+	w.alignToByte()
+	if w.iData*8 > end {
+		return &LengthError{
+			Field:   field,
+			Path:    prefix + field.Name,
+			problem: "encoding of " + strconv.Itoa(w.iData-start/8) + " bytes exceeds size " + strconv.FormatUint(size, 10),
+		}
+	}
+	if n := end - w.iData*8; n > 0 {
+		w.skip(n)
+	}
+	return nil
+}
+
+// sizedEnd returns the end of the region of a field with a sizefrom tag, the
+// i-th field of the struct value rv, starting from offset.
+func sizedEnd(rv reflect.Value, i, offset int) int {
+	rt := rv.Type()
+	field := rt.Field(i)
+	_, unit, _ := sizeTag(field)
+	var size uint64
+	if j := sizeIndex(rt, field); j >= 0 {
+		bitSize, _, _ := fieldBitSize(rt.Field(j))
+		size, _ = fieldValue(rt.Field(j), rv.Field(j), bitSize)
+	}
+	return (offset+7)/8*8 + int(size)*unit*8
+}
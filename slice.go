@@ -0,0 +1,452 @@
+package bitfield
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// isVariable reports whether the field is a slice whose length is given by
+// another field, so that its size depends on the data.
+func isVariable(field reflect.StructField) bool {
+	if field.Type.Kind() != reflect.Slice {
+		return false
+	}
+	_, hasCount := field.Tag.Lookup("count")
+	_, hasLen := field.Tag.Lookup("len")
+	return hasCount || hasLen
+}
+
+// isBytes reports whether a variable-length field is a byte slice with a len
+// tag rather than a slice of structs with a count tag.
+func isBytes(field reflect.StructField) bool {
+	_, ok := field.Tag.Lookup("len")
+	return ok
+}
+
+// isBools reports whether a variable-length field or a field holding the rest
+// of the input is a bool slice, packed one bit per element.
+func isBools(field reflect.StructField) bool {
+	return !isBytes(field) && field.Type.Elem().Kind() == reflect.Bool
+}
+
+// hasVariable reports whether a struct has a variable-length or conditional
+// field of its own.
+func hasVariable(rt reflect.Type) bool {
+	for i := 0; i < rt.NumField(); i++ {
+		if isVariable(rt.Field(i)) || isSwitch(rt.Field(i)) || isSized(rt.Field(i)) || isConditional(rt.Field(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// variableField returns the first variable-length field of a validated struct
+// type, including the fields of nested structs, and its path.
+func variableField(rt reflect.Type, prefix string) (reflect.StructField, string, bool) {
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if isArray(field) {
+			field.Type = field.Type.Elem()
+		}
+		if isVariable(field) || isTLV(field) || isRest(field) || isVarint(field) || isSwitch(field) || isSized(field) || isConditional(field) {
+			return field, prefix + field.Name, true
+		}
+		if isNestedStruct(field) {
+			if f, path, ok := variableField(field.Type, nestedPrefix(prefix, field)); ok {
+				return f, path, true
+			}
+		}
+	}
+	return reflect.StructField{}, "", false
+}
+
+// lengthTag returns the name of the field giving the length of a
+// variable-length field, and the number of bytes counted by each unit of the
+// length. A len tag may give the unit after the name, as in
+// len:"Words,unit=4" for a length in 32-bit words. ok is false if the unit is
+// invalid.
+func lengthTag(field reflect.StructField) (name string, unit int, ok bool) {
+	if !isBytes(field) {
+		return field.Tag.Get("count"), 1, true
+	}
+	name, opt, found := strings.Cut(field.Tag.Get("len"), ",")
+	if !found {
+		return name, 1, true
+	}
+	value, found := strings.CutPrefix(opt, "unit=")
+	if !found {
+		return name, 0, false
+	}
+	unit, err := strconv.Atoi(value)
+	return name, unit, err == nil && unit > 0
+}
+
+// countIndex returns the index of the field giving the length of a
+// variable-length field.
+func countIndex(rt reflect.Type, field reflect.StructField) int {
+	name, _, _ := lengthTag(field)
+	for i := 0; i < rt.NumField(); i++ {
+		if rt.Field(i).Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// validateVariable validates a variable-length field, the i-th field of rt.
+// The count field must be an integer field declared before it in the same
+// struct. visiting lists the element types being validated, which are not
+// validated again for self-referential types.
+func validateVariable(rt reflect.Type, i int, options options, visiting []reflect.Type) error {
+	field := rt.Field(i)
+	if field.Type.Kind() == reflect.Array {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "array of variable-length fields is not supported",
+		}
+	}
+	if _, _, ok := lengthTag(field); !ok {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "len unit must be given as unit=N with positive N",
+		}
+	}
+	j := countIndex(rt, field)
+	if j < 0 || j > i || !isCountType(rt.Field(j)) {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "count or len must name preceding integer field",
+		}
+	}
+	elem := field.Type.Elem()
+	if isBytes(field) {
+		if _, ok := field.Tag.Lookup("count"); ok {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "count and len tags must not be used together",
+			}
+		}
+		if elem.Kind() != reflect.Uint8 {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "len field must be byte slice",
+			}
+		}
+		return nil
+	}
+	if elem.Kind() == reflect.Bool {
+		return nil
+	}
+	if elem.Kind() != reflect.Pointer || elem.Elem().Kind() != reflect.Struct {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "count field must be slice of bools or pointers to structs",
+		}
+	}
+	if slices.Contains(visiting, elem.Elem()) {
+		return nil
+	}
+	if err := validateFields(elem.Elem(), options, append(visiting, elem.Elem())); err != nil {
+		var fieldErr *FieldError
+		if errors.As(err, &fieldErr) {
+			fieldErr.Path = field.Name + "." + fieldErr.Path
+		}
+		return err
+	}
+	return nil
+}
+
+// isCountType reports whether a field can give the length of a variable-length
+// field.
+func isCountType(field reflect.StructField) bool {
+	if field.Type == rawType || isAddrType(field.Type) || isWide(field) || isOptional(field) || field.Type.Kind() == reflect.Bool || isFloat(field.Type.Kind()) || isNestedStruct(field) {
+		return false
+	}
+	_, _, ok := fieldBitSize(field)
+	return ok
+}
+
+// unmarshalSlice reads the elements of a variable-length field whose length
+// is count. exported reports whether the field can be set.
+func (r *bitReader) unmarshalSlice(field reflect.StructField, vf reflect.Value, prefix string, count uint64, exported bool, options options) error {
+	if isBytes(field) {
+		_, unit, _ := lengthTag(field)
+		return r.unmarshalBytes(field, vf, prefix, count, unit, exported, options)
+	}
+	if isBools(field) {
+		return r.unmarshalBools(field, vf, prefix, count, exported, options)
+	}
+	elem := field.Type.Elem().Elem()
+	// Reject a count which cannot be satisfied by the rest of the input
+	// before allocating the elements
+	remaining := max(r.nbits-r.iData*8-r.iBitInData, 0)
+	if count > uint64(remaining/max(planOf(elem).bits, 1)) {
+		return &LengthError{
+			Field:   field,
+			Path:    prefix + field.Name,
+			problem: "count " + strconv.FormatUint(count, 10) + " exceeds the rest of the input",
+		}
+	}
+	if exported {
+		if count == 0 {
+			vf.SetZero()
+		} else {
+			vf.Set(reflect.MakeSlice(field.Type, int(count), int(count)))
+		}
+	}
+	for i := 0; i < int(count); i++ {
+		elemPrefix := prefix + field.Name + "[" + strconv.Itoa(i) + "]."
+		elemPath := elemPrefix[:len(elemPrefix)-1]
+		if !exported {
+			if err := r.unmarshalNested(reflect.New(elem).Elem(), elemPrefix, elemPath, false, options); err != nil {
+				return err
+			}
+			continue
+		}
+		p, err := newElement(elem, options)
+		if err != nil {
+			return fmt.Errorf("bitfield: cannot allocate %s: %w", elemPath, err)
+		}
+		vf.Index(i).Set(p)
+		if err := r.unmarshalNested(p.Elem(), elemPrefix, elemPath, true, options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarshalBytes reads a byte slice of length units of unit bytes, starting
+// from the next byte like a plain integer field.
+func (r *bitReader) unmarshalBytes(field reflect.StructField, vf reflect.Value, prefix string, length uint64, unit int, exported bool, options options) error {
+	r.alignToByte()
+	offset := r.iData * 8
+	if remaining := max(r.nbits-offset, 0) / 8; length > uint64(remaining/unit) {
+		return &LengthError{
+			Field:   field,
+			Path:    prefix + field.Name,
+			problem: "length " + strconv.FormatUint(length, 10) + " exceeds the rest of the input",
+		}
+	}
+	b := r.readRaw(int(length)*unit*8, options.scratch)
+	if options.logger != nil {
+		logField(options.logger, prefix+field.Name, offset, len(b)*8, r.nbits, slog.Any("value", b))
+	}
+	if !exported {
+		return nil
+	}
+	if length == 0 {
+		vf.SetZero()
+	} else {
+		vf.SetBytes(b)
+	}
+	return nil
+}
+
+// unmarshalBools reads a bool slice of count elements packed one bit per
+// element, in the bit order of the reader.
+func (r *bitReader) unmarshalBools(field reflect.StructField, vf reflect.Value, prefix string, count uint64, exported bool, options options) error {
+	offset := r.iData*8 + r.iBitInData
+	if remaining := max(r.nbits-offset, 0); count > uint64(remaining) {
+		return &LengthError{
+			Field:   field,
+			Path:    prefix + field.Name,
+			problem: "count " + strconv.FormatUint(count, 10) + " exceeds the rest of the input",
+		}
+	}
+	bools := make([]bool, count)
+	for i := range bools {
+		bools[i] = r.readValue(1, LittleEndian) != 0
+	}
+	if options.logger != nil {
+		logField(options.logger, prefix+field.Name, offset, len(bools), r.nbits, slog.Any("value", bools))
+	}
+	if !exported {
+		return nil
+	}
+	if count == 0 {
+		vf.SetZero()
+	} else {
+		v := reflect.MakeSlice(field.Type, len(bools), len(bools))
+		for i, b := range bools {
+			v.Index(i).SetBool(b)
+		}
+		vf.Set(v)
+	}
+	return nil
+}
+
+// newElement returns a pointer to a new struct of type rt, allocated by the
+// element factory if any.
+func newElement(rt reflect.Type, options options) (reflect.Value, error) {
+	if options.factory == nil {
+		return reflect.New(rt), nil
+	}
+	v, err := options.factory(rt)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	p := reflect.ValueOf(v)
+	if p.Type() != reflect.PointerTo(rt) || p.IsNil() {
+		return reflect.Value{}, fmt.Errorf("element factory returned %T instead of non-nil *%s", v, rt)
+	}
+	return p, nil
+}
+
+// marshalSlice writes the elements of a variable-length field, whose number
+// must be count, the value of its count or len field. accessible reports
+// whether the field can be read.
+func marshalSlice(w *bitWriter, field reflect.StructField, vf reflect.Value, prefix string, count uint64, accessible bool, options options) error {
+	n := 0
+	if accessible {
+		n = vf.Len()
+	}
+	_, unit, _ := lengthTag(field)
+	if n%unit != 0 || uint64(n/unit) != count {
+		want := "count " + strconv.FormatUint(count, 10)
+		if isBytes(field) {
+			want = "len " + strconv.FormatUint(count, 10)
+		}
+		if unit > 1 {
+			want += " of " + strconv.Itoa(unit) + "-byte units"
+		}
+		return &LengthError{
+			Field:   field,
+			Path:    prefix + field.Name,
+			problem: "slice of " + strconv.Itoa(n) + " elements does not match " + want,
+		}
+	}
+	if isBytes(field) {
+		w.alignToByte()
+		if n > 0 {
+			w.writeRaw(vf.Bytes(), n*8)
+		}
+		return nil
+	}
+	if isBools(field) {
+		if n > 0 {
+			marshalBools(w, vf)
+		}
+		return nil
+	}
+	for i := 0; i < n; i++ {
+		elemPrefix := prefix + field.Name + "[" + strconv.Itoa(i) + "]."
+		p := vf.Index(i)
+		if p.IsNil() {
+			// A nil element is encoded as the zero value
+			p = reflect.New(p.Type().Elem())
+		}
+		if err := marshalNested(w, p.Elem(), elemPrefix, elemPrefix[:len(elemPrefix)-1], true, options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// marshalBools writes a bool slice packed one bit per element, in the bit
+// order of the writer.
+func marshalBools(w *bitWriter, vf reflect.Value) {
+	for i := 0; i < vf.Len(); i++ {
+		var bit uint64
+		if vf.Index(i).Bool() {
+			bit = 1
+		}
+		w.writeValue(bit, 1, LittleEndian)
+	}
+}
+
+// valueEnd is like structEnd, but also counts the elements of the
+// variable-length fields of a struct value. depth is the number of levels of
+// nested structs allowed below rv, and ok is false if they are nested deeper.
+func valueEnd(rv reflect.Value, offset, depth int) (end int, ok bool) {
+	rt := rv.Type()
+	if hasOffsets(rt) {
+		return offsetsEnd(rt, offset), true
+	}
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		vf := rv.Field(i)
+		if isConditional(field) {
+			if c, _ := parseCondition(rt, i); c != nil && !c.holdsFor(rv) && !(c.flag && !vf.IsZero()) {
+				continue
+			}
+		}
+		if isSized(field) {
+			offset = sizedEnd(rv, i, offset)
+			continue
+		}
+		if isArray(field) {
+			for j := 0; j < vf.Len(); j++ {
+				if offset, ok = valueFieldEnd(arrayElement(field, j), vf.Index(j), offset, depth); !ok {
+					return offset, false
+				}
+			}
+			continue
+		}
+		if offset, ok = valueFieldEnd(field, vf, offset, depth); !ok {
+			return offset, false
+		}
+	}
+	return offset, true
+}
+
+func valueFieldEnd(field reflect.StructField, vf reflect.Value, offset, depth int) (end int, ok bool) {
+	switch {
+	case isTLV(field):
+		return tlvEnd(field, vf, offset), true
+	case isRest(field) && isBools(field):
+		return offset + vf.Len(), true
+	case isRest(field):
+		return (offset+7)/8*8 + vf.Len()*8, true
+	case isVarint(field):
+		return (offset+7)/8*8 + varintLen(field, vf)*8, true
+	case isSwitch(field):
+		if vf.IsNil() {
+			return offset, true
+		}
+		if depth == 0 {
+			return offset, false
+		}
+		ev := vf.Elem()
+		if ev.Kind() == reflect.Pointer {
+			if ev.IsNil() {
+				return offset, true
+			}
+			ev = ev.Elem()
+		}
+		return valueEnd(ev, offset, depth-1)
+	case isVariable(field) && isBytes(field):
+		return (offset+7)/8*8 + vf.Len()*8, true
+	case isVariable(field) && isBools(field):
+		return offset + vf.Len(), true
+	case isVariable(field):
+		if vf.Len() > 0 && depth == 0 {
+			return offset, false
+		}
+		for i := 0; i < vf.Len(); i++ {
+			if p := vf.Index(i); p.IsNil() {
+				offset = structEnd(p.Type().Elem(), offset)
+			} else if offset, ok = valueEnd(p.Elem(), offset, depth-1); !ok {
+				return offset, false
+			}
+		}
+		return offset, true
+	case isNestedStruct(field):
+		if depth == 0 {
+			return offset, false
+		}
+		return valueEnd(vf, offset+fieldSkip(field), depth-1)
+	default:
+		return fieldEnd(field, offset), true
+	}
+}
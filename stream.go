@@ -0,0 +1,278 @@
+package bitfield
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// A Decoder reads structs with bit-fields from an input stream.
+type Decoder struct {
+	r         io.Reader
+	options   options
+	err       error // error from the options or the reader, returned by Decode
+	buf       []byte
+	undecoded int // number of bytes read of a struct cut off by the error
+}
+
+// errDecoderClosed is returned by a Decoder after [Decoder.Close].
+var errDecoderClosed = errors.New("bitfield: decoder is closed")
+
+// NewDecoder returns a new decoder that reads from r. The opts are applied to
+// every call of [Decoder.Decode] as in [Unmarshal].
+func NewDecoder(r io.Reader, opts ...Option) *Decoder {
+	options, err := collectOptions(opts)
+	return &Decoder{r: r, options: options, err: err}
+}
+
+// Decode reads the bytes of the next struct from its input and stores the
+// result in the struct pointed by out. It reads exactly the number of bytes
+// occupied by the fields of the struct, so that the following data can be
+// read by the next call or directly from the reader.
+//
+// Errors from reading the input are sticky: once Decode returns one, every
+// later call returns the same error. Thus a loop decoding until [io.EOF] ends
+// at a clean boundary between structs, and [Decoder.Close] reports whether the
+// input ended in the middle of a struct.
+//
+// Returns:
+//
+//   - nil if the struct is successfully decoded
+//   - [io.EOF] if the input is at its end before the first byte of the struct
+//   - [io.ErrUnexpectedEOF] if the input ends in the middle of the struct,
+//     including its frame header with [WithFrameLength]
+//   - any other error returned by the reader
+//   - the same errors as [Unmarshal] for an invalid out; nothing is read
+//   - [FieldError] if out has a variable-length field such as a slice with
+//     a count, len or tlv tag without [WithFrameLength]; nothing is read
+//   - [FrameError] if the frame length is invalid with [WithFrameLength]
+func (d *Decoder) Decode(out any) error {
+	if d.err != nil {
+		return d.err
+	}
+	if err := validateUnmarshalType(out, d.options); err != nil {
+		return err
+	}
+	if d.options.frame != nil {
+		return d.decodeFrame(out)
+	}
+	if field, path, ok := variableField(reflect.TypeOf(out).Elem(), ""); ok {
+		return &FieldError{
+			Field:   field,
+			Path:    path,
+			problem: "variable-length field requires WithFrameLength",
+		}
+	}
+	size := (d.options.wordEnd(encodedBitSize(reflect.TypeOf(out).Elem())) + 7) / 8
+	buf := d.buffer(size)
+	if err := d.read(buf, 0); err != nil {
+		return err
+	}
+	_, err := unmarshal(buf, len(buf)*8, out, d.options)
+	return err
+}
+
+// decodeFrame reads a frame whose length is given by a field of the header.
+func (d *Decoder) decodeFrame(out any) error {
+	frame := d.options.frame
+	layout := layoutOf(reflect.TypeOf(out).Elem(), d.options)
+	var length *FieldLayout
+	for i, f := range layout.Fields {
+		if f.Name == frame.field && f.Type != rawType {
+			length = &layout.Fields[i]
+		}
+	}
+	if length == nil {
+		return &FrameError{problem: "length field " + frame.field + " not found"}
+	}
+
+	headerSize := (length.Offset + length.Bits + 7) / 8
+	header := d.buffer(headerSize)
+	if err := d.read(header, 0); err != nil {
+		return err
+	}
+	r := &bitReader{data: header, nbits: headerSize * 8, iData: length.Offset / 8, iBitInData: length.Offset % 8, bitOrder: d.options.bitOrder, wordSize: d.options.wordSize}
+	val := r.readValue(length.Bits, length.ByteOrder)
+	var bodySize int64
+	switch {
+	case length.Signed:
+		bodySize = signed(val, length.Bits)
+	case val > maxFrameSize:
+		return &FrameError{problem: "frame length " + strconv.FormatUint(val, 10) + " too large"}
+	default:
+		bodySize = int64(val)
+	}
+	bodySize += int64(frame.adjustment)
+	if bodySize < 0 || bodySize > maxFrameSize {
+		return &FrameError{problem: "invalid frame length " + strconv.FormatInt(bodySize, 10)}
+	}
+
+	buf := d.buffer(headerSize + int(bodySize))
+	if err := d.read(buf[headerSize:], headerSize); err != nil {
+		return err
+	}
+	_, err := unmarshal(buf, len(buf)*8, out, d.options)
+	return err
+}
+
+// read fills buf from the input, after the first n bytes of the struct have
+// been read. An error from the reader is kept to be returned by later calls.
+func (d *Decoder) read(buf []byte, n int) error {
+	read, err := io.ReadFull(d.r, buf)
+	if err == nil {
+		return nil
+	}
+	if err == io.EOF && n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	d.err, d.undecoded = err, n+read
+	return err
+}
+
+// Close reports an error wrapping [io.ErrUnexpectedEOF] if the input ended in
+// the middle of a struct, with the number of bytes of the struct which were
+// read but not decoded. Decode returns an error after Close. Close does not
+// close the underlying reader.
+func (d *Decoder) Close() error {
+	if d.err == errDecoderClosed {
+		return nil
+	}
+	d.err = errDecoderClosed
+	if d.undecoded > 0 {
+		return fmt.Errorf("bitfield: %d bytes of incomplete struct not decoded: %w", d.undecoded, io.ErrUnexpectedEOF)
+	}
+	return nil
+}
+
+// maxFrameSize is the limit of the body size of a frame read by a Decoder,
+// which prevents a corrupted length field from exhausting memory.
+const maxFrameSize = 1 << 30
+
+// buffer returns the first size bytes of the buffer of the Decoder, keeping
+// its existing contents.
+func (d *Decoder) buffer(size int) []byte {
+	if cap(d.buf) < size {
+		buf := make([]byte, size)
+		copy(buf, d.buf)
+		d.buf = buf
+	}
+	return d.buf[:size]
+}
+
+// An Encoder writes structs with bit-fields to an output stream.
+//
+// The structs are written as a continuous stream of bits: a struct whose size
+// is not a multiple of 8 bits is followed by the next struct without padding.
+// The Encoder holds the bits of an incomplete last byte until it is completed
+// by the next struct or written by [Encoder.Flush].
+type Encoder struct {
+	w       io.Writer
+	options options
+	err     error // error from the options or the writer, returned by Encode and Flush
+	bw      bitWriter
+	pending int // number of structs encoded since the last write
+}
+
+// errEncoderClosed is returned by an Encoder after [Encoder.Close].
+var errEncoderClosed = errors.New("bitfield: encoder is closed")
+
+// NewEncoder returns a new encoder that writes to w. The opts are applied to
+// every call of [Encoder.Encode] as in [Marshal]. Fields with an auto tag
+// count the structs encoded, in a [Sequence] of the Encoder unless one is given
+// by [WithSequence].
+func NewEncoder(w io.Writer, opts ...Option) *Encoder {
+	options, err := collectOptions(opts)
+	if options.sequence == nil {
+		options.sequence = new(Sequence)
+	}
+	return &Encoder{w: w, options: options, err: err, bw: bitWriter{bitOrder: options.bitOrder, wordSize: options.wordSize}}
+}
+
+// Encode writes the encoding of v to the stream, following the bits written by
+// the previous call. Complete bytes are written to the underlying writer
+// before Encode returns, or after every batch of structs with
+// [WithBatchSize].
+//
+// Plain integer fields start from the next byte of the stream, not of the
+// struct. Call [Encoder.Flush] before Encode to start a struct from the next
+// byte.
+//
+// Returns:
+//
+//   - nil if v is successfully encoded
+//   - any error returned by the writer
+//   - the same errors as [Marshal]; nothing is written
+func (e *Encoder) Encode(v any) error {
+	if e.err != nil {
+		return e.err
+	}
+	rv, err := marshaledValue(v)
+	if err != nil {
+		return err
+	}
+	if err := validateMarshalType(rv.Type(), e.options); err != nil {
+		return err
+	}
+	size, iData, iBitInData := len(e.bw.data), e.bw.iData, e.bw.iBitInData
+	var partial byte
+	if iBitInData > 0 {
+		partial = e.bw.data[iData]
+	}
+	e.bw.autos = e.bw.autos[:0]
+	if err := marshal(&e.bw, addressable(rv, e.options), "", true, e.options); err != nil {
+		// Discard the bits of the failed struct
+		e.bw.data, e.bw.iData, e.bw.iBitInData = e.bw.data[:size], iData, iBitInData
+		if iBitInData > 0 {
+			e.bw.data[iData] = partial
+		}
+		return err
+	}
+	e.bw.alignToWord()
+	e.options.sequence.advance(e.bw.autos)
+	if e.pending++; e.pending < max(e.options.batch, 1) {
+		return nil
+	}
+	return e.write(e.bw.iData)
+}
+
+// Flush writes the structs of an incomplete batch, and the bits of an
+// incomplete last byte, if any, padded with zeros. The next struct is encoded
+// from the next byte.
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	e.bw.alignToByte()
+	return e.write(e.bw.iData)
+}
+
+// Close flushes the Encoder as [Encoder.Flush] does. Encode and Flush return
+// an error after Close. Close does not close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.err == errEncoderClosed {
+		return nil
+	}
+	err := e.Flush()
+	if err == nil {
+		e.err = errEncoderClosed
+	}
+	return err
+}
+
+// write writes the first n bytes of the buffer and keeps the rest.
+func (e *Encoder) write(n int) error {
+	e.pending = 0
+	if n == 0 {
+		return nil
+	}
+	_, err := e.w.Write(e.bw.data[:n])
+	rest := copy(e.bw.data, e.bw.data[n:])
+	e.bw.data = e.bw.data[:rest]
+	e.bw.iData -= n
+	if err != nil {
+		e.err = err
+	}
+	return err
+}
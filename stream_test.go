@@ -0,0 +1,462 @@
+package bitfield
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoder(t *testing.T) {
+	// Setup
+	type header struct {
+		A uint8 `bit:"4"`
+		B uint8 `bit:"4"`
+		C uint16
+	}
+	type trailer struct {
+		D uint8 `bit:"3"`
+	}
+	r := bytes.NewReader([]byte{0x21, 0x12, 0x34, 0x43, 0x65, 0x87, 0x05, 0xFF})
+	d := NewDecoder(r, WithByteOrder(BigEndian))
+
+	// Exercise
+	var got1, got2 header
+	var got3 trailer
+	err1 := d.Decode(&got1)
+	err2 := d.Decode(&got2)
+	err3 := d.Decode(&got3)
+
+	// Verify
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Nil(t, err3)
+	assert.Equal(t, header{A: 1, B: 2, C: 0x1234}, got1)
+	assert.Equal(t, header{A: 3, B: 4, C: 0x6587}, got2)
+	assert.Equal(t, trailer{D: 5}, got3)
+	assert.Equal(t, 1, r.Len())
+}
+
+func TestDecoderError(t *testing.T) {
+	// Setup
+	type a struct{ A uint16 }
+	testCases := map[string]struct {
+		argData []byte
+		argOut  any
+		want    error
+		wantLen int
+	}{
+		"EOF": {
+			argData: []byte{},
+			argOut:  &a{},
+			want:    io.EOF,
+		},
+		"Unexpected EOF": {
+			argData: []byte{0x01},
+			argOut:  &a{},
+			want:    io.ErrUnexpectedEOF,
+		},
+		"Invalid type": {
+			argData: []byte{0x01, 0x02},
+			argOut:  a{},
+			want:    &TypeError{},
+			wantLen: 2,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Setup
+			r := bytes.NewReader(tc.argData)
+
+			// Exercise
+			err := NewDecoder(r).Decode(tc.argOut)
+
+			// Verify
+			if _, ok := tc.want.(*TypeError); ok {
+				assert.IsType(t, tc.want, err)
+			} else {
+				assert.ErrorIs(t, err, tc.want)
+			}
+			assert.Equal(t, tc.wantLen, r.Len())
+		})
+	}
+}
+
+func TestDecoder_Close(t *testing.T) {
+	// Setup
+	type a struct{ A uint16 }
+	type framed struct {
+		Length uint8
+		A      uint16
+	}
+	testCases := map[string]struct {
+		argData []byte
+		argOut  any
+		argOpts []Option
+		want    []error
+		wantErr string
+	}{
+		"CleanBoundary": {
+			argData: []byte{0x01, 0x02},
+			argOut:  &a{},
+			want:    []error{nil, io.EOF, io.EOF},
+		},
+		"MidRecord": {
+			argData: []byte{0x01, 0x02, 0x03},
+			argOut:  &a{},
+			want:    []error{nil, io.ErrUnexpectedEOF, io.ErrUnexpectedEOF},
+			wantErr: "1 bytes of incomplete struct not decoded",
+		},
+		"MidFrame": {
+			argData: []byte{0x02, 0x01},
+			argOut:  &framed{},
+			argOpts: []Option{WithFrameLength("Length", 0)},
+			want:    []error{io.ErrUnexpectedEOF},
+			wantErr: "2 bytes of incomplete struct not decoded",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Setup
+			d := NewDecoder(bytes.NewReader(tc.argData), tc.argOpts...)
+
+			// Exercise
+			var got []error
+			for range tc.want {
+				got = append(got, d.Decode(tc.argOut))
+			}
+			errClose := d.Close()
+			errDecode := d.Decode(tc.argOut)
+
+			// Verify
+			assert.Equal(t, tc.want, got)
+			if tc.wantErr == "" {
+				assert.Nil(t, errClose)
+			} else {
+				assert.ErrorIs(t, errClose, io.ErrUnexpectedEOF)
+				assert.ErrorContains(t, errClose, tc.wantErr)
+			}
+			assert.NotNil(t, errDecode)
+			assert.Nil(t, d.Close())
+		})
+	}
+}
+
+func TestDecoder_WithScratch(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8 `bit:"4"`
+		B Raw   `bit:"12"`
+	}
+	data := bytes.Repeat([]byte{0x21, 0x43}, 20)
+	r := bytes.NewReader(data)
+	var scratch Scratch
+	d := NewDecoder(r, WithScratch(&scratch))
+	var got a
+
+	// Exercise
+	err := d.Decode(&got)
+	allocs := testing.AllocsPerRun(10, func() {
+		scratch.Reset()
+		_ = d.Decode(&got)
+	})
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, a{A: 1, B: Raw{0x32, 0x04}}, got)
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestEncoder(t *testing.T) {
+	// Setup
+	type nibble struct {
+		A uint8 `bit:"4"`
+	}
+	type word struct{ B uint16 }
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, WithByteOrder(BigEndian))
+
+	// Exercise
+	err1 := e.Encode(nibble{A: 0x1})
+	len1 := buf.Len()
+	err2 := e.Encode(&nibble{A: 0x2})
+	err3 := e.Encode(nibble{A: 0x3})
+	err4 := e.Encode(word{B: 0x1234})
+	err5 := e.Encode(nibble{A: 0x4})
+	err6 := e.Flush()
+	err7 := e.Encode(nibble{A: 0x5})
+	err8 := e.Flush()
+
+	// Verify
+	for _, err := range []error{err1, err2, err3, err4, err5, err6, err7, err8} {
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 0, len1)
+	assert.Equal(t, []byte{0x21, 0x03, 0x12, 0x34, 0x04, 0x05}, buf.Bytes())
+}
+
+func TestEncoder_AutoTag(t *testing.T) {
+	// Setup
+	type frame struct {
+		Seq     uint8 `bit:"4" auto:"increment"`
+		Command uint8 `bit:"4"`
+	}
+	var buf, bufSeq bytes.Buffer
+	e := NewEncoder(&buf)
+	var seq Sequence
+	eSeq := NewEncoder(&bufSeq, WithSequence(&seq))
+
+	// Exercise
+	err1 := e.Encode(frame{Command: 1})
+	err2 := e.Encode(frame{Command: 16})
+	err3 := e.Encode(frame{Command: 2})
+	err4 := eSeq.Encode(frame{Command: 3})
+	data, err5 := Marshal(frame{Command: 4}, WithSequence(&seq))
+
+	// Verify
+	assert.Nil(t, err1)
+	assert.NotNil(t, err2)
+	assert.Nil(t, err3)
+	assert.Equal(t, []byte{0x10, 0x21}, buf.Bytes())
+	assert.Nil(t, err4)
+	assert.Equal(t, []byte{0x30}, bufSeq.Bytes())
+	assert.Nil(t, err5)
+	assert.Equal(t, []byte{0x41}, data)
+}
+
+func TestEncoder_WithBitOrder(t *testing.T) {
+	// Setup
+	type nibble struct {
+		A uint8 `bit:"4"`
+	}
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, WithBitOrder(MSBFirst))
+
+	// Exercise
+	err1 := e.Encode(nibble{A: 0x1})
+	err2 := e.Encode(nibble{A: 0x2})
+	err3 := e.Encode(nibble{A: 0x3})
+	err4 := e.Flush()
+
+	// Verify
+	for _, err := range []error{err1, err2, err3, err4} {
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []byte{0x12, 0x30}, buf.Bytes())
+}
+
+func TestEncoder_WithWordSize(t *testing.T) {
+	// Setup
+	type nibble struct {
+		A uint8 `bit:"4"`
+	}
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, WithByteOrder(BigEndian), WithWordSize(16))
+	var got nibble
+
+	// Exercise
+	err1 := e.Encode(nibble{A: 0x1})
+	err2 := e.Encode(nibble{A: 0x2})
+	d := NewDecoder(bytes.NewReader(buf.Bytes()), WithByteOrder(BigEndian), WithWordSize(16))
+	err3 := d.Decode(&got)
+	err4 := d.Decode(&got)
+
+	// Verify
+	for _, err := range []error{err1, err2, err3, err4} {
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []byte{0x00, 0x01, 0x00, 0x02}, buf.Bytes())
+	assert.Equal(t, nibble{A: 0x2}, got)
+}
+
+func TestEncoder_WithBatchSize(t *testing.T) {
+	// Setup
+	type word struct{ A uint16 }
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, WithBatchSize(3))
+
+	// Exercise
+	err1 := e.Encode(word{A: 0x0201})
+	err2 := e.Encode(word{A: 0x0403})
+	len2 := buf.Len()
+	err3 := e.Encode(word{A: 0x0605})
+	len3 := buf.Len()
+	err4 := e.Encode(word{A: 0x0807})
+	len4 := buf.Len()
+	errClose := e.Close()
+	errEncode := e.Encode(word{A: 0x0A09})
+	errCloseAgain := e.Close()
+
+	// Verify
+	for _, err := range []error{err1, err2, err3, err4, errClose, errCloseAgain} {
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 0, len2)
+	assert.Equal(t, 6, len3)
+	assert.Equal(t, 6, len4)
+	assert.NotNil(t, errEncode)
+	assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}, buf.Bytes())
+}
+
+func TestEncoder_Close(t *testing.T) {
+	// Setup
+	type nibble struct {
+		A uint8 `bit:"4"`
+	}
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+
+	// Exercise
+	err1 := e.Encode(nibble{A: 0x1})
+	errClose := e.Close()
+	errFlush := e.Flush()
+
+	// Verify
+	assert.Nil(t, err1)
+	assert.Nil(t, errClose)
+	assert.NotNil(t, errFlush)
+	assert.Equal(t, []byte{0x01}, buf.Bytes())
+}
+
+func TestEncoder_WithBatchSizeError(t *testing.T) {
+	// Setup
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, WithBatchSize(0))
+
+	// Exercise
+	err := e.Encode(struct{ A uint8 }{})
+
+	// Verify
+	assert.ErrorContains(t, err, "batch size must be positive")
+}
+
+func TestEncoderError(t *testing.T) {
+	// Setup
+	type a struct {
+		A uint8 `bit:"4"`
+	}
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+
+	// Exercise
+	err1 := e.Encode(a{A: 0x1})
+	errOverflow := e.Encode(a{A: 0x10})
+	errType := e.Encode(1)
+	err2 := e.Encode(a{A: 0x2})
+
+	// Verify
+	assert.Nil(t, err1)
+	assert.IsType(t, &OverflowError{}, errOverflow)
+	assert.IsType(t, &TypeError{}, errType)
+	assert.Nil(t, err2)
+	assert.Equal(t, []byte{0x21}, buf.Bytes())
+}
+
+func TestDecoder_WithFrameLength(t *testing.T) {
+	// Setup
+	type Header struct {
+		Kind   uint8  `bit:"4"`
+		Length uint16 `bit:"12"`
+	}
+	type message struct {
+		Header
+		A uint16
+		B Raw `bit:"16"`
+	}
+	data := []byte{
+		0x41, 0x00, 0x34, 0x12, // Length=4: header and A
+		0x51, 0x00, 0x34, 0x12, 0x56, // Length=5: header, A and a byte of B
+		0x71, 0x00, 0x34, 0x12, 0x56, 0x78, 0x9A, // Length=7: header, A, B and a skipped byte
+		0xFF,
+	}
+	r := bytes.NewReader(data)
+	d := NewDecoder(r, WithFrameLength("Length", -2))
+
+	// Exercise
+	var got1, got2, got3 message
+	err1 := d.Decode(&got1)
+	err2 := d.Decode(&got2)
+	err3 := d.Decode(&got3)
+
+	// Verify
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Nil(t, err3)
+	assert.Equal(t, message{Header: Header{Kind: 1, Length: 4}, A: 0x1234, B: Raw{0x00, 0x00}}, got1)
+	assert.Equal(t, message{Header: Header{Kind: 1, Length: 5}, A: 0x1234, B: Raw{0x56, 0x00}}, got2)
+	assert.Equal(t, message{Header: Header{Kind: 1, Length: 7}, A: 0x1234, B: Raw{0x56, 0x78}}, got3)
+	assert.Equal(t, 1, r.Len())
+}
+
+func TestDecoder_Slice(t *testing.T) {
+	// Setup
+	type entry struct {
+		Kind uint8
+	}
+	type message struct {
+		Length  uint8
+		N       uint8
+		Entries []*entry `count:"N"`
+	}
+	data := []byte{0x03, 0x02, 0x01, 0x02, 0xFF}
+	r := bytes.NewReader(data)
+
+	// Exercise
+	var got message
+	errUnframed := NewDecoder(r).Decode(&got)
+	err := NewDecoder(r, WithFrameLength("Length", 0)).Decode(&got)
+
+	// Verify
+	var fieldError *FieldError
+	assert.ErrorAs(t, errUnframed, &fieldError)
+	assert.Nil(t, err)
+	assert.Equal(t, message{Length: 3, N: 2, Entries: []*entry{{Kind: 1}, {Kind: 2}}}, got)
+	assert.Equal(t, 1, r.Len())
+}
+
+func TestDecoder_WithFrameLengthError(t *testing.T) {
+	// Setup
+	type message struct {
+		Length int8
+		A      uint8
+	}
+	testCases := map[string]struct {
+		argData  []byte
+		argField string
+		want     error
+	}{
+		"Unknown field": {
+			argData:  []byte{0x01, 0x00},
+			argField: "Size",
+			want:     &FrameError{},
+		},
+		"Negative length": {
+			argData:  []byte{0xFF, 0x00},
+			argField: "Length",
+			want:     &FrameError{},
+		},
+		"Truncated body": {
+			argData:  []byte{0x02, 0x00},
+			argField: "Length",
+			want:     io.ErrUnexpectedEOF,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			var out message
+			err := NewDecoder(bytes.NewReader(tc.argData), WithFrameLength(tc.argField, 0)).Decode(&out)
+
+			// Verify
+			if _, ok := tc.want.(*FrameError); ok {
+				assert.IsType(t, tc.want, err)
+			} else {
+				assert.ErrorIs(t, err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package bitfield
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	// variants maps an interface type to its variants, a map from the value
+	// of the discriminator to the type registered by RegisterVariant.
+	variants   sync.Map
+	variantsMu sync.Mutex // serializes RegisterVariant
+)
+
+// RegisterVariant registers the type of v as the variant of the interface
+// type I decoded for the value key of the discriminator. A field of type I
+// with a struct tag "switch" naming a preceding integer field of the same
+// struct, the discriminator, holds the type-specific body of a message:
+//
+//	type Body interface{ isBody() }
+//
+//	type Ping struct{ Seq uint16 }
+//	type Data struct {
+//		Length  uint8
+//		Payload []byte `len:"Length"`
+//	}
+//
+//	func init() {
+//		bitfield.RegisterVariant[Body](1, Ping{})
+//		bitfield.RegisterVariant[Body](2, &Data{})
+//	}
+//
+//	type Packet struct {
+//		Type uint8
+//		Body Body `switch:"Type"`
+//	}
+//
+// Unmarshal decodes the field into a new value of the variant registered for
+// the decoded discriminator, following the preceding fields as a nested
+// struct, and returns [VariantError] if no variant is registered. Marshal
+// encodes the value of the field, which must be of the variant registered for
+// the discriminator. A variant may be a struct or a pointer to a struct, as
+// given by v.
+//
+// RegisterVariant panics if I is not an interface type, or if v is neither a
+// struct nor a pointer to a struct. Only the type of v is used. Registering a
+// variant for a key again replaces it. It is meant to be called from init
+// functions, but is safe for concurrent use.
+func RegisterVariant[I any](key uint64, v I) {
+	iface := reflect.TypeOf((*I)(nil)).Elem()
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("bitfield: RegisterVariant requires interface type, not %v", iface))
+	}
+	rt := reflect.TypeOf(v)
+	if rt == nil || !(rt.Kind() == reflect.Struct || rt.Kind() == reflect.Pointer && rt.Elem().Kind() == reflect.Struct) {
+		panic(fmt.Sprintf("bitfield: RegisterVariant requires struct or pointer to struct, not %T", v))
+	}
+	variantsMu.Lock()
+	defer variantsMu.Unlock()
+	set := map[uint64]reflect.Type{}
+	if old, ok := variants.Load(iface); ok {
+		for k, t := range old.(map[uint64]reflect.Type) {
+			set[k] = t
+		}
+	}
+	set[key] = rt
+	variants.Store(iface, set)
+}
+
+// variantOf returns the variant of an interface type registered for key.
+func variantOf(iface reflect.Type, key uint64) (reflect.Type, bool) {
+	set, ok := variants.Load(iface)
+	if !ok {
+		return nil, false
+	}
+	rt, ok := set.(map[uint64]reflect.Type)[key]
+	return rt, ok
+}
+
+// isSwitch reports whether the field holds a variant selected by a
+// discriminator.
+func isSwitch(field reflect.StructField) bool {
+	_, ok := field.Tag.Lookup("switch")
+	return ok
+}
+
+// switchIndex returns the index of the discriminator of a switch field.
+func switchIndex(rt reflect.Type, field reflect.StructField) int {
+	name := field.Tag.Get("switch")
+	for i := 0; i < rt.NumField(); i++ {
+		if rt.Field(i).Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// validateSwitch validates a switch field, the i-th field of rt. The
+// discriminator must be an integer field declared before it in the same
+// struct. The variants are validated when they are decoded or encoded, as they
+// may be registered later.
+func validateSwitch(rt reflect.Type, i int) error {
+	field := rt.Field(i)
+	if field.Type.Kind() != reflect.Interface {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "switch field must be interface type",
+		}
+	}
+	for _, tag := range []string{"bit", "bytes", "count", "len", "tlv", "offset", "skip"} {
+		if _, ok := field.Tag.Lookup(tag); ok {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "switch and " + tag + " tags must not be used together",
+			}
+		}
+	}
+	j := switchIndex(rt, field)
+	if j < 0 || j > i || !isCountType(rt.Field(j)) {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "switch must name preceding integer field",
+		}
+	}
+	return nil
+}
+
+// variantStruct returns the struct type of a variant, validated with the
+// options.
+func variantStruct(rt reflect.Type, options options) (reflect.Type, error) {
+	if rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	return rt, validateStruct(rt, options)
+}
+
+// unmarshalVariant reads the variant of a switch field selected by the value
+// key of its discriminator. exported reports whether the field can be set.
+func (r *bitReader) unmarshalVariant(field reflect.StructField, vf reflect.Value, prefix string, key uint64, exported bool, options options) error {
+	rt, ok := variantOf(field.Type, key)
+	if !ok {
+		return &VariantError{Field: field, Path: prefix + field.Name, Value: key}
+	}
+	st, err := variantStruct(rt, options)
+	if err != nil {
+		return err
+	}
+	p := reflect.New(st)
+	if err := r.unmarshalNested(p.Elem(), prefix+field.Name+".", prefix+field.Name, exported, options); err != nil {
+		return err
+	}
+	if !exported {
+		return nil
+	}
+	if rt.Kind() == reflect.Pointer {
+		vf.Set(p)
+	} else {
+		vf.Set(p.Elem())
+	}
+	return nil
+}
+
+// marshalVariant writes the variant held by a switch field, which must be the
+// variant registered for the value key of its discriminator. accessible
+// reports whether the field can be read; nothing is written otherwise.
+func marshalVariant(w *bitWriter, field reflect.StructField, vf reflect.Value, prefix string, key uint64, accessible bool, options options) error {
+	if !accessible {
+		return nil
+	}
+	rt, ok := variantOf(field.Type, key)
+	if vf.IsNil() || !ok || vf.Elem().Type() != rt || rt.Kind() == reflect.Pointer && vf.Elem().IsNil() {
+		err := &VariantError{Field: field, Path: prefix + field.Name, Value: key, encoding: true}
+		if !vf.IsNil() {
+			err.Type = vf.Elem().Type()
+		}
+		return err
+	}
+	if _, err := variantStruct(rt, options); err != nil {
+		return err
+	}
+	ev := vf.Elem()
+	if ev.Kind() == reflect.Pointer {
+		ev = ev.Elem()
+	}
+	return marshalNested(w, ev, prefix+field.Name+".", prefix+field.Name, true, options)
+}
@@ -0,0 +1,190 @@
+package bitfield
+
+import (
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TLV is an entry of a sequence of type-length-value records, held by a field
+// of type []TLV with a struct tag "tlv". Value holds the number of bytes given
+// by the length of the entry. The application decodes Value according to
+// Type, for example with [Unmarshal] into the struct of each type of option.
+type TLV struct {
+	Type  uint64
+	Value []byte
+}
+
+var tlvSliceType = reflect.TypeOf([]TLV(nil))
+
+// isTLV reports whether the field is a sequence of TLV entries.
+func isTLV(field reflect.StructField) bool {
+	_, ok := field.Tag.Lookup("tlv")
+	return ok
+}
+
+// tlvTag returns the numbers of bytes of the type and the length of each
+// entry, given by a tlv tag such as tlv:"type=uint8,len=uint16". ok is false
+// if the tag is invalid.
+func tlvTag(field reflect.StructField) (typeSize, lenSize int, ok bool) {
+	typeName, lenName, found := strings.Cut(field.Tag.Get("tlv"), ",")
+	if !found {
+		return 0, 0, false
+	}
+	typeName, typeFound := strings.CutPrefix(typeName, "type=")
+	lenName, lenFound := strings.CutPrefix(lenName, "len=")
+	if !typeFound || !lenFound {
+		return 0, 0, false
+	}
+	typeSize, lenSize = uintSize(typeName), uintSize(lenName)
+	return typeSize, lenSize, typeSize > 0 && lenSize > 0
+}
+
+// uintSize returns the number of bytes of an unsigned integer type named
+// name, or 0 if there is no such type.
+func uintSize(name string) int {
+	switch name {
+	case "uint8":
+		return 1
+	case "uint16":
+		return 2
+	case "uint32":
+		return 4
+	case "uint64":
+		return 8
+	default:
+		return 0
+	}
+}
+
+// validateTLV validates a sequence of TLV entries, the i-th field of rt. As
+// the entries extend to the end of the input, it must be the last field, and
+// words larger than a byte, whose padding would be read as entries, are
+// rejected.
+func validateTLV(rt reflect.Type, i int, options options) error {
+	field := rt.Field(i)
+	if field.Type != tlvSliceType {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "tlv field must be []bitfield.TLV",
+		}
+	}
+	if _, _, ok := tlvTag(field); !ok {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "tlv must be given as type=uintN,len=uintN",
+		}
+	}
+	for _, tag := range []string{"bit", "bytes", "count", "len", "offset", "skip"} {
+		if _, ok := field.Tag.Lookup(tag); ok {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "tlv and " + tag + " tags must not be used together",
+			}
+		}
+	}
+	if i != rt.NumField()-1 {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "tlv field must be last field of struct",
+		}
+	}
+	if options.wordSize > 8 {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "tlv field does not apply with words larger than a byte",
+		}
+	}
+	return nil
+}
+
+// unmarshalTLVs reads TLV entries from the next byte to the end of the input.
+// The type and the length of each entry are in the byte order of the field.
+func (r *bitReader) unmarshalTLVs(field reflect.StructField, vf reflect.Value, prefix string, exported bool, options options) error {
+	typeSize, lenSize, _ := tlvTag(field)
+	byteOrder := fieldByteOrder(field, options.byteOrder)
+	r.alignToByte()
+	offset := r.iData * 8
+	var entries []TLV
+	for remaining := max(r.nbits-r.iData*8, 0) / 8; remaining > 0; remaining = max(r.nbits-r.iData*8, 0) / 8 {
+		if remaining < typeSize+lenSize {
+			return &LengthError{
+				Field:   field,
+				Path:    prefix + field.Name,
+				problem: "header of entry " + strconv.Itoa(len(entries)) + " exceeds the rest of the input",
+			}
+		}
+		typ := r.readValue(typeSize*8, byteOrder)
+		length := r.readValue(lenSize*8, byteOrder)
+		if length > uint64(remaining-typeSize-lenSize) {
+			return &LengthError{
+				Field:   field,
+				Path:    prefix + field.Name,
+				problem: "length " + strconv.FormatUint(length, 10) + " of entry " + strconv.Itoa(len(entries)) + " exceeds the rest of the input",
+			}
+		}
+		entry := TLV{Type: typ}
+		if length > 0 {
+			entry.Value = r.readRaw(int(length)*8, options.scratch)
+		}
+		entries = append(entries, entry)
+	}
+	if options.logger != nil {
+		logField(options.logger, prefix+field.Name, offset, r.iData*8-offset, r.nbits, slog.Any("value", entries))
+	}
+	if exported {
+		vf.Set(reflect.ValueOf(entries))
+	}
+	return nil
+}
+
+// marshalTLVs writes TLV entries from the next byte. accessible reports
+// whether the field can be read.
+func marshalTLVs(w *bitWriter, field reflect.StructField, vf reflect.Value, prefix string, accessible bool, options options) error {
+	w.alignToByte()
+	if !accessible {
+		return nil
+	}
+	typeSize, lenSize, _ := tlvTag(field)
+	byteOrder := fieldByteOrder(field, options.byteOrder)
+	for i := 0; i < vf.Len(); i++ {
+		entry := vf.Index(i).Interface().(TLV)
+		if typeSize < 8 && entry.Type>>(typeSize*8) != 0 {
+			return &OverflowError{
+				Field: field,
+				Path:  prefix + field.Name + "[" + strconv.Itoa(i) + "].Type",
+				Value: entry.Type,
+			}
+		}
+		if lenSize < 8 && uint64(len(entry.Value))>>(lenSize*8) != 0 {
+			return &LengthError{
+				Field:   field,
+				Path:    prefix + field.Name,
+				problem: "value of " + strconv.Itoa(len(entry.Value)) + " bytes of entry " + strconv.Itoa(i) + " exceeds " + strconv.Itoa(lenSize) + "-byte length",
+			}
+		}
+		w.writeValue(entry.Type, typeSize*8, byteOrder)
+		w.writeValue(uint64(len(entry.Value)), lenSize*8, byteOrder)
+		if len(entry.Value) > 0 {
+			w.writeRaw(entry.Value, len(entry.Value)*8)
+		}
+	}
+	return nil
+}
+
+// tlvEnd returns the offset following the TLV entries of a field value
+// starting at offset.
+func tlvEnd(field reflect.StructField, vf reflect.Value, offset int) int {
+	typeSize, lenSize, _ := tlvTag(field)
+	offset = (offset + 7) / 8 * 8
+	for i := 0; i < vf.Len(); i++ {
+		offset += (typeSize + lenSize + len(vf.Index(i).Interface().(TLV).Value)) * 8
+	}
+	return offset
+}
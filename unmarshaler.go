@@ -0,0 +1,70 @@
+package bitfield
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// BitUnmarshaler is implemented by field types which decode their own bits,
+// such as fixed-point numbers or timestamps. If a pointer to the type of a
+// field with a bit tag implements it, Unmarshal calls UnmarshalBits of the
+// field instead of decoding the bits as an integer.
+//
+// data is the whole input, and the bits of the field are the bitLen bits
+// starting at bitOff, counted as in [FieldLayout]. The bits may extend beyond
+// the end of data if the input is short, in which case the missing bits are
+// to be treated as zero. UnmarshalBits must not retain data.
+//
+// A field of a non-integer type implementing BitUnmarshaler may have any
+// positive bit size. Unless the type also implements [BitMarshaler], such a
+// field cannot be encoded by Marshal, which returns [FieldError] for it.
+// [DecodeHook] is not called for such fields.
+type BitUnmarshaler interface {
+	UnmarshalBits(data []byte, bitOff, bitLen int) error
+}
+
+var bitUnmarshalerType = reflect.TypeOf((*BitUnmarshaler)(nil)).Elem()
+
+// bitUnmarshalers caches whether a pointer to a type implements
+// BitUnmarshaler.
+var bitUnmarshalers sync.Map
+
+// isBitUnmarshaler reports whether a pointer to rt implements BitUnmarshaler.
+func isBitUnmarshaler(rt reflect.Type) bool {
+	if ok, found := bitUnmarshalers.Load(rt); found {
+		return ok.(bool)
+	}
+	ok := reflect.PointerTo(rt).Implements(bitUnmarshalerType)
+	bitUnmarshalers.Store(rt, ok)
+	return ok
+}
+
+// Validator is implemented by structs which check their own invariants, such
+// as supported version numbers or mutually exclusive flags. If a pointer to
+// the struct passed to Unmarshal, or to a nested struct or an element of a
+// slice of pointers to structs, implements it, Unmarshal calls Validate after
+// decoding the fields of the struct, and returns the error of Validate
+// wrapped with the path of the struct.
+//
+// Nested structs are validated before the structs holding them. Structs whose
+// fields are not stored, such as unexported nested structs, are not
+// validated.
+type Validator interface {
+	Validate() error
+}
+
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// validate calls Validate of a decoded struct whose path is given by prefix.
+func validate(rv reflect.Value, prefix string) error {
+	if err := rv.Addr().Interface().(Validator).Validate(); err != nil {
+		name := strings.TrimSuffix(prefix, ".")
+		if name == "" {
+			name = rv.Type().String()
+		}
+		return fmt.Errorf("bitfield: Validate failed for %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package bitfield
+
+import "reflect"
+
+// validateUUIDTag validates the uuid tag of a field, if any, which gives the
+// byte order of a 16-byte identifier: "rfc4122" for the order of RFC 4122, in
+// which the bytes are as stored, or "guid" for the mixed-endian order of
+// Microsoft GUIDs.
+func validateUUIDTag(field reflect.StructField) error {
+	tag, ok := field.Tag.Lookup("uuid")
+	if !ok {
+		return nil
+	}
+	if field.Type.Kind() != reflect.Array || field.Type.Len() != 16 || field.Type.Elem().Kind() != reflect.Uint8 {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "uuid tag requires [16]byte field",
+		}
+	}
+	for _, other := range []string{"bit", "bytes"} {
+		if _, ok := field.Tag.Lookup(other); ok {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "uuid and " + other + " tags must not be used together",
+			}
+		}
+	}
+	if tag != "rfc4122" && tag != "guid" {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "uuid must be rfc4122 or guid",
+		}
+	}
+	return nil
+}
+
+// isGUID reports whether the field is a GUID, whose first three groups are
+// little-endian.
+func isGUID(field reflect.StructField) bool {
+	return field.Tag.Get("uuid") == "guid"
+}
+
+// swapGUID converts the bytes of a GUID between the order of RFC 4122 and
+// the mixed-endian order, by reversing the bytes of the first three groups.
+func swapGUID(b []byte) {
+	b[0], b[1], b[2], b[3] = b[3], b[2], b[1], b[0]
+	b[4], b[5] = b[5], b[4]
+	b[6], b[7] = b[7], b[6]
+}
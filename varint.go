@@ -0,0 +1,205 @@
+package bitfield
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math/bits"
+	"reflect"
+)
+
+// isVarint reports whether the field is an integer encoded in a variable
+// number of bytes.
+func isVarint(field reflect.StructField) bool {
+	_, ok := field.Tag.Lookup("varint")
+	return ok
+}
+
+// validateVarint validates a varint field, the i-th field of rt. The
+// encoding is "leb128" (unsigned LEB128, as varints of Protocol Buffers),
+// "zigzag" (LEB128 of the zigzag encoding of a signed integer, as sint32 and
+// sint64 of Protocol Buffers) or "quic" (the variable-length integers of QUIC
+// in RFC 9000).
+func validateVarint(rt reflect.Type, i int) error {
+	field := rt.Field(i)
+	if !isFixedInteger(field.Type.Kind()) {
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "varint field must be integer",
+		}
+	}
+	switch field.Tag.Get("varint") {
+	case "leb128":
+	case "zigzag":
+		if !isSignedInteger(field.Type.Kind()) {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "zigzag varint requires signed integer field",
+			}
+		}
+	case "quic":
+		if isSignedInteger(field.Type.Kind()) {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "quic varint requires unsigned integer field",
+			}
+		}
+	default:
+		return &FieldError{
+			Field:   field,
+			Path:    field.Name,
+			problem: "varint must be leb128, zigzag or quic",
+		}
+	}
+	for _, tag := range []string{"bit", "bytes", "endian", "const", "default", "check", "offset"} {
+		if _, ok := field.Tag.Lookup(tag); ok {
+			return &FieldError{
+				Field:   field,
+				Path:    field.Name,
+				problem: "varint and " + tag + " tags must not be used together",
+			}
+		}
+	}
+	return validateEnumTag(field)
+}
+
+// unmarshalVarint reads a varint field from the next byte.
+func (r *bitReader) unmarshalVarint(f *fieldPlan, vf reflect.Value, prefix string, exported bool, options options) error {
+	r.alignToByte()
+	offset := r.iData * 8
+	val, n, err := r.readVarint(f.Tag.Get("varint"))
+	if err != nil {
+		return err
+	}
+	if options.logger != nil {
+		logField(options.logger, prefix+f.Name, offset, n*8, r.nbits, slog.Uint64("value", val))
+	}
+	var fits bool
+	if f.Tag.Get("varint") == "zigzag" {
+		val = uint64(int64(val>>1) ^ -int64(val&1))
+	}
+	if isSignedInteger(f.Type.Kind()) {
+		fits = f.Type.Bits() == 64 || int64(val)>>(f.Type.Bits()-1) == 0 || int64(val)>>(f.Type.Bits()-1) == -1
+	} else {
+		fits = f.Type.Bits() == 64 || val>>f.Type.Bits() == 0
+	}
+	if !fits {
+		return fmt.Errorf("bitfield: varint value %#x overflows %s", val, f.Type)
+	}
+	r.last = val
+	if options.dump != nil {
+		dumpField(options.dump, prefix+f.Name, offset, n*8, r.nbits, fmt.Sprintf("% x", r.data[offset/8:offset/8+n]), decodedValue(f.Type, val, f.Type.Bits()))
+	}
+	if !exported {
+		return nil
+	}
+	if vf.CanUint() {
+		vf.SetUint(val)
+	} else {
+		vf.SetInt(int64(val))
+	}
+	if f.enum != nil && !f.enum[enumKey(vf)] {
+		return &EnumError{Field: f.StructField, Path: prefix + f.Name, Value: vf.Interface()}
+	}
+	return checkEnum(f.StructField, vf, prefix+f.Name)
+}
+
+// readVarint reads the bytes of a varint, and returns its value and the
+// number of bytes read. The error wraps [io.ErrUnexpectedEOF] if the input
+// ends before the varint does.
+func (r *bitReader) readVarint(encoding string) (val uint64, n int, err error) {
+	remaining := max(r.nbits-r.iData*8, 0) / 8
+	if encoding == "quic" {
+		if remaining == 0 {
+			return 0, 0, fmt.Errorf("bitfield: input ends before varint: %w", io.ErrUnexpectedEOF)
+		}
+		// The two most significant bits give the length
+		n = 1 << (r.data[r.iData] >> 6)
+		if remaining < n {
+			return 0, 0, fmt.Errorf("bitfield: input ends before varint of %d bytes: %w", n, io.ErrUnexpectedEOF)
+		}
+		for i := 0; i < n; i++ {
+			val = val<<8 | uint64(r.data[r.iData+i])
+		}
+		r.iData += n
+		return val & (1<<(n*8-2) - 1), n, nil
+	}
+	for shift := 0; ; shift += 7 {
+		if n == remaining {
+			return 0, 0, fmt.Errorf("bitfield: input ends before varint: %w", io.ErrUnexpectedEOF)
+		}
+		b := r.data[r.iData+n]
+		n++
+		if shift == 63 && b > 1 {
+			return 0, 0, fmt.Errorf("bitfield: varint overflows 64 bits")
+		}
+		val |= uint64(b&0x7F) << shift
+		if b < 0x80 {
+			break
+		}
+	}
+	r.iData += n
+	return val, n, nil
+}
+
+// marshalVarint writes a varint field from the next byte. accessible reports
+// whether the field can be read.
+func marshalVarint(w *bitWriter, f *fieldPlan, vf reflect.Value, prefix string, accessible bool) error {
+	w.alignToByte()
+	var val uint64
+	if accessible {
+		if vf.CanUint() {
+			val = vf.Uint()
+		} else {
+			val = uint64(vf.Int())
+		}
+	}
+	w.last = val
+	switch f.Tag.Get("varint") {
+	case "zigzag":
+		val = uint64(int64(val)<<1 ^ int64(val)>>63)
+	case "quic":
+		if val >= 1<<62 {
+			return &OverflowError{Field: f.StructField, Path: prefix + f.Name, Value: vf.Interface()}
+		}
+		// The smallest of 1, 2, 4 and 8 bytes holding the value
+		n := 0
+		for val>>(8<<n-2) != 0 {
+			n++
+		}
+		val |= uint64(n) << (8<<n - 2)
+		for i := 8<<n - 8; i >= 0; i -= 8 {
+			w.writeValue(val>>i&0xFF, 8, BigEndian)
+		}
+		return nil
+	}
+	for ; val >= 0x80; val >>= 7 {
+		w.writeValue(val&0x7F|0x80, 8, BigEndian)
+	}
+	w.writeValue(val, 8, BigEndian)
+	return nil
+}
+
+// varintLen returns the number of bytes of the encoding of a varint field.
+func varintLen(field reflect.StructField, vf reflect.Value) int {
+	var val uint64
+	if vf.CanUint() {
+		val = vf.Uint()
+	} else {
+		val = uint64(vf.Int())
+	}
+	switch field.Tag.Get("varint") {
+	case "zigzag":
+		val = uint64(int64(val)<<1 ^ int64(val)>>63)
+	case "quic":
+		n := 1
+		for n < 8 && val>>(n*8-2) != 0 {
+			n *= 2
+		}
+		return n
+	}
+	return max((bits.Len64(val)+6)/7, 1)
+}
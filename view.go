@@ -0,0 +1,141 @@
+package bitfield
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// A View reads and writes the fields of a struct of type T encoded in a byte
+// slice in place, without decoding nor encoding the whole struct. Setting a
+// single flag of a large memory-mapped structure does not then copy the rest:
+//
+//	v, err := bitfield.NewView[Status](mapped)
+//	if err != nil {
+//		return err
+//	}
+//	err = v.SetField("Ready", 1)
+//
+// Fields are named as in the [Layout] of T, such as "Header.Version" or
+// "Addr[0]", and accessed as the bits of an integer of up to 64 bits. The
+// values of signed fields are sign-extended to 64 bits, and those of bool
+// fields are 0 or 1. Encodings given by struct tags such as enc and scale
+// are not applied, and const and check tags are not enforced.
+type View[T any] struct {
+	data   []byte
+	layout *Layout
+	fields map[string]int // index of a field in the layout by name
+}
+
+// NewView returns a view of data holding a struct of type T. T must have a
+// fixed layout, as for [LayoutOf], and data must hold all of its bits. The
+// options are the same as those for [Unmarshal].
+//
+// Returns:
+//
+//   - the view and nil if T is valid and data is long enough
+//   - an error returned by LayoutOf for T
+//   - an error wrapping [io.ErrUnexpectedEOF] if data is shorter than T
+func NewView[T any](data []byte, opts ...Option) (*View[T], error) {
+	layout, err := LayoutOf((*T)(nil), opts...)
+	if err != nil {
+		return nil, err
+	}
+	if size := (layoutEnd(layout) + 7) / 8; len(data) < size {
+		return nil, fmt.Errorf("bitfield: view of %d bytes shorter than %s of %d bytes: %w", len(data), reflect.TypeOf((*T)(nil)).Elem(), size, io.ErrUnexpectedEOF)
+	}
+	fields := make(map[string]int, len(layout.Fields))
+	for i, f := range layout.Fields {
+		fields[f.Name] = i
+	}
+	return &View[T]{data: data, layout: layout, fields: fields}, nil
+}
+
+// layoutEnd returns the number of bits of the data holding a layout, up to
+// the end of the last word if the data is grouped in words.
+func layoutEnd(layout *Layout) int {
+	if layout.WordSize > 8 {
+		return (layout.BitSize + layout.WordSize - 1) / layout.WordSize * layout.WordSize
+	}
+	return layout.BitSize
+}
+
+// Bytes returns the data of the view, which SetField modifies.
+func (v *View[T]) Bytes() []byte {
+	return v.data
+}
+
+// Layout returns the layout of T in the view.
+func (v *View[T]) Layout() *Layout {
+	return v.layout
+}
+
+// field returns the layout of the field named name.
+func (v *View[T]) field(name string) (*FieldLayout, error) {
+	i, ok := v.fields[name]
+	if !ok {
+		return nil, fmt.Errorf("bitfield: %s has no field %s", reflect.TypeOf((*T)(nil)).Elem(), name)
+	}
+	f := &v.layout.Fields[i]
+	if f.Bits > 64 {
+		return nil, fmt.Errorf("bitfield: field %s of %d bits does not fit in 64 bits", name, f.Bits)
+	}
+	return f, nil
+}
+
+// GetField returns the value of the field named name.
+func (v *View[T]) GetField(name string) (uint64, error) {
+	f, err := v.field(name)
+	if err != nil {
+		return 0, err
+	}
+	r := &bitReader{data: v.data, nbits: len(v.data) * 8, bitOrder: f.BitOrder, wordSize: v.layout.WordSize}
+	r.seek(f.Offset)
+	val := r.readValue(f.Bits, f.ByteOrder)
+	if f.Encoding == "magnitude" {
+		val = fromSignMagnitude(val, f.Bits)
+	}
+	if f.Signed {
+		val = uint64(signed(val, f.Bits))
+	}
+	return val, nil
+}
+
+// SetField sets the field named name to val, leaving the other bits of the
+// data as they are. The value of a signed field is that of an int64
+// converted to uint64. It returns an error if the value does not fit in the
+// field.
+func (v *View[T]) SetField(name string, val uint64) error {
+	f, err := v.field(name)
+	if err != nil {
+		return err
+	}
+	bits, ok := val, true
+	if f.Signed && f.Bits < 64 {
+		// The bits above the field must all be copies of its sign bit
+		ok = signed(val, f.Bits) == int64(val)
+		bits &= 1<<f.Bits - 1
+	} else if !f.Signed && f.Bits < 64 {
+		ok = val>>f.Bits == 0
+	}
+	if ok && f.Encoding == "magnitude" {
+		bits, ok = toSignMagnitude(bits, f.Bits)
+	}
+	if !ok {
+		return fmt.Errorf("bitfield: value %#x overflows field %s of %d bits", val, name, f.Bits)
+	}
+	value, mask := v.fieldBits(f, bits), v.fieldBits(f, 1<<f.Bits-1)
+	for i, m := range mask {
+		v.data[i] = v.data[i]&^m | value[i]
+	}
+	return nil
+}
+
+// fieldBits returns the bytes up to the end of a field holding val in the
+// bits of the field, and zero in the others.
+func (v *View[T]) fieldBits(f *FieldLayout, val uint64) []byte {
+	w := &bitWriter{bitOrder: f.BitOrder, wordSize: v.layout.WordSize}
+	w.seek(f.Offset)
+	w.writeValue(val, f.Bits, f.ByteOrder)
+	return w.data
+}
@@ -0,0 +1,145 @@
+package bitfield
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testViewed struct {
+	Version uint8 `bit:"4"`
+	Ready   bool  `bit:"1"`
+	_       uint8 `bit:"3"`
+	Offset  int16 `bit:"12" endian:"big"`
+	Level   int8  `bit:"4,sm"`
+	Header  struct {
+		Length uint16
+	}
+	Addr [2]uint8
+}
+
+func TestView_GetField(t *testing.T) {
+	// Setup
+	arg := testViewed{Version: 4, Ready: true, Offset: -300, Level: -3}
+	arg.Header.Length = 0x1234
+	arg.Addr = [2]uint8{10, 20}
+	data, _ := Marshal(arg)
+	testCases := map[string]int64{
+		"Version":       4,
+		"Ready":         1,
+		"Offset":        -300,
+		"Level":         -3,
+		"Header.Length": 0x1234,
+		"Addr[1]":       20,
+	}
+	v, err := NewView[testViewed](data)
+	assert.Nil(t, err)
+
+	for name, want := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			got, err := v.GetField(name)
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, want, int64(got))
+		})
+	}
+}
+
+func TestView_SetField(t *testing.T) {
+	// Setup
+	arg := testViewed{Version: 4, Offset: 100, Level: 2}
+	arg.Header.Length = 0x1234
+	arg.Addr = [2]uint8{10, 20}
+	testCases := map[string]struct {
+		value int64
+		want  func(*testViewed)
+	}{
+		"Version":       {value: 9, want: func(s *testViewed) { s.Version = 9 }},
+		"Ready":         {value: 1, want: func(s *testViewed) { s.Ready = true }},
+		"Offset":        {value: -2048, want: func(s *testViewed) { s.Offset = -2048 }},
+		"Level":         {value: -7, want: func(s *testViewed) { s.Level = -7 }},
+		"Header.Length": {value: 0xBEEF, want: func(s *testViewed) { s.Header.Length = 0xBEEF }},
+		"Addr[0]":       {value: 0, want: func(s *testViewed) { s.Addr[0] = 0 }},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			data, _ := Marshal(arg)
+			want := arg
+			tc.want(&want)
+			wantData, _ := Marshal(want)
+			v, _ := NewView[testViewed](data)
+
+			// Exercise
+			err := v.SetField(name, uint64(tc.value))
+
+			// Verify
+			assert.Nil(t, err)
+			assert.Equal(t, wantData, v.Bytes())
+			assert.Equal(t, wantData, data)
+		})
+	}
+}
+
+func TestView_SetFieldWords(t *testing.T) {
+	// Setup
+	type register struct {
+		Mode  uint8  `bit:"3"`
+		Count uint16 `bit:"10"`
+		_     uint32 `bit:"19"`
+	}
+	opts := []Option{WithWordSize(32), WithByteOrder(BigEndian), WithBitOrder(MSBFirst)}
+	data, _ := Marshal(register{Mode: 5, Count: 0x2AA}, opts...)
+	want, _ := Marshal(register{Mode: 5, Count: 0x155}, opts...)
+	v, _ := NewView[register](data, opts...)
+
+	// Exercise
+	err := v.SetField("Count", 0x155)
+
+	// Verify
+	assert.Nil(t, err)
+	assert.Equal(t, want, data)
+}
+
+func TestView_Error(t *testing.T) {
+	// Setup
+	data, _ := Marshal(testViewed{})
+	v, _ := NewView[testViewed](data)
+	testCases := map[string]func() error{
+		"UnknownGet": func() error {
+			_, err := v.GetField("Missing")
+			return err
+		},
+		"UnknownSet": func() error { return v.SetField("Missing", 0) },
+		"Overflow":   func() error { return v.SetField("Version", 16) },
+		"Signed":     func() error { return v.SetField("Offset", 2048) },
+		"Magnitude":  func() error { return v.SetField("Level", uint64(0xFFFFFFFFFFFFFFF8)) },
+	}
+
+	for name, exercise := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Exercise
+			err := exercise()
+
+			// Verify
+			assert.Error(t, err)
+			assert.Equal(t, data, make([]byte, len(data)))
+		})
+	}
+}
+
+func TestNewViewError(t *testing.T) {
+	// Exercise
+	_, errShort := NewView[testViewed](make([]byte, 3))
+	_, errVariable := NewView[struct {
+		N    uint8
+		Body []byte `count:"N"`
+	}](make([]byte, 3))
+
+	// Verify
+	assert.ErrorIs(t, errShort, io.ErrUnexpectedEOF)
+	assertFieldError("Body")(t, errVariable)
+}
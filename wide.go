@@ -0,0 +1,123 @@
+package bitfield
+
+import (
+	"math/big"
+	"reflect"
+)
+
+var bigIntType = reflect.TypeOf((*big.Int)(nil))
+
+// isWide reports whether the field is an unsigned integer of any bit size: a
+// *big.Int field, or a byte array field with a bit tag over 64 bits, holding
+// the bytes of the value in big-endian. Smaller bit tags of byte arrays apply
+// to their elements.
+func isWide(field reflect.StructField) bool {
+	if field.Type == bigIntType {
+		return true
+	}
+	if field.Type.Kind() != reflect.Array || field.Type.Elem().Kind() != reflect.Uint8 {
+		return false
+	}
+	tag, ok := field.Tag.Lookup("bit")
+	if !ok {
+		return false
+	}
+	bitSize, _, problem := bitTag(tag)
+	return problem == "" && bitSize > 64
+}
+
+// isArray reports whether the field is an array whose elements are parsed one
+// after another, unlike a byte array holding a wide integer.
+func isArray(field reflect.StructField) bool {
+	return field.Type.Kind() == reflect.Array && !isWide(field)
+}
+
+// validateWideSize validates the bit size of a wide integer field.
+func validateWideSize(field reflect.StructField, bitSize int, options options) error {
+	if options.wordSize > 8 {
+		return &FieldError{
+			Field:   field,
+			problem: "wide field does not apply with words larger than a byte",
+		}
+	}
+	if options.decodeHook != nil {
+		// The decode hook takes the bits as uint64
+		return &FieldError{
+			Field:   field,
+			problem: "wide field cannot be decoded with decode hook",
+		}
+	}
+	if field.Type == bigIntType && bitSize < 1 {
+		return &FieldError{
+			Field:   field,
+			problem: "bit size must be positive",
+		}
+	}
+	if field.Type.Kind() == reflect.Array && bitSize > field.Type.Len()*8 {
+		return &FieldError{
+			Field:   field,
+			problem: "bit size must be within range 1 to its type size",
+		}
+	}
+	return nil
+}
+
+// readBig is readValue for values of any bit size.
+func (r *bitReader) readBig(bitSize int, byteOrder ByteOrder) *big.Int {
+	val, chunkVal := new(big.Int), new(big.Int)
+	for consumedBits := 0; consumedBits < bitSize && r.hasBits(); {
+		chunk, n := r.readChunk(bitSize - consumedBits)
+		chunkVal.SetUint64(chunk)
+		if byteOrder == LittleEndian {
+			val.Or(val, chunkVal.Lsh(chunkVal, uint(consumedBits)))
+		} else {
+			val.Or(val.Lsh(val, uint(n)), chunkVal)
+		}
+		consumedBits += n
+	}
+	return val
+}
+
+// setWide sets a wide integer field to val.
+func setWide(vf reflect.Value, val *big.Int) {
+	if vf.Type() == bigIntType {
+		vf.Set(reflect.ValueOf(val))
+		return
+	}
+	val.FillBytes(vf.Slice(0, vf.Len()).Bytes())
+}
+
+// wideValue returns the value of a wide integer field. A nil *big.Int is
+// zero. ok is false if the value is negative or does not fit in bitSize bits.
+func wideValue(vf reflect.Value, bitSize int) (val *big.Int, ok bool) {
+	if vf.Type() == bigIntType {
+		val = vf.Interface().(*big.Int)
+		if val == nil {
+			return new(big.Int), true
+		}
+	} else {
+		b := make([]byte, vf.Len())
+		reflect.Copy(reflect.ValueOf(b), vf)
+		val = new(big.Int).SetBytes(b)
+	}
+	return val, val.Sign() >= 0 && val.BitLen() <= bitSize
+}
+
+// writeBig is writeValue for values of any bit size.
+func (w *bitWriter) writeBig(val *big.Int, bitSize int, byteOrder ByteOrder) {
+	chunk := new(big.Int)
+	for written := 0; written < bitSize; {
+		n := 8 - w.iBitInData
+		if bitSize-written < n {
+			n = bitSize - written
+		}
+		if byteOrder == LittleEndian {
+			chunk.Rsh(val, uint(written))
+		} else {
+			// The earlier bytes hold the more significant bits
+			chunk.Rsh(val, uint(bitSize-written-n))
+		}
+		w.writeBits(byte(chunk.Uint64()), n)
+		written += n
+	}
+}